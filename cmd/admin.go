@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// adminOpts 管理子命令的公共参数；设置 server 时通过管理接口操作，否则直接操作数据库
+var adminOpts struct {
+	server   string
+	token    string
+	username string
+	password string
+	format   string
+}
+
+// adminBackend 管理子命令的操作目标，直接操作数据库或调用运行中服务的管理接口
+type adminBackend interface {
+	ChannelList(ctx context.Context) ([]model.Channel, error)
+	ChannelEnable(ctx context.Context, id int, enabled bool) error
+	ChannelDelete(ctx context.Context, id int) error
+	GroupList(ctx context.Context) ([]model.Group, error)
+	GroupDelete(ctx context.Context, id int) error
+	APIKeyList(ctx context.Context) ([]model.APIKey, error)
+	APIKeyCreate(ctx context.Context, key *model.APIKey) error
+	APIKeyUpdate(ctx context.Context, key *model.APIKey) error
+	APIKeyDelete(ctx context.Context, id int) error
+	SettingList(ctx context.Context) ([]model.Setting, error)
+	SettingSet(ctx context.Context, setting model.Setting) error
+	Export(ctx context.Context, includeLogs, includeStats bool) (*model.DBDump, error)
+	Import(ctx context.Context, dump *model.DBDump, opts model.DBImportOptions) (*model.DBImportResult, error)
+	LogClear(ctx context.Context) error
+	Close()
+}
+
+// addAdminFlags 为管理子命令注册公共参数
+func addAdminFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&cfgFile, "config", "", "config file (default is ./data/config.json)")
+	flags.StringVar(&adminOpts.format, "format", "table", "output format: table or json")
+	addAdminConnFlags(flags)
+	cmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		log.SetLevel("error")
+	}
+}
+
+// addAdminConnFlags 注册连接运行中服务所需的参数
+func addAdminConnFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&adminOpts.server, "server", os.Getenv("OCTOPUS_SERVER_URL"), "admin API base URL, e.g. http://127.0.0.1:8080 (default is direct database access)")
+	flags.StringVar(&adminOpts.token, "token", os.Getenv("OCTOPUS_TOKEN"), "personal access token for the admin API")
+	flags.StringVar(&adminOpts.username, "username", "", "admin username, used to log in when no token is given")
+	flags.StringVar(&adminOpts.password, "password", os.Getenv("OCTOPUS_PASSWORD"), "admin password, used to log in when no token is given")
+}
+
+// newAdminBackend 根据参数创建操作目标
+func newAdminBackend(ctx context.Context) (adminBackend, error) {
+	if adminOpts.format != "" && adminOpts.format != "table" && adminOpts.format != "json" {
+		return nil, fmt.Errorf("invalid format: must be table or json")
+	}
+	if adminOpts.server != "" {
+		return newAPIBackend(ctx, adminOpts.server, adminOpts.token, adminOpts.username, adminOpts.password)
+	}
+	return newDBBackend()
+}
+
+// dbBackend 直接操作数据库，适用于服务未运行时；服务运行中时其内存缓存不会感知这些修改
+type dbBackend struct{}
+
+func newDBBackend() (*dbBackend, error) {
+	if err := conf.Load(cfgFile); err != nil {
+		return nil, err
+	}
+	if err := db.InitDB(conf.AppConfig.Database.Type, conf.AppConfig.Database.Path, conf.IsDebug()); err != nil {
+		return nil, fmt.Errorf("database init error: %w", err)
+	}
+	if err := op.InitCache(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cache init error: %w", err)
+	}
+	return &dbBackend{}, nil
+}
+
+func (b *dbBackend) ChannelList(ctx context.Context) ([]model.Channel, error) {
+	return op.ChannelList(ctx)
+}
+
+func (b *dbBackend) ChannelEnable(ctx context.Context, id int, enabled bool) error {
+	if ch, err := op.ChannelGet(id, ctx); err == nil && ch.Managed {
+		return fmt.Errorf("%s", resp.ErrManagedResource)
+	}
+	return op.ChannelEnabled(id, enabled, ctx)
+}
+
+func (b *dbBackend) ChannelDelete(ctx context.Context, id int) error {
+	if ch, err := op.ChannelGet(id, ctx); err == nil && ch.Managed {
+		return fmt.Errorf("%s", resp.ErrManagedResource)
+	}
+	return op.ChannelDel(id, ctx)
+}
+
+func (b *dbBackend) GroupList(ctx context.Context) ([]model.Group, error) {
+	return op.GroupList(ctx)
+}
+
+func (b *dbBackend) GroupDelete(ctx context.Context, id int) error {
+	if g, err := op.GroupGet(id, ctx); err == nil && g.Managed {
+		return fmt.Errorf("%s", resp.ErrManagedResource)
+	}
+	return op.GroupDel(id, ctx)
+}
+
+func (b *dbBackend) APIKeyList(ctx context.Context) ([]model.APIKey, error) {
+	return op.APIKeyList(ctx)
+}
+
+func (b *dbBackend) APIKeyCreate(ctx context.Context, key *model.APIKey) error {
+	apiKey, err := op.APIKeyGenerate()
+	if err != nil {
+		return err
+	}
+	key.APIKey = apiKey
+	return op.APIKeyCreate(key, ctx)
+}
+
+func (b *dbBackend) APIKeyUpdate(ctx context.Context, key *model.APIKey) error {
+	if k, err := op.APIKeyGet(key.ID, ctx); err == nil && k.Managed {
+		return fmt.Errorf("%s", resp.ErrManagedResource)
+	}
+	return op.APIKeyUpdate(key, ctx)
+}
+
+func (b *dbBackend) APIKeyDelete(ctx context.Context, id int) error {
+	if k, err := op.APIKeyGet(id, ctx); err == nil && k.Managed {
+		return fmt.Errorf("%s", resp.ErrManagedResource)
+	}
+	return op.APIKeyDelete(id, ctx)
+}
+
+func (b *dbBackend) SettingList(ctx context.Context) ([]model.Setting, error) {
+	return op.SettingList(ctx)
+}
+
+func (b *dbBackend) SettingSet(ctx context.Context, setting model.Setting) error {
+	if err := setting.Validate(); err != nil {
+		return err
+	}
+	if op.SettingIsManaged(setting.Key) {
+		return fmt.Errorf("%s", resp.ErrManagedResource)
+	}
+	return op.SettingSetString(setting.Key, setting.Value, ctx)
+}
+
+func (b *dbBackend) Export(ctx context.Context, includeLogs, includeStats bool) (*model.DBDump, error) {
+	return op.DBExportAll(ctx, includeLogs, includeStats)
+}
+
+func (b *dbBackend) Import(ctx context.Context, dump *model.DBDump, opts model.DBImportOptions) (*model.DBImportResult, error) {
+	return op.DBImport(ctx, dump, opts)
+}
+
+func (b *dbBackend) LogClear(ctx context.Context) error {
+	return op.RelayLogClear(ctx)
+}
+
+// Close 关闭数据库；不回写内存缓存，避免覆盖导入的数据
+func (b *dbBackend) Close() {
+	db.Close()
+}
+
+// runAdmin 创建操作目标并执行 fn，结束后释放
+func runAdmin(fn func(ctx context.Context, b adminBackend) error) error {
+	ctx := context.Background()
+	b, err := newAdminBackend(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	return fn(ctx, b)
+}
+
+// printResult 按 --format 输出：json 输出原始数据，table 输出给定的表头和行
+func printResult(w io.Writer, v any, header []string, rows [][]string) error {
+	if adminOpts.format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// printDone 输出操作结果，json 格式下输出 {"message": ...}
+func printDone(w io.Writer, message string) error {
+	if adminOpts.format == "json" {
+		return printResult(w, map[string]string{"message": message}, nil, nil)
+	}
+	_, err := fmt.Fprintln(w, message)
+	return err
+}
+
+func parseID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid id: %s", arg)
+	}
+	return id, nil
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/server/resp"
+)
+
+// apiBackend 通过运行中服务的管理接口操作，修改会立即反映到服务的内存缓存
+type apiBackend struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// newAPIBackend 创建管理接口客户端，未提供 token 时使用用户名和密码登录获取
+func newAPIBackend(ctx context.Context, server, token, username, password string) (*apiBackend, error) {
+	b := &apiBackend{
+		baseURL: strings.TrimRight(server, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 5 * time.Minute},
+	}
+	if b.token != "" {
+		return b, nil
+	}
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("--token or --username and --password are required with --server")
+	}
+	var login model.UserLoginResponse
+	if err := b.call(ctx, http.MethodPost, "/api/v1/user/login", model.UserLogin{Username: username, Password: password}, &login); err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+	if login.TOTPRequired {
+		return nil, fmt.Errorf("two-factor authentication is enabled for %s, use --token with a personal access token", username)
+	}
+	b.token = login.Token
+	return b, nil
+}
+
+// do 发送请求并返回响应，非 2xx 响应转换为错误
+func (b *apiBackend) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	res, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		defer res.Body.Close()
+		var r resp.ResponseStruct
+		if err := json.NewDecoder(res.Body).Decode(&r); err == nil && r.Message != "" {
+			return nil, fmt.Errorf("%s (HTTP %d)", r.Message, res.StatusCode)
+		}
+		return nil, fmt.Errorf("HTTP %d", res.StatusCode)
+	}
+	return res, nil
+}
+
+// call 发送请求并将统一响应结构中的 data 解析到 out
+func (b *apiBackend) call(ctx context.Context, method, path string, body, out any) error {
+	res, err := b.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	r := resp.ResponseStruct{Data: out}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func (b *apiBackend) ChannelList(ctx context.Context) ([]model.Channel, error) {
+	var channels []model.Channel
+	err := b.call(ctx, http.MethodGet, "/api/v1/channel/list", nil, &channels)
+	return channels, err
+}
+
+func (b *apiBackend) ChannelEnable(ctx context.Context, id int, enabled bool) error {
+	body := map[string]any{"id": id, "enabled": enabled}
+	return b.call(ctx, http.MethodPost, "/api/v1/channel/enable", body, nil)
+}
+
+func (b *apiBackend) ChannelDelete(ctx context.Context, id int) error {
+	return b.call(ctx, http.MethodDelete, "/api/v1/channel/delete/"+strconv.Itoa(id), nil, nil)
+}
+
+func (b *apiBackend) GroupList(ctx context.Context) ([]model.Group, error) {
+	var groups []model.Group
+	err := b.call(ctx, http.MethodGet, "/api/v1/group/list", nil, &groups)
+	return groups, err
+}
+
+func (b *apiBackend) GroupDelete(ctx context.Context, id int) error {
+	return b.call(ctx, http.MethodDelete, "/api/v1/group/delete/"+strconv.Itoa(id), nil, nil)
+}
+
+func (b *apiBackend) APIKeyList(ctx context.Context) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := b.call(ctx, http.MethodGet, "/api/v1/apikey/list", nil, &keys)
+	return keys, err
+}
+
+func (b *apiBackend) APIKeyCreate(ctx context.Context, key *model.APIKey) error {
+	return b.call(ctx, http.MethodPost, "/api/v1/apikey/create", key, key)
+}
+
+func (b *apiBackend) APIKeyUpdate(ctx context.Context, key *model.APIKey) error {
+	return b.call(ctx, http.MethodPost, "/api/v1/apikey/update", key, key)
+}
+
+func (b *apiBackend) APIKeyDelete(ctx context.Context, id int) error {
+	return b.call(ctx, http.MethodDelete, "/api/v1/apikey/delete/"+strconv.Itoa(id), nil, nil)
+}
+
+func (b *apiBackend) SettingList(ctx context.Context) ([]model.Setting, error) {
+	var settings []model.Setting
+	err := b.call(ctx, http.MethodGet, "/api/v1/setting/list", nil, &settings)
+	return settings, err
+}
+
+func (b *apiBackend) SettingSet(ctx context.Context, setting model.Setting) error {
+	return b.call(ctx, http.MethodPost, "/api/v1/setting/set", setting, nil)
+}
+
+// Export 导出接口直接返回备份内容，不使用统一响应结构
+func (b *apiBackend) Export(ctx context.Context, includeLogs, includeStats bool) (*model.DBDump, error) {
+	query := url.Values{}
+	query.Set("include_logs", strconv.FormatBool(includeLogs))
+	query.Set("include_stats", strconv.FormatBool(includeStats))
+	res, err := b.do(ctx, http.MethodGet, "/api/v1/setting/export?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var dump model.DBDump
+	if err := json.NewDecoder(res.Body).Decode(&dump); err != nil {
+		return nil, fmt.Errorf("decode export: %w", err)
+	}
+	return &dump, nil
+}
+
+func (b *apiBackend) Import(ctx context.Context, dump *model.DBDump, opts model.DBImportOptions) (*model.DBImportResult, error) {
+	query := url.Values{}
+	query.Set("mode", string(opts.Mode))
+	query.Set("dry_run", strconv.FormatBool(opts.DryRun))
+	var result model.DBImportResult
+	if err := b.call(ctx, http.MethodPost, "/api/v1/setting/import?"+query.Encode(), dump, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (b *apiBackend) LogClear(ctx context.Context) error {
+	return b.call(ctx, http.MethodDelete, "/api/v1/log/clear", nil, nil)
+}
+
+func (b *apiBackend) Close() {
+	b.client.CloseIdleConnections()
+}
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/resp"
+)
+
+// fakeAdminServer 模拟管理接口：登录返回 login 的结果，其余接口记录请求并要求带上 wantToken
+type fakeAdminServer struct {
+	t         *testing.T
+	login     func(model.UserLogin) (int, any)
+	wantToken string
+	logins    int
+	requests  []*http.Request
+}
+
+func (s *fakeAdminServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/api/v1/user/login" {
+		s.logins++
+		var req model.UserLogin
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.t.Errorf("decode login: %v", err)
+		}
+		status, data := s.login(req)
+		writeFakeResponse(w, status, data)
+		return
+	}
+	s.requests = append(s.requests, r)
+	if r.Header.Get("Authorization") != "Bearer "+s.wantToken {
+		writeFakeResponse(w, http.StatusUnauthorized, nil)
+		return
+	}
+	switch r.URL.Path {
+	case "/api/v1/channel/list":
+		writeFakeResponse(w, http.StatusOK, []model.Channel{{ID: 1, Name: "c1"}})
+	case "/api/v1/setting/import":
+		var dump model.DBDump
+		if err := json.NewDecoder(r.Body).Decode(&dump); err != nil {
+			s.t.Errorf("decode dump: %v", err)
+		}
+		q := r.URL.Query()
+		writeFakeResponse(w, http.StatusOK, model.DBImportResult{
+			Mode:         model.DBImportMode(q.Get("mode")),
+			DryRun:       q.Get("dry_run") == "true",
+			RowsAffected: map[string]int64{"channels": int64(len(dump.Channels))},
+		})
+	case "/api/v1/apikey/delete/7":
+		writeFakeResponse(w, http.StatusForbidden, nil)
+	default:
+		writeFakeResponse(w, http.StatusNotFound, nil)
+	}
+}
+
+func writeFakeResponse(w http.ResponseWriter, status int, data any) {
+	message := "success"
+	if status != http.StatusOK {
+		message = strings.ToLower(http.StatusText(status))
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp.ResponseStruct{Code: status, Message: message, Data: data})
+}
+
+func TestAPIBackendLogin(t *testing.T) {
+	loginOK := func(req model.UserLogin) (int, any) {
+		if req.Username != "admin" || req.Password != "pw" {
+			return http.StatusUnauthorized, nil
+		}
+		return http.StatusOK, model.UserLoginResponse{Token: "session-token"}
+	}
+	tests := []struct {
+		name       string
+		token      string
+		username   string
+		password   string
+		login      func(model.UserLogin) (int, any)
+		wantToken  string
+		wantLogins int
+		wantErr    string
+	}{
+		{"token", "pat-octopus-x", "", "", loginOK, "pat-octopus-x", 0, ""},
+		{"token wins over password", "pat-octopus-x", "admin", "pw", loginOK, "pat-octopus-x", 0, ""},
+		{"password", "", "admin", "pw", loginOK, "session-token", 1, ""},
+		{"wrong password", "", "admin", "bad", loginOK, "", 1, "login failed: unauthorized (HTTP 401)"},
+		{"missing credentials", "", "admin", "", loginOK, "", 0, "--token or --username and --password are required"},
+		{"totp required", "", "admin", "pw", func(model.UserLogin) (int, any) {
+			return http.StatusOK, model.UserLoginResponse{TOTPRequired: true}
+		}, "", 1, "two-factor authentication is enabled for admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeAdminServer{t: t, login: tt.login, wantToken: tt.wantToken}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			b, err := newAPIBackend(context.Background(), srv.URL+"/", tt.token, tt.username, tt.password)
+			if fake.logins != tt.wantLogins {
+				t.Errorf("logins = %d, want %d", fake.logins, tt.wantLogins)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer b.Close()
+			channels, err := b.ChannelList(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(channels) != 1 || channels[0].Name != "c1" {
+				t.Errorf("channels = %+v", channels)
+			}
+		})
+	}
+}
+
+func TestAPIBackendImport(t *testing.T) {
+	fake := &fakeAdminServer{t: t, wantToken: "tok"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	b, err := newAPIBackend(context.Background(), srv.URL, "tok", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	tests := []struct {
+		opts      model.DBImportOptions
+		wantQuery string
+	}{
+		{model.DBImportOptions{Mode: model.DBImportModeMerge}, "dry_run=false&mode=merge"},
+		{model.DBImportOptions{Mode: model.DBImportModeReplace, DryRun: true}, "dry_run=true&mode=replace"},
+	}
+	for _, tt := range tests {
+		dump := &model.DBDump{Version: 2, Channels: []model.Channel{{Name: "a"}, {Name: "b"}}}
+		res, err := b.Import(context.Background(), dump, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q := fake.requests[len(fake.requests)-1].URL.RawQuery; q != tt.wantQuery {
+			t.Errorf("query = %q, want %q", q, tt.wantQuery)
+		}
+		if res.Mode != tt.opts.Mode || res.DryRun != tt.opts.DryRun || res.RowsAffected["channels"] != 2 {
+			t.Errorf("result = %+v, want options %+v", res, tt.opts)
+		}
+	}
+
+	if err := b.APIKeyDelete(context.Background(), 7); err == nil || err.Error() != "forbidden (HTTP 403)" {
+		t.Errorf("delete err = %v", err)
+	}
+}
+
+func TestDBBackend(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "config.json")
+	data := `{"database":{"type":"sqlite","path":"` + filepath.ToSlash(filepath.Join(dir, "data.db")) + `"}}`
+	if err := os.WriteFile(cfg, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := cfgFile
+	cfgFile = cfg
+	t.Cleanup(func() { cfgFile = old })
+
+	ctx := context.Background()
+	b, err := newDBBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	key := model.APIKey{Name: "cli", Enabled: true}
+	if err := b.APIKeyCreate(ctx, &key); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key.APIKey, op.APIKeyPrefix) || key.ID == 0 {
+		t.Fatalf("created key = %+v", key)
+	}
+
+	// 声明式配置管理的对象不能通过命令行修改
+	managed := model.APIKey{Name: "managed", APIKey: op.APIKeyPrefix + "managed", Managed: true}
+	if err := op.APIKeyCreate(&managed, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.APIKeyDelete(ctx, managed.ID); err == nil || err.Error() != resp.ErrManagedResource {
+		t.Errorf("delete managed key err = %v", err)
+	}
+	if err := b.APIKeyDelete(ctx, key.ID); err != nil {
+		t.Errorf("delete key: %v", err)
+	}
+
+	if err := b.SettingSet(ctx, model.Setting{Key: model.SettingKeySyncLLMInterval, Value: "x"}); err == nil {
+		t.Error("invalid setting value accepted")
+	}
+	if err := b.SettingSet(ctx, model.Setting{Key: model.SettingKeySyncLLMInterval, Value: "12"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := op.SettingGetString(model.SettingKeySyncLLMInterval); v != "12" {
+		t.Errorf("setting = %q, want 12", v)
+	}
+
+	dump := &model.DBDump{Version: 2, Channels: []model.Channel{{ID: 100, Name: "imported", Type: 1}}}
+	tests := []struct {
+		opts         model.DBImportOptions
+		wantChannels int
+	}{
+		{model.DBImportOptions{DryRun: true}, 0},
+		{model.DBImportOptions{Mode: model.DBImportModeMerge}, 1},
+	}
+	for _, tt := range tests {
+		res, err := b.Import(ctx, dump, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Mode != model.DBImportModeMerge || res.DryRun != tt.opts.DryRun {
+			t.Errorf("result = %+v", res)
+		}
+		exported, err := b.Export(ctx, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(exported.Channels) != tt.wantChannels {
+			t.Errorf("dry_run=%v: %d channels after import, want %d", tt.opts.DryRun, len(exported.Channels), tt.wantChannels)
+		}
+	}
+	if _, err := b.Import(ctx, dump, model.DBImportOptions{Mode: "upsert"}); err == nil {
+		t.Error("unsupported import mode accepted")
+	}
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/spf13/cobra"
+)
+
+var apiKeyCreateOpts struct {
+	name            string
+	maxCost         float64
+	expireAt        int64
+	supportedModels string
+}
+
+var apiKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manage API keys",
+	Long: `Manage API keys directly in the database, or through the admin API of a running server with --server.
+Direct database access is meant for when the server is stopped; the running server does not see the changes until restarted.`,
+}
+
+var apiKeyListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List API keys",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			keys, err := b.APIKeyList(ctx)
+			if err != nil {
+				return err
+			}
+			rows := make([][]string, 0, len(keys))
+			for _, k := range keys {
+				rows = append(rows, apiKeyRow(k, true))
+			}
+			return printResult(os.Stdout, keys, apiKeyHeader, rows)
+		})
+	},
+}
+
+var apiKeyCreateCmd = &cobra.Command{
+	Use:          "create",
+	Short:        "Create an API key and print it",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if apiKeyCreateOpts.name == "" {
+			return fmt.Errorf("--name is required")
+		}
+		key := model.APIKey{
+			Name:            apiKeyCreateOpts.name,
+			Enabled:         true,
+			MaxCost:         apiKeyCreateOpts.maxCost,
+			ExpireAt:        apiKeyCreateOpts.expireAt,
+			SupportedModels: apiKeyCreateOpts.supportedModels,
+		}
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			if err := b.APIKeyCreate(ctx, &key); err != nil {
+				return err
+			}
+			return printResult(os.Stdout, key, apiKeyHeader, [][]string{apiKeyRow(key, false)})
+		})
+	},
+}
+
+// newAPIKeyEnableCmd 创建启用或禁用 API Key 的子命令
+func newAPIKeyEnableCmd(use string, enabled bool) *cobra.Command {
+	short := "Enable an API key"
+	if !enabled {
+		short = "Disable an API key"
+	}
+	return &cobra.Command{
+		Use:          use + " <id>",
+		Short:        short,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			return runAdmin(func(ctx context.Context, b adminBackend) error {
+				keys, err := b.APIKeyList(ctx)
+				if err != nil {
+					return err
+				}
+				for _, k := range keys {
+					if k.ID != id {
+						continue
+					}
+					k.Enabled = enabled
+					if err := b.APIKeyUpdate(ctx, &k); err != nil {
+						return err
+					}
+					return printDone(os.Stdout, fmt.Sprintf("api key %d %sd", id, use))
+				}
+				return fmt.Errorf("api key %d not found", id)
+			})
+		},
+	}
+}
+
+var apiKeyDeleteCmd = &cobra.Command{
+	Use:          "delete <id>",
+	Short:        "Delete an API key",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			if err := b.APIKeyDelete(ctx, id); err != nil {
+				return err
+			}
+			return printDone(os.Stdout, fmt.Sprintf("api key %d deleted", id))
+		})
+	},
+}
+
+var apiKeyHeader = []string{"ID", "NAME", "KEY", "ENABLED", "EXPIRE_AT", "MAX_COST", "MANAGED"}
+
+// apiKeyRow 表格输出的一行，列表中只显示密钥首尾
+func apiKeyRow(k model.APIKey, mask bool) []string {
+	key := k.APIKey
+	if mask && len(key) > 12 {
+		key = key[:8] + "..." + key[len(key)-4:]
+	}
+	expireAt := "-"
+	if k.ExpireAt > 0 {
+		expireAt = time.Unix(k.ExpireAt, 0).Format(time.DateTime)
+	}
+	maxCost := "-"
+	if k.MaxCost > 0 {
+		maxCost = strconv.FormatFloat(k.MaxCost, 'f', -1, 64)
+	}
+	return []string{
+		strconv.Itoa(k.ID),
+		k.Name,
+		key,
+		strconv.FormatBool(k.Enabled),
+		expireAt,
+		maxCost,
+		strconv.FormatBool(k.Managed),
+	}
+}
+
+func init() {
+	flags := apiKeyCreateCmd.Flags()
+	flags.StringVar(&apiKeyCreateOpts.name, "name", "", "API key name")
+	flags.Float64Var(&apiKeyCreateOpts.maxCost, "max-cost", 0, "maximum total cost, 0 for unlimited")
+	flags.Int64Var(&apiKeyCreateOpts.expireAt, "expire-at", 0, "expiry as a unix timestamp, 0 for never")
+	flags.StringVar(&apiKeyCreateOpts.supportedModels, "supported-models", "", "comma separated models the key may use (default is all)")
+
+	addAdminFlags(apiKeyCmd)
+	apiKeyCmd.AddCommand(apiKeyListCmd)
+	apiKeyCmd.AddCommand(apiKeyCreateCmd)
+	apiKeyCmd.AddCommand(newAPIKeyEnableCmd("enable", true))
+	apiKeyCmd.AddCommand(newAPIKeyEnableCmd("disable", false))
+	apiKeyCmd.AddCommand(apiKeyDeleteCmd)
+	rootCmd.AddCommand(apiKeyCmd)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var channelCmd = &cobra.Command{
+	Use:   "channel",
+	Short: "Manage channels",
+	Long: `Manage channels directly in the database, or through the admin API of a running server with --server.
+Direct database access is meant for when the server is stopped; the running server does not see the changes until restarted.`,
+}
+
+var channelListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List channels",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			channels, err := b.ChannelList(ctx)
+			if err != nil {
+				return err
+			}
+			rows := make([][]string, 0, len(channels))
+			for _, ch := range channels {
+				rows = append(rows, []string{
+					strconv.Itoa(ch.ID),
+					ch.Name,
+					strconv.Itoa(int(ch.Type)),
+					strconv.FormatBool(ch.Enabled),
+					strconv.Itoa(len(ch.Keys)),
+					strconv.FormatBool(ch.Managed),
+				})
+			}
+			return printResult(os.Stdout, channels, []string{"ID", "NAME", "TYPE", "ENABLED", "KEYS", "MANAGED"}, rows)
+		})
+	},
+}
+
+// newChannelEnableCmd 创建启用或禁用渠道的子命令
+func newChannelEnableCmd(use string, enabled bool) *cobra.Command {
+	short := "Enable a channel"
+	if !enabled {
+		short = "Disable a channel"
+	}
+	return &cobra.Command{
+		Use:          use + " <id>",
+		Short:        short,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			return runAdmin(func(ctx context.Context, b adminBackend) error {
+				if err := b.ChannelEnable(ctx, id, enabled); err != nil {
+					return err
+				}
+				return printDone(os.Stdout, fmt.Sprintf("channel %d %sd", id, use))
+			})
+		},
+	}
+}
+
+var channelDeleteCmd = &cobra.Command{
+	Use:          "delete <id>",
+	Short:        "Delete a channel",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			if err := b.ChannelDelete(ctx, id); err != nil {
+				return err
+			}
+			return printDone(os.Stdout, fmt.Sprintf("channel %d deleted", id))
+		})
+	},
+}
+
+func init() {
+	addAdminFlags(channelCmd)
+	channelCmd.AddCommand(channelListCmd)
+	channelCmd.AddCommand(newChannelEnableCmd("enable", true))
+	channelCmd.AddCommand(newChannelEnableCmd("disable", false))
+	channelCmd.AddCommand(channelDeleteCmd)
+	rootCmd.AddCommand(channelCmd)
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/spf13/cobra"
+)
+
+var dbExportOpts struct {
+	output       string
+	includeLogs  bool
+	includeStats bool
+}
+
+var dbImportOpts struct {
+	passphrase string
+	mode       string
+	dryRun     bool
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Export and import database backups",
+	Long: `Export and import database backups directly, or through the admin API of a running server with --server.
+Direct database access is meant for when the server is stopped; the running server does not see imported data until restarted.`,
+}
+
+var dbExportCmd = &cobra.Command{
+	Use:          "export",
+	Short:        "Export the database as JSON",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			dump, err := b.Export(ctx, dbExportOpts.includeLogs, dbExportOpts.includeStats)
+			if err != nil {
+				return err
+			}
+			var out io.Writer = os.Stdout
+			if dbExportOpts.output != "" && dbExportOpts.output != "-" {
+				f, err := os.Create(dbExportOpts.output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(dump)
+		})
+	},
+}
+
+var dbImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a JSON export or scheduled backup",
+	Long: `Import a JSON export or scheduled backup.
+In merge mode existing rows are kept and conflicting rows are skipped; in replace mode the config tables are wiped and restored in one transaction.
+Use --dry-run to preview the per-table counts and the channel, group and API key changes without writing anything.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		dump, err := op.BackupDecode(data, dbImportOpts.passphrase)
+		if err != nil {
+			return fmt.Errorf("invalid export file: %w", err)
+		}
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			result, err := b.Import(ctx, dump, model.DBImportOptions{
+				Mode:   model.DBImportMode(dbImportOpts.mode),
+				DryRun: dbImportOpts.dryRun,
+			})
+			if err != nil {
+				return err
+			}
+			if result.DryRun {
+				return printImportPlan(result)
+			}
+			tables := make([]string, 0, len(result.RowsAffected))
+			for table := range result.RowsAffected {
+				tables = append(tables, table)
+			}
+			sort.Strings(tables)
+			rows := make([][]string, 0, len(tables))
+			for _, table := range tables {
+				rows = append(rows, []string{table, strconv.FormatInt(result.RowsAffected[table], 10)})
+			}
+			return printResult(os.Stdout, result, []string{"TABLE", "ROWS"}, rows)
+		})
+	},
+}
+
+// printImportPlan 输出预览结果：先输出各表的变化行数，再输出渠道、分组和 API Key 的差异
+func printImportPlan(result *model.DBImportResult) error {
+	tables := make([]string, 0, len(result.Tables))
+	for table := range result.Tables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	rows := make([][]string, 0, len(tables))
+	for _, table := range tables {
+		t := result.Tables[table]
+		rows = append(rows, []string{
+			table,
+			strconv.FormatInt(t.Create, 10),
+			strconv.FormatInt(t.Update, 10),
+			strconv.FormatInt(t.Conflict, 10),
+			strconv.FormatInt(t.Delete, 10),
+		})
+	}
+	if err := printResult(os.Stdout, result, []string{"TABLE", "CREATE", "UPDATE", "CONFLICT", "DELETE"}, rows); err != nil {
+		return err
+	}
+	if adminOpts.format == "json" || len(result.Changes) == 0 {
+		return nil
+	}
+	rows = make([][]string, 0, len(result.Changes))
+	for _, change := range result.Changes {
+		rows = append(rows, []string{change.Kind, change.Name, string(change.Action), strings.Join(change.Fields, ",")})
+	}
+	fmt.Println()
+	return printResult(os.Stdout, result, []string{"KIND", "NAME", "ACTION", "FIELDS"}, rows)
+}
+
+func init() {
+	flags := dbExportCmd.Flags()
+	flags.StringVarP(&dbExportOpts.output, "output", "o", "", "output file (default is stdout)")
+	flags.BoolVar(&dbExportOpts.includeLogs, "include-logs", false, "include relay logs")
+	flags.BoolVar(&dbExportOpts.includeStats, "include-stats", false, "include statistics")
+
+	flags = dbImportCmd.Flags()
+	flags.StringVar(&dbImportOpts.passphrase, "passphrase", os.Getenv("OCTOPUS_BACKUP_PASSPHRASE"), "passphrase for encrypted backups")
+	flags.StringVar(&dbImportOpts.mode, "mode", string(model.DBImportModeMerge), "import mode: merge or replace")
+	flags.BoolVar(&dbImportOpts.dryRun, "dry-run", false, "preview changes without writing")
+
+	addAdminFlags(dbCmd)
+	dbCmd.AddCommand(dbExportCmd)
+	dbCmd.AddCommand(dbImportCmd)
+	rootCmd.AddCommand(dbCmd)
+}
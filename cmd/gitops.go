@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/gitops"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/spf13/cobra"
+)
+
+var gitopsOpts struct {
+	dir   string
+	prune bool
+}
+
+var gitopsCmd = &cobra.Command{
+	Use:   "gitops",
+	Short: "Reconcile declarative config with the database",
+	Long: "Reconcile channels, groups, API keys and settings declared in a config directory with the database.\n" +
+		"A running server only picks up changes made here after a restart; use the /api/v1/gitops endpoints instead when it is running.",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		log.SetLevel("error")
+	},
+}
+
+var gitopsPlanCmd = &cobra.Command{
+	Use:          "plan",
+	Short:        "Show the changes that apply would make",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGitOps(cmd, true)
+	},
+}
+
+var gitopsApplyCmd = &cobra.Command{
+	Use:          "apply",
+	Short:        "Apply the declarative config to the database",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGitOps(cmd, false)
+	},
+}
+
+func runGitOps(cmd *cobra.Command, dryRun bool) error {
+	if err := conf.Load(cfgFile); err != nil {
+		return err
+	}
+	dir := conf.AppConfig.GitOps.Path
+	if cmd.Flags().Changed("dir") {
+		dir = gitopsOpts.dir
+	}
+	if dir == "" {
+		return fmt.Errorf("config directory is required: use --dir or set gitops.path in config")
+	}
+	prune := conf.AppConfig.GitOps.Prune
+	if cmd.Flags().Changed("prune") {
+		prune = gitopsOpts.prune
+	}
+
+	cfg, _, err := gitops.Load(dir)
+	if err != nil {
+		return err
+	}
+	if err := db.InitDB(conf.AppConfig.Database.Type, conf.AppConfig.Database.Path, conf.IsDebug()); err != nil {
+		return fmt.Errorf("database init error: %w", err)
+	}
+	defer db.Close()
+	if err := op.InitCache(); err != nil {
+		return fmt.Errorf("cache init error: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	changes, err := gitops.Reconcile(ctx, cfg, dryRun, prune)
+	printGitOpsChanges(os.Stdout, changes)
+	return err
+}
+
+// printGitOpsChanges 按 terraform 风格输出差异：+ 创建，~ 更新，- 删除
+func printGitOpsChanges(w io.Writer, changes []model.GitOpsChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes.")
+		return
+	}
+	for _, change := range changes {
+		symbol := "~"
+		switch change.Action {
+		case model.GitOpsActionCreate:
+			symbol = "+"
+		case model.GitOpsActionDelete, model.GitOpsActionUnmanage:
+			symbol = "-"
+		}
+		line := fmt.Sprintf("%s %s %s", symbol, change.Kind, change.Name)
+		if change.Action == model.GitOpsActionUnmanage {
+			line += " (unmanage)"
+		}
+		if len(change.Fields) > 0 {
+			line += " [" + strings.Join(change.Fields, ", ") + "]"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+func init() {
+	gitopsCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./data/config.json)")
+	gitopsCmd.PersistentFlags().StringVar(&gitopsOpts.dir, "dir", "", "declarative config directory (default is gitops.path in config)")
+	gitopsCmd.PersistentFlags().BoolVar(&gitopsOpts.prune, "prune", true, "delete managed objects removed from the config")
+	gitopsCmd.AddCommand(gitopsPlanCmd, gitopsApplyCmd)
+	rootCmd.AddCommand(gitopsCmd)
+}
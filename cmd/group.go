@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var groupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage groups",
+	Long: `Manage groups directly in the database, or through the admin API of a running server with --server.
+Direct database access is meant for when the server is stopped; the running server does not see the changes until restarted.`,
+}
+
+var groupListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List groups",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			groups, err := b.GroupList(ctx)
+			if err != nil {
+				return err
+			}
+			rows := make([][]string, 0, len(groups))
+			for _, g := range groups {
+				rows = append(rows, []string{
+					strconv.Itoa(g.ID),
+					g.Name,
+					strconv.Itoa(int(g.Mode)),
+					strconv.Itoa(len(g.Items)),
+					g.MatchRegex,
+					strconv.FormatBool(g.Managed),
+				})
+			}
+			return printResult(os.Stdout, groups, []string{"ID", "NAME", "MODE", "ITEMS", "MATCH_REGEX", "MANAGED"}, rows)
+		})
+	},
+}
+
+var groupDeleteCmd = &cobra.Command{
+	Use:          "delete <id>",
+	Short:        "Delete a group",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			if err := b.GroupDelete(ctx, id); err != nil {
+				return err
+			}
+			return printDone(os.Stdout, fmt.Sprintf("group %d deleted", id))
+		})
+	},
+}
+
+func init() {
+	addAdminFlags(groupCmd)
+	groupCmd.AddCommand(groupListCmd)
+	groupCmd.AddCommand(groupDeleteCmd)
+	rootCmd.AddCommand(groupCmd)
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/spf13/cobra"
+)
+
+var logExportOpts struct {
+	format      string
+	output      string
+	includeBody bool
+	startTime   int
+	endTime     int
+	model       string
+	channelID   int
+	apiKeyID    int
+	status      string
+	keyword     string
+}
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Manage relay logs",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// 日志输出到 stdout，避免与导出内容混在一起
+		log.SetLevel("error")
+	},
+}
+
+var logExportCmd = &cobra.Command{
+	Use:          "export",
+	Short:        "Export relay logs as JSONL or CSV",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := conf.Load(cfgFile); err != nil {
+			return err
+		}
+		if err := db.InitDB(conf.AppConfig.Database.Type, conf.AppConfig.Database.Path, conf.IsDebug()); err != nil {
+			return fmt.Errorf("database init error: %w", err)
+		}
+		defer db.Close()
+
+		filter, err := logExportFilter(cmd)
+		if err != nil {
+			return err
+		}
+
+		var out io.Writer = os.Stdout
+		if logExportOpts.output != "" && logExportOpts.output != "-" {
+			f, err := os.Create(logExportOpts.output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		bw := bufio.NewWriter(out)
+		// 失败时也写出已导出的内容和末尾的失败标记
+		err = op.RelayLogExport(context.Background(), bw, op.RelayLogExportFormat(logExportOpts.format), filter, logExportOpts.includeBody, nil)
+		if flushErr := bw.Flush(); err == nil {
+			err = flushErr
+		}
+		return err
+	},
+}
+
+var logClearCmd = &cobra.Command{
+	Use:          "clear",
+	Short:        "Delete all relay logs",
+	Long:         "Delete all relay logs directly in the database, or through the admin API of a running server with --server.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			if err := b.LogClear(ctx); err != nil {
+				return err
+			}
+			return printDone(os.Stdout, "relay logs cleared")
+		})
+	},
+}
+
+// logExportFilter 将命令行参数转换为日志过滤条件，仅使用显式设置的参数
+func logExportFilter(cmd *cobra.Command) (model.RelayLogFilter, error) {
+	flags := cmd.Flags()
+	filter := model.RelayLogFilter{
+		Model:   logExportOpts.model,
+		Keyword: logExportOpts.keyword,
+	}
+	if flags.Changed("start-time") {
+		filter.StartTime = &logExportOpts.startTime
+	}
+	if flags.Changed("end-time") {
+		filter.EndTime = &logExportOpts.endTime
+	}
+	if flags.Changed("channel-id") {
+		filter.ChannelID = &logExportOpts.channelID
+	}
+	if flags.Changed("api-key-id") {
+		filter.APIKeyID = &logExportOpts.apiKeyID
+	}
+	switch logExportOpts.status {
+	case "":
+	case "success", "error":
+		success := logExportOpts.status == "success"
+		filter.Success = &success
+	default:
+		return filter, fmt.Errorf("invalid status: must be success or error")
+	}
+	return filter, nil
+}
+
+func init() {
+	flags := logExportCmd.Flags()
+	flags.StringVar(&logExportOpts.format, "format", string(op.RelayLogExportFormatJSONL), "output format: jsonl or csv")
+	flags.StringVarP(&logExportOpts.output, "output", "o", "", "output file (default is stdout)")
+	flags.BoolVar(&logExportOpts.includeBody, "include-body", false, "include request and response content")
+	flags.IntVar(&logExportOpts.startTime, "start-time", 0, "only logs at or after this unix timestamp")
+	flags.IntVar(&logExportOpts.endTime, "end-time", 0, "only logs at or before this unix timestamp")
+	flags.StringVar(&logExportOpts.model, "model", "", "filter by request or actual model name")
+	flags.IntVar(&logExportOpts.channelID, "channel-id", 0, "filter by channel id")
+	flags.IntVar(&logExportOpts.apiKeyID, "api-key-id", 0, "filter by api key id")
+	flags.StringVar(&logExportOpts.status, "status", "", "filter by result: success or error")
+	flags.StringVarP(&logExportOpts.keyword, "query", "q", "", "search model, channel name and error")
+
+	logCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./data/config.json)")
+	addAdminConnFlags(logClearCmd.Flags())
+
+	logCmd.AddCommand(logExportCmd)
+	logCmd.AddCommand(logClearCmd)
+	rootCmd.AddCommand(logCmd)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var migrateDBOpts struct {
+	from      string
+	to        string
+	batchSize int
+	resume    bool
+}
+
+var migrateDBCmd = &cobra.Command{
+	Use:   "migrate-db",
+	Short: "Copy all data to another database",
+	Long: `Copy every table from one database to another, for example from SQLite to PostgreSQL.
+Databases are given as <type>:<dsn>, where type is sqlite, mysql or postgres:
+
+  octopus migrate-db --from sqlite:data/data.db --to "postgres:host=localhost user=octopus dbname=octopus sslmode=disable"
+
+Rows are copied in primary key order with their IDs preserved, sequences are reset and row counts are verified.
+The target must be empty; if a copy is interrupted, run the same command with --resume to continue.
+Stop the server before migrating, then point database.type and database.path at the new database.`,
+	SilenceUsage: true,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		log.SetLevel("error")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		srcType, srcDSN, err := parseDBSpec(migrateDBOpts.from)
+		if err != nil {
+			return fmt.Errorf("--from: %w", err)
+		}
+		dstType, dstDSN, err := parseDBSpec(migrateDBOpts.to)
+		if err != nil {
+			return fmt.Errorf("--to: %w", err)
+		}
+		if srcType == dstType && srcDSN == dstDSN {
+			return fmt.Errorf("--from and --to are the same database")
+		}
+
+		src, err := db.Open(srcType, srcDSN, false)
+		if err != nil {
+			return fmt.Errorf("open source: %w", err)
+		}
+		defer closeGormDB(src)
+		dst, err := db.Open(dstType, dstDSN, false)
+		if err != nil {
+			return fmt.Errorf("open target: %w", err)
+		}
+		defer closeGormDB(dst)
+
+		// 中断后已写入的批次保留在目标库，可使用 --resume 继续
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		results, copyErr := db.Copy(ctx, src, dst, db.CopyOptions{
+			BatchSize: migrateDBOpts.batchSize,
+			Resume:    migrateDBOpts.resume,
+			Progress: func(table string, copied, total int64) {
+				fmt.Fprintf(os.Stderr, "%s: %d/%d\n", table, copied, total)
+			},
+		})
+		rows := make([][]string, 0, len(results))
+		for _, r := range results {
+			rows = append(rows, []string{
+				r.Table,
+				strconv.FormatInt(r.Source, 10),
+				strconv.FormatInt(r.Target, 10),
+				strconv.FormatInt(r.Copied, 10),
+			})
+		}
+		if len(rows) > 0 {
+			if err := printResult(os.Stdout, results, []string{"TABLE", "SOURCE", "TARGET", "COPIED"}, rows); err != nil {
+				return err
+			}
+		}
+		if copyErr != nil {
+			return copyErr
+		}
+		fmt.Fprintln(os.Stderr, "migration completed")
+		return nil
+	},
+}
+
+// parseDBSpec 解析 <type>:<dsn>，postgres:// 形式的 URL 整体作为 DSN
+func parseDBSpec(spec string) (string, string, error) {
+	if strings.HasPrefix(spec, "postgres://") || strings.HasPrefix(spec, "postgresql://") {
+		return "postgres", spec, nil
+	}
+	dbType, dsn, ok := strings.Cut(spec, ":")
+	if !ok || dsn == "" {
+		return "", "", fmt.Errorf("expected <type>:<dsn>, got %q", spec)
+	}
+	switch dbType {
+	case "sqlite", "mysql", "postgres", "postgresql":
+		return dbType, dsn, nil
+	default:
+		return "", "", fmt.Errorf("unsupported database type: %s", dbType)
+	}
+}
+
+func init() {
+	flags := migrateDBCmd.Flags()
+	flags.StringVar(&migrateDBOpts.from, "from", "", "source database as <type>:<dsn>")
+	flags.StringVar(&migrateDBOpts.to, "to", "", "target database as <type>:<dsn>")
+	flags.IntVar(&migrateDBOpts.batchSize, "batch-size", 500, "rows per batch")
+	flags.BoolVar(&migrateDBOpts.resume, "resume", false, "continue an interrupted migration into a non-empty target")
+	flags.StringVar(&adminOpts.format, "format", "table", "output format: table or json")
+	_ = migrateDBCmd.MarkFlagRequired("from")
+	_ = migrateDBCmd.MarkFlagRequired("to")
+	rootCmd.AddCommand(migrateDBCmd)
+}
+
+func closeGormDB(conn *gorm.DB) {
+	if sqlDB, err := conn.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/spf13/cobra"
+)
+
+var settingCmd = &cobra.Command{
+	Use:   "setting",
+	Short: "Manage settings",
+	Long: `Manage settings directly in the database, or through the admin API of a running server with --server.
+Direct database access is meant for when the server is stopped; the running server does not see the changes until restarted.`,
+}
+
+var settingListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List settings",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			settings, err := b.SettingList(ctx)
+			if err != nil {
+				return err
+			}
+			rows := make([][]string, 0, len(settings))
+			for _, s := range settings {
+				rows = append(rows, []string{string(s.Key), s.Value, strconv.FormatBool(s.Managed)})
+			}
+			return printResult(os.Stdout, settings, []string{"KEY", "VALUE", "MANAGED"}, rows)
+		})
+	},
+}
+
+var settingGetCmd = &cobra.Command{
+	Use:          "get <key>",
+	Short:        "Print a setting value",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			settings, err := b.SettingList(ctx)
+			if err != nil {
+				return err
+			}
+			for _, s := range settings {
+				if string(s.Key) != args[0] {
+					continue
+				}
+				if adminOpts.format == "json" {
+					return printResult(os.Stdout, s, nil, nil)
+				}
+				_, err := fmt.Fprintln(os.Stdout, s.Value)
+				return err
+			}
+			return fmt.Errorf("setting %s not found", args[0])
+		})
+	},
+}
+
+var settingSetCmd = &cobra.Command{
+	Use:          "set <key> <value>",
+	Short:        "Set a setting value",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		setting := model.Setting{Key: model.SettingKey(args[0]), Value: args[1]}
+		return runAdmin(func(ctx context.Context, b adminBackend) error {
+			if err := b.SettingSet(ctx, setting); err != nil {
+				return err
+			}
+			return printDone(os.Stdout, fmt.Sprintf("setting %s updated", setting.Key))
+		})
+	},
+}
+
+func init() {
+	addAdminFlags(settingCmd)
+	settingCmd.AddCommand(settingListCmd)
+	settingCmd.AddCommand(settingGetCmd)
+	settingCmd.AddCommand(settingSetCmd)
+	rootCmd.AddCommand(settingCmd)
+}
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/bestruirui/octopus/internal/conf"
 	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/gitops"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/server"
 	"github.com/bestruirui/octopus/internal/task"
@@ -22,6 +25,10 @@ var startCmd = &cobra.Command{
 		log.SetLevel(conf.AppConfig.Log.Level)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := op.ClusterCheckConfig(); err != nil {
+			log.Errorf("cluster config error: %v", err)
+			return
+		}
 		shutdown.Init(log.Logger)
 		defer shutdown.Listen()
 		if err := db.InitDB(conf.AppConfig.Database.Type, conf.AppConfig.Database.Path, conf.IsDebug()); err != nil {
@@ -30,6 +37,10 @@ var startCmd = &cobra.Command{
 		}
 		shutdown.Register(db.Close)
 
+		if err := op.StatsJournalReplay(context.Background()); err != nil {
+			log.Errorf("stats journal replay error: %v", err)
+			return
+		}
 		if err := op.InitCache(); err != nil {
 			log.Errorf("cache init error: %v", err)
 			return
@@ -40,16 +51,28 @@ var startCmd = &cobra.Command{
 			log.Errorf("user init error: %v", err)
 			return
 		}
+		if err := op.AuthSecretInit(); err != nil {
+			log.Errorf("auth secret init error: %v", err)
+			return
+		}
 		op.SensitiveFilterInit()
+		if err := op.ClusterInit(); err != nil {
+			log.Errorf("cluster init error: %v", err)
+			return
+		}
+		shutdown.Register(op.ClusterClose)
+		gitops.Init()
+
+		task.Init()
+		task.Start()
+		shutdown.Register(task.Stop)
 
+		// 最后注册，退出时最先排空请求，之后再停止任务并保存缓存
 		if err := server.Start(); err != nil {
 			log.Errorf("server start error: %v", err)
 			return
 		}
 		shutdown.Register(server.Close)
-
-		task.Init()
-		go task.RUN()
 	},
 }
 
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/spf13/cobra"
+)
+
+var userResetPasswordOpts struct {
+	username  string
+	password  string
+	resetTOTP bool
+}
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage admin users",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		log.SetLevel("error")
+	},
+}
+
+var userResetPasswordCmd = &cobra.Command{
+	Use:   "reset-password",
+	Short: "Reset an admin user's password",
+	Long: `Reset an admin user's password directly in the database, without the old password.
+The first owner is reset when --username is not given.
+A random password is generated and printed when --password is not given. Restart the server afterwards.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		password := userResetPasswordOpts.password
+		if password == "" {
+			buf := make([]byte, 12)
+			if _, err := rand.Read(buf); err != nil {
+				return err
+			}
+			password = hex.EncodeToString(buf)
+		}
+		if err := conf.Load(cfgFile); err != nil {
+			return err
+		}
+		if err := db.InitDB(conf.AppConfig.Database.Type, conf.AppConfig.Database.Path, conf.IsDebug()); err != nil {
+			return fmt.Errorf("database init error: %w", err)
+		}
+		defer db.Close()
+		user, err := op.UserResetPassword(userResetPasswordOpts.username, password)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("password reset for user %s\n", user.Username)
+		if userResetPasswordOpts.resetTOTP {
+			if err := op.UserTOTPReset(user.ID, context.Background()); err != nil {
+				return err
+			}
+			fmt.Println("two-factor authentication disabled")
+		}
+		if userResetPasswordOpts.password == "" {
+			fmt.Printf("new password: %s\n", password)
+		}
+		return nil
+	},
+}
+
+var userRotateSecretCmd = &cobra.Command{
+	Use:   "rotate-secret",
+	Short: "Rotate the token signing secret and sign out all sessions",
+	Long: `Replace the admin token signing secret and revoke every login session.
+Use this when a token or the database may have leaked. Restart the server afterwards.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := conf.Load(cfgFile); err != nil {
+			return err
+		}
+		if err := db.InitDB(conf.AppConfig.Database.Type, conf.AppConfig.Database.Path, conf.IsDebug()); err != nil {
+			return fmt.Errorf("database init error: %w", err)
+		}
+		defer db.Close()
+		ctx := context.Background()
+		if _, err := op.AuthSecretRotate(0, ctx); err != nil {
+			return err
+		}
+		if err := op.SessionRevokeAll(ctx); err != nil {
+			return err
+		}
+		fmt.Println("signing secret rotated, all sessions revoked")
+		return nil
+	},
+}
+
+func init() {
+	userResetPasswordCmd.Flags().StringVar(&userResetPasswordOpts.username, "username", "", "user to reset (default is the first owner)")
+	userResetPasswordCmd.Flags().StringVar(&userResetPasswordOpts.password, "password", "", "new password (default is a random password)")
+	userResetPasswordCmd.Flags().BoolVar(&userResetPasswordOpts.resetTOTP, "disable-2fa", false, "also disable two-factor authentication")
+
+	userCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./data/config.json)")
+	userCmd.AddCommand(userResetPasswordCmd)
+	userCmd.AddCommand(userRotateSecretCmd)
+	rootCmd.AddCommand(userCmd)
+}
@@ -10,8 +10,9 @@ import (
 )
 
 type Server struct {
-	Host string `mapstructure:"host"`
-	Port int    `mapstructure:"port"`
+	Host            string `mapstructure:"host"`
+	Port            int    `mapstructure:"port"`
+	ShutdownTimeout int    `mapstructure:"shutdown_timeout"` // 退出时等待正在处理的请求结束的最长时间(秒)
 }
 
 type Log struct {
@@ -23,10 +24,60 @@ type Database struct {
 	Path string `mapstructure:"path"`
 }
 
+type GitOps struct {
+	Path     string `mapstructure:"path"`     // 声明式配置目录，为空时不启用
+	Prune    bool   `mapstructure:"prune"`    // 删除配置中已移除的托管对象
+	Interval int    `mapstructure:"interval"` // 检查配置变更的间隔(秒)，0 仅在启动和手动触发时同步
+}
+
+type Backup struct {
+	Path       string `mapstructure:"path"`       // 定时备份保存目录
+	Passphrase string `mapstructure:"passphrase"` // 备份加密口令，为空时不加密
+}
+
+type Cluster struct {
+	Enabled      bool   `mapstructure:"enabled"`       // 多实例共享数据库部署
+	NodeID       string `mapstructure:"node_id"`       // 实例标识，多实例模式下必须配置且重启后保持不变
+	SyncInterval int    `mapstructure:"sync_interval"` // 检查其他实例配置变更的间隔(秒)
+	LeaseTTL     int    `mapstructure:"lease_ttl"`     // 主节点租约时长(秒)，过期后其他实例接管定时任务
+}
+
+type Task struct {
+	Schedules map[string]string `mapstructure:"schedules"` // 按任务名覆盖执行计划，支持 cron 表达式和 @every <duration>
+}
+
+// OIDCRoleMapping 声明值为 Value 的用户获得角色 Role
+type OIDCRoleMapping struct {
+	Value string `mapstructure:"value"`
+	Role  string `mapstructure:"role"`
+}
+
+type OIDC struct {
+	Enabled       bool              `mapstructure:"enabled"`        // 启用单点登录
+	Name          string            `mapstructure:"name"`           // 登录页按钮上显示的名称
+	Issuer        string            `mapstructure:"issuer"`         // 提供方地址，从 /.well-known/openid-configuration 读取端点
+	ClientID      string            `mapstructure:"client_id"`      // 客户端 ID
+	ClientSecret  string            `mapstructure:"client_secret"`  // 客户端密钥，公共客户端留空
+	RedirectURL   string            `mapstructure:"redirect_url"`   // 回调地址，为空时按请求地址生成 /api/v1/user/oidc/callback
+	Scopes        []string          `mapstructure:"scopes"`         // 请求的 scope，需要包含 openid
+	UsernameClaim string            `mapstructure:"username_claim"` // 作为用户名的声明，缺失时依次使用 email 和 sub
+	RoleClaim     string            `mapstructure:"role_claim"`     // 用于映射角色的声明，值可以是字符串或字符串数组
+	RoleMappings  []OIDCRoleMapping `mapstructure:"role_mappings"`  // 声明值到角色的映射，匹配多个时取权限最高的角色
+	DefaultRole   string            `mapstructure:"default_role"`   // 没有匹配的映射时使用的角色，为空时拒绝登录
+	AutoCreate    bool              `mapstructure:"auto_create"`    // 首次登录时自动创建用户
+	LinkExisting  bool              `mapstructure:"link_existing"`  // 允许关联同名的已有用户，只按 username_claim 关联，为 email 时要求 email_verified；该声明须唯一且用户不能自行修改
+	SyncRole      bool              `mapstructure:"sync_role"`      // 每次登录按映射更新用户的角色
+}
+
 type Config struct {
 	Server   Server   `mapstructure:"server"`
 	Log      Log      `mapstructure:"log"`
 	Database Database `mapstructure:"database"`
+	GitOps   GitOps   `mapstructure:"gitops"`
+	Backup   Backup   `mapstructure:"backup"`
+	Cluster  Cluster  `mapstructure:"cluster"`
+	Task     Task     `mapstructure:"task"`
+	OIDC     OIDC     `mapstructure:"oidc"`
 }
 
 var AppConfig Config
@@ -71,7 +122,32 @@ func Load(path string) error {
 func setDefaults() {
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.port", 8080)
+	viper.SetDefault("server.shutdown_timeout", 30)
 	viper.SetDefault("database.type", "sqlite")
 	viper.SetDefault("database.path", "data/data.db")
 	viper.SetDefault("log.level", "info")
+	viper.SetDefault("gitops.path", "")
+	viper.SetDefault("gitops.prune", true)
+	viper.SetDefault("gitops.interval", 30)
+	viper.SetDefault("backup.path", "data/backups")
+	viper.SetDefault("backup.passphrase", "")
+	viper.SetDefault("cluster.enabled", false)
+	viper.SetDefault("cluster.node_id", "")
+	viper.SetDefault("cluster.sync_interval", 5)
+	viper.SetDefault("cluster.lease_ttl", 30)
+	viper.SetDefault("task.schedules", map[string]string{})
+	viper.SetDefault("oidc.enabled", false)
+	viper.SetDefault("oidc.name", "SSO")
+	viper.SetDefault("oidc.issuer", "")
+	viper.SetDefault("oidc.client_id", "")
+	viper.SetDefault("oidc.client_secret", "")
+	viper.SetDefault("oidc.redirect_url", "")
+	viper.SetDefault("oidc.scopes", []string{"openid", "profile", "email"})
+	viper.SetDefault("oidc.username_claim", "preferred_username")
+	viper.SetDefault("oidc.role_claim", "groups")
+	viper.SetDefault("oidc.role_mappings", []map[string]string{})
+	viper.SetDefault("oidc.default_role", "")
+	viper.SetDefault("oidc.auto_create", true)
+	viper.SetDefault("oidc.link_existing", false)
+	viper.SetDefault("oidc.sync_role", true)
 }
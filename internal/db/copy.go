@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/db/migrate"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const defaultCopyBatchSize = 500
+
+// CopyOptions 跨库复制的选项
+type CopyOptions struct {
+	BatchSize int
+	// Resume 允许目标库已有数据，用于继续中断的复制；已存在的行按主键跳过
+	Resume bool
+	// Progress 每写入一批后回调
+	Progress func(table string, copied, total int64)
+}
+
+// CopyResult 单个表的复制结果
+type CopyResult struct {
+	Table  string `json:"table"`
+	Source int64  `json:"source"`
+	Target int64  `json:"target"`
+	Copied int64  `json:"copied"`
+}
+
+// Copy 按主键顺序分批将 src 中所有迁移表的数据复制到 dst，保留主键并重置序列，完成后校验行数
+// 两个库都需要已经通过 Open 完成迁移
+func Copy(ctx context.Context, src, dst *gorm.DB, opts CopyOptions) ([]CopyResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultCopyBatchSize
+	}
+	src = src.WithContext(ctx)
+	dst = dst.WithContext(ctx)
+
+	schemas := make([]*schema.Schema, len(models))
+	for i, m := range models {
+		stmt := &gorm.Statement{DB: src}
+		if err := stmt.Parse(m); err != nil {
+			return nil, err
+		}
+		if len(stmt.Schema.PrimaryFields) == 0 {
+			return nil, fmt.Errorf("table %s has no primary key", stmt.Schema.Table)
+		}
+		schemas[i] = stmt.Schema
+	}
+
+	if !opts.Resume {
+		for i, sch := range schemas {
+			// 迁移记录在打开目标库时就已写入
+			if _, ok := models[i].(*migrate.MigrationRecord); ok {
+				continue
+			}
+			var n int64
+			if err := dst.Model(models[i]).Count(&n).Error; err != nil {
+				return nil, fmt.Errorf("count target %s: %w", sch.Table, err)
+			}
+			if n > 0 {
+				return nil, fmt.Errorf("target table %s is not empty, resume to continue an interrupted copy", sch.Table)
+			}
+		}
+	}
+
+	results := make([]CopyResult, 0, len(models))
+	for i, sch := range schemas {
+		res, err := copyTable(src, dst, models[i], sch, opts)
+		if err != nil {
+			return results, fmt.Errorf("copy %s: %w", sch.Table, err)
+		}
+		results = append(results, res)
+	}
+
+	var mismatched []string
+	for i := range results {
+		if err := dst.Model(models[i]).Count(&results[i].Target).Error; err != nil {
+			return results, fmt.Errorf("count target %s: %w", results[i].Table, err)
+		}
+		if results[i].Target != results[i].Source {
+			mismatched = append(mismatched, results[i].Table)
+		}
+	}
+	if len(mismatched) > 0 {
+		return results, fmt.Errorf("row count mismatch: %s", strings.Join(mismatched, ", "))
+	}
+	return results, nil
+}
+
+// copyTable 以主键作为游标分批读取，续传时整数主键从目标库的最大值之后开始
+func copyTable(src, dst *gorm.DB, m any, sch *schema.Schema, opts CopyOptions) (CopyResult, error) {
+	res := CopyResult{Table: sch.Table}
+	if err := src.Model(m).Count(&res.Source).Error; err != nil {
+		return res, err
+	}
+
+	pks := sch.PrimaryFields
+	quoted := make([]string, len(pks))
+	for i, f := range pks {
+		quoted[i] = src.Statement.Quote(f.DBName)
+	}
+
+	var cursor []any
+	if opts.Resume && len(pks) == 1 && (pks[0].DataType == schema.Int || pks[0].DataType == schema.Uint) {
+		var last sql.NullInt64
+		if err := dst.Model(m).Select("MAX(" + dst.Statement.Quote(pks[0].DBName) + ")").Row().Scan(&last); err != nil {
+			return res, err
+		}
+		if last.Valid {
+			cursor = []any{last.Int64}
+		}
+	}
+
+	sliceType := reflect.SliceOf(reflect.TypeOf(m).Elem())
+	for {
+		query := src.Model(m).Order(strings.Join(quoted, ", ")).Limit(opts.BatchSize)
+		if cursor != nil {
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(cursor)), ", ")
+			query = query.Where(fmt.Sprintf("(%s) > (%s)", strings.Join(quoted, ", "), placeholders), cursor...)
+		}
+		rows := reflect.New(sliceType)
+		if err := query.Find(rows.Interface()).Error; err != nil {
+			return res, err
+		}
+		n := rows.Elem().Len()
+		if n == 0 {
+			break
+		}
+		maps, err := ColumnMaps(dst, rows.Interface())
+		if err != nil {
+			return res, err
+		}
+		result := dst.Model(m).Clauses(clause.OnConflict{DoNothing: true}).Create(&maps)
+		if result.Error != nil {
+			return res, result.Error
+		}
+		res.Copied += result.RowsAffected
+		if opts.Progress != nil {
+			opts.Progress(sch.Table, res.Copied, res.Source)
+		}
+
+		lastRow := rows.Elem().Index(n - 1)
+		cursor = make([]any, len(pks))
+		for i, f := range pks {
+			cursor[i], _ = f.ValueOf(src.Statement.Context, lastRow)
+		}
+		if n < opts.BatchSize {
+			break
+		}
+	}
+
+	for _, f := range pks {
+		if f.AutoIncrement {
+			if err := ResetSequence(dst, sch.Table, f.DBName); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, nil
+}
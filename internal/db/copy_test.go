@@ -0,0 +1,91 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm"
+)
+
+func countRows(t *testing.T, conn *gorm.DB, m any) int64 {
+	t.Helper()
+	var n int64
+	if err := conn.Model(m).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCopySQLiteToSQLite(t *testing.T) {
+	ctx := context.Background()
+	src := dbtest.Open(t, "src.db")
+	dst := dbtest.Open(t, "dst.db")
+
+	channels := []model.Channel{
+		{ID: 3, Name: "enabled", Enabled: true, BaseUrls: []model.BaseUrl{{URL: "https://a.example"}}},
+		{ID: 7, Name: "disabled", Enabled: false},
+	}
+	if err := src.Create(&channels).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 带默认值的零值字段需要原样复制
+	if err := src.Model(&model.Channel{}).Where("id = ?", 7).Update("enabled", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Create(&[]model.Setting{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Create(&[]model.StatsSensitive{{RuleID: 1, APIKeyID: 1}, {RuleID: 1, APIKeyID: 2}, {RuleID: 2, APIKeyID: 1}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	logs := make([]model.RelayLog, 10)
+	for i := range logs {
+		logs[i] = model.RelayLog{ID: int64(1000 + i), Time: int64(i)}
+	}
+	if err := src.Create(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Copy(ctx, src, dst, db.CopyOptions{BatchSize: 3}); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+
+	var got []model.Channel
+	if err := dst.Order("id").Find(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != 3 || got[1].ID != 7 {
+		t.Fatalf("channels = %+v", got)
+	}
+	if got[1].Enabled {
+		t.Errorf("disabled channel copied as enabled")
+	}
+	if len(got[0].BaseUrls) != 1 || got[0].BaseUrls[0].URL != "https://a.example" {
+		t.Errorf("base urls = %+v", got[0].BaseUrls)
+	}
+	if n := countRows(t, dst, &model.StatsSensitive{}); n != 3 {
+		t.Errorf("stats_sensitives = %d, want 3", n)
+	}
+
+	// 目标库非空时必须显式续传
+	if _, err := db.Copy(ctx, src, dst, db.CopyOptions{}); err == nil {
+		t.Fatal("copy into non-empty target should fail without resume")
+	}
+
+	// 模拟中断：目标库缺少后半部分日志
+	if err := dst.Where("id >= ?", 1006).Delete(&model.RelayLog{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	results, err := db.Copy(ctx, src, dst, db.CopyOptions{BatchSize: 3, Resume: true})
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	for _, r := range results {
+		if r.Table == "relay_logs" && (r.Copied != 4 || r.Target != 10) {
+			t.Errorf("relay_logs result = %+v, want 4 copied and 10 in target", r)
+		}
+	}
+}
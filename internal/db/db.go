@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -16,8 +17,49 @@ import (
 
 var db *gorm.DB
 
+// models 需要自动迁移的表，顺序即跨库迁移时的复制顺序
+var models = []any{
+	&model.User{},
+	&model.AuthSecret{},
+	&model.UserSession{},
+	&model.AccessToken{},
+	&model.Channel{},
+	&model.ChannelKey{},
+	&model.Group{},
+	&model.GroupItem{},
+	&model.LLMInfo{},
+	&model.APIKey{},
+	&model.Setting{},
+	&model.SensitiveRuleSet{},
+	&model.SensitiveFilterRule{},
+	&model.StatsTotal{},
+	&model.StatsDaily{},
+	&model.StatsHourly{},
+	&model.StatsModel{},
+	&model.StatsChannel{},
+	&model.StatsAPIKey{},
+	&model.StatsSensitive{},
+	&model.StatsJournal{},
+	&model.RelayLog{},
+	&model.RelayLogBody{},
+	&model.CacheVersion{},
+	&model.ClusterLease{},
+	&migrate.MigrationRecord{},
+}
+
 func InitDB(dbType, dsn string, debug bool) error {
+	conn, err := Open(dbType, dsn, debug)
+	if err != nil {
+		return err
+	}
+	db = conn
+	return nil
+}
+
+// Open 打开数据库并完成迁移，不替换全局连接
+func Open(dbType, dsn string, debug bool) (*gorm.DB, error) {
 	var err error
+	var db *gorm.DB
 	gormConfig := gorm.Config{Logger: logger.Discard}
 	if debug {
 		gormConfig.Logger = logger.Default.LogMode(logger.Info)
@@ -31,16 +73,16 @@ func InitDB(dbType, dsn string, debug bool) error {
 	case "postgres", "postgresql":
 		db, err = initPostgres(dsn, &gormConfig)
 	default:
-		return fmt.Errorf("unsupported database type: %s", dbType)
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
 	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	sqlDB.SetMaxIdleConns(10)
@@ -49,31 +91,13 @@ func InitDB(dbType, dsn string, debug bool) error {
 	sqlDB.SetConnMaxIdleTime(10 * time.Minute)
 
 	if err := migrate.BeforeAutoMigrate(db); err != nil {
-		return err
+		return nil, err
 	}
-	if err := db.AutoMigrate(
-		&model.User{},
-		&model.Channel{},
-		&model.ChannelKey{},
-		&model.Group{},
-		&model.GroupItem{},
-		&model.LLMInfo{},
-		&model.APIKey{},
-		&model.Setting{},
-		&model.SensitiveFilterRule{},
-		&model.StatsTotal{},
-		&model.StatsDaily{},
-		&model.StatsHourly{},
-		&model.StatsModel{},
-		&model.StatsChannel{},
-		&model.StatsAPIKey{},
-		&model.RelayLog{},
-		&migrate.MigrationRecord{},
-	); err != nil {
-		return err
+	if err := db.AutoMigrate(models...); err != nil {
+		return nil, err
 	}
 	if err := migrate.AfterAutoMigrate(db); err != nil {
-		return err
+		return nil, err
 	}
 	// Postgres: schema changes during migrations can invalidate cached prepared plans
 	// (e.g. "cached plan must not change result type"). Clear them.
@@ -81,7 +105,7 @@ func InitDB(dbType, dsn string, debug bool) error {
 		db.Exec("DEALLOCATE ALL")
 		db.Exec("DISCARD ALL")
 	}
-	return nil
+	return db, nil
 }
 
 func initSQLite(path string, config *gorm.Config) (*gorm.DB, error) {
@@ -119,6 +143,33 @@ func Close() error {
 	return sqlDB.Close()
 }
 
+// Ping 检查数据库连接是否可用
+func Ping(ctx context.Context) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
 func GetDB() *gorm.DB {
 	return db
 }
+
+type txKey struct{}
+
+// Transaction 在事务中执行 fn，fn 中通过 Conn(ctx) 获取的连接都属于该事务
+// 已在事务中时使用嵌套事务（保存点）
+func Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// Conn 返回绑定 ctx 的连接，ctx 由 Transaction 传入时返回所在的事务
+func Conn(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
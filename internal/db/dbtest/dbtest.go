@@ -0,0 +1,34 @@
+// Package dbtest 为测试提供临时的 SQLite 数据库
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"gorm.io/gorm"
+)
+
+// Open 在测试的临时目录中创建并迁移 SQLite 数据库，测试结束时关闭
+func Open(t testing.TB, name string) *gorm.DB {
+	t.Helper()
+	conn, err := db.Open("sqlite", filepath.Join(t.TempDir(), name), false)
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return conn
+}
+
+// Init 在测试的临时目录中创建 SQLite 数据库并设为全局连接，供通过 db.Conn 访问数据库的代码使用
+func Init(t testing.TB, name string) {
+	t.Helper()
+	if err := db.InitDB("sqlite", filepath.Join(t.TempDir(), name), false); err != nil {
+		t.Fatalf("init %s: %v", name, err)
+	}
+	t.Cleanup(func() { db.Close() })
+}
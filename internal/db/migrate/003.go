@@ -0,0 +1,137 @@
+package migrate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	RegisterAfterAutoMigration(Migration{
+		Version: 3,
+		Up:      moveRelayLogContentToBodies,
+	})
+}
+
+// 003: move inline relay_logs.request_content / response_content into relay_log_bodies (gzip) and drop the columns
+func moveRelayLogContentToBodies(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	dialect := db.Dialector.Name()
+
+	// column existence helper
+	hasColumn := func(table, column string) bool {
+		if dialect == "sqlite" {
+			var name string
+			db.Raw("SELECT name FROM pragma_table_info(?) WHERE name = ? LIMIT 1", table, column).Scan(&name)
+			return name == column
+		}
+		return db.Migrator().HasColumn(table, column)
+	}
+
+	if !hasColumn("relay_logs", "request_content") || !hasColumn("relay_logs", "response_content") {
+		return nil
+	}
+
+	compress := func(s string) ([]byte, error) {
+		if s == "" {
+			return nil, nil
+		}
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write([]byte(s)); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	type legacyRow struct {
+		ID              int64
+		Time            int64
+		RequestContent  string
+		ResponseContent string
+	}
+	type bodyRow struct {
+		LogID    int64
+		Time     int64
+		Request  []byte
+		Response []byte
+	}
+
+	const batchSize = 200
+	var lastID int64
+	for {
+		var rows []legacyRow
+		if err := db.Raw(
+			"SELECT id, time, request_content, response_content FROM relay_logs WHERE id > ? AND (request_content <> '' OR response_content <> '') ORDER BY id LIMIT ?",
+			lastID, batchSize,
+		).Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to read legacy relay log content: %w", err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		bodies := make([]bodyRow, 0, len(rows))
+		ids := make([]int64, 0, len(rows))
+		for _, r := range rows {
+			req, err := compress(r.RequestContent)
+			if err != nil {
+				return fmt.Errorf("failed to compress relay log %d: %w", r.ID, err)
+			}
+			res, err := compress(r.ResponseContent)
+			if err != nil {
+				return fmt.Errorf("failed to compress relay log %d: %w", r.ID, err)
+			}
+			bodies = append(bodies, bodyRow{LogID: r.ID, Time: r.Time, Request: req, Response: res})
+			ids = append(ids, r.ID)
+		}
+
+		// clear the moved content in the same transaction so a rerun after an interruption skips these rows,
+		// and keep bodies that already exist
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Table("relay_log_bodies").Clauses(clause.OnConflict{DoNothing: true}).Create(&bodies).Error; err != nil {
+				return err
+			}
+			return tx.Table("relay_logs").Where("id IN ?", ids).Updates(map[string]any{
+				"has_body":         true,
+				"request_content":  "",
+				"response_content": "",
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to move relay log content: %w", err)
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+
+	// drop column helper
+	dropColumn := func(table, column string) error {
+		var sql string
+		switch dialect {
+		case "mysql":
+			sql = fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`", table, column)
+		case "postgres":
+			sql = fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s", table, column)
+		default:
+			// SQLite 3.35.0+
+			sql = fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)
+		}
+		return db.Exec(sql).Error
+	}
+
+	for _, column := range []string{"request_content", "response_content"} {
+		if err := dropColumn("relay_logs", column); err != nil {
+			return fmt.Errorf("failed to drop relay_logs.%s: %w", column, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package migrate_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/db/migrate"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm"
+)
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	if len(data) == 0 {
+		return ""
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// rerun003 删除 003 的完成记录后重新执行迁移
+func rerun003(t *testing.T, conn *gorm.DB) {
+	t.Helper()
+	if err := conn.Delete(&migrate.MigrationRecord{}, 3).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate.AfterAutoMigrate(conn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 003 将旧的内联内容移到 relay_log_bodies，中断后或在已迁移的数据库上重新执行不改变结果
+func TestMoveRelayLogContentToBodies(t *testing.T) {
+	conn := dbtest.Open(t, "migrate.db")
+
+	// 还原为旧版本的表结构
+	for _, column := range []string{"request_content", "response_content"} {
+		if err := conn.Exec("ALTER TABLE relay_logs ADD COLUMN " + column + " TEXT NOT NULL DEFAULT ''").Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	logs := []model.RelayLog{{ID: 1, Time: 10}, {ID: 2, Time: 20}, {ID: 3, Time: 30}}
+	if err := conn.Create(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []struct {
+		id                int64
+		request, response string
+	}{
+		{1, "request 1", "response 1"},
+		{2, "", "response 2"},
+	} {
+		if err := conn.Exec("UPDATE relay_logs SET request_content = ?, response_content = ? WHERE id = ?",
+			row.request, row.response, row.id).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 上次执行中断前已经写入的内容保持不变
+	if err := conn.Create(&model.RelayLogBody{LogID: 2, Time: 20, Response: []byte{}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	check := func() {
+		t.Helper()
+		for _, column := range []string{"request_content", "response_content"} {
+			if conn.Migrator().HasColumn("relay_logs", column) {
+				t.Errorf("relay_logs.%s not dropped", column)
+			}
+		}
+		var bodies []model.RelayLogBody
+		if err := conn.Order("log_id").Find(&bodies).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(bodies) != 2 {
+			t.Fatalf("bodies = %+v, want logs 1 and 2", bodies)
+		}
+		if got := gunzip(t, bodies[0].Request); got != "request 1" || bodies[0].Time != 10 {
+			t.Errorf("log 1 request = %q, time = %d", got, bodies[0].Time)
+		}
+		if got := gunzip(t, bodies[0].Response); got != "response 1" {
+			t.Errorf("log 1 response = %q", got)
+		}
+		if len(bodies[1].Response) != 0 {
+			t.Errorf("existing body of log 2 overwritten")
+		}
+		var flagged []int64
+		if err := conn.Model(&model.RelayLog{}).Where("has_body = ?", true).Order("id").Pluck("id", &flagged).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(flagged) != 2 || flagged[0] != 1 || flagged[1] != 2 {
+			t.Errorf("logs with body = %v, want [1 2]", flagged)
+		}
+	}
+
+	rerun003(t, conn)
+	check()
+	rerun003(t, conn)
+	check()
+}
@@ -36,20 +36,13 @@ func RegisterAfterAutoMigration(m Migration) {
 	afterAutoMigrations = append(afterAutoMigrations, m)
 }
 
+// BeforeAutoMigrate 迁移按记录表跳过已完成的版本，同一进程中可对多个数据库执行
 func BeforeAutoMigrate(db *gorm.DB) error {
-	if err := runMigrationsWithRecord(db, beforeAutoMigrations); err != nil {
-		return err
-	}
-	beforeAutoMigrations = nil
-	return nil
+	return runMigrationsWithRecord(db, beforeAutoMigrations)
 }
 
 func AfterAutoMigrate(db *gorm.DB) error {
-	if err := runMigrationsWithRecord(db, afterAutoMigrations); err != nil {
-		return err
-	}
-	afterAutoMigrations = nil
-	return nil
+	return runMigrationsWithRecord(db, afterAutoMigrations)
 }
 
 func runMigrationsWithRecord(db *gorm.DB, migrations []Migration) error {
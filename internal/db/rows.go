@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// ColumnMaps 将模型切片转换为按列名索引的 map，用于原样写入已有数据
+// 直接用结构体 Create 时，gorm 会把带 default 标签的零值字段替换为默认值（如 enabled=false 写成 true），
+// 用 map 写入可以保留零值；序列化字段仍由 gorm 的 serializer 处理
+func ColumnMaps(tx *gorm.DB, rows any) ([]map[string]any, error) {
+	rv := reflect.Indirect(reflect.ValueOf(rows))
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("rows must be a slice, got %s", rv.Kind())
+	}
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(reflect.New(rv.Type().Elem()).Interface()); err != nil {
+		return nil, err
+	}
+	ctx := tx.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	maps := make([]map[string]any, rv.Len())
+	for i := range maps {
+		row := reflect.Indirect(rv.Index(i))
+		m := make(map[string]any, len(stmt.Schema.DBNames))
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || !field.Creatable {
+				continue
+			}
+			m[field.DBName], _ = field.ValueOf(ctx, row)
+		}
+		maps[i] = m
+	}
+	return maps, nil
+}
+
+// ResetSequence 将 PostgreSQL 自增列的序列设置为当前最大值之后，其他数据库插入指定主键时会自动推进
+func ResetSequence(tx *gorm.DB, table, column string) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	sql := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE((SELECT MAX(%q) FROM %q), 0) + 1, false)`, table, column, column, table)
+	if err := tx.Exec(sql).Error; err != nil {
+		return fmt.Errorf("reset %s sequence: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package gitops
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/transformer/outbound"
+	"go.yaml.in/yaml/v3"
+)
+
+// Config 声明式配置，由配置目录下所有 YAML/JSON 文件合并而成
+// 对象按名称匹配，指针字段省略时不参与对账，创建时使用默认值
+type Config struct {
+	Channels []Channel         `yaml:"channels"`
+	Groups   []Group           `yaml:"groups"`
+	APIKeys  []APIKey          `yaml:"api_keys"`
+	Settings map[string]string `yaml:"settings"`
+}
+
+type Channel struct {
+	Name          string                `yaml:"name"`
+	Type          outbound.OutboundType `yaml:"type"`
+	Enabled       *bool                 `yaml:"enabled"`
+	BaseURLs      []string              `yaml:"base_urls"`
+	Keys          []string              `yaml:"keys"`
+	Model         *string               `yaml:"model"`
+	CustomModel   *string               `yaml:"custom_model"`
+	Proxy         *bool                 `yaml:"proxy"`
+	AutoSync      *bool                 `yaml:"auto_sync"`
+	AutoGroup     *model.AutoGroupType  `yaml:"auto_group"`
+	CustomHeaders []Header              `yaml:"custom_headers"`
+	ChannelProxy  *string               `yaml:"channel_proxy"`
+	ParamOverride *string               `yaml:"param_override"`
+}
+
+type Header struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"`
+}
+
+type Group struct {
+	Name              string          `yaml:"name"`
+	Mode              model.GroupMode `yaml:"mode"`
+	MatchRegex        *string         `yaml:"match_regex"`
+	FirstTokenTimeOut *int            `yaml:"first_token_time_out"`
+	SensitiveRuleSet  *string         `yaml:"sensitive_rule_set"` // 规则集名称，空字符串使用默认规则集
+	Items             []GroupItem     `yaml:"items"`
+}
+
+type GroupItem struct {
+	Channel  string `yaml:"channel"`
+	Model    string `yaml:"model"`
+	Priority int    `yaml:"priority"`
+	Weight   int    `yaml:"weight"`
+}
+
+type APIKey struct {
+	Name             string   `yaml:"name"`
+	Key              string   `yaml:"key"` // 为空时创建随机密钥，且不对账密钥值
+	Enabled          *bool    `yaml:"enabled"`
+	ExpireAt         *int64   `yaml:"expire_at"`
+	MaxCost          *float64 `yaml:"max_cost"`
+	SupportedModels  *string  `yaml:"supported_models"`
+	LogBodyDisabled  *bool    `yaml:"log_body_disabled"`
+	LogBodyMaxSize   *int     `yaml:"log_body_max_size"`
+	LogBodyKeepDays  *int     `yaml:"log_body_keep_days"`
+	SensitiveRuleSet *string  `yaml:"sensitive_rule_set"` // 规则集名称，空字符串使用分组或默认规则集
+}
+
+// secretRefRegex 匹配 ${env:NAME} 和 ${file:PATH} 形式的密钥引用
+var secretRefRegex = regexp.MustCompile(`\$\{(env|file):([^}]+)\}`)
+
+// Load 读取目录下的 .yaml/.yml/.json 文件（不递归），合并为一份配置并解析密钥引用
+// 返回值中的 hash 仅由文件名和文件内容决定，用于判断配置是否变化
+func Load(dir string) (*Config, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("read config dir: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+
+	cfg := &Config{Settings: map[string]string{}}
+	hash := sha256.New()
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, "", err
+		}
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write(data)
+		hash.Write([]byte{0})
+
+		var part Config
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&part); err != nil && !errors.Is(err, io.EOF) {
+			return nil, "", fmt.Errorf("%s: %w", name, err)
+		}
+		cfg.Channels = append(cfg.Channels, part.Channels...)
+		cfg.Groups = append(cfg.Groups, part.Groups...)
+		cfg.APIKeys = append(cfg.APIKeys, part.APIKeys...)
+		for key, value := range part.Settings {
+			if _, ok := cfg.Settings[key]; ok {
+				return nil, "", fmt.Errorf("%s: setting %s declared more than once", name, key)
+			}
+			cfg.Settings[key] = value
+		}
+	}
+
+	if err := cfg.resolveSecrets(dir); err != nil {
+		return nil, "", err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, "", err
+	}
+	return cfg, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// resolveSecrets 替换可能包含密钥的字段中的 ${env:NAME} 和 ${file:PATH} 引用，
+// 相对文件路径基于配置目录，文件内容去除首尾空白
+func (c *Config) resolveSecrets(dir string) error {
+	var firstErr error
+	resolve := func(s *string) {
+		if firstErr != nil || !strings.Contains(*s, "${") {
+			return
+		}
+		*s = secretRefRegex.ReplaceAllStringFunc(*s, func(ref string) string {
+			m := secretRefRegex.FindStringSubmatch(ref)
+			switch m[1] {
+			case "env":
+				value, ok := os.LookupEnv(m[2])
+				if !ok && firstErr == nil {
+					firstErr = fmt.Errorf("secret env %s is not set", m[2])
+				}
+				return value
+			default:
+				path := m[2]
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(dir, path)
+				}
+				data, err := os.ReadFile(path)
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("secret file %s: %w", m[2], err)
+				}
+				return strings.TrimSpace(string(data))
+			}
+		})
+	}
+
+	for i := range c.Channels {
+		ch := &c.Channels[i]
+		for j := range ch.BaseURLs {
+			resolve(&ch.BaseURLs[j])
+		}
+		for j := range ch.Keys {
+			resolve(&ch.Keys[j])
+		}
+		for j := range ch.CustomHeaders {
+			resolve(&ch.CustomHeaders[j].Value)
+		}
+		if ch.ChannelProxy != nil {
+			resolve(ch.ChannelProxy)
+		}
+		if ch.ParamOverride != nil {
+			resolve(ch.ParamOverride)
+		}
+	}
+	for i := range c.APIKeys {
+		resolve(&c.APIKeys[i].Key)
+	}
+	for key, value := range c.Settings {
+		resolve(&value)
+		c.Settings[key] = value
+	}
+	return firstErr
+}
+
+// validate 校验配置自身的一致性，引用数据库中对象的校验在对账时进行
+func (c *Config) validate() error {
+	channels := make(map[string]struct{}, len(c.Channels))
+	for _, ch := range c.Channels {
+		if ch.Name == "" {
+			return fmt.Errorf("channel name is required")
+		}
+		if _, ok := channels[ch.Name]; ok {
+			return fmt.Errorf("channel %s declared more than once", ch.Name)
+		}
+		channels[ch.Name] = struct{}{}
+		if outbound.Get(ch.Type) == nil {
+			return fmt.Errorf("channel %s: unsupported type %d", ch.Name, ch.Type)
+		}
+	}
+
+	groups := make(map[string]struct{}, len(c.Groups))
+	for _, g := range c.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("group name is required")
+		}
+		if _, ok := groups[g.Name]; ok {
+			return fmt.Errorf("group %s declared more than once", g.Name)
+		}
+		groups[g.Name] = struct{}{}
+		if g.Mode < model.GroupModeRoundRobin || g.Mode > model.GroupModeWeighted {
+			return fmt.Errorf("group %s: mode must be between %d and %d", g.Name, model.GroupModeRoundRobin, model.GroupModeWeighted)
+		}
+		items := make(map[string]struct{}, len(g.Items))
+		for _, item := range g.Items {
+			if item.Channel == "" || item.Model == "" {
+				return fmt.Errorf("group %s: item channel and model are required", g.Name)
+			}
+			key := item.Channel + "\x00" + item.Model
+			if _, ok := items[key]; ok {
+				return fmt.Errorf("group %s: item %s/%s declared more than once", g.Name, item.Channel, item.Model)
+			}
+			items[key] = struct{}{}
+		}
+	}
+
+	apiKeys := make(map[string]struct{}, len(c.APIKeys))
+	for _, k := range c.APIKeys {
+		if k.Name == "" {
+			return fmt.Errorf("api key name is required")
+		}
+		if _, ok := apiKeys[k.Name]; ok {
+			return fmt.Errorf("api key %s declared more than once", k.Name)
+		}
+		apiKeys[k.Name] = struct{}{}
+	}
+
+	defaults := make(map[model.SettingKey]struct{})
+	for _, s := range model.DefaultSettings() {
+		defaults[s.Key] = struct{}{}
+	}
+	for key, value := range c.Settings {
+		setting := model.Setting{Key: model.SettingKey(key), Value: value}
+		if _, ok := defaults[setting.Key]; !ok {
+			return fmt.Errorf("unknown setting %s", key)
+		}
+		if err := setting.Validate(); err != nil {
+			return fmt.Errorf("setting %s: %w", key, err)
+		}
+	}
+	return nil
+}
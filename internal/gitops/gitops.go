@@ -0,0 +1,125 @@
+// Package gitops 将配置目录中声明的渠道、分组、API Key 和设置项与数据库对账。
+// 启动时同步一次，之后按间隔检查文件变化，也可以通过管理接口或命令行手动触发。
+// 由配置管理的对象在管理接口中只读。
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/task"
+	"github.com/bestruirui/octopus/internal/utils/log"
+)
+
+const TaskGitOpsSync = "gitops_sync"
+
+var (
+	syncMu sync.Mutex
+	status model.GitOpsStatus
+)
+
+// Enabled 是否配置了声明式配置目录
+func Enabled() bool {
+	return conf.AppConfig.GitOps.Path != ""
+}
+
+// Init 启动时同步一次配置，并注册定时检查任务；同步失败只记录日志，不阻止启动
+// 多实例部署时只由主节点对账，其他实例通过缓存版本号得到变更
+func Init() {
+	if !Enabled() {
+		return
+	}
+	if op.ClusterIsLeader() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if _, err := Sync(ctx, false); err != nil {
+			log.Errorf("gitops sync failed: %v", err)
+		}
+	}
+	interval := conf.AppConfig.GitOps.Interval
+	task.Register(TaskGitOpsSync, task.Every(time.Duration(interval)*time.Second), false, syncIfChanged)
+}
+
+// Sync 读取配置目录并对账，dryRun 为 true 时只返回差异
+func Sync(ctx context.Context, dryRun bool) (*model.GitOpsPlan, error) {
+	if !Enabled() {
+		return nil, fmt.Errorf("gitops is not enabled: set gitops.path in config")
+	}
+	syncMu.Lock()
+	defer syncMu.Unlock()
+	return syncLocked(ctx, dryRun)
+}
+
+func syncLocked(ctx context.Context, dryRun bool) (*model.GitOpsPlan, error) {
+	cfg, hash, err := Load(conf.AppConfig.GitOps.Path)
+	if err != nil {
+		if !dryRun {
+			setStatus(nil, err)
+		}
+		return nil, err
+	}
+	changes, err := Reconcile(ctx, cfg, dryRun, conf.AppConfig.GitOps.Prune)
+	plan := &model.GitOpsPlan{DryRun: dryRun, Hash: hash, Changes: changes}
+	if !dryRun {
+		setStatus(plan, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// syncIfChanged 定时任务：配置文件内容变化时重新对账，内容不变时不重试失败的同步
+func syncIfChanged(ctx context.Context) error {
+	if task.TriggerOf(ctx) != model.TaskTriggerManual && !op.ClusterIsLeader() {
+		return nil
+	}
+	syncMu.Lock()
+	defer syncMu.Unlock()
+	_, hash, err := Load(conf.AppConfig.GitOps.Path)
+	if err != nil {
+		if status.LastError != err.Error() {
+			setStatus(nil, err)
+			return fmt.Errorf("gitops load failed: %w", err)
+		}
+		return nil
+	}
+	if hash == status.LastHash {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	if _, err := syncLocked(ctx, false); err != nil {
+		return fmt.Errorf("gitops sync failed: %w", err)
+	}
+	return nil
+}
+
+func setStatus(plan *model.GitOpsPlan, err error) {
+	status.LastSyncTime = time.Now().Unix()
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	if plan != nil {
+		status.LastHash = plan.Hash
+		status.LastChanges = plan.Changes
+	}
+}
+
+// Status 返回当前配置和最近一次同步的结果
+func Status() model.GitOpsStatus {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+	s := status
+	s.Enabled = Enabled()
+	s.Path = conf.AppConfig.GitOps.Path
+	s.Prune = conf.AppConfig.GitOps.Prune
+	s.Interval = conf.AppConfig.GitOps.Interval
+	return s
+}
@@ -0,0 +1,724 @@
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/helper"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/task"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/dlclark/regexp2"
+)
+
+// reconciler 对比声明式配置与当前数据，dryRun 时只记录差异
+type reconciler struct {
+	ctx     context.Context
+	dryRun  bool
+	prune   bool
+	changes []model.GitOpsChange
+	// afterCommit 事务提交后执行的操作，如重新调度任务、后台更新渠道信息
+	afterCommit []func()
+
+	channels     map[string]model.Channel
+	channelNames map[int]string
+	ruleSets     map[string]int
+}
+
+// Reconcile 将配置与数据库对账，返回差异列表；dryRun 为 false 时按顺序应用变更：
+// 设置项、渠道、分组、API Key 的创建和更新，然后删除配置中已移除的托管对象
+// 只有托管对象会被删除，未托管的同名对象在首次对账时被接管
+// 所有变更在同一事务中应用，任一步失败时整体回滚，并从数据库重新加载已被修改的缓存
+func Reconcile(ctx context.Context, cfg *Config, dryRun, prune bool) ([]model.GitOpsChange, error) {
+	r := &reconciler{
+		dryRun:       dryRun,
+		prune:        prune,
+		changes:      []model.GitOpsChange{},
+		channels:     map[string]model.Channel{},
+		channelNames: map[int]string{},
+		ruleSets:     map[string]int{"": 0},
+	}
+	if dryRun {
+		err := r.run(ctx, cfg)
+		return r.changes, err
+	}
+
+	err := db.Transaction(ctx, func(ctx context.Context) error {
+		return r.run(ctx, cfg)
+	})
+	if err != nil {
+		if reloadErr := op.CacheReload(context.WithoutCancel(ctx)); reloadErr != nil {
+			log.Warnf("gitops: failed to reload cache after rollback: %v", reloadErr)
+		}
+		return r.changes, err
+	}
+	for _, fn := range r.afterCommit {
+		fn()
+	}
+	return r.changes, nil
+}
+
+func (r *reconciler) run(ctx context.Context, cfg *Config) error {
+	r.ctx = ctx
+	channels, err := op.ChannelList(ctx)
+	if err != nil {
+		return err
+	}
+	for _, ch := range channels {
+		r.channels[ch.Name] = ch
+		r.channelNames[ch.ID] = ch.Name
+	}
+	ruleSets, err := op.SensitiveRuleSetList(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rs := range ruleSets {
+		r.ruleSets[rs.Name] = rs.ID
+	}
+
+	if err := r.settings(cfg.Settings); err != nil {
+		return err
+	}
+	if err := r.upsertChannels(cfg.Channels); err != nil {
+		return err
+	}
+	if err := r.upsertGroups(cfg.Groups); err != nil {
+		return err
+	}
+	if err := r.upsertAPIKeys(cfg.APIKeys); err != nil {
+		return err
+	}
+	if r.prune {
+		if err := r.pruneAPIKeys(cfg.APIKeys); err != nil {
+			return err
+		}
+		if err := r.pruneGroups(cfg.Groups); err != nil {
+			return err
+		}
+		if err := r.pruneChannels(cfg.Channels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) record(kind model.GitOpsKind, name string, action model.GitOpsAction, fields []string) {
+	r.changes = append(r.changes, model.GitOpsChange{Kind: kind, Name: name, Action: action, Fields: fields})
+	if !r.dryRun {
+		log.Infof("gitops: %s %s %s %v", action, kind, name, fields)
+	}
+}
+
+func (r *reconciler) settings(desired map[string]string) error {
+	current, err := op.SettingList(r.ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(current, func(i, j int) bool { return current[i].Key < current[j].Key })
+
+	for _, setting := range current {
+		value, ok := desired[string(setting.Key)]
+		if !ok {
+			if r.prune && setting.Managed {
+				r.record(model.GitOpsKindSetting, string(setting.Key), model.GitOpsActionUnmanage, nil)
+				if !r.dryRun {
+					if err := op.SettingSetManaged(setting.Key, false, r.ctx); err != nil {
+						return fmt.Errorf("setting %s: %w", setting.Key, err)
+					}
+				}
+			}
+			continue
+		}
+		var fields []string
+		if setting.Value != value {
+			fields = append(fields, "value")
+		}
+		if !setting.Managed {
+			fields = append(fields, "managed")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		r.record(model.GitOpsKindSetting, string(setting.Key), model.GitOpsActionUpdate, fields)
+		if r.dryRun {
+			continue
+		}
+		if err := op.SettingSetString(setting.Key, value, r.ctx); err != nil {
+			return fmt.Errorf("setting %s: %w", setting.Key, err)
+		}
+		key := setting.Key
+		r.afterCommit = append(r.afterCommit, func() {
+			if err := task.SettingUpdated(key, value); err != nil {
+				log.Warnf("gitops: setting %s: %v", key, err)
+			}
+		})
+		if err := op.SettingSetManaged(setting.Key, true, r.ctx); err != nil {
+			return fmt.Errorf("setting %s: %w", setting.Key, err)
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) upsertChannels(desired []Channel) error {
+	for _, want := range desired {
+		cur, ok := r.channels[want.Name]
+		if !ok {
+			ch := newChannel(want)
+			r.record(model.GitOpsKindChannel, want.Name, model.GitOpsActionCreate, nil)
+			if !r.dryRun {
+				if err := op.ChannelCreate(&ch, r.ctx); err != nil {
+					return fmt.Errorf("channel %s: %w", want.Name, err)
+				}
+				r.afterCommit = append(r.afterCommit, func() { channelChanged(ch) })
+			}
+			r.channels[ch.Name] = ch
+			continue
+		}
+
+		req, fields := diffChannel(cur, want)
+		if !cur.Managed {
+			fields = append(fields, "managed")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		r.record(model.GitOpsKindChannel, want.Name, model.GitOpsActionUpdate, fields)
+		if r.dryRun {
+			continue
+		}
+		ch, err := op.ChannelUpdate(req, r.ctx)
+		if err != nil {
+			return fmt.Errorf("channel %s: %w", want.Name, err)
+		}
+		if !cur.Managed {
+			if err := op.ChannelSetManaged(cur.ID, true, r.ctx); err != nil {
+				return fmt.Errorf("channel %s: %w", want.Name, err)
+			}
+		}
+		r.channels[ch.Name] = *ch
+		changed := *ch
+		r.afterCommit = append(r.afterCommit, func() { channelChanged(changed) })
+	}
+	return nil
+}
+
+func (r *reconciler) upsertGroups(desired []Group) error {
+	groups, err := op.GroupList(r.ctx)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]model.Group, len(groups))
+	for _, g := range groups {
+		current[g.Name] = g
+	}
+
+	for _, want := range desired {
+		if want.MatchRegex != nil && *want.MatchRegex != "" {
+			if _, err := regexp2.Compile(*want.MatchRegex, regexp2.ECMAScript); err != nil {
+				return fmt.Errorf("group %s: %w", want.Name, err)
+			}
+		}
+		ruleSetID, err := r.ruleSetID(want.SensitiveRuleSet)
+		if err != nil {
+			return fmt.Errorf("group %s: %w", want.Name, err)
+		}
+		for _, item := range want.Items {
+			if _, ok := r.channels[item.Channel]; !ok {
+				return fmt.Errorf("group %s: channel %s not found", want.Name, item.Channel)
+			}
+		}
+
+		cur, ok := current[want.Name]
+		if !ok {
+			group := model.Group{
+				Name:    want.Name,
+				Mode:    want.Mode,
+				Managed: true,
+			}
+			if ruleSetID >= 0 {
+				group.SensitiveRuleSetID = ruleSetID
+			}
+			if want.MatchRegex != nil {
+				group.MatchRegex = *want.MatchRegex
+			}
+			if want.FirstTokenTimeOut != nil {
+				group.FirstTokenTimeOut = *want.FirstTokenTimeOut
+			}
+			for _, item := range want.Items {
+				group.Items = append(group.Items, model.GroupItem{
+					ChannelID: r.channels[item.Channel].ID,
+					ModelName: item.Model,
+					Priority:  item.Priority,
+					Weight:    item.Weight,
+				})
+			}
+			r.record(model.GitOpsKindGroup, want.Name, model.GitOpsActionCreate, nil)
+			if !r.dryRun {
+				if err := op.GroupCreate(&group, r.ctx); err != nil {
+					return fmt.Errorf("group %s: %w", want.Name, err)
+				}
+			}
+			continue
+		}
+
+		req, fields := r.diffGroup(cur, want, ruleSetID)
+		if !cur.Managed {
+			fields = append(fields, "managed")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		r.record(model.GitOpsKindGroup, want.Name, model.GitOpsActionUpdate, fields)
+		if r.dryRun {
+			continue
+		}
+		if _, err := op.GroupUpdate(req, r.ctx); err != nil {
+			return fmt.Errorf("group %s: %w", want.Name, err)
+		}
+		if !cur.Managed {
+			if err := op.GroupSetManaged(cur.ID, true, r.ctx); err != nil {
+				return fmt.Errorf("group %s: %w", want.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) upsertAPIKeys(desired []APIKey) error {
+	keys, err := op.APIKeyList(r.ctx)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]model.APIKey, len(keys))
+	duplicated := make(map[string]struct{})
+	for _, k := range keys {
+		if _, ok := current[k.Name]; ok {
+			duplicated[k.Name] = struct{}{}
+		}
+		current[k.Name] = k
+	}
+
+	for _, want := range desired {
+		if _, ok := duplicated[want.Name]; ok {
+			return fmt.Errorf("api key %s: name is not unique in database", want.Name)
+		}
+		ruleSetID, err := r.ruleSetID(want.SensitiveRuleSet)
+		if err != nil {
+			return fmt.Errorf("api key %s: %w", want.Name, err)
+		}
+
+		cur, ok := current[want.Name]
+		if !ok {
+			key := model.APIKey{Name: want.Name, APIKey: want.Key, Enabled: true, Managed: true}
+			if key.APIKey == "" {
+				if key.APIKey, err = op.APIKeyGenerate(); err != nil {
+					return fmt.Errorf("api key %s: %w", want.Name, err)
+				}
+			}
+			applyAPIKey(&key, want, ruleSetID)
+			r.record(model.GitOpsKindAPIKey, want.Name, model.GitOpsActionCreate, nil)
+			if !r.dryRun {
+				if err := op.APIKeyCreate(&key, r.ctx); err != nil {
+					return fmt.Errorf("api key %s: %w", want.Name, err)
+				}
+			}
+			continue
+		}
+
+		next := cur
+		next.Managed = true
+		applyAPIKey(&next, want, ruleSetID)
+		fields := diffAPIKey(cur, next)
+		rotate := want.Key != "" && want.Key != cur.APIKey
+		if rotate {
+			fields = append(fields, "api_key")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		r.record(model.GitOpsKindAPIKey, want.Name, model.GitOpsActionUpdate, fields)
+		if r.dryRun {
+			continue
+		}
+		if err := op.APIKeyUpdate(&next, r.ctx); err != nil {
+			return fmt.Errorf("api key %s: %w", want.Name, err)
+		}
+		if rotate {
+			if err := op.APIKeySetKey(cur.ID, want.Key, r.ctx); err != nil {
+				return fmt.Errorf("api key %s: %w", want.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) pruneAPIKeys(desired []APIKey) error {
+	keep := make(map[string]struct{}, len(desired))
+	for _, k := range desired {
+		keep[k.Name] = struct{}{}
+	}
+	keys, err := op.APIKeyList(r.ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	for _, k := range keys {
+		if _, ok := keep[k.Name]; ok || !k.Managed {
+			continue
+		}
+		r.record(model.GitOpsKindAPIKey, k.Name, model.GitOpsActionDelete, nil)
+		if !r.dryRun {
+			if err := op.APIKeyDelete(k.ID, r.ctx); err != nil {
+				return fmt.Errorf("api key %s: %w", k.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) pruneGroups(desired []Group) error {
+	keep := make(map[string]struct{}, len(desired))
+	for _, g := range desired {
+		keep[g.Name] = struct{}{}
+	}
+	groups, err := op.GroupList(r.ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	for _, g := range groups {
+		if _, ok := keep[g.Name]; ok || !g.Managed {
+			continue
+		}
+		r.record(model.GitOpsKindGroup, g.Name, model.GitOpsActionDelete, nil)
+		if !r.dryRun {
+			if err := op.GroupDel(g.ID, r.ctx); err != nil {
+				return fmt.Errorf("group %s: %w", g.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) pruneChannels(desired []Channel) error {
+	keep := make(map[string]struct{}, len(desired))
+	for _, ch := range desired {
+		keep[ch.Name] = struct{}{}
+	}
+	names := make([]string, 0, len(r.channels))
+	for name := range r.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ch := r.channels[name]
+		if _, ok := keep[name]; ok || !ch.Managed {
+			continue
+		}
+		r.record(model.GitOpsKindChannel, name, model.GitOpsActionDelete, nil)
+		if !r.dryRun {
+			if err := op.ChannelDel(ch.ID, r.ctx); err != nil {
+				return fmt.Errorf("channel %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ruleSetID 将规则集名称解析为 ID，nil 表示不对账该字段，返回 -1
+func (r *reconciler) ruleSetID(name *string) (int, error) {
+	if name == nil {
+		return -1, nil
+	}
+	id, ok := r.ruleSets[*name]
+	if !ok {
+		return 0, fmt.Errorf("sensitive rule set %s not found", *name)
+	}
+	return id, nil
+}
+
+func newChannel(want Channel) model.Channel {
+	ch := model.Channel{
+		Name:          want.Name,
+		Type:          want.Type,
+		Enabled:       true,
+		BaseUrls:      toBaseUrls(want.BaseURLs),
+		CustomHeader:  toCustomHeaders(want.CustomHeaders),
+		ChannelProxy:  want.ChannelProxy,
+		ParamOverride: want.ParamOverride,
+		Managed:       true,
+	}
+	if want.Enabled != nil {
+		ch.Enabled = *want.Enabled
+	}
+	for _, key := range uniqueStrings(want.Keys) {
+		ch.Keys = append(ch.Keys, model.ChannelKey{Enabled: true, ChannelKey: key})
+	}
+	if want.Model != nil {
+		ch.Model = *want.Model
+	}
+	if want.CustomModel != nil {
+		ch.CustomModel = *want.CustomModel
+	}
+	if want.Proxy != nil {
+		ch.Proxy = *want.Proxy
+	}
+	if want.AutoSync != nil {
+		ch.AutoSync = *want.AutoSync
+	}
+	if want.AutoGroup != nil {
+		ch.AutoGroup = *want.AutoGroup
+	}
+	return ch
+}
+
+// diffChannel 生成只包含差异字段的渠道更新请求；base_urls 只比较地址，延迟由运行时测量
+func diffChannel(cur model.Channel, want Channel) (*model.ChannelUpdateRequest, []string) {
+	req := &model.ChannelUpdateRequest{ID: cur.ID}
+	var fields []string
+
+	if cur.Type != want.Type {
+		req.Type = &want.Type
+		fields = append(fields, "type")
+	}
+	if want.Enabled != nil && cur.Enabled != *want.Enabled {
+		req.Enabled = want.Enabled
+		fields = append(fields, "enabled")
+	}
+	if want.BaseURLs != nil {
+		curURLs := make([]string, 0, len(cur.BaseUrls))
+		for _, u := range cur.BaseUrls {
+			curURLs = append(curURLs, u.URL)
+		}
+		if !slices.Equal(curURLs, want.BaseURLs) {
+			urls := toBaseUrls(want.BaseURLs)
+			req.BaseUrls = &urls
+			fields = append(fields, "base_urls")
+		}
+	}
+	if want.Keys != nil {
+		wantKeys := uniqueStrings(want.Keys)
+		existing := make(map[string]struct{}, len(cur.Keys))
+		for _, k := range cur.Keys {
+			if !slices.Contains(wantKeys, k.ChannelKey) {
+				req.KeysToDelete = append(req.KeysToDelete, k.ID)
+				continue
+			}
+			existing[k.ChannelKey] = struct{}{}
+			if !k.Enabled {
+				enabled := true
+				req.KeysToUpdate = append(req.KeysToUpdate, model.ChannelKeyUpdateRequest{ID: k.ID, Enabled: &enabled})
+			}
+		}
+		for _, key := range wantKeys {
+			if _, ok := existing[key]; !ok {
+				req.KeysToAdd = append(req.KeysToAdd, model.ChannelKeyAddRequest{Enabled: true, ChannelKey: key})
+			}
+		}
+		if len(req.KeysToDelete) > 0 || len(req.KeysToUpdate) > 0 || len(req.KeysToAdd) > 0 {
+			fields = append(fields, "keys")
+		}
+	}
+	if want.Model != nil && cur.Model != *want.Model {
+		req.Model = want.Model
+		fields = append(fields, "model")
+	}
+	if want.CustomModel != nil && cur.CustomModel != *want.CustomModel {
+		req.CustomModel = want.CustomModel
+		fields = append(fields, "custom_model")
+	}
+	if want.Proxy != nil && cur.Proxy != *want.Proxy {
+		req.Proxy = want.Proxy
+		fields = append(fields, "proxy")
+	}
+	if want.AutoSync != nil && cur.AutoSync != *want.AutoSync {
+		req.AutoSync = want.AutoSync
+		fields = append(fields, "auto_sync")
+	}
+	if want.AutoGroup != nil && cur.AutoGroup != *want.AutoGroup {
+		req.AutoGroup = want.AutoGroup
+		fields = append(fields, "auto_group")
+	}
+	if want.CustomHeaders != nil {
+		headers := toCustomHeaders(want.CustomHeaders)
+		if !slices.Equal(cur.CustomHeader, headers) {
+			req.CustomHeader = &headers
+			fields = append(fields, "custom_header")
+		}
+	}
+	if want.ChannelProxy != nil && derefString(cur.ChannelProxy) != *want.ChannelProxy {
+		req.ChannelProxy = want.ChannelProxy
+		fields = append(fields, "channel_proxy")
+	}
+	if want.ParamOverride != nil && derefString(cur.ParamOverride) != *want.ParamOverride {
+		req.ParamOverride = want.ParamOverride
+		fields = append(fields, "param_override")
+	}
+	return req, fields
+}
+
+// diffGroup 生成只包含差异字段的分组更新请求，成员按渠道名称和模型名称匹配
+func (r *reconciler) diffGroup(cur model.Group, want Group, ruleSetID int) (*model.GroupUpdateRequest, []string) {
+	req := &model.GroupUpdateRequest{ID: cur.ID}
+	var fields []string
+
+	if cur.Mode != want.Mode {
+		req.Mode = &want.Mode
+		fields = append(fields, "mode")
+	}
+	if want.MatchRegex != nil && cur.MatchRegex != *want.MatchRegex {
+		req.MatchRegex = want.MatchRegex
+		fields = append(fields, "match_regex")
+	}
+	if want.FirstTokenTimeOut != nil && cur.FirstTokenTimeOut != *want.FirstTokenTimeOut {
+		req.FirstTokenTimeOut = want.FirstTokenTimeOut
+		fields = append(fields, "first_token_time_out")
+	}
+	if ruleSetID >= 0 && cur.SensitiveRuleSetID != ruleSetID {
+		req.SensitiveRuleSetID = &ruleSetID
+		fields = append(fields, "sensitive_rule_set_id")
+	}
+
+	wantItems := make(map[string]GroupItem, len(want.Items))
+	for _, item := range want.Items {
+		wantItems[item.Channel+"\x00"+item.Model] = item
+	}
+	existing := make(map[string]struct{}, len(cur.Items))
+	for _, item := range cur.Items {
+		key := r.channelNames[item.ChannelID] + "\x00" + item.ModelName
+		w, ok := wantItems[key]
+		if !ok {
+			req.ItemsToDelete = append(req.ItemsToDelete, item.ID)
+			continue
+		}
+		existing[key] = struct{}{}
+		if item.Priority != w.Priority || item.Weight != w.Weight {
+			req.ItemsToUpdate = append(req.ItemsToUpdate, model.GroupItemUpdateRequest{ID: item.ID, Priority: w.Priority, Weight: w.Weight})
+		}
+	}
+	for _, item := range want.Items {
+		if _, ok := existing[item.Channel+"\x00"+item.Model]; ok {
+			continue
+		}
+		req.ItemsToAdd = append(req.ItemsToAdd, model.GroupItemAddRequest{
+			ChannelID: r.channels[item.Channel].ID,
+			ModelName: item.Model,
+			Priority:  item.Priority,
+			Weight:    item.Weight,
+		})
+	}
+	if len(req.ItemsToDelete) > 0 || len(req.ItemsToUpdate) > 0 || len(req.ItemsToAdd) > 0 {
+		fields = append(fields, "items")
+	}
+	return req, fields
+}
+
+func applyAPIKey(key *model.APIKey, want APIKey, ruleSetID int) {
+	if want.Enabled != nil {
+		key.Enabled = *want.Enabled
+	}
+	if want.ExpireAt != nil {
+		key.ExpireAt = *want.ExpireAt
+	}
+	if want.MaxCost != nil {
+		key.MaxCost = *want.MaxCost
+	}
+	if want.SupportedModels != nil {
+		key.SupportedModels = *want.SupportedModels
+	}
+	if want.LogBodyDisabled != nil {
+		key.LogBodyDisabled = *want.LogBodyDisabled
+	}
+	if want.LogBodyMaxSize != nil {
+		key.LogBodyMaxSize = *want.LogBodyMaxSize
+	}
+	if want.LogBodyKeepDays != nil {
+		key.LogBodyKeepDays = *want.LogBodyKeepDays
+	}
+	if ruleSetID >= 0 {
+		key.SensitiveRuleSetID = ruleSetID
+	}
+}
+
+func diffAPIKey(cur, next model.APIKey) []string {
+	var fields []string
+	if cur.Enabled != next.Enabled {
+		fields = append(fields, "enabled")
+	}
+	if cur.ExpireAt != next.ExpireAt {
+		fields = append(fields, "expire_at")
+	}
+	if cur.MaxCost != next.MaxCost {
+		fields = append(fields, "max_cost")
+	}
+	if cur.SupportedModels != next.SupportedModels {
+		fields = append(fields, "supported_models")
+	}
+	if cur.LogBodyDisabled != next.LogBodyDisabled {
+		fields = append(fields, "log_body_disabled")
+	}
+	if cur.LogBodyMaxSize != next.LogBodyMaxSize {
+		fields = append(fields, "log_body_max_size")
+	}
+	if cur.LogBodyKeepDays != next.LogBodyKeepDays {
+		fields = append(fields, "log_body_keep_days")
+	}
+	if cur.SensitiveRuleSetID != next.SensitiveRuleSetID {
+		fields = append(fields, "sensitive_rule_set_id")
+	}
+	if cur.Managed != next.Managed {
+		fields = append(fields, "managed")
+	}
+	return fields
+}
+
+// channelChanged 与管理接口一致，渠道变更后在后台更新模型价格和地址延迟
+func channelChanged(ch model.Channel) {
+	go func(channel *model.Channel) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		modelStr := channel.Model + "," + channel.CustomModel
+		helper.LLMPriceAddToDB(strings.Split(modelStr, ","), ctx)
+		helper.ChannelBaseUrlDelayUpdate(channel, ctx)
+		helper.ChannelAutoGroup(channel, ctx)
+	}(&ch)
+}
+
+func toBaseUrls(urls []string) []model.BaseUrl {
+	out := make([]model.BaseUrl, 0, len(urls))
+	for _, u := range urls {
+		out = append(out, model.BaseUrl{URL: u})
+	}
+	return out
+}
+
+func toCustomHeaders(headers []Header) []model.CustomHeader {
+	out := make([]model.CustomHeader, 0, len(headers))
+	for _, h := range headers {
+		out = append(out, model.CustomHeader{HeaderKey: h.Key, HeaderValue: h.Value})
+	}
+	return out
+}
+
+func uniqueStrings(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,247 @@
+package gitops
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/outbound"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// testReconcileInit 创建临时数据库并加载缓存
+func testReconcileInit(t *testing.T) {
+	t.Helper()
+	dbtest.Init(t, "gitops.db")
+	if err := op.InitCache(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testChannel(t *testing.T, name string) model.Channel {
+	t.Helper()
+	ch, ok := channelByName(t)[name]
+	if !ok {
+		t.Fatalf("channel %s not found", name)
+	}
+	return ch
+}
+
+func channelByName(t *testing.T) map[string]model.Channel {
+	t.Helper()
+	channels, err := op.ChannelList(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]model.Channel, len(channels))
+	for _, ch := range channels {
+		out[ch.Name] = ch
+	}
+	return out
+}
+
+func countRows(t *testing.T, m any) int64 {
+	t.Helper()
+	var n int64
+	if err := db.GetDB().Model(m).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestReconcile(t *testing.T) {
+	testReconcileInit(t)
+	ctx := context.Background()
+	// 未托管的同名渠道在首次对账时被接管，配置中没有的未托管 API Key 不被删除
+	legacy := model.Channel{Name: "legacy", Type: outbound.OutboundTypeOpenAIChat, Enabled: true}
+	if err := op.ChannelCreate(&legacy, ctx); err != nil {
+		t.Fatal(err)
+	}
+	manual := model.APIKey{Name: "manual", APIKey: "sk-manual", Enabled: true}
+	if err := op.APIKeyCreate(&manual, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		Settings: map[string]string{string(model.SettingKeyRelayLogBodyMaxSize): "1024"},
+		Channels: []Channel{
+			{Name: "a", Type: outbound.OutboundTypeOpenAIChat, BaseURLs: []string{"http://127.0.0.1:1"}, Keys: []string{"k1", "k2", "k1"}},
+			{Name: "b", Type: outbound.OutboundTypeOpenAIChat},
+			{Name: "legacy", Type: outbound.OutboundTypeOpenAIChat},
+		},
+		Groups: []Group{
+			{Name: "g", Mode: model.GroupModeRoundRobin, Items: []GroupItem{{Channel: "a", Model: "m1", Priority: 1, Weight: 1}}},
+		},
+		APIKeys: []APIKey{{Name: "key1", Key: "sk-key1", MaxCost: ptr(10.0)}},
+	}
+	created := []model.GitOpsChange{
+		{Kind: model.GitOpsKindSetting, Name: string(model.SettingKeyRelayLogBodyMaxSize), Action: model.GitOpsActionUpdate, Fields: []string{"value", "managed"}},
+		{Kind: model.GitOpsKindChannel, Name: "a", Action: model.GitOpsActionCreate},
+		{Kind: model.GitOpsKindChannel, Name: "b", Action: model.GitOpsActionCreate},
+		{Kind: model.GitOpsKindChannel, Name: "legacy", Action: model.GitOpsActionUpdate, Fields: []string{"managed"}},
+		{Kind: model.GitOpsKindGroup, Name: "g", Action: model.GitOpsActionCreate},
+		{Kind: model.GitOpsKindAPIKey, Name: "key1", Action: model.GitOpsActionCreate},
+	}
+
+	// dryRun 只返回差异
+	changes, err := Reconcile(ctx, cfg, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes, created) {
+		t.Fatalf("dry run changes = %+v, want %+v", changes, created)
+	}
+	if n := countRows(t, &model.Channel{}); n != 1 {
+		t.Fatalf("dry run wrote channels: %d", n)
+	}
+	if op.SettingIsManaged(model.SettingKeyRelayLogBodyMaxSize) {
+		t.Fatal("dry run marked setting managed")
+	}
+
+	changes, err = Reconcile(ctx, cfg, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes, created) {
+		t.Fatalf("changes = %+v, want %+v", changes, created)
+	}
+	a := testChannel(t, "a")
+	if !a.Managed || len(a.Keys) != 2 || len(a.BaseUrls) != 1 {
+		t.Errorf("channel a = %+v", a)
+	}
+	if !testChannel(t, "legacy").Managed {
+		t.Error("legacy channel not taken over")
+	}
+	if v, _ := op.SettingGetString(model.SettingKeyRelayLogBodyMaxSize); v != "1024" || !op.SettingIsManaged(model.SettingKeyRelayLogBodyMaxSize) {
+		t.Errorf("setting = %q, managed %v", v, op.SettingIsManaged(model.SettingKeyRelayLogBodyMaxSize))
+	}
+	var group model.Group
+	if err := db.GetDB().Preload("Items").Where("name = ?", "g").First(&group).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !group.Managed || group.SensitiveRuleSetID != 0 || len(group.Items) != 1 || group.Items[0].ChannelID != a.ID {
+		t.Errorf("group = %+v", group)
+	}
+	var key model.APIKey
+	if err := db.GetDB().Where("name = ?", "key1").First(&key).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !key.Managed || key.APIKey != "sk-key1" || key.MaxCost != 10 {
+		t.Errorf("api key = %+v", key)
+	}
+
+	// 配置不变时没有差异
+	changes, err = Reconcile(ctx, cfg, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("second reconcile changes = %+v", changes)
+	}
+
+	// 更新并删除配置中移除的托管对象
+	cfg = &Config{
+		Channels: []Channel{
+			{Name: "a", Type: outbound.OutboundTypeOpenAIChat, Enabled: ptr(false), Keys: []string{"k2", "k3"}},
+			{Name: "legacy", Type: outbound.OutboundTypeOpenAIChat},
+		},
+		Groups: []Group{
+			{Name: "g", Mode: model.GroupModeRoundRobin, Items: []GroupItem{{Channel: "a", Model: "m1", Priority: 2, Weight: 1}}},
+		},
+	}
+	changes, err = Reconcile(ctx, cfg, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.GitOpsChange{
+		{Kind: model.GitOpsKindSetting, Name: string(model.SettingKeyRelayLogBodyMaxSize), Action: model.GitOpsActionUnmanage},
+		{Kind: model.GitOpsKindChannel, Name: "a", Action: model.GitOpsActionUpdate, Fields: []string{"enabled", "keys"}},
+		{Kind: model.GitOpsKindGroup, Name: "g", Action: model.GitOpsActionUpdate, Fields: []string{"items"}},
+		{Kind: model.GitOpsKindAPIKey, Name: "key1", Action: model.GitOpsActionDelete},
+		{Kind: model.GitOpsKindChannel, Name: "b", Action: model.GitOpsActionDelete},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	a = testChannel(t, "a")
+	var keys []string
+	for _, k := range a.Keys {
+		keys = append(keys, k.ChannelKey)
+	}
+	if a.Enabled || !reflect.DeepEqual(keys, []string{"k2", "k3"}) {
+		t.Errorf("channel a enabled %v, keys %v", a.Enabled, keys)
+	}
+	if _, ok := channelByName(t)["b"]; ok {
+		t.Error("channel b not pruned")
+	}
+	if op.SettingIsManaged(model.SettingKeyRelayLogBodyMaxSize) {
+		t.Error("setting still managed")
+	}
+	keyList, _ := op.APIKeyList(ctx)
+	if len(keyList) != 1 || keyList[0].Name != "manual" {
+		t.Errorf("api keys = %+v", keyList)
+	}
+	if err := db.GetDB().Preload("Items").First(&group, group.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(group.Items) != 1 || group.Items[0].Priority != 2 {
+		t.Errorf("group items = %+v", group.Items)
+	}
+
+	// 不开启 prune 时不删除
+	changes, err = Reconcile(ctx, &Config{}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("reconcile without prune changes = %+v", changes)
+	}
+}
+
+func TestReconcileRollback(t *testing.T) {
+	testReconcileInit(t)
+	ctx := context.Background()
+	// 数据库中存在同名 API Key 时对账在最后一步失败
+	for _, k := range []model.APIKey{{Name: "dup", APIKey: "sk-dup-1"}, {Name: "dup", APIKey: "sk-dup-2"}} {
+		if err := op.APIKeyCreate(&k, ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &Config{
+		Settings: map[string]string{string(model.SettingKeyRelayLogBodyMaxSize): "1024"},
+		Channels: []Channel{{Name: "a", Type: outbound.OutboundTypeOpenAIChat, Keys: []string{"k1"}}},
+		Groups:   []Group{{Name: "g", Mode: model.GroupModeRoundRobin, Items: []GroupItem{{Channel: "a", Model: "m1"}}}},
+		APIKeys:  []APIKey{{Name: "dup"}},
+	}
+	_, err := Reconcile(ctx, cfg, false, true)
+	if err == nil || !strings.Contains(err.Error(), "api key dup: name is not unique") {
+		t.Fatalf("err = %v", err)
+	}
+
+	for _, m := range []any{&model.Channel{}, &model.ChannelKey{}, &model.Group{}, &model.GroupItem{}} {
+		if n := countRows(t, m); n != 0 {
+			t.Errorf("%T rows = %d after rollback", m, n)
+		}
+	}
+	if len(channelByName(t)) != 0 {
+		t.Error("channel cache not reloaded after rollback")
+	}
+	if groups, _ := op.GroupList(ctx); len(groups) != 0 {
+		t.Errorf("group cache = %+v after rollback", groups)
+	}
+	if v, _ := op.SettingGetString(model.SettingKeyRelayLogBodyMaxSize); v != "0" {
+		t.Errorf("setting = %q after rollback", v)
+	}
+	if op.SettingIsManaged(model.SettingKeyRelayLogBodyMaxSize) {
+		t.Error("setting managed after rollback")
+	}
+}
@@ -72,6 +72,10 @@ func ChannelAutoGroup(channel *model.Channel, ctx context.Context) {
 	}
 
 	for _, group := range groups {
+		// 声明式配置管理的分组成员只来自配置文件
+		if group.Managed {
+			continue
+		}
 		matchedModelNames := make([]string, 0, len(channelModelNames))
 
 		switch channel.AutoGroup {
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"net/http"
+	"slices"
+)
+
+// AccessToken 管理接口的个人访问令牌，供脚本等自动化调用，数据库只保存 SHA-256 哈希
+// 令牌的权限不超过所属用户当前角色的权限
+type AccessToken struct {
+	ID         int          `json:"id" gorm:"primaryKey"`
+	UserID     uint         `json:"user_id" gorm:"index;not null"`
+	Name       string       `json:"name" gorm:"not null"`
+	TokenHash  string       `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	Hint       string       `json:"hint"`                          // 令牌的末尾几位，便于辨认
+	Scopes     []Permission `json:"scopes" gorm:"serializer:json"` // 可以访问的接口权限，为空时不限制
+	ReadOnly   bool         `json:"read_only"`                     // 只能发送 GET 请求
+	ExpiresAt  int64        `json:"expires_at,omitempty"`          // 0 为永不过期
+	LastUsedAt int64        `json:"last_used_at,omitempty"`
+	LastUsedIP string       `json:"last_used_ip,omitempty"`
+	CreatedAt  int64        `json:"created_at"`
+}
+
+// AccessTokenCreateRequest 创建个人访问令牌
+type AccessTokenCreateRequest struct {
+	Name      string       `json:"name" binding:"required"`
+	Scopes    []Permission `json:"scopes"`
+	ReadOnly  bool         `json:"read_only"`
+	ExpiresAt int64        `json:"expires_at"`
+}
+
+// AccessTokenCreateResponse 创建结果，明文令牌只在此时返回一次
+type AccessTokenCreateResponse struct {
+	AccessToken
+	Token string `json:"token"`
+}
+
+// Allows 令牌本身是否允许访问需要权限 p 的接口，角色权限另行检查
+// 只需登录的接口（修改密码、管理会话和令牌等）只能以 GET 访问
+func (t *AccessToken) Allows(p Permission, method string) bool {
+	read := method == http.MethodGet || method == http.MethodHead
+	if t.ReadOnly && !read {
+		return false
+	}
+	if p == PermAuthenticated {
+		return read
+	}
+	return len(t.Scopes) == 0 || slices.Contains(t.Scopes, p)
+}
+
+// Valid 是否为可以授予令牌的权限
+func (p Permission) Valid() bool {
+	return p != PermAuthenticated && slices.Contains(allPermissions, p)
+}
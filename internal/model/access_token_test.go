@@ -0,0 +1,37 @@
+package model
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAccessTokenAllows(t *testing.T) {
+	unscoped := AccessToken{}
+	scoped := AccessToken{Scopes: []Permission{PermChannelRead, PermChannelWrite}}
+	readOnly := AccessToken{Scopes: []Permission{PermChannelWrite}, ReadOnly: true}
+	tests := []struct {
+		name   string
+		token  AccessToken
+		perm   Permission
+		method string
+		want   bool
+	}{
+		{"unscoped read", unscoped, PermStatsRead, http.MethodGet, true},
+		{"unscoped write", unscoped, PermChannelWrite, http.MethodPost, true},
+		{"unscoped owner-only route", unscoped, "", http.MethodDelete, true},
+		{"in scope", scoped, PermChannelWrite, http.MethodPost, true},
+		{"out of scope", scoped, PermGroupRead, http.MethodGet, false},
+		{"scoped owner-only route", scoped, "", http.MethodGet, false},
+		{"authenticated get", scoped, PermAuthenticated, http.MethodGet, true},
+		{"authenticated head", scoped, PermAuthenticated, http.MethodHead, true},
+		{"authenticated post", unscoped, PermAuthenticated, http.MethodPost, false},
+		{"read-only get", readOnly, PermChannelWrite, http.MethodGet, true},
+		{"read-only post", readOnly, PermChannelWrite, http.MethodPost, false},
+		{"read-only delete", readOnly, PermChannelWrite, http.MethodDelete, false},
+	}
+	for _, tt := range tests {
+		if got := tt.token.Allows(tt.perm, tt.method); got != tt.want {
+			t.Errorf("%s: Allows(%q, %s) = %v, want %v", tt.name, tt.perm, tt.method, got, tt.want)
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package model
 
 type APIKey struct {
-	ID              int     `json:"id" gorm:"primaryKey"`
-	Name            string  `json:"name" gorm:"not null"`
-	APIKey          string  `json:"api_key" gorm:"not null"`
-	Enabled         bool    `json:"enabled" gorm:"default:true"`
-	ExpireAt        int64   `json:"expire_at,omitempty"`
-	MaxCost         float64 `json:"max_cost,omitempty"`
-	SupportedModels string  `json:"supported_models,omitempty"`
+	ID                 int     `json:"id" gorm:"primaryKey"`
+	Name               string  `json:"name" gorm:"not null"`
+	APIKey             string  `json:"api_key" gorm:"not null"`
+	Enabled            bool    `json:"enabled" gorm:"default:true"`
+	ExpireAt           int64   `json:"expire_at,omitempty"`
+	MaxCost            float64 `json:"max_cost,omitempty"`
+	SupportedModels    string  `json:"supported_models,omitempty"`
+	LogBodyDisabled    bool    `json:"log_body_disabled,omitempty"`            // 不保存请求/响应内容
+	LogBodyMaxSize     int     `json:"log_body_max_size,omitempty"`            // 请求/响应内容最大保存字节数，0 使用全局设置
+	LogBodyKeepDays    int     `json:"log_body_keep_days,omitempty"`           // 请求/响应内容保存天数，0 使用全局设置
+	SensitiveRuleSetID int     `json:"sensitive_rule_set_id,omitempty"`        // 敏感信息规则集，0 使用分组或默认规则集
+	Managed            bool    `json:"managed,omitempty" gorm:"default:false"` // 由声明式配置管理，API 只读
 }
@@ -3,32 +3,103 @@ package model
 import "time"
 
 // DBDump is a full-database JSON export format for Octopus.
-// Import uses incremental semantics (insert new rows, and upsert on certain key-based tables).
+// Import merges by default (insert new rows, and upsert on certain key-based tables),
+// or replaces the config tables with DBImportModeReplace.
+//
+// Version history:
+//   - 1: channel keys were not exported, associations may be nested in their parents
+//   - 2: channel keys are exported in ChannelKeys, associations are always flat
 type DBDump struct {
 	Version      int       `json:"version"`
 	ExportedAt   time.Time `json:"exported_at"`
 	IncludeLogs  bool      `json:"include_logs"`
 	IncludeStats bool      `json:"include_stats"`
 
-	Channels   []Channel   `json:"channels,omitempty"`
-	Groups     []Group     `json:"groups,omitempty"`
-	GroupItems []GroupItem `json:"group_items,omitempty"`
-	LLMInfos   []LLMInfo   `json:"llm_infos,omitempty"`
-	APIKeys    []APIKey    `json:"api_keys,omitempty"`
-	Settings   []Setting   `json:"settings,omitempty"`
+	Channels             []Channel             `json:"channels,omitempty"`
+	ChannelKeys          []ChannelKey          `json:"channel_keys,omitempty"`
+	Groups               []Group               `json:"groups,omitempty"`
+	GroupItems           []GroupItem           `json:"group_items,omitempty"`
+	LLMInfos             []LLMInfo             `json:"llm_infos,omitempty"`
+	APIKeys              []APIKey              `json:"api_keys,omitempty"`
+	Settings             []Setting             `json:"settings,omitempty"`
+	SensitiveRuleSets    []SensitiveRuleSet    `json:"sensitive_rule_sets,omitempty"`
 	SensitiveFilterRules []SensitiveFilterRule `json:"sensitive_filter_rules,omitempty"`
 
-	StatsTotal   []StatsTotal   `json:"stats_total,omitempty"`
-	StatsDaily   []StatsDaily   `json:"stats_daily,omitempty"`
-	StatsHourly  []StatsHourly  `json:"stats_hourly,omitempty"`
-	StatsModel   []StatsModel   `json:"stats_model,omitempty"`
-	StatsChannel []StatsChannel `json:"stats_channel,omitempty"`
-	StatsAPIKey  []StatsAPIKey  `json:"stats_api_key,omitempty"`
+	StatsTotal     []StatsTotal     `json:"stats_total,omitempty"`
+	StatsDaily     []StatsDaily     `json:"stats_daily,omitempty"`
+	StatsHourly    []StatsHourly    `json:"stats_hourly,omitempty"`
+	StatsModel     []StatsModel     `json:"stats_model,omitempty"`
+	StatsChannel   []StatsChannel   `json:"stats_channel,omitempty"`
+	StatsAPIKey    []StatsAPIKey    `json:"stats_api_key,omitempty"`
+	StatsSensitive []StatsSensitive `json:"stats_sensitive,omitempty"`
 
-	RelayLogs []RelayLog `json:"relay_logs,omitempty"`
+	RelayLogs      []RelayLog     `json:"relay_logs,omitempty"`
+	RelayLogBodies []RelayLogBody `json:"relay_log_bodies,omitempty"`
+}
+
+// DBImportMode 导入方式
+type DBImportMode string
+
+const (
+	DBImportModeMerge   DBImportMode = "merge"   // 增量导入：新增缺失的行，按键覆盖部分表
+	DBImportModeReplace DBImportMode = "replace" // 替换：在一个事务中清空配置表后导入
+)
+
+// DBImportOptions 导入选项，DryRun 时只比较差异不写入
+type DBImportOptions struct {
+	Mode   DBImportMode `json:"mode"`
+	DryRun bool         `json:"dry_run"`
 }
 
 type DBImportResult struct {
+	Mode        DBImportMode `json:"mode"`
+	DryRun      bool         `json:"dry_run"`
+	FromVersion int          `json:"from_version"` // 导入文件的原始版本，旧版本会先升级到当前版本
+
 	// RowsAffected contains the rows affected for each table operation (insert/upsert depending on table).
 	RowsAffected map[string]int64 `json:"rows_affected"`
+
+	// Tables 和 Changes 仅在 DryRun 时返回
+	Tables  map[string]DBImportTableStats `json:"tables,omitempty"`
+	Changes []DBImportChange              `json:"changes,omitempty"`
+}
+
+// DBImportTableStats 预览时单个表的变化行数，内容相同的行不计入
+type DBImportTableStats struct {
+	Create   int64 `json:"create"`
+	Update   int64 `json:"update"`
+	Conflict int64 `json:"conflict"` // 增量导入时与已有行冲突而被跳过
+	Delete   int64 `json:"delete"`   // 仅替换模式
+}
+
+type DBImportAction string
+
+const (
+	DBImportActionCreate   DBImportAction = "create"
+	DBImportActionUpdate   DBImportAction = "update"
+	DBImportActionConflict DBImportAction = "conflict"
+	DBImportActionDelete   DBImportAction = "delete"
+)
+
+// DBImportChange 预览时渠道、分组或 API Key 的一处差异，按名称匹配
+type DBImportChange struct {
+	Kind   string         `json:"kind"` // channel, group, api_key
+	Name   string         `json:"name"`
+	Action DBImportAction `json:"action"`
+	Fields []string       `json:"fields,omitempty"`
+}
+
+// BackupFile 定时备份目录中的一份备份
+type BackupFile struct {
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	CreatedAt int64  `json:"created_at"`
+	Encrypted bool   `json:"encrypted"`
+}
+
+// BackupList 定时备份目录及其中的备份，按创建时间倒序
+type BackupList struct {
+	Path              string       `json:"path"`
+	EncryptionEnabled bool         `json:"encryption_enabled"`
+	Files             []BackupFile `json:"files"`
 }
@@ -30,6 +30,7 @@ type Channel struct {
 	CustomHeader  []CustomHeader        `json:"custom_header" gorm:"serializer:json"`
 	ParamOverride *string               `json:"param_override"`
 	ChannelProxy  *string               `json:"channel_proxy"`
+	Managed       bool                  `json:"managed" gorm:"default:false"` // 由声明式配置管理，API 只读
 	Stats         *StatsChannel         `json:"stats,omitempty" gorm:"foreignKey:ChannelID"`
 }
 
@@ -131,10 +132,8 @@ func (c *Channel) GetChannelKey() ChannelKey {
 		if !k.Enabled || k.ChannelKey == "" {
 			continue
 		}
-		if k.StatusCode == 429 && k.LastUseTimeStamp > 0 {
-			if nowSec-k.LastUseTimeStamp < int64(5*time.Minute/time.Second) {
-				continue
-			}
+		if k.CoolingDown(nowSec) {
+			continue
 		}
 		if !bestSet || k.TotalCost < bestCost {
 			best = k
@@ -148,3 +147,11 @@ func (c *Channel) GetChannelKey() ChannelKey {
 	}
 	return best
 }
+
+// keyCooldown 密钥被上游限流后暂停使用的时间
+const keyCooldown = 5 * time.Minute
+
+// CoolingDown 密钥最近一次请求被限流，仍在暂停使用期内
+func (k ChannelKey) CoolingDown(nowSec int64) bool {
+	return k.StatusCode == 429 && k.LastUseTimeStamp > 0 && nowSec-k.LastUseTimeStamp < int64(keyCooldown/time.Second)
+}
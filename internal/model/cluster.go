@@ -0,0 +1,14 @@
+package model
+
+// CacheVersion 缓存版本号，多实例部署时修改配置后递增，其他实例据此刷新对应缓存
+type CacheVersion struct {
+	Name    string `json:"name" gorm:"primaryKey"`
+	Version int64  `json:"version"`
+}
+
+// ClusterLease 实例间的租约，持有者在过期前需要续约
+type ClusterLease struct {
+	Name      string `json:"name" gorm:"primaryKey"`
+	Holder    string `json:"holder"`
+	ExpiresAt int64  `json:"expires_at"` // 毫秒时间戳
+}
@@ -0,0 +1,46 @@
+package model
+
+type GitOpsKind string
+
+const (
+	GitOpsKindChannel GitOpsKind = "channel"
+	GitOpsKindGroup   GitOpsKind = "group"
+	GitOpsKindAPIKey  GitOpsKind = "api_key"
+	GitOpsKindSetting GitOpsKind = "setting"
+)
+
+type GitOpsAction string
+
+const (
+	GitOpsActionCreate   GitOpsAction = "create"
+	GitOpsActionUpdate   GitOpsAction = "update"
+	GitOpsActionDelete   GitOpsAction = "delete"
+	GitOpsActionUnmanage GitOpsAction = "unmanage" // 设置项无法删除，仅取消托管
+)
+
+// GitOpsChange 声明式配置与数据库之间的一处差异，Fields 只包含字段名，不包含值，避免泄露密钥
+type GitOpsChange struct {
+	Kind   GitOpsKind   `json:"kind"`
+	Name   string       `json:"name"`
+	Action GitOpsAction `json:"action"`
+	Fields []string     `json:"fields,omitempty"`
+}
+
+// GitOpsPlan 一次对账的结果，DryRun 为 true 时变更未写入数据库
+type GitOpsPlan struct {
+	DryRun  bool           `json:"dry_run"`
+	Hash    string         `json:"hash"`
+	Changes []GitOpsChange `json:"changes"`
+}
+
+// GitOpsStatus 声明式配置同步状态
+type GitOpsStatus struct {
+	Enabled      bool           `json:"enabled"`
+	Path         string         `json:"path"`
+	Prune        bool           `json:"prune"`
+	Interval     int            `json:"interval"`
+	LastSyncTime int64          `json:"last_sync_time,omitempty"`
+	LastHash     string         `json:"last_hash,omitempty"`
+	LastError    string         `json:"last_error,omitempty"`
+	LastChanges  []GitOpsChange `json:"last_changes,omitempty"`
+}
@@ -10,12 +10,14 @@ const (
 )
 
 type Group struct {
-	ID                int         `json:"id" gorm:"primaryKey"`
-	Name              string      `json:"name" gorm:"unique;not null"`
-	Mode              GroupMode   `json:"mode" gorm:"not null"`
-	MatchRegex        string      `json:"match_regex"`
-	FirstTokenTimeOut int         `json:"first_token_time_out"` // 单个渠道首个Token响应超时时间(秒)
-	Items             []GroupItem `json:"items,omitempty" gorm:"foreignKey:GroupID"`
+	ID                 int         `json:"id" gorm:"primaryKey"`
+	Name               string      `json:"name" gorm:"unique;not null"`
+	Mode               GroupMode   `json:"mode" gorm:"not null"`
+	MatchRegex         string      `json:"match_regex"`
+	FirstTokenTimeOut  int         `json:"first_token_time_out"`         // 单个渠道首个Token响应超时时间(秒)
+	SensitiveRuleSetID int         `json:"sensitive_rule_set_id"`        // 敏感信息规则集，0 使用默认规则集
+	Managed            bool        `json:"managed" gorm:"default:false"` // 由声明式配置管理，API 只读
+	Items              []GroupItem `json:"items,omitempty" gorm:"foreignKey:GroupID"`
 }
 
 type GroupItem struct {
@@ -29,14 +31,15 @@ type GroupItem struct {
 
 // GroupUpdateRequest 分组更新请求 - 仅包含变更的数据
 type GroupUpdateRequest struct {
-	ID                int                      `json:"id" binding:"required"`
-	Name              *string                  `json:"name,omitempty"`                 // 仅在名称变更时发送
-	Mode              *GroupMode               `json:"mode,omitempty"`                 // 仅在模式变更时发送
-	MatchRegex        *string                  `json:"match_regex,omitempty"`          // 仅在匹配正则变更时发送
-	FirstTokenTimeOut *int                     `json:"first_token_time_out,omitempty"` // 仅在超时变更时发送(秒)
-	ItemsToAdd        []GroupItemAddRequest    `json:"items_to_add,omitempty"`         // 新增的 items
-	ItemsToUpdate     []GroupItemUpdateRequest `json:"items_to_update,omitempty"`      // 更新的 items (priority 变更)
-	ItemsToDelete     []int                    `json:"items_to_delete,omitempty"`      // 删除的 item IDs
+	ID                 int                      `json:"id" binding:"required"`
+	Name               *string                  `json:"name,omitempty"`                  // 仅在名称变更时发送
+	Mode               *GroupMode               `json:"mode,omitempty"`                  // 仅在模式变更时发送
+	MatchRegex         *string                  `json:"match_regex,omitempty"`           // 仅在匹配正则变更时发送
+	FirstTokenTimeOut  *int                     `json:"first_token_time_out,omitempty"`  // 仅在超时变更时发送(秒)
+	SensitiveRuleSetID *int                     `json:"sensitive_rule_set_id,omitempty"` // 仅在规则集变更时发送
+	ItemsToAdd         []GroupItemAddRequest    `json:"items_to_add,omitempty"`          // 新增的 items
+	ItemsToUpdate      []GroupItemUpdateRequest `json:"items_to_update,omitempty"`       // 更新的 items (priority 变更)
+	ItemsToDelete      []int                    `json:"items_to_delete,omitempty"`       // 删除的 item IDs
 }
 
 // GroupItemAddRequest 新增 item 请求
@@ -0,0 +1,76 @@
+package model
+
+type HealthState string
+
+const (
+	HealthStateOK          HealthState = "ok"
+	HealthStateDegraded    HealthState = "degraded"    // 可以提供服务，但有任务、写入或渠道异常
+	HealthStateUnavailable HealthState = "unavailable" // 未就绪，不应接收请求
+)
+
+// HealthCheck 就绪检查的一项结果
+type HealthCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// HealthStatus 服务的详细状态
+type HealthStatus struct {
+	Status    HealthState      `json:"status"`
+	Version   string           `json:"version"`
+	NodeID    string           `json:"node_id"`
+	Leader    bool             `json:"leader"`
+	StartTime int64            `json:"start_time"` // 秒级时间戳
+	Inflight  int64            `json:"inflight"`   // 正在处理的请求数
+	Checks    []HealthCheck    `json:"checks"`
+	Tasks     []TaskHealth     `json:"tasks"`
+	Stats     StatsFlushStatus `json:"stats"`
+	RelayLog  RelayLogBacklog  `json:"relay_log"`
+	Channels  []ChannelHealth  `json:"channels"`
+}
+
+// TaskHealth 定时任务最近的执行情况
+type TaskHealth struct {
+	Name      string `json:"name"`
+	Paused    bool   `json:"paused"`
+	Running   bool   `json:"running"`
+	LastRun   int64  `json:"last_run"` // 毫秒时间戳，未执行过为 0
+	LastError string `json:"last_error,omitempty"`
+	Failures  int    `json:"failures"` // 最近连续失败的次数
+}
+
+// StatsFlushStatus 统计数据写入数据库的状态
+type StatsFlushStatus struct {
+	LastFlush      int64  `json:"last_flush"` // 秒级时间戳，启动后未写入过为 0
+	LastError      string `json:"last_error,omitempty"`
+	PendingJournal int    `json:"pending_journal"` // 已记录流水、等待汇总的请求数
+}
+
+// RelayLogBacklog 内存中请求日志的积压情况
+type RelayLogBacklog struct {
+	KeepEnabled bool   `json:"keep_enabled"`
+	Pending     int    `json:"pending"`    // 内存中的日志数，保存到数据库时即为未写入的数量
+	LastFlush   int64  `json:"last_flush"` // 秒级时间戳，启动后未写入过为 0
+	LastError   string `json:"last_error,omitempty"`
+}
+
+type ChannelCircuit string
+
+const (
+	ChannelCircuitClosed   ChannelCircuit = "closed"   // 所有启用的密钥都可用
+	ChannelCircuitPartial  ChannelCircuit = "partial"  // 部分密钥因限流暂停使用
+	ChannelCircuitOpen     ChannelCircuit = "open"     // 没有可用的密钥，请求会跳过该渠道
+	ChannelCircuitDisabled ChannelCircuit = "disabled" // 渠道已禁用
+)
+
+// ChannelHealth 渠道及其密钥的可用状态
+type ChannelHealth struct {
+	ID             int            `json:"id"`
+	Name           string         `json:"name"`
+	Circuit        ChannelCircuit `json:"circuit"`
+	Keys           int            `json:"keys"`         // 启用的密钥数
+	CoolingDown    int            `json:"cooling_down"` // 因限流暂停使用的密钥数
+	LastStatusCode int            `json:"last_status_code"`
+	LastUsed       int64          `json:"last_used"` // 秒级时间戳
+}
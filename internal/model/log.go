@@ -1,18 +1,140 @@
 package model
 
+import (
+	"strings"
+)
+
 type RelayLog struct {
-	ID               int64   `json:"id" gorm:"primaryKey;autoIncrement:false"` // Snowflake ID
-	Time             int64   `json:"time"`                                     // 时间戳（秒）
-	RequestModelName string  `json:"request_model_name"`                       // 请求模型名称
-	ChannelId        int     `json:"channel"`                                  // 实际使用的渠道ID
-	ChannelName      string  `json:"channel_name"`                             // 渠道名称
-	ActualModelName  string  `json:"actual_model_name"`                        // 实际使用模型名称
-	InputTokens      int     `json:"input_tokens"`                             // 输入Token
-	OutputTokens     int     `json:"output_tokens"`                            // 输出 Token
-	Ftut             int     `json:"ftut"`                                     // 首字时间(毫秒)
-	UseTime          int     `json:"use_time"`                                 // 总用时(毫秒)
-	Cost             float64 `json:"cost"`                                     // 消耗费用
-	RequestContent   string  `json:"request_content"`                          // 请求内容
-	ResponseContent  string  `json:"response_content"`                         // 响应内容
-	Error            string  `json:"error"`                                    // 错误信息
+	ID               int64          `json:"id" gorm:"primaryKey;autoIncrement:false"` // Snowflake ID
+	Time             int64          `json:"time" gorm:"index"`                        // 时间戳（秒）
+	APIKeyID         int            `json:"api_key_id" gorm:"index"`                  // 请求使用的 API Key ID
+	InboundType      int            `json:"inbound_type"`                             // 入站协议类型，取值同 inbound.InboundType
+	Stream           bool           `json:"stream"`                                   // 是否为流式请求
+	StatusCode       int            `json:"status_code"`                              // 返回给客户端的 HTTP 状态码
+	RequestModelName string         `json:"request_model_name"`                       // 请求模型名称
+	ChannelId        int            `json:"channel"`                                  // 实际使用的渠道ID
+	ChannelName      string         `json:"channel_name"`                             // 渠道名称
+	ActualModelName  string         `json:"actual_model_name"`                        // 实际使用模型名称
+	InputTokens      int            `json:"input_tokens"`                             // 输入Token
+	OutputTokens     int            `json:"output_tokens"`                            // 输出 Token
+	Ftut             int            `json:"ftut"`                                     // 首字时间(毫秒)
+	UseTime          int            `json:"use_time"`                                 // 总用时(毫秒)
+	Cost             float64        `json:"cost"`                                     // 消耗费用
+	RequestContent   string         `json:"request_content,omitempty" gorm:"-"`       // 请求内容，单独存储于 RelayLogBody
+	ResponseContent  string         `json:"response_content,omitempty" gorm:"-"`      // 响应内容，单独存储于 RelayLogBody
+	HasBody          bool           `json:"has_body"`                                 // 是否存有请求/响应内容，内容以日志 ID 关联
+	BodyTruncated    bool           `json:"body_truncated,omitempty" gorm:"-"`        // 内容是否被截断
+	Error            string         `json:"error"`                                    // 错误信息
+	AttemptCount     int            `json:"attempt_count" gorm:"index"`               // 上游尝试次数
+	Attempts         []RelayAttempt `json:"attempts" gorm:"serializer:json"`          // 按顺序记录的上游尝试
+}
+
+// RelayLogBody 日志的请求/响应内容，gzip 压缩后与日志元数据分表存储
+type RelayLogBody struct {
+	LogID     int64  `json:"log_id" gorm:"primaryKey;autoIncrement:false"` // 对应 RelayLog.ID
+	Time      int64  `json:"time" gorm:"index"`                            // 与日志时间一致，用于独立的保留期清理
+	APIKeyID  int    `json:"api_key_id" gorm:"index"`
+	Request   []byte `json:"request"`   // gzip 压缩的请求内容
+	Response  []byte `json:"response"`  // gzip 压缩的响应内容
+	Truncated bool   `json:"truncated"` // 内容是否因超过长度限制被截断
+}
+
+// RelayLogBodyContent 解压后的日志内容
+type RelayLogBodyContent struct {
+	RequestContent  string `json:"request_content"`
+	ResponseContent string `json:"response_content"`
+	Truncated       bool   `json:"truncated"`
+}
+
+// RelayAttempt 一次上游请求尝试
+type RelayAttempt struct {
+	ChannelID   int    `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	KeyID       int    `json:"key_id"`
+	BaseUrl     string `json:"base_url"`
+	ModelName   string `json:"model_name"`
+	StatusCode  int    `json:"status_code"` // 上游状态码，未收到响应时为 0
+	Latency     int    `json:"latency"`     // 耗时(毫秒)
+	Error       string `json:"error,omitempty"`
+}
+
+// RelayLogFilter 日志查询过滤条件，零值字段表示不过滤
+type RelayLogFilter struct {
+	Cursor           *int64 // 游标，只返回 ID 小于该值的日志
+	StartTime        *int
+	EndTime          *int
+	Model            string   // 请求模型或实际模型
+	ChannelID        *int     // 最终使用的渠道
+	APIKeyID         *int     // 请求使用的 API Key
+	Success          *bool    // true 仅成功，false 仅失败
+	MinCost          *float64 // 最小费用
+	MaxCost          *float64 // 最大费用
+	Keyword          string   // 在模型、渠道名称和错误信息中模糊搜索
+	AttemptChannelID *int     // 尝试过指定渠道的日志
+	Failover         *bool    // 是否发生过故障转移(尝试次数大于 1)
+}
+
+// HasAttemptChannel 判断日志是否尝试过指定渠道
+func (l *RelayLog) HasAttemptChannel(channelID int) bool {
+	for _, a := range l.Attempts {
+		if a.ChannelID == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+// Match 判断日志是否满足过滤条件，用于内存缓存和实时推送
+func (f *RelayLogFilter) Match(l *RelayLog) bool {
+	if f == nil {
+		return true
+	}
+	if f.Cursor != nil && l.ID >= *f.Cursor {
+		return false
+	}
+	if f.StartTime != nil && l.Time < int64(*f.StartTime) {
+		return false
+	}
+	if f.EndTime != nil && l.Time > int64(*f.EndTime) {
+		return false
+	}
+	if f.Model != "" && l.RequestModelName != f.Model && l.ActualModelName != f.Model {
+		return false
+	}
+	if f.ChannelID != nil && l.ChannelId != *f.ChannelID {
+		return false
+	}
+	if f.APIKeyID != nil && l.APIKeyID != *f.APIKeyID {
+		return false
+	}
+	if f.Success != nil && (l.Error == "") != *f.Success {
+		return false
+	}
+	if f.MinCost != nil && l.Cost < *f.MinCost {
+		return false
+	}
+	if f.MaxCost != nil && l.Cost > *f.MaxCost {
+		return false
+	}
+	if f.Keyword != "" && !l.containsKeyword(f.Keyword) {
+		return false
+	}
+	if f.AttemptChannelID != nil && !l.HasAttemptChannel(*f.AttemptChannelID) {
+		return false
+	}
+	if f.Failover != nil && (l.AttemptCount > 1) != *f.Failover {
+		return false
+	}
+	return true
+}
+
+// containsKeyword 与数据库 LIKE 查询保持一致的模糊匹配(不区分大小写)
+func (l *RelayLog) containsKeyword(keyword string) bool {
+	keyword = strings.ToLower(keyword)
+	for _, field := range []string{l.RequestModelName, l.ActualModelName, l.ChannelName, l.Error} {
+		if strings.Contains(strings.ToLower(field), keyword) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,95 @@
+package model
+
+import "slices"
+
+type UserRole string
+
+const (
+	UserRoleOwner    UserRole = "owner"    // 全部权限，包括用户管理、设置和备份
+	UserRoleOperator UserRole = "operator" // 管理渠道、分组和模型
+	UserRoleFinance  UserRole = "finance"  // 只读查看统计和 API Key
+	UserRoleViewer   UserRole = "viewer"   // 只读查看统计、分组和模型
+)
+
+// Permission 访问接口所需的权限，格式为 资源:read 或 资源:write
+type Permission string
+
+const (
+	// PermAuthenticated 登录即可访问，用于修改自己的密码等接口
+	PermAuthenticated Permission = "authenticated"
+
+	PermChannelRead    Permission = "channel:read"
+	PermChannelWrite   Permission = "channel:write"
+	PermGroupRead      Permission = "group:read"
+	PermGroupWrite     Permission = "group:write"
+	PermModelRead      Permission = "model:read"
+	PermModelWrite     Permission = "model:write"
+	PermAPIKeyRead     Permission = "apikey:read"
+	PermAPIKeyWrite    Permission = "apikey:write"
+	PermStatsRead      Permission = "stats:read"
+	PermLogRead        Permission = "log:read"
+	PermLogWrite       Permission = "log:write"
+	PermSensitiveRead  Permission = "sensitive:read"
+	PermSensitiveWrite Permission = "sensitive:write"
+	PermSettingRead    Permission = "setting:read"
+	PermSettingWrite   Permission = "setting:write"
+	PermBackupRead     Permission = "backup:read"
+	PermBackupWrite    Permission = "backup:write"
+	PermGitOpsRead     Permission = "gitops:read"
+	PermGitOpsWrite    Permission = "gitops:write"
+	PermTaskRead       Permission = "task:read"
+	PermTaskWrite      Permission = "task:write"
+	PermUpdateWrite    Permission = "update:write"
+	PermStatusRead     Permission = "status:read"
+	PermUserRead       Permission = "user:read"
+	PermUserWrite      Permission = "user:write"
+)
+
+// allPermissions owner 拥有的权限
+var allPermissions = []Permission{
+	PermAuthenticated,
+	PermChannelRead, PermChannelWrite, PermGroupRead, PermGroupWrite, PermModelRead, PermModelWrite,
+	PermAPIKeyRead, PermAPIKeyWrite, PermStatsRead, PermLogRead, PermLogWrite,
+	PermSensitiveRead, PermSensitiveWrite, PermSettingRead, PermSettingWrite,
+	PermBackupRead, PermBackupWrite, PermGitOpsRead, PermGitOpsWrite, PermTaskRead, PermTaskWrite,
+	PermUpdateWrite, PermStatusRead, PermUserRead, PermUserWrite,
+}
+
+// rolePermissions 除 owner 外各角色拥有的权限
+var rolePermissions = map[UserRole][]Permission{
+	UserRoleOperator: {
+		PermAuthenticated,
+		PermChannelRead, PermChannelWrite, PermGroupRead, PermGroupWrite, PermModelRead, PermModelWrite,
+		PermStatsRead, PermLogRead, PermTaskRead, PermStatusRead,
+	},
+	UserRoleFinance: {
+		PermAuthenticated,
+		PermStatsRead, PermAPIKeyRead, PermModelRead, PermStatusRead,
+	},
+	UserRoleViewer: {
+		PermAuthenticated,
+		PermStatsRead, PermGroupRead, PermModelRead, PermTaskRead, PermStatusRead,
+	},
+}
+
+// Valid 是否为已知的角色
+func (r UserRole) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok || r == UserRoleOwner
+}
+
+// Permissions 返回角色拥有的权限
+func (r UserRole) Permissions() []Permission {
+	if r == UserRoleOwner {
+		return slices.Clone(allPermissions)
+	}
+	return slices.Clone(rolePermissions[r])
+}
+
+// Can 角色是否拥有权限，未声明权限的接口只有 owner 可以访问
+func (r UserRole) Can(p Permission) bool {
+	if r == UserRoleOwner {
+		return true
+	}
+	return p != "" && slices.Contains(rolePermissions[r], p)
+}
@@ -1,14 +1,102 @@
 package model
 
+import "fmt"
+
+// SensitiveDirection 规则作用的方向
+type SensitiveDirection string
+
+const (
+	SensitiveDirectionRequest  SensitiveDirection = "request"  // 仅过滤发往上游的请求
+	SensitiveDirectionResponse SensitiveDirection = "response" // 仅过滤返回给客户端的响应
+	SensitiveDirectionBoth     SensitiveDirection = "both"     // 请求和响应都过滤
+)
+
+// SensitiveAction 规则命中后的处理方式
+type SensitiveAction string
+
+const (
+	SensitiveActionReplace SensitiveAction = "replace" // 替换为固定文本
+	SensitiveActionMask    SensitiveAction = "mask"    // 替换为请求内唯一的占位符，响应中还原
+	SensitiveActionBlock   SensitiveAction = "block"   // 拒绝请求
+	SensitiveActionLog     SensitiveAction = "log"     // 仅记录命中，不修改内容
+)
+
+// SensitiveRuleSet 敏感信息规则集，可分配给 API Key 和分组
+// 未分配规则集的规则属于默认规则集，未指定规则集的请求使用默认规则集
+type SensitiveRuleSet struct {
+	ID             int    `json:"id" gorm:"primaryKey"`
+	Name           string `json:"name" gorm:"unique;not null"`
+	Description    string `json:"description"`
+	IncludeDefault bool   `json:"include_default"` // 是否同时应用默认规则集中的规则
+}
+
 // SensitiveFilterRule 敏感信息过滤规则
 type SensitiveFilterRule struct {
-	ID          int    `json:"id" gorm:"primaryKey"`
-	Name        string `json:"name" gorm:"not null"`
-	Pattern     string `json:"pattern" gorm:"not null"`
-	Replacement string `json:"replacement" gorm:"not null"`
-	Enabled     bool   `json:"enabled" gorm:"default:true"`
-	BuiltIn     bool   `json:"built_in" gorm:"default:false"`
-	Priority    int    `json:"priority" gorm:"default:0"`
+	ID          int                `json:"id" gorm:"primaryKey"`
+	Name        string             `json:"name" gorm:"not null"`
+	Pattern     string             `json:"pattern" gorm:"not null"`
+	Replacement string             `json:"replacement" gorm:"not null"`
+	Direction   SensitiveDirection `json:"direction" gorm:"default:'request'"`
+	Action      SensitiveAction    `json:"action" gorm:"default:'replace'"`
+	Enabled     bool               `json:"enabled" gorm:"default:true"`
+	BuiltIn     bool               `json:"built_in" gorm:"default:false"`
+	Priority    int                `json:"priority" gorm:"default:0"`
+	RuleSetID   int                `json:"rule_set_id" gorm:"index;default:0"` // 所属规则集，0 为默认规则集
+}
+
+// SensitiveTestRequest 规则测试请求
+type SensitiveTestRequest struct {
+	Text      string             `json:"text" binding:"required"`
+	Direction SensitiveDirection `json:"direction"`
+	RuleSetID int                `json:"rule_set_id"`
+}
+
+// SensitiveRuleHit 单条规则的命中次数
+type SensitiveRuleHit struct {
+	RuleID int             `json:"rule_id"`
+	Name   string          `json:"name"`
+	Action SensitiveAction `json:"action"`
+	Count  int64           `json:"count"`
+}
+
+// SensitiveTestResult 规则测试结果
+type SensitiveTestResult struct {
+	Text        string             `json:"text"`
+	Hits        []SensitiveRuleHit `json:"hits"`
+	Blocked     bool               `json:"blocked"`
+	BlockedRule string             `json:"blocked_rule,omitempty"`
+}
+
+// AppliesTo 判断规则是否作用于指定方向，未设置方向的旧规则视为仅作用于请求
+func (r *SensitiveFilterRule) AppliesTo(direction SensitiveDirection) bool {
+	switch r.Direction {
+	case SensitiveDirectionBoth:
+		return true
+	case "":
+		return direction == SensitiveDirectionRequest
+	default:
+		return r.Direction == direction
+	}
+}
+
+// Validate 校验规则字段
+func (r *SensitiveFilterRule) Validate() error {
+	switch r.Direction {
+	case "", SensitiveDirectionRequest, SensitiveDirectionResponse, SensitiveDirectionBoth:
+	default:
+		return fmt.Errorf("direction must be request, response or both")
+	}
+	switch r.Action {
+	case "", SensitiveActionReplace, SensitiveActionLog:
+	case SensitiveActionMask, SensitiveActionBlock:
+		// 掩码在请求中生成、在响应中还原；响应可能已经开始输出，无法拦截
+		if r.Direction != "" && r.Direction != SensitiveDirectionRequest {
+			return fmt.Errorf("%s action only applies to request direction", r.Action)
+		}
+	default:
+		return fmt.Errorf("action must be replace, mask, block or log")
+	}
+	return nil
 }
 
 // DefaultSensitiveFilterRules 返回内置的默认过滤规则
@@ -0,0 +1,34 @@
+package model
+
+// AuthSecret 管理后台令牌的签名密钥，ID 写入令牌头部的 kid
+// 轮换后旧密钥在访问令牌的有效期内仍可用于校验
+type AuthSecret struct {
+	ID        uint   `gorm:"primaryKey"`
+	Secret    string `gorm:"not null"`
+	CreatedAt int64  `gorm:"not null"`
+	RetiredAt int64  `gorm:"not null;default:0"` // 被新密钥替换的时间，0 为当前密钥
+}
+
+// UserSession 登录会话，撤销会话后由它签发的访问令牌和刷新令牌立即失效
+// 刷新令牌只保存 SHA-256 哈希，每次刷新都会更换
+type UserSession struct {
+	ID          string `json:"id" gorm:"primaryKey;size:32"`
+	UserID      uint   `json:"user_id" gorm:"index;not null"`
+	RefreshHash string `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	UserAgent   string `json:"user_agent"`
+	IP          string `json:"ip"`
+	CreatedAt   int64  `json:"created_at"`
+	LastUsedAt  int64  `json:"last_used_at"`
+	ExpiresAt   int64  `json:"expires_at" gorm:"index"`
+	Current     bool   `json:"current" gorm:"-"`
+}
+
+// UserRefreshRequest 使用刷新令牌换取新的访问令牌
+type UserRefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// UserSessionRevokeRequest 撤销当前用户的一个会话
+type UserSessionRevokeRequest struct {
+	ID string `json:"id" binding:"required"`
+}
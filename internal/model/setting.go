@@ -15,37 +15,55 @@ const (
 	SettingKeySyncLLMInterval         SettingKey = "sync_llm_interval"          // LLM 同步间隔(小时)
 	SettingKeyRelayLogKeepPeriod      SettingKey = "relay_log_keep_period"      // 日志保存时间范围(天)
 	SettingKeyRelayLogKeepEnabled     SettingKey = "relay_log_keep_enabled"     // 是否保留历史日志
+	SettingKeyRelayLogBodyKeepPeriod  SettingKey = "relay_log_body_keep_period" // 日志请求/响应内容保存时间范围(天)
+	SettingKeyRelayLogBodyMaxSize     SettingKey = "relay_log_body_max_size"    // 日志请求/响应内容最大保存字节数, 0 不限制
 	SettingKeyCORSAllowOrigins        SettingKey = "cors_allow_origins"         // 跨域白名单(逗号分隔, 如 "example.com,example2.com"). 为空不允许跨域, "*"允许所有
 	SettingKeySensitiveFilterEnabled  SettingKey = "sensitive_filter_enabled"   // 敏感信息过滤全局开关
+	SettingKeyBackupInterval          SettingKey = "backup_interval"            // 定时备份间隔(小时), 0 不备份
+	SettingKeyBackupKeepCount         SettingKey = "backup_keep_count"          // 最多保留的备份数量, 0 不限制
+	SettingKeyBackupKeepDays          SettingKey = "backup_keep_days"           // 备份保留天数, 0 不限制
+	SettingKeyBackupIncludeLogs       SettingKey = "backup_include_logs"        // 定时备份是否包含日志
+	SettingKeyBackupIncludeStats      SettingKey = "backup_include_stats"       // 定时备份是否包含统计信息
 )
 
 type Setting struct {
-	Key   SettingKey `json:"key" gorm:"primaryKey"`
-	Value string     `json:"value" gorm:"not null"`
+	Key     SettingKey `json:"key" gorm:"primaryKey"`
+	Value   string     `json:"value" gorm:"not null"`
+	Managed bool       `json:"managed,omitempty" gorm:"default:false"` // 由声明式配置管理，API 只读
 }
 
 func DefaultSettings() []Setting {
 	return []Setting{
 		{Key: SettingKeyProxyURL, Value: ""},
-		{Key: SettingKeyStatsSaveInterval, Value: "10"},       // 默认10分钟保存一次统计信息
-		{Key: SettingKeyCORSAllowOrigins, Value: ""},          // CORS 默认不允许跨域，设置为 "*" 才允许所有来源
-		{Key: SettingKeyModelInfoUpdateInterval, Value: "24"}, // 默认24小时更新一次模型信息
-		{Key: SettingKeySyncLLMInterval, Value: "24"},         // 默认24小时同步一次LLM
-		{Key: SettingKeyRelayLogKeepPeriod, Value: "7"},       // 默认日志保存7天
-		{Key: SettingKeyRelayLogKeepEnabled, Value: "true"},   // 默认保留历史日志
+		{Key: SettingKeyStatsSaveInterval, Value: "10"},        // 默认10分钟保存一次统计信息
+		{Key: SettingKeyCORSAllowOrigins, Value: ""},           // CORS 默认不允许跨域，设置为 "*" 才允许所有来源
+		{Key: SettingKeyModelInfoUpdateInterval, Value: "24"},  // 默认24小时更新一次模型信息
+		{Key: SettingKeySyncLLMInterval, Value: "24"},          // 默认24小时同步一次LLM
+		{Key: SettingKeyRelayLogKeepPeriod, Value: "7"},        // 默认日志保存7天
+		{Key: SettingKeyRelayLogKeepEnabled, Value: "true"},    // 默认保留历史日志
+		{Key: SettingKeyRelayLogBodyKeepPeriod, Value: "7"},    // 默认日志内容保存7天
+		{Key: SettingKeyRelayLogBodyMaxSize, Value: "0"},       // 默认不截断日志内容
 		{Key: SettingKeySensitiveFilterEnabled, Value: "true"}, // 默认启用敏感信息过滤
+		{Key: SettingKeyBackupInterval, Value: "0"},            // 默认不定时备份
+		{Key: SettingKeyBackupKeepCount, Value: "7"},           // 默认保留最近7份备份
+		{Key: SettingKeyBackupKeepDays, Value: "0"},            // 默认不按天数清理备份
+		{Key: SettingKeyBackupIncludeLogs, Value: "false"},     // 默认备份不包含日志
+		{Key: SettingKeyBackupIncludeStats, Value: "true"},     // 默认备份包含统计信息
 	}
 }
 
 func (s *Setting) Validate() error {
 	switch s.Key {
-	case SettingKeyModelInfoUpdateInterval, SettingKeySyncLLMInterval, SettingKeyRelayLogKeepPeriod:
+	case SettingKeyModelInfoUpdateInterval, SettingKeySyncLLMInterval, SettingKeyRelayLogKeepPeriod,
+		SettingKeyRelayLogBodyKeepPeriod, SettingKeyRelayLogBodyMaxSize,
+		SettingKeyBackupInterval, SettingKeyBackupKeepCount, SettingKeyBackupKeepDays:
 		_, err := strconv.Atoi(s.Value)
 		if err != nil {
 			return fmt.Errorf("model info update interval must be an integer")
 		}
 		return nil
-	case SettingKeyRelayLogKeepEnabled, SettingKeySensitiveFilterEnabled:
+	case SettingKeyRelayLogKeepEnabled, SettingKeySensitiveFilterEnabled,
+		SettingKeyBackupIncludeLogs, SettingKeyBackupIncludeStats:
 		if s.Value != "true" && s.Value != "false" {
 			return fmt.Errorf("setting value must be true or false")
 		}
@@ -43,6 +43,37 @@ type StatsAPIKey struct {
 	StatsMetrics
 }
 
+// StatsSensitive 敏感信息规则的命中次数，按规则和 API Key 汇总
+type StatsSensitive struct {
+	RuleID      int   `json:"rule_id" gorm:"primaryKey;autoIncrement:false"`
+	APIKeyID    int   `json:"api_key_id" gorm:"primaryKey;autoIncrement:false"`
+	Hits        int64 `json:"hits" gorm:"bigint"`
+	LastHitTime int64 `json:"last_hit_time"`
+}
+
+// StatsJournal 单个请求的统计流水，写入汇总表时删除
+// 进程异常退出时尚未汇总的流水在下次启动时重放，保证用量、渠道密钥费用和敏感信息命中次数不丢失
+// 按模型的统计（StatsModel）目前没有调用方记录，不经过流水
+type StatsJournal struct {
+	ID           int64  `json:"id" gorm:"primaryKey"`
+	Node         string `json:"node" gorm:"index"`
+	Time         int64  `json:"time" gorm:"index"`
+	Date         string `json:"date"` // 格式：20060102
+	Hour         int    `json:"hour"`
+	ChannelID    int    `json:"channel_id"`
+	ChannelKeyID int    `json:"channel_key_id"` // 费用同时累加到该渠道密钥的 total_cost，为 0 时不累加
+	APIKeyID     int    `json:"api_key_id"`
+	StatsMetrics
+	// SensitiveHits 各敏感信息规则的命中次数，只记录命中次数的流水没有用量
+	SensitiveHits map[int]int64 `json:"sensitive_hits,omitempty" gorm:"serializer:json"`
+}
+
+// StatsSensitiveKey 敏感信息命中统计的缓存键
+type StatsSensitiveKey struct {
+	RuleID   int
+	APIKeyID int
+}
+
 // Add aggregates another StatsMetrics into the current one.
 func (s *StatsMetrics) Add(delta StatsMetrics) {
 	s.InputToken += delta.InputToken
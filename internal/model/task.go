@@ -0,0 +1,27 @@
+package model
+
+type TaskTrigger string
+
+const (
+	TaskTriggerStart    TaskTrigger = "start"
+	TaskTriggerSchedule TaskTrigger = "schedule"
+	TaskTriggerManual   TaskTrigger = "manual"
+)
+
+// TaskRun 定时任务的一次执行记录
+type TaskRun struct {
+	Trigger   TaskTrigger `json:"trigger"`
+	StartTime int64       `json:"start_time"` // 毫秒时间戳
+	Duration  int64       `json:"duration"`   // 毫秒
+	Error     string      `json:"error,omitempty"`
+}
+
+// TaskInfo 定时任务的状态，History 按时间倒序，只保留最近的记录
+type TaskInfo struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Paused   bool      `json:"paused"`
+	Running  bool      `json:"running"`
+	NextRun  int64     `json:"next_run"` // 秒级时间戳，暂停或没有下次执行时为 0
+	History  []TaskRun `json:"history"`
+}
@@ -7,15 +7,50 @@ import (
 )
 
 type User struct {
-	ID       uint   `gorm:"primaryKey"`
-	Username string `gorm:"unique"`
-	Password string `gorm:"not null"`
+	ID       uint     `json:"id" gorm:"primaryKey"`
+	Username string   `json:"username" gorm:"unique"`
+	Password string   `json:"-" gorm:"not null"`
+	Role     UserRole `json:"role" gorm:"not null;default:owner"` // 升级前的唯一用户默认为 owner
+
+	TOTPSecret    string   `json:"-"` // 启用前为待验证的密钥
+	TOTPEnabled   bool     `json:"totp_enabled" gorm:"not null;default:false"`
+	TOTPLastStep  int64    `json:"-"`                        // 最近一次使用的验证码时间步，防止重放
+	RecoveryCodes []string `json:"-" gorm:"serializer:json"` // 恢复码的 SHA-256 哈希，使用后删除
+
+	OIDCSubject string `json:"-" gorm:"column:oidc_subject;index"` // 单点登录关联的身份，格式为 issuer|sub
+	SSO         bool   `json:"sso" gorm:"-"`                       // 是否已关联单点登录，仅用于返回给前端
+}
+
+// UserCreateRequest 创建用户
+type UserCreateRequest struct {
+	Username string   `json:"username" binding:"required"`
+	Password string   `json:"password" binding:"required"`
+	Role     UserRole `json:"role" binding:"required"`
+}
+
+// UserUpdateRequest 修改用户的角色、重置密码或关闭两步验证，字段为空时不修改
+type UserUpdateRequest struct {
+	ID          uint      `json:"id" binding:"required"`
+	Role        *UserRole `json:"role,omitempty"`
+	Password    *string   `json:"password,omitempty"`
+	DisableTOTP bool      `json:"disable_totp,omitempty"`
+}
+
+// UserInfo 当前登录用户的信息
+type UserInfo struct {
+	ID          uint         `json:"id"`
+	Username    string       `json:"username"`
+	Role        UserRole     `json:"role"`
+	TOTPEnabled bool         `json:"totp_enabled"`
+	Permissions []Permission `json:"permissions"`
 }
 
 type UserLogin struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Expire   int    `json:"expire"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	Expire       int    `json:"expire"`        // 会话有效期（分钟），0 为 15 分钟，-1 为 30 天
+	TOTPCode     string `json:"totp_code"`     // 启用两步验证时必填，或使用恢复码
+	RecoveryCode string `json:"recovery_code"` // 一次性恢复码
 }
 
 type UserChangePassword struct {
@@ -27,9 +62,42 @@ type UserChangeUsername struct {
 	NewUsername string `json:"new_username"`
 }
 
+// UserLoginResponse 登录和刷新的结果，Token 为短期访问令牌，过期前使用 RefreshToken 换取新令牌
+// 密码正确但需要两步验证时只返回 TOTPRequired，客户端带上验证码重新登录
 type UserLoginResponse struct {
-	Token    string `json:"token"`
-	ExpireAt string `json:"expire_at"`
+	Token           string    `json:"token,omitempty"`
+	ExpireAt        string    `json:"expire_at,omitempty"`
+	RefreshToken    string    `json:"refresh_token,omitempty"`
+	RefreshExpireAt string    `json:"refresh_expire_at,omitempty"`
+	User            *UserInfo `json:"user,omitempty"`
+	TOTPRequired    bool      `json:"totp_required,omitempty"`
+}
+
+// UserTOTPSetupResponse 两步验证的待验证密钥，otpauth URI 可以生成二维码供验证器扫描
+type UserTOTPSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// UserTOTPEnableRequest 使用验证器生成的验证码确认启用两步验证
+type UserTOTPEnableRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// UserPasswordConfirm 关闭两步验证或重新生成恢复码前确认密码
+type UserPasswordConfirm struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// UserRecoveryCodes 新生成的恢复码，只显示一次
+type UserRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// UserOIDCInfo 登录页显示单点登录按钮所需的信息
+type UserOIDCInfo struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name,omitempty"`
 }
 
 func (u *User) HashPassword() error {
@@ -44,3 +112,8 @@ func (u *User) HashPassword() error {
 func (u *User) ComparePassword(password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 }
+
+// Info 返回用户信息和角色拥有的权限
+func (u *User) Info() UserInfo {
+	return UserInfo{ID: u.ID, Username: u.Username, Role: u.Role, TOTPEnabled: u.TOTPEnabled, Permissions: u.Role.Permissions()}
+}
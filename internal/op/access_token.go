@@ -0,0 +1,175 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/cache"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"gorm.io/gorm"
+)
+
+var accessTokenCache = cache.New[int, model.AccessToken](16)
+var accessTokenIDMap = cache.New[string, int](16)
+
+// accessTokenTouchInterval 最近使用时间和地址写入数据库的最小间隔，地址变化也不会提前写入，
+// 避免来自多个地址的调用每次都写数据库
+const accessTokenTouchInterval = time.Minute
+
+var ErrAccessTokenNotFound = errors.New("access token not found")
+
+// AccessTokenList 返回用户的个人访问令牌，按创建顺序排列
+func AccessTokenList(userID uint) []model.AccessToken {
+	tokens := []model.AccessToken{}
+	for _, t := range accessTokenCache.GetAll() {
+		if t.UserID == userID {
+			tokens = append(tokens, t)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b model.AccessToken) int { return a.ID - b.ID })
+	return tokens
+}
+
+// AccessTokenCreate 为用户创建个人访问令牌，token 为明文令牌，只保存其哈希
+// 限定的权限必须是用户角色拥有的权限
+func AccessTokenCreate(user model.User, req *model.AccessTokenCreateRequest, token string, ctx context.Context) (*model.AccessToken, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	for _, p := range req.Scopes {
+		if !p.Valid() {
+			return nil, fmt.Errorf("invalid scope: %s", p)
+		}
+		if !user.Role.Can(p) {
+			return nil, fmt.Errorf("role %s does not have scope %s", user.Role, p)
+		}
+	}
+	scopes := slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+	if scopes == nil {
+		scopes = []model.Permission{}
+	}
+	now := time.Now().Unix()
+	if req.ExpiresAt != 0 && req.ExpiresAt <= now {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+	accessToken := model.AccessToken{
+		UserID:    user.ID,
+		Name:      name,
+		TokenHash: tokenHash(token),
+		Hint:      token[max(len(token)-4, 0):],
+		Scopes:    scopes,
+		ReadOnly:  req.ReadOnly,
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: now,
+	}
+	if err := db.Conn(ctx).Create(&accessToken).Error; err != nil {
+		return nil, fmt.Errorf("failed to create access token: %w", err)
+	}
+	accessTokenCache.Set(accessToken.ID, accessToken)
+	accessTokenIDMap.Set(accessToken.TokenHash, accessToken.ID)
+	return &accessToken, nil
+}
+
+// AccessTokenDelete 删除用户的一个个人访问令牌
+func AccessTokenDelete(userID uint, id int, ctx context.Context) error {
+	accessToken, ok := accessTokenCache.Get(id)
+	if !ok || accessToken.UserID != userID {
+		return ErrAccessTokenNotFound
+	}
+	if err := db.Conn(ctx).Delete(&model.AccessToken{ID: id}).Error; err != nil {
+		return fmt.Errorf("failed to delete access token: %w", err)
+	}
+	accessTokenCache.Del(id)
+	accessTokenIDMap.Del(accessToken.TokenHash)
+	return nil
+}
+
+// AccessTokenDeleteUser 删除用户的所有个人访问令牌
+func AccessTokenDeleteUser(userID uint, ctx context.Context) error {
+	if err := db.Conn(ctx).Where("user_id = ?", userID).Delete(&model.AccessToken{}).Error; err != nil {
+		return fmt.Errorf("failed to delete access tokens: %w", err)
+	}
+	cacheRetain(accessTokenCache, func(_ int, t model.AccessToken) bool {
+		if t.UserID == userID {
+			accessTokenIDMap.Del(t.TokenHash)
+			return false
+		}
+		return true
+	})
+	return nil
+}
+
+// AccessTokenVerify 查找明文令牌对应的未过期令牌，并按 accessTokenTouchInterval 记录最近使用的时间和地址
+// 缓存中没有时查询数据库，多实例模式下令牌可能由其他实例创建或删除，总是以数据库为准
+func AccessTokenVerify(token, ip string, ctx context.Context) (model.AccessToken, bool) {
+	hash := tokenHash(token)
+	var accessToken model.AccessToken
+	id, ok := accessTokenIDMap.Get(hash)
+	if ok {
+		accessToken, ok = accessTokenCache.Get(id)
+	}
+	if !ok || ClusterEnabled() {
+		accessToken, ok = accessTokenLoad(hash, ctx)
+		id = accessToken.ID
+	}
+	now := time.Now().Unix()
+	if !ok || (accessToken.ExpiresAt > 0 && accessToken.ExpiresAt <= now) {
+		return model.AccessToken{}, false
+	}
+	if now-accessToken.LastUsedAt >= int64(accessTokenTouchInterval.Seconds()) {
+		accessToken.LastUsedAt = now
+		accessToken.LastUsedIP = ip
+		accessTokenCache.Set(id, accessToken)
+		if err := db.Conn(ctx).Model(&model.AccessToken{ID: id}).
+			UpdateColumns(map[string]any{"last_used_at": now, "last_used_ip": ip}).Error; err != nil {
+			log.Warnf("failed to record access token %d usage: %v", id, err)
+		}
+	}
+	return accessToken, true
+}
+
+// accessTokenLoad 按令牌哈希从数据库读取令牌并更新缓存，令牌已被删除时同时移出缓存，查询失败时视为不存在
+func accessTokenLoad(hash string, ctx context.Context) (model.AccessToken, bool) {
+	var accessToken model.AccessToken
+	err := db.Conn(ctx).Where("token_hash = ?", hash).First(&accessToken).Error
+	switch {
+	case err == nil:
+		accessTokenCache.Set(accessToken.ID, accessToken)
+		accessTokenIDMap.Set(hash, accessToken.ID)
+		return accessToken, true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if id, ok := accessTokenIDMap.Get(hash); ok {
+			accessTokenCache.Del(id)
+			accessTokenIDMap.Del(hash)
+		}
+	default:
+		log.Warnf("failed to load access token: %v", err)
+	}
+	return model.AccessToken{}, false
+}
+
+func accessTokenRefreshCache(ctx context.Context) error {
+	tokens := []model.AccessToken{}
+	if err := db.Conn(ctx).Find(&tokens).Error; err != nil {
+		return err
+	}
+	cacheRetain(accessTokenCache, func(id int, t model.AccessToken) bool {
+		if slices.ContainsFunc(tokens, func(n model.AccessToken) bool { return n.ID == id }) {
+			return true
+		}
+		accessTokenIDMap.Del(t.TokenHash)
+		return false
+	})
+	for _, t := range tokens {
+		accessTokenCache.Set(t.ID, t)
+		accessTokenIDMap.Set(t.TokenHash, t.ID)
+	}
+	return nil
+}
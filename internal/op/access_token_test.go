@@ -0,0 +1,90 @@
+package op
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+// 最近使用的时间和地址按间隔写入，间隔内换了地址也不写数据库
+func TestAccessTokenVerifyThrottlesUsage(t *testing.T) {
+	dbtest.Init(t, "token.db")
+	ctx := context.Background()
+	user := model.User{ID: 1, Username: "u", Role: model.UserRoleOwner}
+	token := "pat-test-token"
+	created, err := AccessTokenCreate(user, &model.AccessTokenCreateRequest{Name: "t"}, token, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := func() model.AccessToken {
+		t.Helper()
+		var row model.AccessToken
+		if err := db.Conn(ctx).First(&row, created.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return row
+	}
+
+	if _, ok := AccessTokenVerify(token, "10.0.0.1", ctx); !ok {
+		t.Fatal("token rejected")
+	}
+	first := stored()
+	if first.LastUsedAt == 0 || first.LastUsedIP != "10.0.0.1" {
+		t.Fatalf("first use not recorded: %+v", first)
+	}
+
+	if _, ok := AccessTokenVerify(token, "10.0.0.2", ctx); !ok {
+		t.Fatal("token rejected")
+	}
+	if got := stored(); got.LastUsedAt != first.LastUsedAt || got.LastUsedIP != "10.0.0.1" {
+		t.Errorf("usage written within interval: %+v", got)
+	}
+
+	// 超过间隔后记录新的时间和地址
+	cached, _ := accessTokenCache.Get(created.ID)
+	cached.LastUsedAt -= int64(accessTokenTouchInterval.Seconds())
+	accessTokenCache.Set(created.ID, cached)
+	if _, ok := AccessTokenVerify(token, "10.0.0.2", ctx); !ok {
+		t.Fatal("token rejected")
+	}
+	if got := stored(); got.LastUsedIP != "10.0.0.2" {
+		t.Errorf("usage after interval = %+v", got)
+	}
+
+	if _, ok := AccessTokenVerify("pat-test-other", "10.0.0.1", ctx); ok {
+		t.Error("unknown token accepted")
+	}
+}
+
+// 其他实例创建的令牌在缓存中没有时从数据库读取，多实例模式下其他实例删除的令牌立即失效
+func TestAccessTokenVerifyOtherInstance(t *testing.T) {
+	dbtest.Init(t, "token.db")
+	ctx := context.Background()
+	enabled := conf.AppConfig.Cluster.Enabled
+	t.Cleanup(func() { conf.AppConfig.Cluster.Enabled = enabled })
+
+	token := "pat-test-remote"
+	other := model.AccessToken{UserID: 1, Name: "remote", TokenHash: tokenHash(token), CreatedAt: time.Now().Unix()}
+	if err := db.Conn(ctx).Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := AccessTokenVerify(token, "10.0.0.1", ctx); !ok || got.ID != other.ID {
+		t.Fatalf("token created by another instance = %+v, %v", got, ok)
+	}
+
+	conf.AppConfig.Cluster.Enabled = true
+	if err := db.Conn(ctx).Delete(&model.AccessToken{ID: other.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := AccessTokenVerify(token, "10.0.0.1", ctx); ok {
+		t.Error("token deleted by another instance still valid")
+	}
+	if _, ok := accessTokenIDMap.Get(tokenHash(token)); ok {
+		t.Error("deleted token kept in cache")
+	}
+}
@@ -4,16 +4,32 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/bestruirui/octopus/internal/conf"
 	"github.com/bestruirui/octopus/internal/db"
 	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/utils/cache"
+	"github.com/bestruirui/octopus/internal/utils/xcrypto"
 )
 
+// APIKeyPrefix API Key 的前缀
+const APIKeyPrefix = "sk-" + conf.APP_NAME + "-"
+
+const apiKeyChars = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
 var apiKeyCache = cache.New[int, model.APIKey](16)
 var apiKeyIDMap = cache.New[string, int](16)
 
+// APIKeyGenerate 生成新的 API Key
+func APIKeyGenerate() (string, error) {
+	key, err := xcrypto.RandomString(48, apiKeyChars)
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + key, nil
+}
+
 func APIKeyCreate(key *model.APIKey, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Create(key).Error; err != nil {
+	if err := db.Conn(ctx).Create(key).Error; err != nil {
 		return fmt.Errorf("failed to create API key: %w", err)
 	}
 	apiKeyCache.Set(key.ID, *key)
@@ -26,7 +42,7 @@ func APIKeyUpdate(key *model.APIKey, ctx context.Context) error {
 	if !ok {
 		return fmt.Errorf("API key not found")
 	}
-	if err := db.GetDB().WithContext(ctx).Omit("api_key").Save(key).Error; err != nil {
+	if err := db.Conn(ctx).Omit("api_key").Save(key).Error; err != nil {
 		return fmt.Errorf("failed to update API key: %w", err)
 	}
 	key.APIKey = existing.APIKey
@@ -34,6 +50,22 @@ func APIKeyUpdate(key *model.APIKey, ctx context.Context) error {
 	return nil
 }
 
+// APIKeySetKey 替换 API Key 的密钥值，用于声明式配置中的密钥轮换
+func APIKeySetKey(id int, apiKey string, ctx context.Context) error {
+	existing, ok := apiKeyCache.Get(id)
+	if !ok {
+		return fmt.Errorf("API key not found")
+	}
+	if err := db.Conn(ctx).Model(&model.APIKey{}).Where("id = ?", id).Update("api_key", apiKey).Error; err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	apiKeyIDMap.Del(existing.APIKey)
+	existing.APIKey = apiKey
+	apiKeyCache.Set(id, existing)
+	apiKeyIDMap.Set(apiKey, id)
+	return nil
+}
+
 func APIKeyList(ctx context.Context) ([]model.APIKey, error) {
 	keys := make([]model.APIKey, 0, apiKeyCache.Len())
 	for _, apiKey := range apiKeyCache.GetAll() {
@@ -62,10 +94,10 @@ func APIKeyDelete(id int, ctx context.Context) error {
 	k := model.APIKey{
 		ID: id,
 	}
-	if err := StatsAPIKeyDel(id); err != nil {
+	if err := StatsAPIKeyDel(id, ctx); err != nil {
 		return fmt.Errorf("failed to delete stats API key: %v", err)
 	}
-	result := db.GetDB().WithContext(ctx).Delete(&k)
+	result := db.Conn(ctx).Delete(&k)
 	if result.RowsAffected == 0 {
 		return fmt.Errorf("API key not found")
 	}
@@ -79,12 +111,18 @@ func APIKeyDelete(id int, ctx context.Context) error {
 
 func apiKeyRefreshCache(ctx context.Context) error {
 	apiKeys := []model.APIKey{}
-	if err := db.GetDB().WithContext(ctx).Find(&apiKeys).Error; err != nil {
+	if err := db.Conn(ctx).Find(&apiKeys).Error; err != nil {
 		return err
 	}
+	ids := make(map[int]struct{}, len(apiKeys))
+	keys := make(map[string]struct{}, len(apiKeys))
 	for _, apiKey := range apiKeys {
 		apiKeyCache.Set(apiKey.ID, apiKey)
 		apiKeyIDMap.Set(apiKey.APIKey, apiKey.ID)
+		ids[apiKey.ID] = struct{}{}
+		keys[apiKey.APIKey] = struct{}{}
 	}
+	cacheRetain(apiKeyCache, func(id int, _ model.APIKey) bool { _, ok := ids[id]; return ok })
+	cacheRetain(apiKeyIDMap, func(key string, _ int) bool { _, ok := keys[key]; return ok })
 	return nil
 }
@@ -11,10 +11,10 @@ import (
 	"gorm.io/gorm/clause"
 )
 
-const dbDumpVersion = 1
+const dbDumpVersion = 2
 
 func DBExportAll(ctx context.Context, includeLogs, includeStats bool) (*model.DBDump, error) {
-	conn := db.GetDB().WithContext(ctx)
+	conn := db.Conn(ctx)
 
 	d := &model.DBDump{
 		Version:      dbDumpVersion,
@@ -26,6 +26,9 @@ func DBExportAll(ctx context.Context, includeLogs, includeStats bool) (*model.DB
 	if err := conn.Find(&d.Channels).Error; err != nil {
 		return nil, fmt.Errorf("export channels: %w", err)
 	}
+	if err := conn.Find(&d.ChannelKeys).Error; err != nil {
+		return nil, fmt.Errorf("export channel_keys: %w", err)
+	}
 	if err := conn.Find(&d.Groups).Error; err != nil {
 		return nil, fmt.Errorf("export groups: %w", err)
 	}
@@ -41,6 +44,9 @@ func DBExportAll(ctx context.Context, includeLogs, includeStats bool) (*model.DB
 	if err := conn.Find(&d.Settings).Error; err != nil {
 		return nil, fmt.Errorf("export settings: %w", err)
 	}
+	if err := conn.Find(&d.SensitiveRuleSets).Error; err != nil {
+		return nil, fmt.Errorf("export sensitive_rule_sets: %w", err)
+	}
 	if err := conn.Find(&d.SensitiveFilterRules).Error; err != nil {
 		return nil, fmt.Errorf("export sensitive_filter_rules: %w", err)
 	}
@@ -64,36 +70,87 @@ func DBExportAll(ctx context.Context, includeLogs, includeStats bool) (*model.DB
 		if err := conn.Find(&d.StatsAPIKey).Error; err != nil {
 			return nil, fmt.Errorf("export stats_api_key: %w", err)
 		}
+		if err := conn.Find(&d.StatsSensitive).Error; err != nil {
+			return nil, fmt.Errorf("export stats_sensitive: %w", err)
+		}
 	}
 
 	if includeLogs {
 		if err := conn.Find(&d.RelayLogs).Error; err != nil {
 			return nil, fmt.Errorf("export relay_logs: %w", err)
 		}
+		if err := conn.Find(&d.RelayLogBodies).Error; err != nil {
+			return nil, fmt.Errorf("export relay_log_bodies: %w", err)
+		}
 	}
 
 	return d, nil
 }
 
-func DBImportIncremental(ctx context.Context, dump *model.DBDump) (*model.DBImportResult, error) {
+// DBImport 导入数据库备份，旧版本的备份先升级到当前版本
+// 增量模式新增缺失的行，并按键覆盖 llm_infos、settings、内置规则和统计表；
+// 替换模式在同一事务中清空配置表后导入，统计和日志仍按增量方式导入；
+// DryRun 时只返回与当前数据库的差异
+func DBImport(ctx context.Context, dump *model.DBDump, opts model.DBImportOptions) (*model.DBImportResult, error) {
 	if dump == nil {
 		return nil, fmt.Errorf("empty dump")
 	}
+	switch opts.Mode {
+	case "":
+		opts.Mode = model.DBImportModeMerge
+	case model.DBImportModeMerge, model.DBImportModeReplace:
+	default:
+		return nil, fmt.Errorf("unsupported import mode: %s", opts.Mode)
+	}
 
-	if dump.Version != 0 && dump.Version != dbDumpVersion {
-		return nil, fmt.Errorf("unsupported dump version: %d", dump.Version)
+	fromVersion := dump.Version
+	if err := dbDumpUpgrade(dump); err != nil {
+		return nil, err
+	}
+	replace := opts.Mode == model.DBImportModeReplace
+	if replace && fromVersion < 2 && len(dump.Channels) > 0 && len(dump.ChannelKeys) == 0 {
+		return nil, fmt.Errorf("dump version %d does not contain channel keys, replacing would remove them: use merge mode", fromVersion)
 	}
 
-	conn := db.GetDB().WithContext(ctx)
-	res := &model.DBImportResult{RowsAffected: map[string]int64{}}
+	res := &model.DBImportResult{
+		Mode:         opts.Mode,
+		DryRun:       opts.DryRun,
+		FromVersion:  fromVersion,
+		RowsAffected: map[string]int64{},
+	}
+	if opts.DryRun {
+		current, err := DBExportAll(ctx, false, dump.IncludeStats)
+		if err != nil {
+			return nil, err
+		}
+		res.Tables, err = dbImportPlanTables(ctx, current, dump, replace)
+		if err != nil {
+			return nil, err
+		}
+		res.Changes = dbImportPlanChanges(current, dump, replace)
+		return res, nil
+	}
+
+	conn := db.Conn(ctx)
 
 	err := conn.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			if err := dbDeleteConfigTables(tx, res); err != nil {
+				return err
+			}
+		}
+
 		// base tables
 		if n, err := createDoNothing(tx, dump.Channels); err != nil {
 			return fmt.Errorf("import channels: %w", err)
 		} else {
 			res.RowsAffected["channels"] = n
 		}
+		if n, err := createDoNothing(tx, dump.ChannelKeys); err != nil {
+			return fmt.Errorf("import channel_keys: %w", err)
+		} else {
+			res.RowsAffected["channel_keys"] = n
+		}
 		if n, err := createDoNothing(tx, dump.Groups); err != nil {
 			return fmt.Errorf("import groups: %w", err)
 		} else {
@@ -120,6 +177,12 @@ func DBImportIncremental(ctx context.Context, dump *model.DBDump) (*model.DBImpo
 			res.RowsAffected["settings"] = n
 		}
 
+		if n, err := createDoNothing(tx, dump.SensitiveRuleSets); err != nil {
+			return fmt.Errorf("import sensitive_rule_sets: %w", err)
+		} else {
+			res.RowsAffected["sensitive_rule_sets"] = n
+		}
+
 		// 分离内置规则和自定义规则
 		var builtInRules []model.SensitiveFilterRule
 		var customRules []model.SensitiveFilterRule
@@ -138,7 +201,7 @@ func DBImportIncremental(ctx context.Context, dump *model.DBDump) (*model.DBImpo
 			res.RowsAffected["sensitive_filter_rules_custom"] = n
 		}
 
-		// 导入内置规则（仅更新 enabled 和 priority）
+		// 导入内置规则（仅更新 enabled、priority、direction 和 action）
 		if n, err := createUpsertBuiltInRules(tx, builtInRules); err != nil {
 			return fmt.Errorf("import builtin sensitive_filter_rules: %w", err)
 		} else {
@@ -176,6 +239,11 @@ func DBImportIncremental(ctx context.Context, dump *model.DBDump) (*model.DBImpo
 			} else {
 				res.RowsAffected["stats_api_key"] = n
 			}
+			if n, err := createUpsertAll(tx, dump.StatsSensitive, []clause.Column{{Name: "rule_id"}, {Name: "api_key_id"}}); err != nil {
+				return fmt.Errorf("import stats_sensitive: %w", err)
+			} else {
+				res.RowsAffected["stats_sensitive"] = n
+			}
 		}
 
 		if dump.IncludeLogs {
@@ -184,9 +252,14 @@ func DBImportIncremental(ctx context.Context, dump *model.DBDump) (*model.DBImpo
 			} else {
 				res.RowsAffected["relay_logs"] = n
 			}
+			if n, err := createDoNothing(tx, dump.RelayLogBodies); err != nil {
+				return fmt.Errorf("import relay_log_bodies: %w", err)
+			} else {
+				res.RowsAffected["relay_log_bodies"] = n
+			}
 		}
 
-		return nil
+		return resetSequences(tx, dbSerialTables...)
 	})
 	if err != nil {
 		return nil, err
@@ -195,43 +268,83 @@ func DBImportIncremental(ctx context.Context, dump *model.DBDump) (*model.DBImpo
 }
 
 func createDoNothing[T any](tx *gorm.DB, rows []T) (int64, error) {
-	if len(rows) == 0 {
-		return 0, nil
-	}
-	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows)
-	return result.RowsAffected, result.Error
+	return createRows(tx, rows, clause.OnConflict{DoNothing: true})
 }
 
 func createUpsertAll[T any](tx *gorm.DB, rows []T, columns []clause.Column) (int64, error) {
-	if len(rows) == 0 {
-		return 0, nil
-	}
-	result := tx.Clauses(clause.OnConflict{
+	return createRows(tx, rows, clause.OnConflict{
 		Columns:   columns,
 		UpdateAll: true,
-	}).Create(&rows)
-	return result.RowsAffected, result.Error
+	})
 }
 
 func createUpsertSettings(tx *gorm.DB, rows []model.Setting) (int64, error) {
-	if len(rows) == 0 {
-		return 0, nil
-	}
-	result := tx.Clauses(clause.OnConflict{
+	return createRows(tx, rows, clause.OnConflict{
 		Columns:   []clause.Column{{Name: "key"}},
 		DoUpdates: clause.AssignmentColumns([]string{"value"}),
-	}).Create(&rows)
-	return result.RowsAffected, result.Error
+	})
 }
 
 func createUpsertBuiltInRules(tx *gorm.DB, rows []model.SensitiveFilterRule) (int64, error) {
+	// 内置规则仅更新 enabled、priority、direction 和 action 字段，不更新核心字段
+	return createRows(tx, rows, clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "priority", "direction", "action"}),
+	})
+}
+
+// createRows 以列 map 写入，保留带默认值字段的零值，不写入关联
+func createRows[T any](tx *gorm.DB, rows []T, onConflict clause.OnConflict) (int64, error) {
 	if len(rows) == 0 {
 		return 0, nil
 	}
-	// 内置规则仅更新 enabled 和 priority 字段，不更新核心字段
-	result := tx.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"enabled", "priority"}),
-	}).Create(&rows)
+	maps, err := db.ColumnMaps(tx, rows)
+	if err != nil {
+		return 0, err
+	}
+	result := tx.Model(new(T)).Clauses(onConflict).Create(&maps)
 	return result.RowsAffected, result.Error
 }
+
+// dbConfigTables 替换模式下清空的配置表，按依赖关系先删除子表
+var dbConfigTables = []struct {
+	name  string
+	model any
+}{
+	{"group_items", &model.GroupItem{}},
+	{"channel_keys", &model.ChannelKey{}},
+	{"groups", &model.Group{}},
+	{"channels", &model.Channel{}},
+	{"api_keys", &model.APIKey{}},
+	{"llm_infos", &model.LLMInfo{}},
+	{"settings", &model.Setting{}},
+	{"sensitive_filter_rules", &model.SensitiveFilterRule{}},
+	{"sensitive_rule_sets", &model.SensitiveRuleSet{}},
+}
+
+// dbSerialTables 使用自增主键的表，导入指定主键的行后需要重置序列
+var dbSerialTables = []string{
+	"channels", "channel_keys", "groups", "group_items", "api_keys",
+	"sensitive_rule_sets", "sensitive_filter_rules",
+}
+
+func dbDeleteConfigTables(tx *gorm.DB, res *model.DBImportResult) error {
+	for _, table := range dbConfigTables {
+		result := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(table.model)
+		if result.Error != nil {
+			return fmt.Errorf("clear %s: %w", table.name, result.Error)
+		}
+		res.RowsAffected[table.name+"_deleted"] = result.RowsAffected
+	}
+	return nil
+}
+
+// resetSequences PostgreSQL 插入指定主键的行不会推进序列，需要将序列设置为当前最大主键之后
+func resetSequences(tx *gorm.DB, tables ...string) error {
+	for _, table := range tables {
+		if err := db.ResetSequence(tx, table, "id"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
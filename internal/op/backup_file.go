@@ -0,0 +1,293 @@
+package op
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/bestruirui/octopus/internal/utils/xcrypto"
+)
+
+const (
+	backupFilePrefix     = "octopus-backup-"
+	backupTimeLayout     = "20060102-150405"
+	backupExt            = ".json.gz"
+	backupEncryptedExt   = ".json.gz.enc"
+	backupTempFilePrefix = ".tmp-"
+)
+
+// backupMu 串行化备份文件的创建、恢复和清理
+var backupMu sync.Mutex
+
+// BackupCreate 导出数据库并写入备份目录，gzip 压缩，配置了口令时加密，完成后按保留策略清理旧备份
+func BackupCreate(ctx context.Context) (*model.BackupFile, error) {
+	includeLogs, _ := SettingGetBool(model.SettingKeyBackupIncludeLogs)
+	includeStats, _ := SettingGetBool(model.SettingKeyBackupIncludeStats)
+
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	if includeStats {
+		// 先写入内存中的统计数据，保证备份是最新的
+		if err := StatsSaveDB(ctx); err != nil {
+			return nil, fmt.Errorf("save stats: %w", err)
+		}
+	}
+	dump, err := DBExportAll(ctx, includeLogs, includeStats)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(dump); err != nil {
+		return nil, fmt.Errorf("encode backup: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("compress backup: %w", err)
+	}
+	data := buf.Bytes()
+
+	passphrase := conf.AppConfig.Backup.Passphrase
+	ext := backupExt
+	if passphrase != "" {
+		if data, err = xcrypto.Encrypt(data, passphrase); err != nil {
+			return nil, fmt.Errorf("encrypt backup: %w", err)
+		}
+		ext = backupEncryptedExt
+	}
+
+	dir := conf.AppConfig.Backup.Path
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create backup dir: %w", err)
+	}
+	now := time.Now()
+	name := backupFilePrefix + now.Format(backupTimeLayout) + ext
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("backup %s already exists", name)
+	}
+	// 先写入临时文件再重命名，避免中断时留下不完整的备份
+	tmp := filepath.Join(dir, backupTempFilePrefix+name)
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("write backup: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("write backup: %w", err)
+	}
+
+	if err := backupPrune(); err != nil {
+		log.Warnf("failed to prune backups: %v", err)
+	}
+	return &model.BackupFile{
+		Name:      name,
+		Size:      int64(len(data)),
+		CreatedAt: now.Unix(),
+		Encrypted: passphrase != "",
+	}, nil
+}
+
+// BackupList 列出备份目录中的备份，按创建时间倒序
+func BackupList() (*model.BackupList, error) {
+	files, err := backupFiles()
+	if err != nil {
+		return nil, err
+	}
+	return &model.BackupList{
+		Path:              conf.AppConfig.Backup.Path,
+		EncryptionEnabled: conf.AppConfig.Backup.Passphrase != "",
+		Files:             files,
+	}, nil
+}
+
+// BackupPath 返回备份文件的完整路径，名称不合法或文件不存在时返回错误
+func BackupPath(name string) (string, error) {
+	if _, ok := parseBackupName(name); !ok || filepath.Base(name) != name {
+		return "", fmt.Errorf("invalid backup name")
+	}
+	path := filepath.Join(conf.AppConfig.Backup.Path, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("backup %s not found", name)
+	}
+	return path, nil
+}
+
+// BackupRestore 将备份导入数据库，调用方负责刷新缓存
+func BackupRestore(ctx context.Context, name string, opts model.DBImportOptions) (*model.DBImportResult, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	path, err := BackupPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dump, err := BackupDecode(data, conf.AppConfig.Backup.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return DBImport(ctx, dump, opts)
+}
+
+// BackupDelete 删除一份备份
+func BackupDelete(name string) error {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	path, err := BackupPath(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// BackupDecode 解析备份内容，支持加密、gzip 压缩和未压缩的 JSON
+func BackupDecode(data []byte, passphrase string) (*model.DBDump, error) {
+	if xcrypto.IsEncrypted(data) {
+		if passphrase == "" {
+			return nil, fmt.Errorf("backup is encrypted but no passphrase is configured")
+		}
+		var err error
+		if data, err = xcrypto.Decrypt(data, passphrase); err != nil {
+			return nil, err
+		}
+	}
+	var r io.Reader = bytes.NewReader(data)
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("decompress backup: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	var dump model.DBDump
+	if err := json.NewDecoder(r).Decode(&dump); err != nil {
+		return nil, fmt.Errorf("decode backup: %w", err)
+	}
+	return &dump, nil
+}
+
+// BackupTask 定时任务：距最近一次备份超过设置的间隔时创建备份
+// 以备份文件的时间为准，重启服务不会打乱备份周期
+// force 为 true 时不检查备份间隔，总是创建
+func BackupTask(ctx context.Context, force bool) error {
+	if !force {
+		intervalHours, err := SettingGetInt(model.SettingKeyBackupInterval)
+		if err != nil || intervalHours <= 0 {
+			return nil
+		}
+		files, err := backupFiles()
+		if err != nil {
+			return fmt.Errorf("failed to list backups: %w", err)
+		}
+		interval := time.Duration(intervalHours) * time.Hour
+		if len(files) > 0 && time.Since(time.Unix(files[0].CreatedAt, 0)) < interval {
+			return nil
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+	file, err := BackupCreate(ctx)
+	if err != nil {
+		return fmt.Errorf("scheduled backup failed: %w", err)
+	}
+	log.Infof("scheduled backup created: %s", file.Name)
+	return nil
+}
+
+// backupPrune 按保留数量和保留天数删除旧备份，两者都设置时同时生效
+func backupPrune() error {
+	keepCount, _ := SettingGetInt(model.SettingKeyBackupKeepCount)
+	keepDays, _ := SettingGetInt(model.SettingKeyBackupKeepDays)
+	if keepCount <= 0 && keepDays <= 0 {
+		return nil
+	}
+	files, err := backupFiles()
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().AddDate(0, 0, -keepDays).Unix()
+	for i, f := range files {
+		if (keepCount > 0 && i >= keepCount) || (keepDays > 0 && f.CreatedAt < cutoff) {
+			if err := os.Remove(filepath.Join(conf.AppConfig.Backup.Path, f.Name)); err != nil {
+				return err
+			}
+			log.Infof("backup pruned: %s", f.Name)
+		}
+	}
+	return nil
+}
+
+// backupFiles 读取备份目录，忽略不符合命名规则的文件，按创建时间倒序
+func backupFiles() ([]model.BackupFile, error) {
+	entries, err := os.ReadDir(conf.AppConfig.Backup.Path)
+	if os.IsNotExist(err) {
+		return []model.BackupFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read backup dir: %w", err)
+	}
+	files := make([]model.BackupFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		createdAt, ok := parseBackupName(entry.Name())
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, model.BackupFile{
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			CreatedAt: createdAt.Unix(),
+			Encrypted: strings.HasSuffix(entry.Name(), backupEncryptedExt),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CreatedAt > files[j].CreatedAt
+	})
+	return files, nil
+}
+
+// parseBackupName 从备份文件名中解析创建时间
+func parseBackupName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, backupFilePrefix) {
+		return time.Time{}, false
+	}
+	stamp := strings.TrimPrefix(name, backupFilePrefix)
+	switch {
+	case strings.HasSuffix(stamp, backupEncryptedExt):
+		stamp = strings.TrimSuffix(stamp, backupEncryptedExt)
+	case strings.HasSuffix(stamp, backupExt):
+		stamp = strings.TrimSuffix(stamp, backupExt)
+	default:
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
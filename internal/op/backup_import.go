@@ -0,0 +1,364 @@
+package op
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/diff"
+)
+
+// dbDumpMigrations 按版本升级旧的导出，key 为升级前的版本
+var dbDumpMigrations = map[int]func(*model.DBDump) error{
+	// 0: 早期导出没有版本号，格式与 1 相同
+	0: func(*model.DBDump) error { return nil },
+	1: dbDumpMigrateV1,
+}
+
+// dbDumpUpgrade 将导出逐个版本升级到当前版本
+func dbDumpUpgrade(dump *model.DBDump) error {
+	if dump.Version < 0 || dump.Version > dbDumpVersion {
+		return fmt.Errorf("unsupported dump version: %d", dump.Version)
+	}
+	for dump.Version < dbDumpVersion {
+		migrate, ok := dbDumpMigrations[dump.Version]
+		if !ok {
+			return fmt.Errorf("no migration for dump version %d", dump.Version)
+		}
+		if err := migrate(dump); err != nil {
+			return fmt.Errorf("migrate dump version %d: %w", dump.Version, err)
+		}
+		dump.Version++
+	}
+	return nil
+}
+
+// dbDumpMigrateV1 1 -> 2：将嵌套在渠道和分组中的密钥和分组项移到独立的列表
+// 版本 1 的导出不包含渠道密钥，只有手动拼接的导出才会嵌套
+func dbDumpMigrateV1(dump *model.DBDump) error {
+	keyIDs := make(map[int]struct{}, len(dump.ChannelKeys))
+	for _, key := range dump.ChannelKeys {
+		keyIDs[key.ID] = struct{}{}
+	}
+	for i := range dump.Channels {
+		ch := &dump.Channels[i]
+		for _, key := range ch.Keys {
+			if _, ok := keyIDs[key.ID]; ok && key.ID != 0 {
+				continue
+			}
+			key.ChannelID = ch.ID
+			dump.ChannelKeys = append(dump.ChannelKeys, key)
+		}
+		ch.Keys = nil
+		ch.Stats = nil
+	}
+
+	itemIDs := make(map[int]struct{}, len(dump.GroupItems))
+	for _, item := range dump.GroupItems {
+		itemIDs[item.ID] = struct{}{}
+	}
+	for i := range dump.Groups {
+		g := &dump.Groups[i]
+		for _, item := range g.Items {
+			if _, ok := itemIDs[item.ID]; ok && item.ID != 0 {
+				continue
+			}
+			item.GroupID = g.ID
+			dump.GroupItems = append(dump.GroupItems, item)
+		}
+		g.Items = nil
+	}
+	return nil
+}
+
+// dbImportPlanTables 统计每个表在导入后的变化，与 DBImport 实际写入的方式一致
+func dbImportPlanTables(ctx context.Context, current, dump *model.DBDump, replace bool) (map[string]model.DBImportTableStats, error) {
+	tables := map[string]model.DBImportTableStats{}
+	byID := func(id int) string { return strconv.Itoa(id) }
+
+	tables["channels"] = planTable(current.Channels, dump.Channels,
+		func(r model.Channel) string { return byID(r.ID) }, func(r model.Channel) string { return r.Name }, false, replace)
+	tables["channel_keys"] = planTable(current.ChannelKeys, dump.ChannelKeys,
+		func(r model.ChannelKey) string { return byID(r.ID) }, nil, false, replace)
+	tables["groups"] = planTable(current.Groups, dump.Groups,
+		func(r model.Group) string { return byID(r.ID) }, func(r model.Group) string { return r.Name }, false, replace)
+	tables["group_items"] = planTable(current.GroupItems, dump.GroupItems,
+		func(r model.GroupItem) string { return byID(r.ID) },
+		func(r model.GroupItem) string { return fmt.Sprintf("%d/%d/%s", r.GroupID, r.ChannelID, r.ModelName) }, false, replace)
+	tables["llm_infos"] = planTable(current.LLMInfos, dump.LLMInfos,
+		func(r model.LLMInfo) string { return r.Name }, nil, true, replace)
+	tables["api_keys"] = planTable(current.APIKeys, dump.APIKeys,
+		func(r model.APIKey) string { return byID(r.ID) }, nil, false, replace)
+	tables["sensitive_rule_sets"] = planTable(current.SensitiveRuleSets, dump.SensitiveRuleSets,
+		func(r model.SensitiveRuleSet) string { return byID(r.ID) }, func(r model.SensitiveRuleSet) string { return r.Name }, false, replace)
+
+	// 增量导入只更新设置项的值
+	settingValue := func(rows []model.Setting) []model.Setting {
+		out := make([]model.Setting, len(rows))
+		for i, r := range rows {
+			out[i] = model.Setting{Key: r.Key, Value: r.Value}
+		}
+		return out
+	}
+	tables["settings"] = planTable(settingValue(current.Settings), settingValue(dump.Settings),
+		func(r model.Setting) string { return string(r.Key) }, nil, true, replace)
+
+	ruleKey := func(r model.SensitiveFilterRule) string { return byID(r.ID) }
+	if replace {
+		tables["sensitive_filter_rules"] = planTable(current.SensitiveFilterRules, dump.SensitiveFilterRules, ruleKey, nil, false, true)
+	} else {
+		// 自定义规则只新增；内置规则只更新 enabled、priority、direction 和 action
+		split := func(rows []model.SensitiveFilterRule) (builtIn, custom []model.SensitiveFilterRule) {
+			for _, r := range rows {
+				if r.BuiltIn {
+					builtIn = append(builtIn, model.SensitiveFilterRule{
+						ID: r.ID, Enabled: r.Enabled, Priority: r.Priority, Direction: r.Direction, Action: r.Action,
+					})
+				} else {
+					custom = append(custom, r)
+				}
+			}
+			return
+		}
+		currentBuiltIn, currentCustom := split(current.SensitiveFilterRules)
+		dumpBuiltIn, dumpCustom := split(dump.SensitiveFilterRules)
+		tables["sensitive_filter_rules_custom"] = planTable(currentCustom, dumpCustom, ruleKey, nil, false, false)
+		tables["sensitive_filter_rules_builtin"] = planTable(currentBuiltIn, dumpBuiltIn, ruleKey, nil, true, false)
+	}
+
+	// 统计和日志在两种模式下都按增量方式导入
+	if dump.IncludeStats {
+		tables["stats_total"] = planTable(current.StatsTotal, dump.StatsTotal,
+			func(r model.StatsTotal) string { return byID(r.ID) }, nil, true, false)
+		tables["stats_daily"] = planTable(current.StatsDaily, dump.StatsDaily,
+			func(r model.StatsDaily) string { return r.Date }, nil, true, false)
+		tables["stats_hourly"] = planTable(current.StatsHourly, dump.StatsHourly,
+			func(r model.StatsHourly) string { return byID(r.Hour) }, nil, true, false)
+		tables["stats_model"] = planTable(current.StatsModel, dump.StatsModel,
+			func(r model.StatsModel) string { return byID(r.ID) }, nil, true, false)
+		tables["stats_channel"] = planTable(current.StatsChannel, dump.StatsChannel,
+			func(r model.StatsChannel) string { return byID(r.ChannelID) }, nil, true, false)
+		tables["stats_api_key"] = planTable(current.StatsAPIKey, dump.StatsAPIKey,
+			func(r model.StatsAPIKey) string { return byID(r.APIKeyID) }, nil, true, false)
+		tables["stats_sensitive"] = planTable(current.StatsSensitive, dump.StatsSensitive,
+			func(r model.StatsSensitive) string { return byID(r.RuleID) + "/" + byID(r.APIKeyID) }, nil, true, false)
+	}
+	if dump.IncludeLogs {
+		// 日志量可能很大，只按主键检查是否已存在
+		logIDs := make([]int64, len(dump.RelayLogs))
+		for i, l := range dump.RelayLogs {
+			logIDs[i] = l.ID
+		}
+		stats, err := planExisting(ctx, &model.RelayLog{}, "id", logIDs)
+		if err != nil {
+			return nil, err
+		}
+		tables["relay_logs"] = stats
+		bodyIDs := make([]int64, len(dump.RelayLogBodies))
+		for i, b := range dump.RelayLogBodies {
+			bodyIDs[i] = b.LogID
+		}
+		if stats, err = planExisting(ctx, &model.RelayLogBody{}, "log_id", bodyIDs); err != nil {
+			return nil, err
+		}
+		tables["relay_log_bodies"] = stats
+	}
+	return tables, nil
+}
+
+// planTable 按主键比较已有行和导入的行，内容相同的行不计入
+// 主键已存在且内容不同时，upsert 表或替换模式计为更新，其他表计为冲突；
+// unique 为其他唯一约束，增量导入时主键不同但唯一约束相同的行也会冲突
+func planTable[T any](existing, incoming []T, key func(T) string, unique func(T) string, upsert, replace bool) model.DBImportTableStats {
+	var stats model.DBImportTableStats
+	rows := make(map[string]string, len(existing))
+	uniques := make(map[string]struct{}, len(existing))
+	for _, row := range existing {
+		rows[key(row)] = rowJSON(row)
+		if unique != nil {
+			uniques[unique(row)] = struct{}{}
+		}
+	}
+	seen := make(map[string]struct{}, len(incoming))
+	for _, row := range incoming {
+		k := key(row)
+		seen[k] = struct{}{}
+		old, ok := rows[k]
+		if !ok {
+			if unique != nil && !replace {
+				if _, dup := uniques[unique(row)]; dup {
+					stats.Conflict++
+					continue
+				}
+			}
+			stats.Create++
+			continue
+		}
+		switch {
+		case old == rowJSON(row):
+		case upsert || replace:
+			stats.Update++
+		default:
+			stats.Conflict++
+		}
+	}
+	if replace {
+		for k := range rows {
+			if _, ok := seen[k]; !ok {
+				stats.Delete++
+			}
+		}
+	}
+	return stats
+}
+
+func rowJSON(row any) string {
+	data, _ := json.Marshal(row)
+	return string(data)
+}
+
+// planExisting 统计已存在于数据库中的主键数量，已存在的行在导入时跳过
+func planExisting(ctx context.Context, table any, column string, ids []int64) (model.DBImportTableStats, error) {
+	var stats model.DBImportTableStats
+	const batchSize = 500
+	for start := 0; start < len(ids); start += batchSize {
+		end := min(start+batchSize, len(ids))
+		var count int64
+		if err := db.Conn(ctx).Model(table).Where(column+" IN ?", ids[start:end]).Count(&count).Error; err != nil {
+			return stats, err
+		}
+		stats.Conflict += count
+		stats.Create += int64(end-start) - count
+	}
+	return stats, nil
+}
+
+// dbImportPlanChanges 按名称比较渠道、分组和 API Key，列出有差异的对象和字段
+func dbImportPlanChanges(current, dump *model.DBDump, replace bool) []model.DBImportChange {
+	var changes []model.DBImportChange
+
+	keysOf := func(keys []model.ChannelKey) map[int][]string {
+		out := make(map[int][]string)
+		for _, k := range keys {
+			out[k.ChannelID] = append(out[k.ChannelID], k.ChannelKey)
+		}
+		return out
+	}
+	currentKeys, dumpKeys := keysOf(current.ChannelKeys), keysOf(dump.ChannelKeys)
+	changes = append(changes, planObjects("channel", current.Channels, dump.Channels,
+		func(ch model.Channel) (int, string) { return ch.ID, ch.Name },
+		func(old, new model.Channel) []string {
+			fields := diffFields(old, new, "id", "keys", "stats")
+			if deleted, added := diff.Diff(currentKeys[old.ID], dumpKeys[new.ID]); len(deleted) > 0 || len(added) > 0 {
+				fields = append(fields, "keys")
+			}
+			return fields
+		}, true, replace)...)
+
+	itemsOf := func(items []model.GroupItem) map[int][]string {
+		out := make(map[int][]string)
+		for _, item := range items {
+			out[item.GroupID] = append(out[item.GroupID], fmt.Sprintf("%d/%s/%d/%d", item.ChannelID, item.ModelName, item.Priority, item.Weight))
+		}
+		return out
+	}
+	currentItems, dumpItems := itemsOf(current.GroupItems), itemsOf(dump.GroupItems)
+	changes = append(changes, planObjects("group", current.Groups, dump.Groups,
+		func(g model.Group) (int, string) { return g.ID, g.Name },
+		func(old, new model.Group) []string {
+			fields := diffFields(old, new, "id", "items")
+			if deleted, added := diff.Diff(currentItems[old.ID], dumpItems[new.ID]); len(deleted) > 0 || len(added) > 0 {
+				fields = append(fields, "items")
+			}
+			return fields
+		}, true, replace)...)
+
+	changes = append(changes, planObjects("api_key", current.APIKeys, dump.APIKeys,
+		func(k model.APIKey) (int, string) { return k.ID, k.Name },
+		func(old, new model.APIKey) []string { return diffFields(old, new, "id") }, false, replace)...)
+	return changes
+}
+
+// planObjects 按名称匹配对象：替换模式下有差异的对象会被更新，不在导入中的对象会被删除；
+// 增量导入不会修改已有对象，有差异时计为冲突，uniqueName 表示名称有唯一约束
+func planObjects[T any](kind string, existing, incoming []T, ident func(T) (int, string), fields func(old, new T) []string, uniqueName, replace bool) []model.DBImportChange {
+	byName := make(map[string]T, len(existing))
+	ids := make(map[int]struct{}, len(existing))
+	for _, row := range existing {
+		id, name := ident(row)
+		byName[name] = row
+		ids[id] = struct{}{}
+	}
+
+	var changes []model.DBImportChange
+	seen := make(map[string]struct{}, len(incoming))
+	for _, row := range incoming {
+		id, name := ident(row)
+		seen[name] = struct{}{}
+		old, ok := byName[name]
+		if !ok {
+			action := model.DBImportActionCreate
+			if _, taken := ids[id]; taken && !replace {
+				action = model.DBImportActionConflict
+			}
+			changes = append(changes, model.DBImportChange{Kind: kind, Name: name, Action: action})
+			continue
+		}
+		diffs := fields(old, row)
+		if len(diffs) == 0 {
+			continue
+		}
+		action := model.DBImportActionUpdate
+		if !replace {
+			oldID, _ := ident(old)
+			action = model.DBImportActionConflict
+			if !uniqueName && oldID != id {
+				if _, taken := ids[id]; !taken {
+					// 名称没有唯一约束时会新增一个同名对象
+					action = model.DBImportActionCreate
+				}
+			}
+		}
+		changes = append(changes, model.DBImportChange{Kind: kind, Name: name, Action: action, Fields: diffs})
+	}
+	if replace {
+		for _, row := range existing {
+			_, name := ident(row)
+			if _, ok := seen[name]; !ok {
+				changes = append(changes, model.DBImportChange{Kind: kind, Name: name, Action: model.DBImportActionDelete})
+			}
+		}
+	}
+	return changes
+}
+
+// diffFields 比较两个对象的 JSON 字段，返回值不同的字段名
+func diffFields(old, new any, ignore ...string) []string {
+	var a, b map[string]any
+	oldJSON, _ := json.Marshal(old)
+	newJSON, _ := json.Marshal(new)
+	_ = json.Unmarshal(oldJSON, &a)
+	_ = json.Unmarshal(newJSON, &b)
+	for _, key := range ignore {
+		delete(a, key)
+		delete(b, key)
+	}
+	var fields []string
+	for key, value := range b {
+		if !reflect.DeepEqual(a[key], value) {
+			fields = append(fields, key)
+		}
+	}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
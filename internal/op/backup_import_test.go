@@ -0,0 +1,164 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm"
+)
+
+// testBackupSeed 写入渠道 a、分组 g、API Key k 和设置项 proxy_url
+func testBackupSeed(t *testing.T) {
+	t.Helper()
+	dbtest.Init(t, "backup.db")
+	conn := db.GetDB()
+	rows := []any{
+		&model.Channel{ID: 1, Name: "a", Model: "m1", Keys: []model.ChannelKey{{ID: 1, ChannelID: 1, Enabled: true, ChannelKey: "sk-a"}}},
+		&model.Group{ID: 1, Name: "g", Mode: model.GroupModeRoundRobin, Items: []model.GroupItem{{ID: 1, GroupID: 1, ChannelID: 1, ModelName: "m1"}}},
+		&model.APIKey{ID: 1, Name: "k", APIKey: "sk-k", Enabled: true},
+		&model.Setting{Key: model.SettingKeyProxyURL, Value: ""},
+	}
+	for _, row := range rows {
+		if err := conn.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testBackupExport 导出当前数据库，忽略导出时间
+func testBackupExport(t *testing.T) *model.DBDump {
+	t.Helper()
+	dump, err := DBExportAll(context.Background(), true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump.ExportedAt = time.Time{}
+	return dump
+}
+
+// testBackupDump 在当前数据库的基础上修改渠道 a 的模型、新增渠道 b、删除 API Key k 并修改设置项
+func testBackupDump(t *testing.T) *model.DBDump {
+	t.Helper()
+	dump := testBackupExport(t)
+	dump.IncludeLogs, dump.IncludeStats = false, false
+	dump.Channels[0].Model = "m2"
+	dump.Channels = append(dump.Channels, model.Channel{ID: 2, Name: "b"})
+	dump.ChannelKeys = append(dump.ChannelKeys, model.ChannelKey{ID: 2, ChannelID: 2, Enabled: true, ChannelKey: "sk-b"})
+	dump.APIKeys = nil
+	for i := range dump.Settings {
+		if dump.Settings[i].Key == model.SettingKeyProxyURL {
+			dump.Settings[i].Value = "http://proxy"
+		}
+	}
+	return dump
+}
+
+func TestDBImportDryRun(t *testing.T) {
+	tests := []struct {
+		mode     model.DBImportMode
+		channels model.DBImportTableStats
+		apiKeys  model.DBImportTableStats
+		changes  []model.DBImportChange
+	}{
+		{
+			mode:     model.DBImportModeMerge,
+			channels: model.DBImportTableStats{Create: 1, Conflict: 1},
+			changes: []model.DBImportChange{
+				{Kind: "channel", Name: "a", Action: model.DBImportActionConflict, Fields: []string{"model"}},
+				{Kind: "channel", Name: "b", Action: model.DBImportActionCreate},
+			},
+		},
+		{
+			mode:     model.DBImportModeReplace,
+			channels: model.DBImportTableStats{Create: 1, Update: 1},
+			apiKeys:  model.DBImportTableStats{Delete: 1},
+			changes: []model.DBImportChange{
+				{Kind: "channel", Name: "a", Action: model.DBImportActionUpdate, Fields: []string{"model"}},
+				{Kind: "channel", Name: "b", Action: model.DBImportActionCreate},
+				{Kind: "api_key", Name: "k", Action: model.DBImportActionDelete},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			testBackupSeed(t)
+			ctx := context.Background()
+			before := testBackupExport(t)
+
+			res, err := DBImport(ctx, testBackupDump(t), model.DBImportOptions{Mode: tt.mode, DryRun: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after := testBackupExport(t); !reflect.DeepEqual(after, before) {
+				t.Fatalf("dry run wrote to the database:\nbefore %+v\nafter  %+v", before, after)
+			}
+			if !res.DryRun || len(res.RowsAffected) != 0 {
+				t.Errorf("result = %+v", res)
+			}
+			if res.Tables["channels"] != tt.channels || res.Tables["api_keys"] != tt.apiKeys {
+				t.Errorf("channels %+v, api_keys %+v", res.Tables["channels"], res.Tables["api_keys"])
+			}
+			if res.Tables["settings"] != (model.DBImportTableStats{Update: 1}) {
+				t.Errorf("settings %+v", res.Tables["settings"])
+			}
+			if !reflect.DeepEqual(res.Changes, tt.changes) {
+				t.Errorf("changes = %+v, want %+v", res.Changes, tt.changes)
+			}
+
+			// 实际导入的结果与预览一致
+			if _, err := DBImport(ctx, testBackupDump(t), model.DBImportOptions{Mode: tt.mode}); err != nil {
+				t.Fatal(err)
+			}
+			after := testBackupExport(t)
+			var names []string
+			for _, ch := range after.Channels {
+				names = append(names, ch.Name+"/"+ch.Model)
+			}
+			wantModel := "m1"
+			if tt.mode == model.DBImportModeReplace {
+				wantModel = "m2"
+			}
+			if want := []string{"a/" + wantModel, "b/"}; !slices.Equal(names, want) {
+				t.Errorf("channels = %v, want %v", names, want)
+			}
+			if wantKeys := 1 - int(tt.apiKeys.Delete); len(after.APIKeys) != wantKeys {
+				t.Errorf("api keys = %+v", after.APIKeys)
+			}
+		})
+	}
+}
+
+func TestDBImportReplaceRollback(t *testing.T) {
+	testBackupSeed(t)
+	ctx := context.Background()
+	before := testBackupExport(t)
+
+	// 清空配置表并写入渠道后，写入 API Key 时失败
+	dump := testBackupDump(t)
+	dump.APIKeys = []model.APIKey{{ID: 3, Name: "new", APIKey: "sk-new"}}
+	injected := errors.New("injected failure")
+	err := db.GetDB().Callback().Create().Before("gorm:create").Register("test:fail_api_keys", func(tx *gorm.DB) {
+		if tx.Statement.Table == "api_keys" {
+			tx.AddError(injected)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := DBImport(ctx, dump, model.DBImportOptions{Mode: model.DBImportModeReplace})
+	if !errors.Is(err, injected) || !strings.Contains(err.Error(), "import api_keys") {
+		t.Fatalf("res = %+v, err = %v", res, err)
+	}
+	if after := testBackupExport(t); !reflect.DeepEqual(after, before) {
+		t.Fatalf("replace was not rolled back:\nbefore %+v\nafter  %+v", before, after)
+	}
+}
@@ -3,9 +3,14 @@ package op
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
+
+	"github.com/bestruirui/octopus/internal/utils/cache"
 )
 
+var cacheLoaded atomic.Bool
+
 func InitCache() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -27,9 +32,34 @@ func InitCache() error {
 	if err := statsRefreshCache(ctx); err != nil {
 		return fmt.Errorf("stats refresh cache error: %v", err)
 	}
+	if err := sessionRefreshCache(ctx); err != nil {
+		return fmt.Errorf("session refresh cache error: %v", err)
+	}
+	if err := accessTokenRefreshCache(ctx); err != nil {
+		return fmt.Errorf("access token refresh cache error: %v", err)
+	}
+	cacheLoaded.Store(true)
+	return nil
+}
+
+// CacheReload 从数据库重新加载配置缓存和统计缓存，用于事务回滚后丢弃已写入缓存的修改
+func CacheReload(ctx context.Context) error {
+	for _, r := range clusterCacheRefreshers {
+		if err := r.refresh(ctx); err != nil {
+			return fmt.Errorf("%s refresh cache error: %v", r.name, err)
+		}
+	}
+	if err := statsRefreshCache(ctx); err != nil {
+		return fmt.Errorf("stats refresh cache error: %v", err)
+	}
 	return nil
 }
 
+// CacheLoaded InitCache 是否已成功加载所有缓存
+func CacheLoaded() bool {
+	return cacheLoaded.Load()
+}
+
 func SaveCache() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -44,3 +74,12 @@ func SaveCache() error {
 	}
 	return nil
 }
+
+// cacheRetain 删除缓存中 keep 返回 false 的项，用于刷新时移除数据库中已删除的对象
+func cacheRetain[K comparable, V any](c cache.Cache[K, V], keep func(K, V) bool) {
+	for k, v := range c.GetAll() {
+		if !keep(k, v) {
+			c.Del(k)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package op
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/bestruirui/octopus/internal/db"
 	"github.com/bestruirui/octopus/internal/model"
@@ -11,12 +13,26 @@ import (
 	"github.com/bestruirui/octopus/internal/utils/cache"
 	"github.com/bestruirui/octopus/internal/utils/log"
 	"github.com/bestruirui/octopus/internal/utils/xstrings"
+	"gorm.io/gorm"
 )
 
 var channelCache = cache.New[int, model.Channel](16)
 var channelKeyCache = cache.New[int, model.ChannelKey](16)
-var channelKeyCacheNeedUpdate = make(map[int]struct{})
-var channelKeyCacheNeedUpdateLock sync.Mutex
+var channelKeyPending = make(map[int]channelKeyUsage)
+var channelKeyPendingLock sync.Mutex
+
+// channelKeyUsage 本实例运行时记录、尚未写入数据库的 Key 状态
+// 费用随统计流水累加到数据库（见 StatsRecord），不在这里记录
+type channelKeyUsage struct {
+	StatusCode       int
+	LastUseTimeStamp int64
+}
+
+// apply 将尚未写入数据库的状态覆盖到从数据库读取的 Key 上
+func (u channelKeyUsage) apply(k *model.ChannelKey) {
+	k.StatusCode = u.StatusCode
+	k.LastUseTimeStamp = u.LastUseTimeStamp
+}
 
 func ChannelList(ctx context.Context) ([]model.Channel, error) {
 	channels := make([]model.Channel, 0, channelCache.Len())
@@ -27,7 +43,7 @@ func ChannelList(ctx context.Context) ([]model.Channel, error) {
 }
 
 func ChannelCreate(channel *model.Channel, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Create(channel).Error; err != nil {
+	if err := db.Conn(ctx).Create(channel).Error; err != nil {
 		return err
 	}
 	channelCache.Set(channel.ID, *channel)
@@ -54,11 +70,38 @@ func ChannelCreate(channel *model.Channel, ctx context.Context) error {
 	return nil
 }
 
-// ChannelKeyUpdate 仅更新 ChannelKey 的内存缓存（不落库），并标记为需要在 SaveCache 时写入数据库。
+// ChannelKeyUpdate 仅更新 ChannelKey 的内存缓存（不落库），记录状态码和最后使用时间，
+// 在 SaveCache 时写入数据库。传入 key 的 TotalCost 会被忽略，以缓存中的值为准，费用随统计流水累加。
 func ChannelKeyUpdate(key model.ChannelKey) error {
 	if key.ID == 0 || key.ChannelID == 0 {
 		return fmt.Errorf("invalid channel key")
 	}
+	channelKeyPendingLock.Lock()
+	defer channelKeyPendingLock.Unlock()
+	if cached, ok := channelKeyCache.Get(key.ID); ok {
+		key.TotalCost = cached.TotalCost
+	}
+	if err := channelKeySetCache(key); err != nil {
+		return err
+	}
+	channelKeyPending[key.ID] = channelKeyUsage{StatusCode: key.StatusCode, LastUseTimeStamp: key.LastUseTimeStamp}
+	return nil
+}
+
+// channelKeyAddCost 将请求的费用累加到缓存中的 Key，数据库中的费用由统计流水汇总时累加
+func channelKeyAddCost(id int, cost float64) {
+	channelKeyPendingLock.Lock()
+	defer channelKeyPendingLock.Unlock()
+	key, ok := channelKeyCache.Get(id)
+	if !ok {
+		return
+	}
+	key.TotalCost += cost
+	channelKeySetCache(key)
+}
+
+// channelKeySetCache 更新 Key 缓存和所属渠道缓存中的副本，调用方需持有 channelKeyPendingLock
+func channelKeySetCache(key model.ChannelKey) error {
 	ch, ok := channelCache.Get(key.ChannelID)
 	if !ok {
 		return fmt.Errorf("channel not found")
@@ -76,9 +119,6 @@ func ChannelKeyUpdate(key model.ChannelKey) error {
 	}
 	channelCache.Set(key.ChannelID, ch)
 	channelKeyCache.Set(key.ID, key)
-	channelKeyCacheNeedUpdateLock.Lock()
-	channelKeyCacheNeedUpdate[key.ID] = struct{}{}
-	channelKeyCacheNeedUpdateLock.Unlock()
 	return nil
 }
 func ChannelBaseUrlUpdate(channelID int, baseUrl []model.BaseUrl) error {
@@ -98,29 +138,36 @@ func ChannelBaseUrlUpdate(channelID int, baseUrl []model.BaseUrl) error {
 	return nil
 }
 
-// ChannelKeySaveDB 将运行时更新过的 ChannelKey 缓存写入数据库。
+// ChannelKeySaveDB 将运行时记录的 Key 状态和最后使用时间按列写入数据库，避免覆盖其他实例或管理操作写入的数据；
+// 写入失败的部分保留到下次写入。
 func ChannelKeySaveDB(ctx context.Context) error {
-	channelKeyCacheNeedUpdateLock.Lock()
-	keyIDs := make([]int, 0, len(channelKeyCacheNeedUpdate))
-	for id := range channelKeyCacheNeedUpdate {
-		keyIDs = append(keyIDs, id)
-	}
-	channelKeyCacheNeedUpdate = make(map[int]struct{})
-	channelKeyCacheNeedUpdateLock.Unlock()
+	channelKeyPendingLock.Lock()
+	pending := channelKeyPending
+	channelKeyPending = make(map[int]channelKeyUsage)
+	channelKeyPendingLock.Unlock()
 
-	if len(keyIDs) == 0 {
+	if len(pending) == 0 {
 		return nil
 	}
 
-	dbConn := db.GetDB().WithContext(ctx)
-	for _, id := range keyIDs {
-		k, ok := channelKeyCache.Get(id)
-		if !ok {
-			continue
-		}
-		if err := dbConn.Save(&k).Error; err != nil {
+	dbConn := db.Conn(ctx)
+	for id, u := range pending {
+		err := dbConn.Model(&model.ChannelKey{}).Where("id = ?", id).UpdateColumns(map[string]any{
+			"status_code":         u.StatusCode,
+			"last_use_time_stamp": u.LastUseTimeStamp,
+		}).Error
+		if err != nil {
+			channelKeyPendingLock.Lock()
+			for id, u := range pending {
+				// 期间又有新的使用记录时以新的为准
+				if _, ok := channelKeyPending[id]; !ok {
+					channelKeyPending[id] = u
+				}
+			}
+			channelKeyPendingLock.Unlock()
 			return err
 		}
+		delete(pending, id)
 	}
 	return nil
 }
@@ -131,121 +178,112 @@ func ChannelUpdate(req *model.ChannelUpdateRequest, ctx context.Context) (*model
 		return nil, fmt.Errorf("channel not found")
 	}
 
-	tx := db.GetDB().WithContext(ctx).Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+	err := db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var selectFields []string
+		updates := model.Channel{ID: req.ID}
 
-	var selectFields []string
-	updates := model.Channel{ID: req.ID}
-
-	if req.Name != nil {
-		selectFields = append(selectFields, "name")
-		updates.Name = *req.Name
-	}
-	if req.Type != nil {
-		selectFields = append(selectFields, "type")
-		updates.Type = *req.Type
-	}
-	if req.Enabled != nil {
-		selectFields = append(selectFields, "enabled")
-		updates.Enabled = *req.Enabled
-	}
-	if req.BaseUrls != nil {
-		selectFields = append(selectFields, "base_urls")
-		updates.BaseUrls = *req.BaseUrls
-	}
-	if req.Model != nil {
-		selectFields = append(selectFields, "model")
-		updates.Model = *req.Model
-	}
-	if req.CustomModel != nil {
-		selectFields = append(selectFields, "custom_model")
-		updates.CustomModel = *req.CustomModel
-	}
-	if req.Proxy != nil {
-		selectFields = append(selectFields, "proxy")
-		updates.Proxy = *req.Proxy
-	}
-	if req.AutoSync != nil {
-		selectFields = append(selectFields, "auto_sync")
-		updates.AutoSync = *req.AutoSync
-	}
-	if req.AutoGroup != nil {
-		selectFields = append(selectFields, "auto_group")
-		updates.AutoGroup = *req.AutoGroup
-	}
-	if req.CustomHeader != nil {
-		selectFields = append(selectFields, "custom_header")
-		updates.CustomHeader = *req.CustomHeader
-	}
-	if req.ChannelProxy != nil {
-		selectFields = append(selectFields, "channel_proxy")
-		updates.ChannelProxy = req.ChannelProxy
-	}
-	if req.ParamOverride != nil {
-		selectFields = append(selectFields, "param_override")
-		updates.ParamOverride = req.ParamOverride
-	}
-
-	// 只有当有字段需要更新时才执行 UPDATE
-	if len(selectFields) > 0 {
-		if err := tx.Model(&model.Channel{}).Where("id = ?", req.ID).Select(selectFields).Updates(&updates).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("failed to update channel: %w", err)
+		if req.Name != nil {
+			selectFields = append(selectFields, "name")
+			updates.Name = *req.Name
 		}
-	}
-
-	// 删除 keys
-	if len(req.KeysToDelete) > 0 {
-		if err := tx.Where("id IN ? AND channel_id = ?", req.KeysToDelete, req.ID).Delete(&model.ChannelKey{}).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("failed to delete channel keys: %w", err)
+		if req.Type != nil {
+			selectFields = append(selectFields, "type")
+			updates.Type = *req.Type
+		}
+		if req.Enabled != nil {
+			selectFields = append(selectFields, "enabled")
+			updates.Enabled = *req.Enabled
+		}
+		if req.BaseUrls != nil {
+			selectFields = append(selectFields, "base_urls")
+			updates.BaseUrls = *req.BaseUrls
+		}
+		if req.Model != nil {
+			selectFields = append(selectFields, "model")
+			updates.Model = *req.Model
+		}
+		if req.CustomModel != nil {
+			selectFields = append(selectFields, "custom_model")
+			updates.CustomModel = *req.CustomModel
+		}
+		if req.Proxy != nil {
+			selectFields = append(selectFields, "proxy")
+			updates.Proxy = *req.Proxy
+		}
+		if req.AutoSync != nil {
+			selectFields = append(selectFields, "auto_sync")
+			updates.AutoSync = *req.AutoSync
+		}
+		if req.AutoGroup != nil {
+			selectFields = append(selectFields, "auto_group")
+			updates.AutoGroup = *req.AutoGroup
+		}
+		if req.CustomHeader != nil {
+			selectFields = append(selectFields, "custom_header")
+			updates.CustomHeader = *req.CustomHeader
+		}
+		if req.ChannelProxy != nil {
+			selectFields = append(selectFields, "channel_proxy")
+			updates.ChannelProxy = req.ChannelProxy
+		}
+		if req.ParamOverride != nil {
+			selectFields = append(selectFields, "param_override")
+			updates.ParamOverride = req.ParamOverride
 		}
-	}
 
-	// 更新 keys（逐条，只更新提供的字段）
-	if len(req.KeysToUpdate) > 0 {
-		for _, ku := range req.KeysToUpdate {
-			updates := map[string]interface{}{}
-			if ku.Enabled != nil {
-				updates["enabled"] = *ku.Enabled
-			}
-			if ku.ChannelKey != nil {
-				updates["channel_key"] = *ku.ChannelKey
-			}
-			if len(updates) == 0 {
-				continue
-			}
-			if err := tx.Model(&model.ChannelKey{}).
-				Where("id = ? AND channel_id = ?", ku.ID, req.ID).
-				Updates(updates).Error; err != nil {
-				tx.Rollback()
-				return nil, fmt.Errorf("failed to update channel key %d: %w", ku.ID, err)
+		// 只有当有字段需要更新时才执行 UPDATE
+		if len(selectFields) > 0 {
+			if err := tx.Model(&model.Channel{}).Where("id = ?", req.ID).Select(selectFields).Updates(&updates).Error; err != nil {
+				return fmt.Errorf("failed to update channel: %w", err)
 			}
 		}
-	}
 
-	// 新增 keys
-	if len(req.KeysToAdd) > 0 {
-		newKeys := make([]model.ChannelKey, 0, len(req.KeysToAdd))
-		for _, ka := range req.KeysToAdd {
-			newKeys = append(newKeys, model.ChannelKey{
-				ChannelID:  req.ID,
-				Enabled:    ka.Enabled,
-				ChannelKey: ka.ChannelKey,
-			})
+		// 删除 keys
+		if len(req.KeysToDelete) > 0 {
+			if err := tx.Where("id IN ? AND channel_id = ?", req.KeysToDelete, req.ID).Delete(&model.ChannelKey{}).Error; err != nil {
+				return fmt.Errorf("failed to delete channel keys: %w", err)
+			}
 		}
-		if err := tx.Create(&newKeys).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("failed to create channel keys: %w", err)
+
+		// 更新 keys（逐条，只更新提供的字段）
+		if len(req.KeysToUpdate) > 0 {
+			for _, ku := range req.KeysToUpdate {
+				updates := map[string]interface{}{}
+				if ku.Enabled != nil {
+					updates["enabled"] = *ku.Enabled
+				}
+				if ku.ChannelKey != nil {
+					updates["channel_key"] = *ku.ChannelKey
+				}
+				if len(updates) == 0 {
+					continue
+				}
+				if err := tx.Model(&model.ChannelKey{}).
+					Where("id = ? AND channel_id = ?", ku.ID, req.ID).
+					Updates(updates).Error; err != nil {
+					return fmt.Errorf("failed to update channel key %d: %w", ku.ID, err)
+				}
+			}
 		}
-	}
 
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		// 新增 keys
+		if len(req.KeysToAdd) > 0 {
+			newKeys := make([]model.ChannelKey, 0, len(req.KeysToAdd))
+			for _, ka := range req.KeysToAdd {
+				newKeys = append(newKeys, model.ChannelKey{
+					ChannelID:  req.ID,
+					Enabled:    ka.Enabled,
+					ChannelKey: ka.ChannelKey,
+				})
+			}
+			if err := tx.Create(&newKeys).Error; err != nil {
+				return fmt.Errorf("failed to create channel keys: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// 刷新缓存并返回最新数据
@@ -291,7 +329,7 @@ func ChannelEnabled(id int, enabled bool, ctx context.Context) error {
 	if !ok {
 		return fmt.Errorf("channel not found")
 	}
-	if err := db.GetDB().WithContext(ctx).Model(&model.Channel{}).Where("id = ?", id).Update("enabled", enabled).Error; err != nil {
+	if err := db.Conn(ctx).Model(&model.Channel{}).Where("id = ?", id).Update("enabled", enabled).Error; err != nil {
 		return err
 	}
 	oldChannel.Enabled = enabled
@@ -299,55 +337,58 @@ func ChannelEnabled(id int, enabled bool, ctx context.Context) error {
 	return nil
 }
 
-func ChannelDel(id int, ctx context.Context) error {
+// ChannelSetManaged 设置渠道是否由声明式配置管理
+func ChannelSetManaged(id int, managed bool, ctx context.Context) error {
 	ch, ok := channelCache.Get(id)
 	if !ok {
 		return fmt.Errorf("channel not found")
 	}
+	if err := db.Conn(ctx).Model(&model.Channel{}).Where("id = ?", id).Update("managed", managed).Error; err != nil {
+		return err
+	}
+	ch.Managed = managed
+	channelCache.Set(id, ch)
+	return nil
+}
 
-	// 开启事务
-	tx := db.GetDB().WithContext(ctx).Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+func ChannelDel(id int, ctx context.Context) error {
+	ch, ok := channelCache.Get(id)
+	if !ok {
+		return fmt.Errorf("channel not found")
+	}
 
 	// 获取所有受影响的 GroupID，用于刷新缓存
 	var affectedGroupIDs []int
-	if err := tx.Model(&model.GroupItem{}).
-		Where("channel_id = ?", id).
-		Pluck("group_id", &affectedGroupIDs).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to get affected groups: %w", err)
-	}
-
-	// 删除所有引用该渠道的 GroupItem
-	if err := tx.Where("channel_id = ?", id).Delete(&model.GroupItem{}).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to delete group items: %w", err)
-	}
+	err := db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.GroupItem{}).
+			Where("channel_id = ?", id).
+			Pluck("group_id", &affectedGroupIDs).Error; err != nil {
+			return fmt.Errorf("failed to get affected groups: %w", err)
+		}
 
-	// 删除渠道 keys
-	if err := tx.Where("channel_id = ?", id).Delete(&model.ChannelKey{}).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to delete channel keys: %w", err)
-	}
+		// 删除所有引用该渠道的 GroupItem
+		if err := tx.Where("channel_id = ?", id).Delete(&model.GroupItem{}).Error; err != nil {
+			return fmt.Errorf("failed to delete group items: %w", err)
+		}
 
-	// 删除统计数据
-	if err := tx.Where("channel_id = ?", id).Delete(&model.StatsChannel{}).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to delete channel stats: %w", err)
-	}
+		// 删除渠道 keys
+		if err := tx.Where("channel_id = ?", id).Delete(&model.ChannelKey{}).Error; err != nil {
+			return fmt.Errorf("failed to delete channel keys: %w", err)
+		}
 
-	// 删除渠道
-	if err := tx.Delete(&model.Channel{}, id).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to delete channel: %w", err)
-	}
+		// 删除统计数据
+		if err := tx.Where("channel_id = ?", id).Delete(&model.StatsChannel{}).Error; err != nil {
+			return fmt.Errorf("failed to delete channel stats: %w", err)
+		}
 
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		// 删除渠道
+		if err := tx.Delete(&model.Channel{}, id).Error; err != nil {
+			return fmt.Errorf("failed to delete channel: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// 删除缓存
@@ -357,7 +398,7 @@ func ChannelDel(id int, ctx context.Context) error {
 			channelKeyCache.Del(k.ID)
 		}
 	}
-	StatsChannelDel(id)
+	StatsChannelDel(id, ctx)
 
 	// 刷新受影响的分组缓存
 	for _, groupID := range affectedGroupIDs {
@@ -397,30 +438,65 @@ func ChannelGet(id int, ctx context.Context) (*model.Channel, error) {
 }
 
 func channelRefreshCache(ctx context.Context) error {
+	// 读取数据库期间持有统计增量锁，费用增量不会在期间被汇总到数据库而重复叠加
+	statsDeltaLock.Lock()
+	defer statsDeltaLock.Unlock()
+	pendingCost := statsPendingChannelKeyCost()
+
 	channels := []model.Channel{}
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Preload("Keys").
 		Preload("Stats").
 		Find(&channels).Error; err != nil {
 		log.Warnf("failed to get channels: %v", err)
 		return err
 	}
+	// 在数据库中的值上叠加本实例尚未写入的 Key 使用情况，并保留测得的地址延迟，多实例部署时其他实例的修改也会触发刷新
+	channelKeyPendingLock.Lock()
+	defer channelKeyPendingLock.Unlock()
 	channelKeyCache.Clear()
-	channelKeyCacheNeedUpdateLock.Lock()
-	channelKeyCacheNeedUpdate = make(map[int]struct{})
-	channelKeyCacheNeedUpdateLock.Unlock()
+
+	ids := make(map[int]struct{}, len(channels))
 	for _, channel := range channels {
-		channelCache.Set(channel.ID, channel)
-		for _, k := range channel.Keys {
+		if old, ok := channelCache.Get(channel.ID); ok {
+			channelKeepBaseUrlDelay(&channel, old)
+		}
+		for i, k := range channel.Keys {
+			if u, ok := channelKeyPending[k.ID]; ok {
+				u.apply(&k)
+			}
+			k.TotalCost += pendingCost[k.ID]
+			channel.Keys[i] = k
 			if k.ID != 0 {
 				channelKeyCache.Set(k.ID, k)
 			}
 		}
+		channelCache.Set(channel.ID, channel)
+		ids[channel.ID] = struct{}{}
 	}
+	cacheRetain(channelCache, func(id int, _ model.Channel) bool { _, ok := ids[id]; return ok })
 	return nil
 }
 
+// channelKeepBaseUrlDelay 地址延迟只在内存中测量，刷新时沿用旧缓存中相同地址的延迟
+func channelKeepBaseUrlDelay(channel *model.Channel, old model.Channel) {
+	delays := make(map[string]int, len(old.BaseUrls))
+	for _, u := range old.BaseUrls {
+		delays[u.URL] = u.Delay
+	}
+	for i, u := range channel.BaseUrls {
+		if d, ok := delays[u.URL]; ok && u.Delay == 0 {
+			channel.BaseUrls[i].Delay = d
+		}
+	}
+}
+
 func channelRefreshCacheByID(id int, ctx context.Context) error {
+	statsDeltaLock.Lock()
+	defer statsDeltaLock.Unlock()
+	pendingCost := statsPendingChannelKeyCost()
+	channelKeyPendingLock.Lock()
+	defer channelKeyPendingLock.Unlock()
 	if old, ok := channelCache.Get(id); ok {
 		for _, k := range old.Keys {
 			if k.ID != 0 {
@@ -429,12 +505,18 @@ func channelRefreshCacheByID(id int, ctx context.Context) error {
 		}
 	}
 	var channel model.Channel
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Preload("Keys").
 		Preload("Stats").
 		First(&channel, id).Error; err != nil {
 		return err
 	}
+	for i := range channel.Keys {
+		if u, ok := channelKeyPending[channel.Keys[i].ID]; ok {
+			u.apply(&channel.Keys[i])
+		}
+		channel.Keys[i].TotalCost += pendingCost[channel.Keys[i].ID]
+	}
 	channelCache.Set(channel.ID, channel)
 	for _, k := range channel.Keys {
 		if k.ID != 0 {
@@ -443,3 +525,39 @@ func channelRefreshCacheByID(id int, ctx context.Context) error {
 	}
 	return nil
 }
+
+// ChannelHealthList 按渠道 ID 返回各渠道密钥的可用状态
+func ChannelHealthList() []model.ChannelHealth {
+	nowSec := time.Now().Unix()
+	channels := channelCache.GetAll()
+	list := make([]model.ChannelHealth, 0, len(channels))
+	for _, ch := range channels {
+		h := model.ChannelHealth{ID: ch.ID, Name: ch.Name}
+		for _, k := range ch.Keys {
+			if !k.Enabled || k.ChannelKey == "" {
+				continue
+			}
+			h.Keys++
+			if k.CoolingDown(nowSec) {
+				h.CoolingDown++
+			}
+			if k.LastUseTimeStamp > h.LastUsed {
+				h.LastUsed = k.LastUseTimeStamp
+				h.LastStatusCode = k.StatusCode
+			}
+		}
+		switch {
+		case !ch.Enabled:
+			h.Circuit = model.ChannelCircuitDisabled
+		case h.CoolingDown == h.Keys:
+			h.Circuit = model.ChannelCircuitOpen
+		case h.CoolingDown > 0:
+			h.Circuit = model.ChannelCircuitPartial
+		default:
+			h.Circuit = model.ChannelCircuitClosed
+		}
+		list = append(list, h)
+	}
+	slices.SortFunc(list, func(a, b model.ChannelHealth) int { return a.ID - b.ID })
+	return list
+}
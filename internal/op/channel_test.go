@@ -0,0 +1,100 @@
+package op
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm"
+)
+
+// Key 费用随统计流水按增量写入数据库，其他实例同时写入的费用不会被覆盖
+func TestChannelKeySaveDBAddsCost(t *testing.T) {
+	dbtest.Init(t, "channel.db")
+	ctx := context.Background()
+	statsDelta, statsJournalPending = newStatsDeltaSet(), newStatsDeltaSet()
+
+	channel := model.Channel{Name: "c1", Keys: []model.ChannelKey{{ChannelKey: "sk-1", Enabled: true, TotalCost: 1}}}
+	if err := db.Conn(ctx).Create(&channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := channelRefreshCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	key := channel.Keys[0]
+
+	addOther := func(cost float64) {
+		t.Helper()
+		if err := db.Conn(ctx).Model(&model.ChannelKey{}).Where("id = ?", key.ID).
+			UpdateColumn("total_cost", gorm.Expr("total_cost + ?", cost)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	cachedCost := func() float64 {
+		t.Helper()
+		k, ok := channelKeyCache.Get(key.ID)
+		if !ok {
+			t.Fatal("key not cached")
+		}
+		return k.TotalCost
+	}
+
+	key.StatusCode = 200
+	key.LastUseTimeStamp = 100
+	key.TotalCost = 999 // 调用方持有的旧值会被忽略
+	if err := ChannelKeyUpdate(key); err != nil {
+		t.Fatal(err)
+	}
+	record := func(cost float64) {
+		StatsRecord(ctx, model.StatsJournal{
+			ChannelID:    channel.ID,
+			ChannelKeyID: key.ID,
+			StatsMetrics: model.StatsMetrics{OutputCost: cost, RequestSuccess: 1},
+		})
+	}
+	record(0.5)
+	record(0.25)
+	if got := cachedCost(); got != 1.75 {
+		t.Errorf("cached cost = %v, want 1.75", got)
+	}
+
+	// 刷新缓存时以数据库值为准，再叠加尚未汇总的增量
+	addOther(2)
+	if err := channelRefreshCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := cachedCost(); got != 3.75 {
+		t.Errorf("cached cost after refresh = %v, want 3.75", got)
+	}
+
+	addOther(4)
+	if err := ChannelKeySaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := StatsSaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var saved model.ChannelKey
+	if err := db.Conn(ctx).First(&saved, key.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.TotalCost != 7.75 || saved.StatusCode != 200 || saved.LastUseTimeStamp != 100 || saved.ChannelKey != "sk-1" {
+		t.Errorf("saved key = %+v", saved)
+	}
+
+	// 写入后没有待写增量，重复写入不会再次累加
+	if err := ChannelKeySaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := StatsSaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := channelRefreshCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := cachedCost(); got != 7.75 {
+		t.Errorf("cached cost after save = %v, want 7.75", got)
+	}
+}
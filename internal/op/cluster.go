@@ -0,0 +1,309 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	cacheChannel   = "channel"
+	cacheGroup     = "group"
+	cacheAPIKey    = "api_key"
+	cacheSetting   = "setting"
+	cacheLLM       = "llm"
+	cacheSensitive = "sensitive"
+	cacheUser      = "user"
+	cacheAuth      = "auth_secret"
+	cacheSession   = "session"
+	cacheToken     = "access_token"
+
+	clusterLeaderLease = "leader"
+)
+
+// clusterCacheTables 表与缓存的对应关系，写入这些表后递增对应缓存的版本号
+var clusterCacheTables = map[string]string{
+	"channels":               cacheChannel,
+	"channel_keys":           cacheChannel,
+	"groups":                 cacheGroup,
+	"group_items":            cacheGroup,
+	"api_keys":               cacheAPIKey,
+	"settings":               cacheSetting,
+	"llm_infos":              cacheLLM,
+	"sensitive_filter_rules": cacheSensitive,
+	"sensitive_rule_sets":    cacheSensitive,
+	"users":                  cacheUser,
+	"auth_secrets":           cacheAuth,
+	"user_sessions":          cacheSession,
+	"access_tokens":          cacheToken,
+}
+
+// clusterUsageColumns 只记录使用情况的列，更新只涉及这些列时不递增缓存版本
+// 每个实例在内存中维护自己的使用情况，不需要为此让所有实例重新加载整个缓存；
+// 会话刷新同时更换刷新令牌，不属于此类
+var clusterUsageColumns = map[string][]string{
+	"channel_keys":  {"status_code", "last_use_time_stamp", "total_cost"},
+	"access_tokens": {"last_used_at", "last_used_ip"},
+}
+
+// clusterCacheRefreshers 各缓存的刷新函数，顺序即刷新顺序
+var clusterCacheRefreshers = []struct {
+	name    string
+	refresh func(ctx context.Context) error
+}{
+	{cacheSetting, settingRefreshCache},
+	{cacheChannel, channelRefreshCache},
+	{cacheGroup, groupRefreshCache},
+	{cacheAPIKey, apiKeyRefreshCache},
+	{cacheLLM, llmRefreshCache},
+	{cacheSensitive, func(context.Context) error { SensitiveFilterRefresh(); return nil }},
+	{cacheUser, userRefreshCache},
+	{cacheAuth, authSecretRefreshCache},
+	{cacheSession, sessionRefreshCache},
+	{cacheToken, accessTokenRefreshCache},
+}
+
+var (
+	clusterNodeID   string
+	clusterNodeOnce sync.Once
+	clusterLeader   atomic.Bool
+	clusterVersions = make(map[string]int64)
+	clusterStop     = make(chan struct{})
+	clusterStopOnce sync.Once
+)
+
+// ClusterEnabled 是否以多实例模式运行
+func ClusterEnabled() bool {
+	return conf.AppConfig.Cluster.Enabled
+}
+
+// ClusterIsLeader 当前实例是否负责执行只需运行一份的定时任务，单实例模式下始终为 true
+func ClusterIsLeader() bool {
+	return !ClusterEnabled() || clusterLeader.Load()
+}
+
+// ClusterCheckConfig 检查多实例配置，多实例模式下必须配置固定的实例标识，
+// 否则重启后标识改变，启动时找不到本实例遗留的流水
+func ClusterCheckConfig() error {
+	if ClusterEnabled() && conf.AppConfig.Cluster.NodeID == "" {
+		return fmt.Errorf("cluster.node_id is required when cluster.enabled is set")
+	}
+	return nil
+}
+
+// ClusterNodeID 当前实例的标识，单实例模式下未配置时使用主机名和进程号
+func ClusterNodeID() string {
+	clusterNodeOnce.Do(func() {
+		clusterNodeID = conf.AppConfig.Cluster.NodeID
+		if clusterNodeID == "" {
+			host, _ := os.Hostname()
+			clusterNodeID = fmt.Sprintf("%s-%d", host, os.Getpid())
+		}
+	})
+	return clusterNodeID
+}
+
+// ClusterInit 多实例模式下注册缓存版本通知，竞选主节点，并在后台同步其他实例的配置变更
+// 需要在 InitCache 之后调用
+func ClusterInit() error {
+	if !ClusterEnabled() {
+		return nil
+	}
+	ClusterNodeID()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := db.Conn(ctx)
+	for _, r := range clusterCacheRefreshers {
+		if err := conn.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.CacheVersion{Name: r.name}).Error; err != nil {
+			return fmt.Errorf("init cache version: %w", err)
+		}
+	}
+	versions, err := clusterLoadVersions(ctx)
+	if err != nil {
+		return err
+	}
+	clusterVersions = versions
+
+	if err := clusterRegisterCallbacks(db.GetDB()); err != nil {
+		return err
+	}
+	clusterRenewLease(ctx)
+
+	go clusterLoop()
+	log.Infof("cluster mode enabled, node %s, leader: %v", clusterNodeID, clusterLeader.Load())
+	return nil
+}
+
+// ClusterClose 停止后台同步并释放主节点租约，使其他实例可以立即接管
+func ClusterClose() error {
+	if !ClusterEnabled() {
+		return nil
+	}
+	clusterStopOnce.Do(func() { close(clusterStop) })
+	if !clusterLeader.Load() {
+		return nil
+	}
+	clusterLeader.Store(false)
+	return db.GetDB().Model(&model.ClusterLease{}).
+		Where("name = ? AND holder = ?", clusterLeaderLease, clusterNodeID).
+		Update("expires_at", 0).Error
+}
+
+func clusterLoop() {
+	syncInterval := time.Duration(max(conf.AppConfig.Cluster.SyncInterval, 1)) * time.Second
+	leaseInterval := clusterLeaseTTL() / 3
+	syncTicker := time.NewTicker(syncInterval)
+	defer syncTicker.Stop()
+	leaseTicker := time.NewTicker(leaseInterval)
+	defer leaseTicker.Stop()
+	for {
+		select {
+		case <-syncTicker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), syncInterval)
+			clusterSyncCaches(ctx)
+			cancel()
+		case <-leaseTicker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), leaseInterval)
+			clusterRenewLease(ctx)
+			cancel()
+		case <-clusterStop:
+			return
+		}
+	}
+}
+
+func clusterLeaseTTL() time.Duration {
+	return time.Duration(max(conf.AppConfig.Cluster.LeaseTTL, 3)) * time.Second
+}
+
+// clusterRenewLease 续约或抢占已过期的主节点租约
+// 过期时间按本机时间写入和比较，要求各实例时钟同步且误差远小于租约时长，否则时钟偏快的实例可能提前抢占租约
+func clusterRenewLease(ctx context.Context) {
+	now := time.Now()
+	expiresAt := now.Add(clusterLeaseTTL()).UnixMilli()
+	conn := db.Conn(ctx)
+
+	result := conn.Model(&model.ClusterLease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", clusterLeaderLease, clusterNodeID, now.UnixMilli()).
+		Updates(map[string]any{"holder": clusterNodeID, "expires_at": expiresAt})
+	if result.Error == nil && result.RowsAffected == 0 {
+		result = conn.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.ClusterLease{Name: clusterLeaderLease, Holder: clusterNodeID, ExpiresAt: expiresAt})
+	}
+	if result.Error != nil {
+		// 无法确认租约时放弃主节点身份，避免出现两个主节点
+		log.Warnf("cluster lease renew failed: %v", result.Error)
+		if clusterLeader.Swap(false) {
+			log.Warnf("cluster leadership lost: node %s", clusterNodeID)
+		}
+		return
+	}
+	leader := result.RowsAffected > 0
+	if clusterLeader.Swap(leader) != leader {
+		if leader {
+			log.Infof("cluster leadership acquired: node %s", clusterNodeID)
+		} else {
+			log.Warnf("cluster leadership lost: node %s", clusterNodeID)
+		}
+	}
+}
+
+func clusterLoadVersions(ctx context.Context) (map[string]int64, error) {
+	var rows []model.CacheVersion
+	if err := db.Conn(ctx).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("load cache versions: %w", err)
+	}
+	versions := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		versions[r.Name] = r.Version
+	}
+	return versions, nil
+}
+
+// clusterSyncCaches 刷新版本号发生变化的缓存，包括本实例自己的修改，刷新失败时下次重试
+func clusterSyncCaches(ctx context.Context) {
+	versions, err := clusterLoadVersions(ctx)
+	if err != nil {
+		log.Warnf("cluster sync failed: %v", err)
+		return
+	}
+	for _, r := range clusterCacheRefreshers {
+		if versions[r.name] == clusterVersions[r.name] {
+			continue
+		}
+		if err := r.refresh(ctx); err != nil {
+			log.Warnf("cluster refresh %s cache failed: %v", r.name, err)
+			continue
+		}
+		log.Debugf("cluster refreshed %s cache: version %d", r.name, versions[r.name])
+		clusterVersions[r.name] = versions[r.name]
+	}
+}
+
+// clusterRegisterCallbacks 在写入配置表的同一事务中递增缓存版本号
+func clusterRegisterCallbacks(conn *gorm.DB) error {
+	callbacks := conn.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("cluster:cache_version", clusterBumpVersion); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("cluster:cache_version", clusterBumpVersionUpdate); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register("cluster:cache_version", clusterBumpVersion)
+}
+
+func clusterBumpVersion(tx *gorm.DB) {
+	if tx.Error != nil || tx.RowsAffected == 0 {
+		return
+	}
+	name, ok := clusterCacheTables[clusterStatementTable(tx)]
+	if !ok {
+		return
+	}
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&model.CacheVersion{}).
+		Where("name = ?", name).
+		UpdateColumn("version", gorm.Expr("version + 1")).Error
+	if err != nil {
+		tx.AddError(fmt.Errorf("bump %s cache version: %w", name, err))
+	}
+}
+
+// clusterBumpVersionUpdate 更新只以列名写入了表的使用情况列时不递增版本，按结构体更新时总是视为配置变更
+func clusterBumpVersionUpdate(tx *gorm.DB) {
+	columns := clusterUsageColumns[clusterStatementTable(tx)]
+	values, ok := tx.Statement.Dest.(map[string]any)
+	if ok && len(values) > 0 && len(columns) > 0 {
+		usageOnly := true
+		for column := range values {
+			if !slices.Contains(columns, column) {
+				usageOnly = false
+				break
+			}
+		}
+		if usageOnly {
+			return
+		}
+	}
+	clusterBumpVersion(tx)
+}
+
+func clusterStatementTable(tx *gorm.DB) string {
+	if tx.Statement.Table == "" && tx.Statement.Schema != nil {
+		return tx.Statement.Schema.Table
+	}
+	return tx.Statement.Table
+}
@@ -0,0 +1,90 @@
+package op
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm"
+)
+
+// 只更新使用情况列时不递增缓存版本，其他修改照常递增
+func TestClusterBumpVersionSkipsUsage(t *testing.T) {
+	dbtest.Init(t, "cluster.db")
+	ctx := context.Background()
+	conn := db.GetDB()
+	for _, name := range []string{cacheChannel, cacheToken} {
+		if err := conn.Create(&model.CacheVersion{Name: name}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := clusterRegisterCallbacks(conn); err != nil {
+		t.Fatal(err)
+	}
+	channel := model.Channel{Name: "c", Keys: []model.ChannelKey{{ChannelKey: "sk-1", Enabled: true}}}
+	if err := conn.Create(&channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	token := model.AccessToken{UserID: 1, Name: "t", TokenHash: "hash"}
+	if err := conn.Create(&token).Error; err != nil {
+		t.Fatal(err)
+	}
+	keyID := channel.Keys[0].ID
+
+	version := func(name string) int64 {
+		t.Helper()
+		var v model.CacheVersion
+		if err := conn.First(&v, "name = ?", name).Error; err != nil {
+			t.Fatal(err)
+		}
+		return v.Version
+	}
+	key := func() *gorm.DB { return conn.Model(&model.ChannelKey{}).Where("id = ?", keyID) }
+
+	tests := []struct {
+		name  string
+		cache string
+		write func() error
+		bump  bool
+	}{
+		{"key usage", cacheChannel, func() error {
+			channelKeyPendingLock.Lock()
+			channelKeyPending[keyID] = channelKeyUsage{StatusCode: 200, LastUseTimeStamp: 100}
+			channelKeyPendingLock.Unlock()
+			return ChannelKeySaveDB(ctx)
+		}, false},
+		{"key cost", cacheChannel, func() error {
+			return key().UpdateColumn("total_cost", gorm.Expr("total_cost + ?", 0.5)).Error
+		}, false},
+		{"key enabled", cacheChannel, func() error {
+			return key().Update("enabled", false).Error
+		}, true},
+		{"key usage and enabled", cacheChannel, func() error {
+			return key().UpdateColumns(map[string]any{"status_code": 500, "enabled": true}).Error
+		}, true},
+		{"channel", cacheChannel, func() error {
+			return conn.Model(&channel).Update("name", "c2").Error
+		}, true},
+		{"token last used", cacheToken, func() error {
+			return conn.Model(&model.AccessToken{ID: token.ID}).
+				UpdateColumns(map[string]any{"last_used_at": 100, "last_used_ip": "127.0.0.1"}).Error
+		}, false},
+		{"token name", cacheToken, func() error {
+			return conn.Model(&model.AccessToken{ID: token.ID}).Update("name", "t2").Error
+		}, true},
+		{"key delete", cacheChannel, func() error {
+			return conn.Delete(&model.ChannelKey{}, keyID).Error
+		}, true},
+	}
+	for _, tt := range tests {
+		before := version(tt.cache)
+		if err := tt.write(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if bumped := version(tt.cache) != before; bumped != tt.bump {
+			t.Errorf("%s: bumped = %v, want %v", tt.name, bumped, tt.bump)
+		}
+	}
+}
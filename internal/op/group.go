@@ -47,7 +47,7 @@ func GroupGetMap(name string, ctx context.Context) (model.Group, error) {
 }
 
 func GroupCreate(group *model.Group, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Create(group).Error; err != nil {
+	if err := db.Conn(ctx).Create(group).Error; err != nil {
 		return err
 	}
 	groupCache.Set(group.ID, *group)
@@ -62,92 +62,87 @@ func GroupUpdate(req *model.GroupUpdateRequest, ctx context.Context) (*model.Gro
 	}
 	oldName := oldGroup.Name
 
-	tx := db.GetDB().WithContext(ctx).Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	var selectFields []string
-	updates := model.Group{ID: req.ID}
+	err := db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var selectFields []string
+		updates := model.Group{ID: req.ID}
 
-	if req.Name != nil {
-		selectFields = append(selectFields, "name")
-		updates.Name = *req.Name
-	}
-	if req.Mode != nil {
-		selectFields = append(selectFields, "mode")
-		updates.Mode = *req.Mode
-	}
-	if req.MatchRegex != nil {
-		selectFields = append(selectFields, "match_regex")
-		updates.MatchRegex = *req.MatchRegex
-	}
-	if req.FirstTokenTimeOut != nil {
-		selectFields = append(selectFields, "first_token_time_out")
-		updates.FirstTokenTimeOut = *req.FirstTokenTimeOut
-	}
-
-	if len(selectFields) > 0 {
-		if err := tx.Model(&model.Group{}).Where("id = ?", req.ID).Select(selectFields).Updates(&updates).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("failed to update group: %w", err)
+		if req.Name != nil {
+			selectFields = append(selectFields, "name")
+			updates.Name = *req.Name
 		}
-	}
-
-	// 删除 items
-	if len(req.ItemsToDelete) > 0 {
-		if err := tx.Where("id IN ? AND group_id = ?", req.ItemsToDelete, req.ID).Delete(&model.GroupItem{}).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("failed to delete items: %w", err)
+		if req.Mode != nil {
+			selectFields = append(selectFields, "mode")
+			updates.Mode = *req.Mode
 		}
-	}
-
-	// 批量更新 items
-	if len(req.ItemsToUpdate) > 0 {
-		ids := make([]int, len(req.ItemsToUpdate))
-		priorityCase := "CASE id"
-		weightCase := "CASE id"
-		for i, item := range req.ItemsToUpdate {
-			ids[i] = item.ID
-			priorityCase += fmt.Sprintf(" WHEN %d THEN %d", item.ID, item.Priority)
-			weightCase += fmt.Sprintf(" WHEN %d THEN %d", item.ID, item.Weight)
+		if req.MatchRegex != nil {
+			selectFields = append(selectFields, "match_regex")
+			updates.MatchRegex = *req.MatchRegex
 		}
-		priorityCase += " END"
-		weightCase += " END"
-
-		if err := tx.Model(&model.GroupItem{}).
-			Where("id IN ? AND group_id = ?", ids, req.ID).
-			Updates(map[string]interface{}{
-				"priority": gorm.Expr(priorityCase),
-				"weight":   gorm.Expr(weightCase),
-			}).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("failed to update items: %w", err)
+		if req.FirstTokenTimeOut != nil {
+			selectFields = append(selectFields, "first_token_time_out")
+			updates.FirstTokenTimeOut = *req.FirstTokenTimeOut
+		}
+		if req.SensitiveRuleSetID != nil {
+			selectFields = append(selectFields, "sensitive_rule_set_id")
+			updates.SensitiveRuleSetID = *req.SensitiveRuleSetID
 		}
-	}
 
-	// 批量新增 items
-	if len(req.ItemsToAdd) > 0 {
-		newItems := make([]model.GroupItem, len(req.ItemsToAdd))
-		for i, item := range req.ItemsToAdd {
-			newItems[i] = model.GroupItem{
-				GroupID:   req.ID,
-				ChannelID: item.ChannelID,
-				ModelName: item.ModelName,
-				Priority:  item.Priority,
-				Weight:    item.Weight,
+		if len(selectFields) > 0 {
+			if err := tx.Model(&model.Group{}).Where("id = ?", req.ID).Select(selectFields).Updates(&updates).Error; err != nil {
+				return fmt.Errorf("failed to update group: %w", err)
 			}
 		}
-		if err := tx.Create(&newItems).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("failed to create items: %w", err)
+
+		// 删除 items
+		if len(req.ItemsToDelete) > 0 {
+			if err := tx.Where("id IN ? AND group_id = ?", req.ItemsToDelete, req.ID).Delete(&model.GroupItem{}).Error; err != nil {
+				return fmt.Errorf("failed to delete items: %w", err)
+			}
 		}
-	}
 
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		// 批量更新 items
+		if len(req.ItemsToUpdate) > 0 {
+			ids := make([]int, len(req.ItemsToUpdate))
+			priorityCase := "CASE id"
+			weightCase := "CASE id"
+			for i, item := range req.ItemsToUpdate {
+				ids[i] = item.ID
+				priorityCase += fmt.Sprintf(" WHEN %d THEN %d", item.ID, item.Priority)
+				weightCase += fmt.Sprintf(" WHEN %d THEN %d", item.ID, item.Weight)
+			}
+			priorityCase += " END"
+			weightCase += " END"
+
+			if err := tx.Model(&model.GroupItem{}).
+				Where("id IN ? AND group_id = ?", ids, req.ID).
+				Updates(map[string]interface{}{
+					"priority": gorm.Expr(priorityCase),
+					"weight":   gorm.Expr(weightCase),
+				}).Error; err != nil {
+				return fmt.Errorf("failed to update items: %w", err)
+			}
+		}
+
+		// 批量新增 items
+		if len(req.ItemsToAdd) > 0 {
+			newItems := make([]model.GroupItem, len(req.ItemsToAdd))
+			for i, item := range req.ItemsToAdd {
+				newItems[i] = model.GroupItem{
+					GroupID:   req.ID,
+					ChannelID: item.ChannelID,
+					ModelName: item.ModelName,
+					Priority:  item.Priority,
+					Weight:    item.Weight,
+				}
+			}
+			if err := tx.Create(&newItems).Error; err != nil {
+				return fmt.Errorf("failed to create items: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// 刷新缓存并返回最新数据
@@ -162,31 +157,39 @@ func GroupUpdate(req *model.GroupUpdateRequest, ctx context.Context) (*model.Gro
 	return &group, nil
 }
 
-func GroupDel(id int, ctx context.Context) error {
+// GroupSetManaged 设置分组是否由声明式配置管理
+func GroupSetManaged(id int, managed bool, ctx context.Context) error {
 	group, ok := groupCache.Get(id)
 	if !ok {
 		return fmt.Errorf("group not found")
 	}
-
-	tx := db.GetDB().WithContext(ctx).Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	if err := tx.Where("group_id = ?", id).Delete(&model.GroupItem{}).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to delete group items: %w", err)
+	if err := db.Conn(ctx).Model(&model.Group{}).Where("id = ?", id).Update("managed", managed).Error; err != nil {
+		return err
 	}
+	group.Managed = managed
+	groupCache.Set(id, group)
+	groupMap.Set(group.Name, group)
+	return nil
+}
 
-	if err := tx.Delete(&model.Group{}, id).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to delete group: %w", err)
+func GroupDel(id int, ctx context.Context) error {
+	group, ok := groupCache.Get(id)
+	if !ok {
+		return fmt.Errorf("group not found")
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	err := db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&model.GroupItem{}).Error; err != nil {
+			return fmt.Errorf("failed to delete group items: %w", err)
+		}
+
+		if err := tx.Delete(&model.Group{}, id).Error; err != nil {
+			return fmt.Errorf("failed to delete group: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	groupCache.Del(id)
@@ -199,7 +202,7 @@ func GroupItemAdd(item *model.GroupItem, ctx context.Context) error {
 		return fmt.Errorf("group not found")
 	}
 
-	if err := db.GetDB().WithContext(ctx).Create(item).Error; err != nil {
+	if err := db.Conn(ctx).Create(item).Error; err != nil {
 		return err
 	}
 
@@ -252,7 +255,7 @@ func GroupItemBatchAdd(groupID int, items []model.GroupIDAndLLMName, ctx context
 		nextPriority++
 	}
 
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "group_id"}, {Name: "channel_id"}, {Name: "model_name"}},
 			DoNothing: true,
@@ -265,7 +268,7 @@ func GroupItemBatchAdd(groupID int, items []model.GroupIDAndLLMName, ctx context
 }
 
 func GroupItemUpdate(item *model.GroupItem, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Model(item).
+	if err := db.Conn(ctx).Model(item).
 		Select("ModelName", "Priority", "Weight").
 		Updates(item).Error; err != nil {
 		return err
@@ -276,11 +279,11 @@ func GroupItemUpdate(item *model.GroupItem, ctx context.Context) error {
 
 func GroupItemDel(id int, ctx context.Context) error {
 	var item model.GroupItem
-	if err := db.GetDB().WithContext(ctx).First(&item, id).Error; err != nil {
+	if err := db.Conn(ctx).First(&item, id).Error; err != nil {
 		return fmt.Errorf("group item not found")
 	}
 
-	if err := db.GetDB().WithContext(ctx).Delete(&item).Error; err != nil {
+	if err := db.Conn(ctx).Delete(&item).Error; err != nil {
 		return err
 	}
 
@@ -299,7 +302,7 @@ func GroupItemBatchDelByChannelAndModels(keys []model.GroupIDAndLLMName, ctx con
 	}
 
 	var groupIDs []int
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Model(&model.GroupItem{}).
 		Distinct("group_id").
 		Where("(channel_id, model_name) IN ?", conditions).
@@ -311,7 +314,7 @@ func GroupItemBatchDelByChannelAndModels(keys []model.GroupIDAndLLMName, ctx con
 		return nil
 	}
 
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Where("(channel_id, model_name) IN ?", conditions).
 		Delete(&model.GroupItem{}).Error; err != nil {
 		return fmt.Errorf("failed to delete group items: %w", err)
@@ -326,7 +329,7 @@ func GroupItemBatchDelByChannelAndModels(keys []model.GroupIDAndLLMName, ctx con
 
 func GroupItemList(groupID int, ctx context.Context) ([]model.GroupItem, error) {
 	var items []model.GroupItem
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Where("group_id = ?", groupID).
 		Order("priority ASC").
 		Find(&items).Error; err != nil {
@@ -337,21 +340,27 @@ func GroupItemList(groupID int, ctx context.Context) ([]model.GroupItem, error)
 
 func groupRefreshCache(ctx context.Context) error {
 	groups := []model.Group{}
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Preload("Items").
 		Find(&groups).Error; err != nil {
 		return err
 	}
+	ids := make(map[int]struct{}, len(groups))
+	names := make(map[string]struct{}, len(groups))
 	for _, group := range groups {
 		groupCache.Set(group.ID, group)
 		groupMap.Set(group.Name, group)
+		ids[group.ID] = struct{}{}
+		names[group.Name] = struct{}{}
 	}
+	cacheRetain(groupCache, func(id int, _ model.Group) bool { _, ok := ids[id]; return ok })
+	cacheRetain(groupMap, func(name string, _ model.Group) bool { _, ok := names[name]; return ok })
 	return nil
 }
 
 func groupRefreshCacheByID(id int, ctx context.Context) error {
 	var group model.Group
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Preload("Items").
 		First(&group, id).Error; err != nil {
 		return err
@@ -366,7 +375,7 @@ func groupRefreshCacheByIDs(ids []int, ctx context.Context) error {
 		return nil
 	}
 	var groups []model.Group
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Preload("Items").
 		Where("id IN ?", ids).
 		Find(&groups).Error; err != nil {
@@ -28,7 +28,7 @@ func LLMUpdate(model model.LLMInfo, ctx context.Context) error {
 	if !ok {
 		return fmt.Errorf("model not found")
 	}
-	if err := db.GetDB().WithContext(ctx).Save(model).Error; err != nil {
+	if err := db.Conn(ctx).Save(model).Error; err != nil {
 		return err
 	}
 	llmModelCache.Set(model.Name, model.LLMPrice)
@@ -40,7 +40,7 @@ func LLMDelete(modelName string, ctx context.Context) error {
 	if !ok {
 		return fmt.Errorf("model not found")
 	}
-	if err := db.GetDB().WithContext(ctx).Delete(&model.LLMInfo{Name: modelName}).Error; err != nil {
+	if err := db.Conn(ctx).Delete(&model.LLMInfo{Name: modelName}).Error; err != nil {
 		return err
 	}
 	llmModelCache.Del(modelName)
@@ -50,7 +50,7 @@ func LLMBatchDelete(modelNames []string, ctx context.Context) error {
 	if len(modelNames) == 0 {
 		return nil
 	}
-	if err := db.GetDB().WithContext(ctx).Where("name IN ?", modelNames).Delete(&model.LLMInfo{}).Error; err != nil {
+	if err := db.Conn(ctx).Where("name IN ?", modelNames).Delete(&model.LLMInfo{}).Error; err != nil {
 		return err
 	}
 	llmModelCache.Del(modelNames...)
@@ -62,7 +62,7 @@ func LLMCreate(model model.LLMInfo, ctx context.Context) error {
 	if ok {
 		return fmt.Errorf("model already exists")
 	}
-	if err := db.GetDB().WithContext(ctx).Create(&model).Error; err != nil {
+	if err := db.Conn(ctx).Create(&model).Error; err != nil {
 		return err
 	}
 	llmModelCache.Set(model.Name, model.LLMPrice)
@@ -88,7 +88,7 @@ func LLMBatchCreate(llmInfos []model.LLMInfo, ctx context.Context) error {
 	if len(newLLMInfos) == 0 {
 		return nil
 	}
-	if err := db.GetDB().WithContext(ctx).Create(&newLLMInfos).Error; err != nil {
+	if err := db.Conn(ctx).Create(&newLLMInfos).Error; err != nil {
 		return err
 	}
 	for _, llmInfo := range newLLMInfos {
@@ -106,11 +106,14 @@ func LLMGet(name string) (model.LLMPrice, error) {
 
 func llmRefreshCache(ctx context.Context) error {
 	models := []model.LLMInfo{}
-	if err := db.GetDB().WithContext(ctx).Find(&models).Error; err != nil {
+	if err := db.Conn(ctx).Find(&models).Error; err != nil {
 		return err
 	}
+	names := make(map[string]struct{}, len(models))
 	for _, model := range models {
 		llmModelCache.Set(model.Name, model.LLMPrice)
+		names[model.Name] = struct{}{}
 	}
+	cacheRetain(llmModelCache, func(name string, _ model.LLMPrice) bool { _, ok := names[name]; return ok })
 	return nil
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/utils/log"
 	"github.com/bestruirui/octopus/internal/utils/snowflake"
+	"gorm.io/gorm"
 )
 
 const relayLogMaxSize = 20
@@ -95,9 +98,22 @@ func relayLogFlushToDB(ctx context.Context) error {
 	flushedUpto := len(batch)
 	relayLogCacheLock.Unlock()
 
-	result := db.GetDB().WithContext(ctx).Create(&batch)
-	if result.Error != nil {
-		return result.Error
+	bodies, err := relayLogBuildBodies(batch)
+	if err != nil {
+		return err
+	}
+	err = db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&batch).Error; err != nil {
+			return err
+		}
+		if len(bodies) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&bodies, 100).Error
+	})
+	relayLogFlushRecord(err)
+	if err != nil {
+		return err
 	}
 
 	relayLogCacheLock.Lock()
@@ -114,6 +130,35 @@ func relayLogFlushToDB(ctx context.Context) error {
 	return nil
 }
 
+var (
+	relayLogFlushStatusLock sync.Mutex
+	relayLogFlushStatus     model.RelayLogBacklog
+)
+
+func relayLogFlushRecord(err error) {
+	relayLogFlushStatusLock.Lock()
+	defer relayLogFlushStatusLock.Unlock()
+	if err != nil {
+		relayLogFlushStatus.LastError = err.Error()
+		return
+	}
+	relayLogFlushStatus.LastFlush = time.Now().Unix()
+	relayLogFlushStatus.LastError = ""
+}
+
+// RelayLogBacklogInfo 返回内存中请求日志的积压情况
+func RelayLogBacklogInfo() model.RelayLogBacklog {
+	relayLogFlushStatusLock.Lock()
+	status := relayLogFlushStatus
+	relayLogFlushStatusLock.Unlock()
+
+	status.KeepEnabled, _ = SettingGetBool(model.SettingKeyRelayLogKeepEnabled)
+	relayLogCacheLock.Lock()
+	status.Pending = len(relayLogCache)
+	relayLogCacheLock.Unlock()
+	return status
+}
+
 func RelayLogAdd(ctx context.Context, relayLog model.RelayLog) error {
 	enabled, err := SettingGetBool(model.SettingKeyRelayLogKeepEnabled)
 	if err != nil {
@@ -124,6 +169,7 @@ func RelayLogAdd(ctx context.Context, relayLog model.RelayLog) error {
 		maxSize = relayLogMaxSizeNoDB
 	}
 	relayLog.ID = snowflake.GenerateID()
+	relayLog.BodyTruncated = relayLogApplyBodyPolicy(&relayLog)
 	go notifySubscribers(relayLog)
 
 	relayLogCacheLock.Lock()
@@ -173,6 +219,10 @@ func RelayLogSaveDBTask(ctx context.Context) error {
 }
 
 func relayLogCleanup(ctx context.Context) error {
+	if err := relayLogBodyCleanup(ctx); err != nil {
+		return err
+	}
+
 	keepPeriod, err := SettingGetInt(model.SettingKeyRelayLogKeepPeriod)
 	if err != nil {
 		return err
@@ -183,28 +233,28 @@ func relayLogCleanup(ctx context.Context) error {
 	}
 
 	cutoffTime := time.Now().Add(-time.Duration(keepPeriod) * 24 * time.Hour).Unix()
-	return db.GetDB().WithContext(ctx).Where("time < ?", cutoffTime).Delete(&model.RelayLog{}).Error
+	return db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("time < ?", cutoffTime).Delete(&model.RelayLogBody{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("time < ?", cutoffTime).Delete(&model.RelayLog{}).Error
+	})
 }
 
-// RelayLogList 查询日志列表，支持可选的时间范围过滤
-// startTime 和 endTime 为 nil 时表示不限制时间范围
-func RelayLogList(ctx context.Context, startTime, endTime *int, page, pageSize int) ([]model.RelayLog, error) {
+// RelayLogList 查询日志列表，按 ID 倒序返回
+// filter 中为空的字段表示不限制；设置 filter.Cursor 时应配合 page=1 使用游标分页
+func RelayLogList(ctx context.Context, filter model.RelayLogFilter, page, pageSize int) ([]model.RelayLog, error) {
 	enabled, err := SettingGetBool(model.SettingKeyRelayLogKeepEnabled)
 	if err != nil {
 		return nil, err
 	}
-	hasTimeFilter := startTime != nil && endTime != nil
 
 	// 获取缓存中符合条件的日志
 	relayLogCacheLock.Lock()
 	var cachedLogs []model.RelayLog
-	for _, log := range relayLogCache {
-		if hasTimeFilter {
-			if log.Time >= int64(*startTime) && log.Time <= int64(*endTime) {
-				cachedLogs = append(cachedLogs, log)
-			}
-		} else {
-			cachedLogs = append(cachedLogs, log)
+	for i := range relayLogCache {
+		if filter.Match(&relayLogCache[i]) {
+			cachedLogs = append(cachedLogs, relayLogCache[i])
 		}
 	}
 	relayLogCacheLock.Unlock()
@@ -237,10 +287,7 @@ func RelayLogList(ctx context.Context, startTime, endTime *int, page, pageSize i
 				dbOffset = offset - cacheCount
 			}
 
-			query := db.GetDB().WithContext(ctx)
-			if hasTimeFilter {
-				query = query.Where("time >= ? AND time <= ?", *startTime, *endTime)
-			}
+			query := relayLogFilterQuery(db.Conn(ctx), filter)
 
 			var dbLogs []model.RelayLog
 			if err := query.Order("id DESC").Offset(dbOffset).Limit(remaining).Find(&dbLogs).Error; err != nil {
@@ -253,9 +300,75 @@ func RelayLogList(ctx context.Context, startTime, endTime *int, page, pageSize i
 	return result, nil
 }
 
+// relayLogFilterQuery 将过滤条件转换为数据库查询条件
+func relayLogFilterQuery(query *gorm.DB, filter model.RelayLogFilter) *gorm.DB {
+	if filter.Cursor != nil {
+		query = query.Where("id < ?", *filter.Cursor)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("time >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("time <= ?", *filter.EndTime)
+	}
+	if filter.Model != "" {
+		query = query.Where("request_model_name = ? OR actual_model_name = ?", filter.Model, filter.Model)
+	}
+	if filter.ChannelID != nil {
+		query = query.Where("channel_id = ?", *filter.ChannelID)
+	}
+	if filter.APIKeyID != nil {
+		query = query.Where("api_key_id = ?", *filter.APIKeyID)
+	}
+	if filter.Success != nil {
+		if *filter.Success {
+			query = query.Where("error = ''")
+		} else {
+			query = query.Where("error <> ''")
+		}
+	}
+	if filter.MinCost != nil {
+		query = query.Where("cost >= ?", *filter.MinCost)
+	}
+	if filter.MaxCost != nil {
+		query = query.Where("cost <= ?", *filter.MaxCost)
+	}
+	if filter.Keyword != "" {
+		like := "%" + likeEscape(strings.ToLower(filter.Keyword)) + "%"
+		query = query.Where("LOWER(request_model_name) LIKE ? ESCAPE '!' OR LOWER(actual_model_name) LIKE ? ESCAPE '!' OR LOWER(channel_name) LIKE ? ESCAPE '!' OR LOWER(error) LIKE ? ESCAPE '!'",
+			like, like, like, like)
+	}
+	if filter.AttemptChannelID != nil {
+		// attempts 以 JSON 序列化存储，channel_id 后面可能是下一个字段或对象结尾，不依赖字段顺序
+		// 字段名中的下划线是 LIKE 的通配符，同样需要转义
+		field := likeEscape(fmt.Sprintf(`"channel_id":%d`, *filter.AttemptChannelID))
+		query = query.Where("(attempts LIKE ? ESCAPE '!' OR attempts LIKE ? ESCAPE '!')",
+			"%"+field+",%", "%"+field+"}%")
+	}
+	if filter.Failover != nil {
+		if *filter.Failover {
+			query = query.Where("attempt_count > 1")
+		} else {
+			query = query.Where("attempt_count <= 1")
+		}
+	}
+	return query
+}
+
+// likeEscape 转义 LIKE 的通配符，配合 ESCAPE '!' 使用
+// 不用反斜杠作为转义符，MySQL 默认会把字符串中的反斜杠当作转义
+func likeEscape(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
 func RelayLogClear(ctx context.Context) error {
 	relayLogCacheLock.Lock()
 	relayLogCache = make([]model.RelayLog, 0, relayLogMaxSize)
 	relayLogCacheLock.Unlock()
-	return db.GetDB().WithContext(ctx).Where("1 = 1").Delete(&model.RelayLog{}).Error
+	return db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&model.RelayLogBody{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("1 = 1").Delete(&model.RelayLog{}).Error
+	})
 }
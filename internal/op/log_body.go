@@ -0,0 +1,199 @@
+package op
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm"
+)
+
+// relayLogApplyBodyPolicy 按 API Key 和全局设置裁剪日志内容
+// 返回内容是否被截断
+func relayLogApplyBodyPolicy(relayLog *model.RelayLog) bool {
+	maxSize, err := SettingGetInt(model.SettingKeyRelayLogBodyMaxSize)
+	if err != nil {
+		maxSize = 0
+	}
+	if apiKey, err := APIKeyGet(relayLog.APIKeyID, context.Background()); err == nil {
+		if apiKey.LogBodyDisabled {
+			relayLog.RequestContent = ""
+			relayLog.ResponseContent = ""
+			return false
+		}
+		if apiKey.LogBodyMaxSize > 0 {
+			maxSize = apiKey.LogBodyMaxSize
+		}
+	}
+	if maxSize <= 0 {
+		return false
+	}
+	var reqTruncated, respTruncated bool
+	relayLog.RequestContent, reqTruncated = truncateUTF8(relayLog.RequestContent, maxSize)
+	relayLog.ResponseContent, respTruncated = truncateUTF8(relayLog.ResponseContent, maxSize)
+	return reqTruncated || respTruncated
+}
+
+// truncateUTF8 按字节截断字符串，不会切断多字节字符
+func truncateUTF8(s string, maxSize int) (string, bool) {
+	if len(s) <= maxSize {
+		return s, false
+	}
+	for maxSize > 0 && !utf8.RuneStart(s[maxSize]) {
+		maxSize--
+	}
+	return s[:maxSize], true
+}
+
+// relayLogBuildBodies 从待写入的日志中提取内容并压缩，同时设置 HasBody
+func relayLogBuildBodies(batch []model.RelayLog) ([]model.RelayLogBody, error) {
+	bodies := make([]model.RelayLogBody, 0, len(batch))
+	for i := range batch {
+		relayLog := &batch[i]
+		if relayLog.RequestContent == "" && relayLog.ResponseContent == "" {
+			continue
+		}
+		request, err := gzipCompress(relayLog.RequestContent)
+		if err != nil {
+			return nil, err
+		}
+		response, err := gzipCompress(relayLog.ResponseContent)
+		if err != nil {
+			return nil, err
+		}
+		relayLog.HasBody = true
+		bodies = append(bodies, model.RelayLogBody{
+			LogID:     relayLog.ID,
+			Time:      relayLog.Time,
+			APIKeyID:  relayLog.APIKeyID,
+			Request:   request,
+			Response:  response,
+			Truncated: relayLog.BodyTruncated,
+		})
+	}
+	return bodies, nil
+}
+
+// RelayLogBodyGet 获取日志的请求/响应内容
+func RelayLogBodyGet(ctx context.Context, id int64) (*model.RelayLogBodyContent, error) {
+	relayLogCacheLock.Lock()
+	for _, relayLog := range relayLogCache {
+		if relayLog.ID == id {
+			relayLogCacheLock.Unlock()
+			return &model.RelayLogBodyContent{
+				RequestContent:  relayLog.RequestContent,
+				ResponseContent: relayLog.ResponseContent,
+				Truncated:       relayLog.BodyTruncated,
+			}, nil
+		}
+	}
+	relayLogCacheLock.Unlock()
+
+	var body model.RelayLogBody
+	if err := db.Conn(ctx).Where("log_id = ?", id).First(&body).Error; err != nil {
+		return nil, fmt.Errorf("log body not found: %w", err)
+	}
+	request, err := gzipDecompress(body.Request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := gzipDecompress(body.Response)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RelayLogBodyContent{
+		RequestContent:  request,
+		ResponseContent: response,
+		Truncated:       body.Truncated,
+	}, nil
+}
+
+// relayLogBodyCleanup 按全局和 API Key 的保留期清理日志内容，日志元数据保留
+func relayLogBodyCleanup(ctx context.Context) error {
+	keepPeriod, err := SettingGetInt(model.SettingKeyRelayLogBodyKeepPeriod)
+	if err != nil {
+		return err
+	}
+
+	// 单独配置了保留期的 API Key
+	overrides := make(map[int]int)
+	for _, apiKey := range apiKeyCache.GetAll() {
+		if apiKey.LogBodyKeepDays > 0 {
+			overrides[apiKey.ID] = apiKey.LogBodyKeepDays
+		}
+	}
+
+	for apiKeyID, days := range overrides {
+		cutoff := time.Now().Add(-time.Duration(days) * 24 * time.Hour).Unix()
+		query := func(tx *gorm.DB) *gorm.DB {
+			return tx.Where("api_key_id = ? AND time < ?", apiKeyID, cutoff)
+		}
+		if err := relayLogBodyDelete(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	if keepPeriod <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-time.Duration(keepPeriod) * 24 * time.Hour).Unix()
+	overrideIDs := make([]int, 0, len(overrides))
+	for id := range overrides {
+		overrideIDs = append(overrideIDs, id)
+	}
+	return relayLogBodyDelete(ctx, func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("time < ?", cutoff)
+		if len(overrideIDs) > 0 {
+			tx = tx.Where("api_key_id NOT IN ?", overrideIDs)
+		}
+		return tx
+	})
+}
+
+// relayLogBodyDelete 删除满足条件的日志内容并清除对应日志的 HasBody 标记
+func relayLogBodyDelete(ctx context.Context, scope func(tx *gorm.DB) *gorm.DB) error {
+	return db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := scope(tx.Model(&model.RelayLog{})).Where("has_body = ?", true).
+			Update("has_body", false).Error; err != nil {
+			return err
+		}
+		return scope(tx).Delete(&model.RelayLogBody{}).Error
+	})
+}
+
+func gzipCompress(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		return nil, fmt.Errorf("failed to compress log body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress log body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func gzipDecompress(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress log body: %w", err)
+	}
+	defer r.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress log body: %w", err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,198 @@
+package op
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+// testSetting 在测试期间覆盖设置缓存
+func testSetting(t *testing.T, key model.SettingKey, value string) {
+	t.Helper()
+	old, ok := settingCache.Get(key)
+	settingCache.Set(key, value)
+	t.Cleanup(func() {
+		if ok {
+			settingCache.Set(key, old)
+		} else {
+			settingCache.Del(key)
+		}
+	})
+}
+
+// testRelayLogCache 在测试期间替换内存中尚未落库的日志
+func testRelayLogCache(t *testing.T, logs ...model.RelayLog) {
+	t.Helper()
+	relayLogCacheLock.Lock()
+	relayLogCache = append([]model.RelayLog(nil), logs...)
+	relayLogCacheLock.Unlock()
+	t.Cleanup(func() {
+		relayLogCacheLock.Lock()
+		relayLogCache = make([]model.RelayLog, 0, relayLogMaxSize)
+		relayLogCacheLock.Unlock()
+	})
+}
+
+// testAPIKeys 在测试期间替换 API Key 缓存
+func testAPIKeys(t *testing.T, keys ...model.APIKey) {
+	t.Helper()
+	apiKeyCache.Clear()
+	for _, k := range keys {
+		apiKeyCache.Set(k.ID, k)
+	}
+	t.Cleanup(apiKeyCache.Clear)
+}
+
+func TestRelayLogApplyBodyPolicy(t *testing.T) {
+	testAPIKeys(t,
+		model.APIKey{ID: 1, LogBodyDisabled: true},
+		model.APIKey{ID: 2, LogBodyMaxSize: 5},
+		model.APIKey{ID: 3},
+	)
+	tests := []struct {
+		name          string
+		apiKeyID      int
+		globalMax     int
+		request       string
+		wantRequest   string
+		wantResponse  string
+		wantTruncated bool
+	}{
+		{"disabled", 1, 100, "hello", "", "", false},
+		{"key limit on a character boundary", 2, 100, "中文字", "中", "ok", true},
+		{"key limit overrides global", 2, 2, "abcdef", "abcde", "ok", true},
+		{"global limit", 3, 4, "中文字", "中", "ok", true},
+		{"unknown key uses global limit", 9, 6, "中文字", "中文", "ok", true},
+		{"within limit", 2, 0, "abc", "abc", "ok", false},
+		{"no limit", 3, 0, strings.Repeat("a", 1000), strings.Repeat("a", 1000), "ok", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testSetting(t, model.SettingKeyRelayLogBodyMaxSize, strconv.Itoa(tt.globalMax))
+			relayLog := model.RelayLog{APIKeyID: tt.apiKeyID, RequestContent: tt.request, ResponseContent: "ok"}
+			truncated := relayLogApplyBodyPolicy(&relayLog)
+			if relayLog.RequestContent != tt.wantRequest || relayLog.ResponseContent != tt.wantResponse {
+				t.Errorf("content = %q / %q, want %q / %q", relayLog.RequestContent, relayLog.ResponseContent, tt.wantRequest, tt.wantResponse)
+			}
+			if truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+// 内容压缩后写入数据库，读取时解压得到原文
+func TestRelayLogBodyRoundTrip(t *testing.T) {
+	dbtest.Init(t, "log.db")
+	ctx := context.Background()
+	testRelayLogCache(t)
+
+	request := `{"messages":[{"role":"user","content":"` + strings.Repeat("你好 ", 500) + `"}]}`
+	batch := []model.RelayLog{
+		{ID: 1, Time: 100, APIKeyID: 1, RequestContent: request, ResponseContent: "ok", BodyTruncated: true},
+		{ID: 2, Time: 100, APIKeyID: 1, RequestContent: request},
+		{ID: 3, Time: 100, APIKeyID: 1},
+	}
+	bodies, err := relayLogBuildBodies(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || !batch[0].HasBody || !batch[1].HasBody || batch[2].HasBody {
+		t.Fatalf("bodies = %d, has body = %v %v %v", len(bodies), batch[0].HasBody, batch[1].HasBody, batch[2].HasBody)
+	}
+	if len(bodies[0].Request) >= len(request) {
+		t.Errorf("request not compressed: %d bytes", len(bodies[0].Request))
+	}
+	if bodies[1].Response != nil {
+		t.Errorf("empty response stored as %d bytes", len(bodies[1].Response))
+	}
+	if err := db.Conn(ctx).Create(&batch).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Conn(ctx).Create(&bodies).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []model.RelayLogBodyContent{
+		{RequestContent: request, ResponseContent: "ok", Truncated: true},
+		{RequestContent: request},
+	} {
+		id := int64(1)
+		if !want.Truncated {
+			id = 2
+		}
+		got, err := RelayLogBodyGet(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *got != want {
+			t.Errorf("log %d body = %+v", id, got)
+		}
+	}
+	if _, err := RelayLogBodyGet(ctx, 3); err == nil {
+		t.Error("log without body should return an error")
+	}
+}
+
+// API Key 单独设置的保留期优先于全局保留期，清理后日志保留但 HasBody 被清除
+func TestRelayLogBodyCleanup(t *testing.T) {
+	dbtest.Init(t, "log.db")
+	ctx := context.Background()
+	testSetting(t, model.SettingKeyRelayLogBodyKeepPeriod, "30")
+	testAPIKeys(t, model.APIKey{ID: 1, LogBodyKeepDays: 1}, model.APIKey{ID: 2}, model.APIKey{ID: 3, LogBodyKeepDays: 60})
+
+	now := time.Now()
+	daysAgo := func(days int) int64 { return now.Add(-time.Duration(days) * 24 * time.Hour).Unix() }
+	logs := []model.RelayLog{
+		{ID: 1, APIKeyID: 1, Time: daysAgo(0)},  // 保留：在 API Key 保留期内
+		{ID: 2, APIKeyID: 1, Time: daysAgo(2)},  // 删除：超过 API Key 保留期
+		{ID: 3, APIKeyID: 2, Time: daysAgo(2)},  // 保留：在全局保留期内
+		{ID: 4, APIKeyID: 2, Time: daysAgo(40)}, // 删除：超过全局保留期
+		{ID: 5, APIKeyID: 3, Time: daysAgo(40)}, // 保留：API Key 保留期比全局长
+		{ID: 6, APIKeyID: 3, Time: daysAgo(70)}, // 删除
+		{ID: 7, APIKeyID: 9, Time: daysAgo(40)}, // 删除：API Key 已删除，使用全局保留期
+	}
+	bodies := make([]model.RelayLogBody, 0, len(logs))
+	for i := range logs {
+		logs[i].HasBody = true
+		bodies = append(bodies, model.RelayLogBody{LogID: logs[i].ID, Time: logs[i].Time, APIKeyID: logs[i].APIKeyID})
+	}
+	if err := db.Conn(ctx).Create(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Conn(ctx).Create(&bodies).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := relayLogBodyCleanup(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int64{1, 3, 5}
+	var kept, flagged, remaining []int64
+	if err := db.Conn(ctx).Model(&model.RelayLogBody{}).Order("log_id").Pluck("log_id", &kept).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Conn(ctx).Model(&model.RelayLog{}).Where("has_body = ?", true).Order("id").Pluck("id", &flagged).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Conn(ctx).Model(&model.RelayLog{}).Order("id").Pluck("id", &remaining).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(kept, want) {
+		t.Errorf("bodies kept = %v, want %v", kept, want)
+	}
+	if !slices.Equal(flagged, want) {
+		t.Errorf("logs with body = %v, want %v", flagged, want)
+	}
+	if len(remaining) != len(logs) {
+		t.Errorf("logs = %v, cleanup must keep the metadata", remaining)
+	}
+}
@@ -0,0 +1,216 @@
+package op
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+type RelayLogExportFormat string
+
+const (
+	RelayLogExportFormatJSONL RelayLogExportFormat = "jsonl"
+	RelayLogExportFormatCSV   RelayLogExportFormat = "csv"
+)
+
+const relayLogExportBatchSize = 500
+
+var relayLogExportCSVHeader = []string{
+	"id", "time", "api_key_id", "inbound_type", "stream", "status_code",
+	"request_model_name", "channel", "channel_name", "actual_model_name",
+	"input_tokens", "output_tokens", "ftut", "use_time", "cost", "attempt_count", "error",
+}
+
+// relayLogExportErrorField 导出中途失败时写在末尾的标记：JSONL 为只含该字段的一行对象，CSV 为以 "#" 加该字段名开头的一行
+const relayLogExportErrorField = "export_error"
+
+// RelayLogExport 按过滤条件将日志以 JSONL 或 CSV 格式流式写出，按 ID 倒序
+// 数据库按批次游标读取，不会一次性加载全部日志；flush 不为 nil 时每批写出后调用
+// 开始写出后失败时，在已写出的内容末尾追加 relayLogExportErrorField 标记再返回错误，
+// 调用方可能已经发出了成功的状态码，读取方需以该标记判断导出是否完整
+func RelayLogExport(ctx context.Context, w io.Writer, format RelayLogExportFormat, filter model.RelayLogFilter, includeBody bool, flush func()) error {
+	var write func(relayLog *model.RelayLog) error
+	var flushFormat func() error
+	var writeError func(err error)
+	switch format {
+	case RelayLogExportFormatJSONL:
+		enc := json.NewEncoder(w)
+		write = func(relayLog *model.RelayLog) error {
+			return enc.Encode(relayLog)
+		}
+		flushFormat = func() error { return nil }
+		writeError = func(err error) {
+			enc.Encode(map[string]string{relayLogExportErrorField: err.Error()})
+		}
+	case RelayLogExportFormatCSV:
+		cw := csv.NewWriter(w)
+		header := relayLogExportCSVHeader
+		if includeBody {
+			header = append(header[:len(header):len(header)], "request_content", "response_content")
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		write = func(relayLog *model.RelayLog) error {
+			record := relayLogCSVRecord(relayLog)
+			if includeBody {
+				record = append(record, relayLog.RequestContent, relayLog.ResponseContent)
+			}
+			return cw.Write(record)
+		}
+		flushFormat = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+		writeError = func(err error) {
+			cw.Write([]string{"#" + relayLogExportErrorField, err.Error()})
+			cw.Flush()
+		}
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	err := relayLogExportRows(ctx, write, flushFormat, filter, includeBody, flush)
+	if err != nil {
+		flushFormat()
+		writeError(err)
+		if flush != nil {
+			flush()
+		}
+	}
+	return err
+}
+
+// relayLogExportRows 先写出缓存中的日志，再按游标分批写出数据库中的日志，跳过已从缓存写出的日志
+func relayLogExportRows(ctx context.Context, write func(*model.RelayLog) error, flushFormat func() error, filter model.RelayLogFilter, includeBody bool, flush func()) error {
+
+	// 缓存中尚未落库的日志最新，先导出
+	relayLogCacheLock.Lock()
+	var cachedLogs []model.RelayLog
+	for i := len(relayLogCache) - 1; i >= 0; i-- {
+		if filter.Match(&relayLogCache[i]) {
+			cachedLogs = append(cachedLogs, relayLogCache[i])
+		}
+	}
+	relayLogCacheLock.Unlock()
+
+	exported := make(map[int64]struct{}, len(cachedLogs))
+	for i := range cachedLogs {
+		relayLog := &cachedLogs[i]
+		if !includeBody {
+			relayLog.RequestContent = ""
+			relayLog.ResponseContent = ""
+		}
+		if err := write(relayLog); err != nil {
+			return err
+		}
+		exported[relayLog.ID] = struct{}{}
+	}
+
+	cursorFilter := filter
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var batch []model.RelayLog
+		query := relayLogFilterQuery(db.Conn(ctx), cursorFilter)
+		if err := query.Order("id DESC").Limit(relayLogExportBatchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if includeBody {
+			if err := relayLogAttachBodies(ctx, batch); err != nil {
+				return err
+			}
+		}
+		for i := range batch {
+			if _, ok := exported[batch[i].ID]; ok {
+				continue
+			}
+			if err := write(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if err := flushFormat(); err != nil {
+			return err
+		}
+		if flush != nil {
+			flush()
+		}
+		if len(batch) < relayLogExportBatchSize {
+			break
+		}
+		lastID := batch[len(batch)-1].ID
+		cursorFilter.Cursor = &lastID
+	}
+	return flushFormat()
+}
+
+// relayLogAttachBodies 为一批日志加载并解压请求/响应内容
+func relayLogAttachBodies(ctx context.Context, batch []model.RelayLog) error {
+	ids := make([]int64, 0, len(batch))
+	for _, relayLog := range batch {
+		if relayLog.HasBody {
+			ids = append(ids, relayLog.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var bodies []model.RelayLogBody
+	if err := db.Conn(ctx).Where("log_id IN ?", ids).Find(&bodies).Error; err != nil {
+		return err
+	}
+	byID := make(map[int64]*model.RelayLogBody, len(bodies))
+	for i := range bodies {
+		byID[bodies[i].LogID] = &bodies[i]
+	}
+	for i := range batch {
+		body, ok := byID[batch[i].ID]
+		if !ok {
+			continue
+		}
+		request, err := gzipDecompress(body.Request)
+		if err != nil {
+			return err
+		}
+		response, err := gzipDecompress(body.Response)
+		if err != nil {
+			return err
+		}
+		batch[i].RequestContent = request
+		batch[i].ResponseContent = response
+		batch[i].BodyTruncated = body.Truncated
+	}
+	return nil
+}
+
+func relayLogCSVRecord(l *model.RelayLog) []string {
+	return []string{
+		strconv.FormatInt(l.ID, 10),
+		strconv.FormatInt(l.Time, 10),
+		strconv.Itoa(l.APIKeyID),
+		strconv.Itoa(l.InboundType),
+		strconv.FormatBool(l.Stream),
+		strconv.Itoa(l.StatusCode),
+		l.RequestModelName,
+		strconv.Itoa(l.ChannelId),
+		l.ChannelName,
+		l.ActualModelName,
+		strconv.Itoa(l.InputTokens),
+		strconv.Itoa(l.OutputTokens),
+		strconv.Itoa(l.Ftut),
+		strconv.Itoa(l.UseTime),
+		strconv.FormatFloat(l.Cost, 'f', -1, 64),
+		strconv.Itoa(l.AttemptCount),
+		l.Error,
+	}
+}
@@ -0,0 +1,278 @@
+package op
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+// testExportLog 生成字段各不相同的日志，用于覆盖各个过滤条件
+func testExportLog(id int) model.RelayLog {
+	l := model.RelayLog{
+		ID:               int64(id),
+		Time:             int64(100 + id),
+		APIKeyID:         id % 3,
+		ChannelId:        id % 4,
+		ChannelName:      fmt.Sprintf("channel-%d", id%4),
+		RequestModelName: fmt.Sprintf("m%d", id%2),
+		ActualModelName:  fmt.Sprintf("actual-%d", id%3),
+		Cost:             float64(id) / 10,
+		Attempts:         []model.RelayAttempt{{ChannelID: id % 4}},
+	}
+	if id%5 == 0 {
+		l.Error = "upstream failed"
+		l.Attempts = append(l.Attempts, model.RelayAttempt{ChannelID: (id + 1) % 4})
+	}
+	l.AttemptCount = len(l.Attempts)
+	return l
+}
+
+// exportIDs 以 JSONL 导出并返回日志 ID
+func exportIDs(t *testing.T, filter model.RelayLogFilter, flush func()) []int64 {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := RelayLogExport(context.Background(), &buf, RelayLogExportFormatJSONL, filter, false, flush); err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var l model.RelayLog
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, l.ID)
+	}
+	return ids
+}
+
+// 导出与列表使用相同的过滤条件，包括缓存中尚未落库的日志
+func TestRelayLogExportFilterParity(t *testing.T) {
+	dbtest.Init(t, "log.db")
+	testSetting(t, model.SettingKeyRelayLogKeepEnabled, "true")
+	var stored, cached []model.RelayLog
+	for id := 1; id <= 20; id++ {
+		stored = append(stored, testExportLog(id))
+	}
+	for id := 21; id <= 25; id++ {
+		cached = append(cached, testExportLog(id))
+	}
+	if err := db.GetDB().Create(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	testRelayLogCache(t, cached...)
+
+	intp := func(v int) *int { return &v }
+	filters := map[string]model.RelayLogFilter{
+		"none":          {},
+		"cursor":        {Cursor: func() *int64 { v := int64(22); return &v }()},
+		"time":          {StartTime: intp(105), EndTime: intp(122)},
+		"model":         {Model: "m1"},
+		"actual model":  {Model: "actual-2"},
+		"channel":       {ChannelID: intp(2)},
+		"api key":       {APIKeyID: intp(1)},
+		"success":       {Success: func() *bool { v := true; return &v }()},
+		"error":         {Success: func() *bool { v := false; return &v }()},
+		"cost":          {MinCost: func() *float64 { v := 0.5; return &v }(), MaxCost: func() *float64 { v := 2.1; return &v }()},
+		"keyword":       {Keyword: "channel-3"},
+		"attempt":       {AttemptChannelID: intp(1)},
+		"failover":      {Failover: func() *bool { v := true; return &v }()},
+		"no failover":   {Failover: func() *bool { v := false; return &v }()},
+		"combined":      {Model: "m0", Success: func() *bool { v := false; return &v }()},
+		"nothing match": {Model: "missing"},
+	}
+	for name, filter := range filters {
+		t.Run(name, func(t *testing.T) {
+			list, err := RelayLogList(context.Background(), filter, 1, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []int64
+			for _, l := range list {
+				want = append(want, l.ID)
+			}
+			if got := exportIDs(t, filter, nil); !slices.Equal(got, want) {
+				t.Errorf("export = %v, list = %v", got, want)
+			}
+		})
+	}
+}
+
+// 写入数据库后尚未从缓存移除的日志只导出一次
+func TestRelayLogExportDedupe(t *testing.T) {
+	dbtest.Init(t, "log.db")
+	stored := []model.RelayLog{testExportLog(1), testExportLog(2), testExportLog(3)}
+	if err := db.GetDB().Create(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	testRelayLogCache(t, testExportLog(3), testExportLog(4))
+
+	if got, want := exportIDs(t, model.RelayLogFilter{}, nil), []int64{4, 3, 2, 1}; !slices.Equal(got, want) {
+		t.Errorf("export = %v, want %v", got, want)
+	}
+}
+
+// 跨越批次边界时每条日志恰好导出一次，按 ID 倒序，每批之后调用 flush
+func TestRelayLogExportBatches(t *testing.T) {
+	dbtest.Init(t, "log.db")
+	testRelayLogCache(t)
+	n := relayLogExportBatchSize*2 + 3
+	logs := make([]model.RelayLog, 0, n)
+	for id := 1; id <= n; id++ {
+		logs = append(logs, model.RelayLog{ID: int64(id), Time: int64(id)})
+	}
+	if err := db.GetDB().CreateInBatches(&logs, 200).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	flushes := 0
+	got := exportIDs(t, model.RelayLogFilter{}, func() { flushes++ })
+	if len(got) != n {
+		t.Fatalf("exported %d logs, want %d", len(got), n)
+	}
+	for i, id := range got {
+		if id != int64(n-i) {
+			t.Fatalf("log %d = %d, want %d", i, id, n-i)
+		}
+	}
+	if flushes != 3 {
+		t.Errorf("flushes = %d, want 3", flushes)
+	}
+
+	// 总数恰好是批次大小的整数倍时，最后一次查询为空
+	if err := db.GetDB().Where("id > ?", relayLogExportBatchSize*2).Delete(&model.RelayLog{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := exportIDs(t, model.RelayLogFilter{}, nil); len(got) != relayLogExportBatchSize*2 {
+		t.Errorf("exported %d logs, want %d", len(got), relayLogExportBatchSize*2)
+	}
+}
+
+func TestRelayLogExportCSV(t *testing.T) {
+	dbtest.Init(t, "log.db")
+	ctx := context.Background()
+	request := "{\"q\":\"a, \\\"b\\\"\"}\nline 2"
+	stored := []model.RelayLog{
+		{ID: 1, Time: 1, ChannelName: "a,b", Error: "quota \"exceeded\",\nretry later", RequestContent: request, ResponseContent: "ok"},
+		{ID: 2, Time: 2, RequestModelName: "m"},
+	}
+	bodies, err := relayLogBuildBodies(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Conn(ctx).Create(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Conn(ctx).Create(&bodies).Error; err != nil {
+		t.Fatal(err)
+	}
+	cached := model.RelayLog{ID: 3, Time: 3, Error: "cached", RequestContent: "cached request"}
+	testRelayLogCache(t, cached)
+
+	for _, includeBody := range []bool{false, true} {
+		t.Run(fmt.Sprintf("include_body=%v", includeBody), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := RelayLogExport(ctx, &buf, RelayLogExportFormatCSV, model.RelayLogFilter{}, includeBody, nil); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), `"quota ""exceeded"",`) {
+				t.Errorf("quotes not escaped:\n%s", buf.String())
+			}
+			records, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			columns := len(relayLogExportCSVHeader)
+			if includeBody {
+				columns += 2
+			}
+			if len(records) != 4 {
+				t.Fatalf("records = %d, want header and 3 logs", len(records))
+			}
+			for _, record := range records {
+				if len(record) != columns {
+					t.Fatalf("record %v has %d columns, want %d", record, len(record), columns)
+				}
+			}
+			col := func(name string) int { return slices.Index(records[0], name) }
+			if records[1][col("id")] != "3" || records[2][col("id")] != "2" || records[3][col("id")] != "1" {
+				t.Errorf("order = %s %s %s", records[1][0], records[2][0], records[3][0])
+			}
+			if got := records[3][col("error")]; got != stored[0].Error {
+				t.Errorf("error = %q, want %q", got, stored[0].Error)
+			}
+			if got := records[3][col("channel_name")]; got != "a,b" {
+				t.Errorf("channel name = %q", got)
+			}
+			if !includeBody {
+				return
+			}
+			if got := records[3][col("request_content")]; got != request {
+				t.Errorf("request = %q, want %q", got, request)
+			}
+			if got := records[3][col("response_content")]; got != "ok" {
+				t.Errorf("response = %q", got)
+			}
+			if got := records[1][col("request_content")]; got != cached.RequestContent {
+				t.Errorf("cached request = %q", got)
+			}
+			if got := records[2][col("request_content")]; got != "" {
+				t.Errorf("log without body request = %q", got)
+			}
+		})
+	}
+}
+
+// 开始写出后失败时，已写出的内容之后追加失败标记
+func TestRelayLogExportErrorMarker(t *testing.T) {
+	dbtest.Init(t, "log.db")
+	testRelayLogCache(t, testExportLog(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("jsonl", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := RelayLogExport(ctx, &buf, RelayLogExportFormatJSONL, model.RelayLogFilter{}, false, nil); err == nil {
+			t.Fatal("export should fail")
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("lines = %q", lines)
+		}
+		var marker map[string]string
+		if err := json.Unmarshal([]byte(lines[1]), &marker); err != nil {
+			t.Fatal(err)
+		}
+		if marker[relayLogExportErrorField] != context.Canceled.Error() {
+			t.Errorf("marker = %v", marker)
+		}
+	})
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := RelayLogExport(ctx, &buf, RelayLogExportFormatCSV, model.RelayLogFilter{}, false, nil); err == nil {
+			t.Fatal("export should fail")
+		}
+		r := csv.NewReader(&buf)
+		r.FieldsPerRecord = -1
+		records, err := r.ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 3 {
+			t.Fatalf("records = %v, want header, log and marker", records)
+		}
+		if want := []string{"#" + relayLogExportErrorField, context.Canceled.Error()}; !slices.Equal(records[2], want) {
+			t.Errorf("marker = %v, want %v", records[2], want)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package op
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+func TestRelayLogFilterAttemptChannel(t *testing.T) {
+	conn := dbtest.Open(t, "log.db")
+	logs := []model.RelayLog{
+		{ID: 1, Attempts: []model.RelayAttempt{{ChannelID: 3}, {ChannelID: 12}}},
+		{ID: 2, Attempts: []model.RelayAttempt{{ChannelID: 31}}},
+		{ID: 3, Attempts: []model.RelayAttempt{{ChannelID: 1}}},
+		{ID: 4},
+	}
+	if err := conn.Create(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+	// channel_id 为对象最后一个字段时也能匹配
+	if err := conn.Exec(`UPDATE relay_logs SET attempts = ? WHERE id = 4`, `[{"status_code":502,"channel_id":3}]`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 按实际的结构体序列化，其他字段中看起来像 channel_id 的内容不能被匹配
+	attempts, err := json.Marshal([]model.RelayAttempt{{
+		ChannelID:   5,
+		ChannelName: `"channel_id":7,`,
+		ModelName:   "channelXid",
+		Error:       `upstream said {"channel_id":8}`,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Create(&model.RelayLog{ID: 5}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Exec(`UPDATE relay_logs SET attempts = ? WHERE id = 5`, string(attempts)).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 字段名的下划线不能当作通配符匹配任意字符
+	if err := conn.Create(&model.RelayLog{ID: 6}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Exec(`UPDATE relay_logs SET attempts = ? WHERE id = 6`, `[{"channelXid":9,"status_code":0}]`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		channel int
+		want    []int64
+	}{
+		{3, []int64{1, 4}},
+		{12, []int64{1}},
+		{1, []int64{3}},
+		{31, []int64{2}},
+		{5, []int64{5}},
+		{7, nil},
+		{8, nil},
+		{9, nil},
+		{99, nil},
+	}
+	var stored []model.RelayLog
+	if err := conn.Order("id").Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		filter := model.RelayLogFilter{AttemptChannelID: &tt.channel}
+		var got []int64
+		query := relayLogFilterQuery(conn.Model(&model.RelayLog{}), filter)
+		if err := query.Order("id").Pluck("id", &got).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("channel %d: got %v, want %v", tt.channel, got, tt.want)
+		}
+		// 与内存缓存的过滤结果一致
+		var matched []int64
+		for i := range stored {
+			if filter.Match(&stored[i]) {
+				matched = append(matched, stored[i].ID)
+			}
+		}
+		if !slices.Equal(matched, tt.want) {
+			t.Errorf("channel %d: cache match %v, want %v", tt.channel, matched, tt.want)
+		}
+	}
+}
+
+func TestRelayLogFilterKeywordEscapesWildcards(t *testing.T) {
+	conn := dbtest.Open(t, "log.db")
+	logs := []model.RelayLog{
+		{ID: 1, RequestModelName: "gpt_4o"},
+		{ID: 2, RequestModelName: "gpt-4o"},
+		{ID: 3, Error: "quota 100% used"},
+		{ID: 4, Error: "quota 1000 used"},
+		{ID: 5, ChannelName: "a!b"},
+	}
+	if err := conn.Create(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		keyword string
+		want    []int64
+	}{
+		{"gpt_4o", []int64{1}},
+		{"GPT", []int64{1, 2}},
+		{"100%", []int64{3}},
+		{"a!b", []int64{5}},
+		{"%", []int64{3}},
+	}
+	for _, tt := range tests {
+		var got []int64
+		query := relayLogFilterQuery(conn.Model(&model.RelayLog{}), model.RelayLogFilter{Keyword: tt.keyword})
+		if err := query.Order("id").Pluck("id", &got).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("keyword %q: got %v, want %v", tt.keyword, got, tt.want)
+		}
+	}
+}
@@ -2,16 +2,19 @@ package op
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"sync"
 
 	"github.com/bestruirui/octopus/internal/db"
 	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/ahocorasick"
 	"github.com/bestruirui/octopus/internal/utils/log"
+	"gorm.io/gorm"
 )
 
 var (
-	sensitiveRulesCache     []*CompiledRule
+	sensitivePolicyCache    map[int]*SensitivePolicy // 规则集 ID → 生效的规则，0 为默认规则集
 	sensitiveRulesCacheLock sync.RWMutex
 	sensitiveFilterEnabled  bool
 )
@@ -20,6 +23,19 @@ var (
 type CompiledRule struct {
 	Rule  *model.SensitiveFilterRule
 	Regex *regexp.Regexp
+
+	literals []string // 每个匹配都必须包含其中之一的字面量，为空时无法预过滤
+	expand   bool     // 替换文本包含 $ 引用，需要展开捕获组
+}
+
+// SensitivePolicy 一个规则集实际生效的规则，按优先级从高到低排序
+type SensitivePolicy struct {
+	rules []*CompiledRule
+
+	matcher  *ahocorasick.Matcher // 规则必需字面量的多模式匹配器
+	always   []int                // 没有必需字面量、总是需要执行的规则
+	request  bool                 // 是否存在作用于请求的规则
+	response bool                 // 是否存在作用于响应的规则
 }
 
 // SensitiveFilterInit 初始化敏感过滤规则
@@ -46,9 +62,7 @@ func SensitiveFilterRefresh() {
 	// 获取全局开关状态
 	enabled, err := SettingGetBool(model.SettingKeySensitiveFilterEnabled)
 	if err != nil {
-		sensitiveFilterEnabled = true // 默认启用
-	} else {
-		sensitiveFilterEnabled = enabled
+		enabled = true // 默认启用
 	}
 
 	// 加载启用的规则
@@ -57,25 +71,55 @@ func SensitiveFilterRefresh() {
 		log.Warnf("failed to load sensitive filter rules: %v", err)
 		return
 	}
+	var ruleSets []model.SensitiveRuleSet
+	if err := db.GetDB().Find(&ruleSets).Error; err != nil {
+		log.Warnf("failed to load sensitive rule sets: %v", err)
+		return
+	}
 
 	compiled := make([]*CompiledRule, 0, len(rules))
 	for i := range rules {
-		regex, err := regexp.Compile(rules[i].Pattern)
+		rule, err := compileSensitiveRule(&rules[i])
 		if err != nil {
 			log.Warnf("invalid regex pattern for rule %s: %v", rules[i].Name, err)
 			continue
 		}
-		compiled = append(compiled, &CompiledRule{
-			Rule:  &rules[i],
-			Regex: regex,
-		})
+		compiled = append(compiled, rule)
+	}
+
+	// 规则集包含自身的规则，IncludeDefault 时再加上默认规则集的规则，保持优先级顺序
+	ruleSetRules := map[int][]*CompiledRule{0: nil}
+	for _, ruleSet := range ruleSets {
+		ruleSetRules[ruleSet.ID] = nil
+	}
+	includeDefault := make(map[int]bool, len(ruleSets))
+	for _, ruleSet := range ruleSets {
+		includeDefault[ruleSet.ID] = ruleSet.IncludeDefault
+	}
+	for _, rule := range compiled {
+		if rule.Rule.RuleSetID == 0 {
+			for id := range ruleSetRules {
+				if id == 0 || includeDefault[id] {
+					ruleSetRules[id] = append(ruleSetRules[id], rule)
+				}
+			}
+			continue
+		}
+		if _, ok := ruleSetRules[rule.Rule.RuleSetID]; ok {
+			ruleSetRules[rule.Rule.RuleSetID] = append(ruleSetRules[rule.Rule.RuleSetID], rule)
+		}
+	}
+	policies := make(map[int]*SensitivePolicy, len(ruleSetRules))
+	for id, rules := range ruleSetRules {
+		policies[id] = newSensitivePolicy(rules)
 	}
 
 	sensitiveRulesCacheLock.Lock()
-	sensitiveRulesCache = compiled
+	sensitiveFilterEnabled = enabled
+	sensitivePolicyCache = policies
 	sensitiveRulesCacheLock.Unlock()
 
-	log.Infof("loaded %d sensitive filter rules, global enabled: %v", len(compiled), sensitiveFilterEnabled)
+	log.Infof("loaded %d sensitive filter rules in %d rule sets, global enabled: %v", len(compiled), len(ruleSets), enabled)
 }
 
 // SensitiveFilterGetEnabled 获取是否启用过滤
@@ -85,32 +129,163 @@ func SensitiveFilterGetEnabled() bool {
 	return sensitiveFilterEnabled
 }
 
-// SensitiveFilterText 过滤文本中的敏感信息
-func SensitiveFilterText(text string) (string, int) {
+// SensitivePolicyGet 获取规则集生效的规则，规则集不存在时使用默认规则集，全局关闭时返回空策略
+func SensitivePolicyGet(ruleSetID int) *SensitivePolicy {
 	sensitiveRulesCacheLock.RLock()
-	rules := sensitiveRulesCache
-	enabled := sensitiveFilterEnabled
-	sensitiveRulesCacheLock.RUnlock()
+	defer sensitiveRulesCacheLock.RUnlock()
+	if !sensitiveFilterEnabled {
+		return &SensitivePolicy{}
+	}
+	if policy, ok := sensitivePolicyCache[ruleSetID]; ok {
+		return policy
+	}
+	if policy, ok := sensitivePolicyCache[0]; ok {
+		return policy
+	}
+	return &SensitivePolicy{}
+}
+
+// SensitivePolicyResolve 解析请求生效的规则集：优先使用 API Key 的规则集，其次使用分组的规则集，都未设置时使用默认规则集
+func SensitivePolicyResolve(apiKeyID int, groupID int) *SensitivePolicy {
+	if apiKey, ok := apiKeyCache.Get(apiKeyID); ok && apiKey.SensitiveRuleSetID > 0 {
+		return SensitivePolicyGet(apiKey.SensitiveRuleSetID)
+	}
+	if group, ok := groupCache.Get(groupID); ok && group.SensitiveRuleSetID > 0 {
+		return SensitivePolicyGet(group.SensitiveRuleSetID)
+	}
+	return SensitivePolicyGet(0)
+}
+
+// Rule 获取策略中的规则，不存在时返回 nil
+func (p *SensitivePolicy) Rule(id int) *model.SensitiveFilterRule {
+	for _, rule := range p.rules {
+		if rule.Rule.ID == id {
+			return rule.Rule
+		}
+	}
+	return nil
+}
+
+// HasRules 判断指定方向是否存在生效的规则
+func (p *SensitivePolicy) HasRules(direction model.SensitiveDirection) bool {
+	switch direction {
+	case model.SensitiveDirectionRequest:
+		return p.request
+	case model.SensitiveDirectionResponse:
+		return p.response
+	default:
+		return p.request || p.response
+	}
+}
+
+// SensitiveMaskFunc 为掩码规则的一次命中生成替换内容
+type SensitiveMaskFunc func(rule *model.SensitiveFilterRule, match string) string
+
+// SensitiveFilterResult 累计一次或多次过滤的命中情况
+type SensitiveFilterResult struct {
+	Hits    map[int]int64              // 规则 ID → 命中次数
+	Blocked *model.SensitiveFilterRule // 第一条命中的拦截规则
+}
 
-	if !enabled || len(rules) == 0 {
-		return text, 0
+func (r *SensitiveFilterResult) hit(rule *model.SensitiveFilterRule, count int) {
+	if r.Hits == nil {
+		r.Hits = make(map[int]int64)
 	}
+	r.Hits[rule.ID] += int64(count)
+	if r.Blocked == nil && rule.Action == model.SensitiveActionBlock {
+		r.Blocked = rule
+	}
+}
 
-	count := 0
-	result := text
-	for _, rule := range rules {
-		if rule.Regex.MatchString(result) {
-			result = rule.Regex.ReplaceAllString(result, rule.Rule.Replacement)
-			count++
+// Merge 合并另一次过滤的命中情况
+func (r *SensitiveFilterResult) Merge(other *SensitiveFilterResult) {
+	for id, count := range other.Hits {
+		if r.Hits == nil {
+			r.Hits = make(map[int]int64)
 		}
+		r.Hits[id] += count
+	}
+	if r.Blocked == nil {
+		r.Blocked = other.Blocked
 	}
-	return result, count
+}
+
+// Total 返回所有规则的命中次数之和
+func (r *SensitiveFilterResult) Total() int {
+	total := 0
+	for _, count := range r.Hits {
+		total += int(count)
+	}
+	return total
+}
+
+// SensitiveFilterText 使用默认规则集过滤文本中的敏感信息，掩码规则按替换文本处理，返回命中次数
+func SensitiveFilterText(text string, direction model.SensitiveDirection) (string, int) {
+	var result SensitiveFilterResult
+	filtered := SensitivePolicyGet(0).Apply(text, direction, nil, &result)
+	return filtered, result.Total()
+}
+
+// Apply 按规则的处理方式过滤文本，命中情况累计到 result
+// mask 不为 nil 时，掩码规则的每个命中由 mask 生成替换内容；为 nil 时掩码规则按替换文本处理
+// 拦截和仅记录规则不修改文本；没有任何规则命中时直接返回原字符串，不产生拷贝
+func (p *SensitivePolicy) Apply(text string, direction model.SensitiveDirection, mask SensitiveMaskFunc, result *SensitiveFilterResult) string {
+	if text == "" || !p.HasRules(direction) {
+		return text
+	}
+	cand, found := p.candidates(text)
+	if !found {
+		return text
+	}
+	for i, rule := range p.rules {
+		if cand[i] < 0 || !rule.Rule.AppliesTo(direction) {
+			continue
+		}
+		var matches [][]int
+		if rule.expand && !(mask != nil && rule.Rule.Action == model.SensitiveActionMask) {
+			matches = rule.Regex.FindAllStringSubmatchIndex(text, -1)
+		} else {
+			matches = rule.Regex.FindAllStringIndex(text, -1)
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		result.hit(rule.Rule, len(matches))
+		if rule.Rule.Action == model.SensitiveActionBlock || rule.Rule.Action == model.SensitiveActionLog {
+			continue
+		}
+		start := matches[0][0]
+		text = rule.replace(text, matches, mask)
+		// 替换内容可能让后续规则命中或不再命中，从第一个替换位置继续扫描，之前的部分未变
+		if i+1 < len(p.rules) {
+			p.rescan(text, start, i, cand)
+		}
+	}
+	return text
+}
+
+// Matches 返回文本中所有规则命中的位置 [start, end)，用于流式场景确定安全的输出边界
+func (p *SensitivePolicy) Matches(text string, direction model.SensitiveDirection) [][]int {
+	if text == "" || !p.HasRules(direction) {
+		return nil
+	}
+	cand, found := p.candidates(text)
+	if !found {
+		return nil
+	}
+	var matches [][]int
+	for i, rule := range p.rules {
+		if cand[i] >= 0 && rule.Rule.AppliesTo(direction) {
+			matches = append(matches, rule.Regex.FindAllStringIndex(text, -1)...)
+		}
+	}
+	return matches
 }
 
 // SensitiveFilterRuleList 获取所有规则
 func SensitiveFilterRuleList(ctx context.Context) ([]model.SensitiveFilterRule, error) {
 	var rules []model.SensitiveFilterRule
-	if err := db.GetDB().WithContext(ctx).Order("priority DESC, id ASC").Find(&rules).Error; err != nil {
+	if err := db.Conn(ctx).Order("priority DESC, id ASC").Find(&rules).Error; err != nil {
 		return nil, err
 	}
 	return rules, nil
@@ -122,8 +297,14 @@ func SensitiveFilterRuleCreate(rule *model.SensitiveFilterRule, ctx context.Cont
 	if _, err := regexp.Compile(rule.Pattern); err != nil {
 		return err
 	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if err := sensitiveRuleSetCheck(rule.RuleSetID, ctx); err != nil {
+		return err
+	}
 	rule.BuiltIn = false
-	if err := db.GetDB().WithContext(ctx).Create(rule).Error; err != nil {
+	if err := db.Conn(ctx).Create(rule).Error; err != nil {
 		return err
 	}
 	SensitiveFilterRefresh()
@@ -132,25 +313,46 @@ func SensitiveFilterRuleCreate(rule *model.SensitiveFilterRule, ctx context.Cont
 
 // SensitiveFilterRuleUpdate 更新规则
 func SensitiveFilterRuleUpdate(rule *model.SensitiveFilterRule, ctx context.Context) error {
-	// 验证正则表达式
-	if _, err := regexp.Compile(rule.Pattern); err != nil {
-		return err
-	}
-
-	// 内置规则只能修改 Enabled
 	var existing model.SensitiveFilterRule
-	if err := db.GetDB().WithContext(ctx).First(&existing, rule.ID).Error; err != nil {
+	if err := db.Conn(ctx).First(&existing, rule.ID).Error; err != nil {
 		return err
 	}
 
+	// 内置规则只能修改 Enabled、Direction 和 Action，始终属于默认规则集
+	// 未提交的字段沿用原值，先合并再校验，避免作用于双向的内置规则被改为拦截或掩码
 	if existing.BuiltIn {
-		if err := db.GetDB().WithContext(ctx).Model(rule).Update("enabled", rule.Enabled).Error; err != nil {
+		merged := existing
+		merged.Enabled = rule.Enabled
+		if rule.Direction != "" {
+			merged.Direction = rule.Direction
+		}
+		if rule.Action != "" {
+			merged.Action = rule.Action
+		}
+		if err := merged.Validate(); err != nil {
 			return err
 		}
-	} else {
-		if err := db.GetDB().WithContext(ctx).Save(rule).Error; err != nil {
+		updates := map[string]any{"enabled": merged.Enabled, "direction": merged.Direction, "action": merged.Action}
+		if err := db.Conn(ctx).Model(&existing).Updates(updates).Error; err != nil {
 			return err
 		}
+		*rule = merged
+		SensitiveFilterRefresh()
+		return nil
+	}
+
+	// 验证正则表达式
+	if _, err := regexp.Compile(rule.Pattern); err != nil {
+		return err
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if err := sensitiveRuleSetCheck(rule.RuleSetID, ctx); err != nil {
+		return err
+	}
+	if err := db.Conn(ctx).Save(rule).Error; err != nil {
+		return err
 	}
 	SensitiveFilterRefresh()
 	return nil
@@ -160,14 +362,17 @@ func SensitiveFilterRuleUpdate(rule *model.SensitiveFilterRule, ctx context.Cont
 func SensitiveFilterRuleDelete(id int, ctx context.Context) error {
 	// 内置规则不能删除
 	var rule model.SensitiveFilterRule
-	if err := db.GetDB().WithContext(ctx).First(&rule, id).Error; err != nil {
+	if err := db.Conn(ctx).First(&rule, id).Error; err != nil {
 		return err
 	}
 	if rule.BuiltIn {
 		return nil // 静默忽略
 	}
 
-	if err := db.GetDB().WithContext(ctx).Delete(&model.SensitiveFilterRule{}, id).Error; err != nil {
+	if err := db.Conn(ctx).Delete(&model.SensitiveFilterRule{}, id).Error; err != nil {
+		return err
+	}
+	if err := StatsSensitiveRuleDel(id); err != nil {
 		return err
 	}
 	SensitiveFilterRefresh()
@@ -176,7 +381,99 @@ func SensitiveFilterRuleDelete(id int, ctx context.Context) error {
 
 // SensitiveFilterRuleToggle 切换规则启用状态
 func SensitiveFilterRuleToggle(id int, enabled bool, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Model(&model.SensitiveFilterRule{}).Where("id = ?", id).Update("enabled", enabled).Error; err != nil {
+	if err := db.Conn(ctx).Model(&model.SensitiveFilterRule{}).Where("id = ?", id).Update("enabled", enabled).Error; err != nil {
+		return err
+	}
+	SensitiveFilterRefresh()
+	return nil
+}
+
+// sensitiveRuleSetCheck 检查规则集是否存在，0 为默认规则集
+func sensitiveRuleSetCheck(ruleSetID int, ctx context.Context) error {
+	if ruleSetID == 0 {
+		return nil
+	}
+	var count int64
+	if err := db.Conn(ctx).Model(&model.SensitiveRuleSet{}).Where("id = ?", ruleSetID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("sensitive rule set %d not found", ruleSetID)
+	}
+	return nil
+}
+
+// SensitiveRuleSetList 获取所有规则集
+func SensitiveRuleSetList(ctx context.Context) ([]model.SensitiveRuleSet, error) {
+	var ruleSets []model.SensitiveRuleSet
+	if err := db.Conn(ctx).Order("id ASC").Find(&ruleSets).Error; err != nil {
+		return nil, err
+	}
+	return ruleSets, nil
+}
+
+// SensitiveRuleSetCreate 创建规则集
+func SensitiveRuleSetCreate(ruleSet *model.SensitiveRuleSet, ctx context.Context) error {
+	if err := db.Conn(ctx).Create(ruleSet).Error; err != nil {
+		return err
+	}
+	SensitiveFilterRefresh()
+	return nil
+}
+
+// SensitiveRuleSetUpdate 更新规则集
+func SensitiveRuleSetUpdate(ruleSet *model.SensitiveRuleSet, ctx context.Context) error {
+	if ruleSet.ID == 0 {
+		return fmt.Errorf("sensitive rule set id is required")
+	}
+	if err := sensitiveRuleSetCheck(ruleSet.ID, ctx); err != nil {
+		return err
+	}
+	if err := db.Conn(ctx).Save(ruleSet).Error; err != nil {
+		return err
+	}
+	SensitiveFilterRefresh()
+	return nil
+}
+
+// SensitiveRuleSetDelete 删除规则集及其规则，引用该规则集的 API Key 和分组改为使用默认规则集
+func SensitiveRuleSetDelete(id int, ctx context.Context) error {
+	var ruleIDs []int
+	err := db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.SensitiveFilterRule{}).Where("rule_set_id = ?", id).Pluck("id", &ruleIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("rule_set_id = ?", id).Delete(&model.SensitiveFilterRule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.APIKey{}).Where("sensitive_rule_set_id = ?", id).Update("sensitive_rule_set_id", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Group{}).Where("sensitive_rule_set_id = ?", id).Update("sensitive_rule_set_id", 0).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&model.SensitiveRuleSet{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("sensitive rule set %d not found", id)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ruleID := range ruleIDs {
+		if err := StatsSensitiveRuleDel(ruleID); err != nil {
+			return err
+		}
+	}
+	if err := apiKeyRefreshCache(ctx); err != nil {
+		return err
+	}
+	if err := groupRefreshCache(ctx); err != nil {
 		return err
 	}
 	SensitiveFilterRefresh()
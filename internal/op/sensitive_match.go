@@ -0,0 +1,323 @@
+package op
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/ahocorasick"
+)
+
+const (
+	// sensitivePrefixMaxCount 单条规则最多提取的字面量数量，超过时放弃预过滤
+	sensitivePrefixMaxCount = 64
+	// sensitivePrefixMaxLen 字面量的最大长度，足够区分规则即可
+	sensitivePrefixMaxLen = 16
+	// sensitiveClassMaxSize 可以展开为前缀的字符类最大字符数
+	sensitiveClassMaxSize = 16
+)
+
+// compileSensitiveRule 编译规则并提取用于预过滤的必需字面量
+func compileSensitiveRule(rule *model.SensitiveFilterRule) (*CompiledRule, error) {
+	regex, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiled := &CompiledRule{
+		Rule:   rule,
+		Regex:  regex,
+		expand: strings.Contains(rule.Replacement, "$"),
+	}
+	if re, err := syntax.Parse(rule.Pattern, syntax.Perl); err == nil {
+		compiled.literals = requiredLiterals(re.Simplify())
+	}
+	return compiled, nil
+}
+
+// newSensitivePolicy 根据按优先级排序的规则构建策略和必需字面量的多模式预过滤器
+func newSensitivePolicy(rules []*CompiledRule) *SensitivePolicy {
+	p := &SensitivePolicy{rules: rules}
+	var patterns []string
+	var values []int
+	for i, rule := range rules {
+		if rule.Rule.AppliesTo(model.SensitiveDirectionRequest) {
+			p.request = true
+		}
+		if rule.Rule.AppliesTo(model.SensitiveDirectionResponse) {
+			p.response = true
+		}
+		if len(rule.literals) == 0 {
+			p.always = append(p.always, i)
+			continue
+		}
+		for _, literal := range rule.literals {
+			patterns = append(patterns, literal)
+			values = append(values, i)
+		}
+	}
+	if len(patterns) > 0 {
+		p.matcher = ahocorasick.New(patterns, values)
+	}
+	return p
+}
+
+// candidates 扫描一次文本，返回每条规则必需字面量首次出现的结束位置，-1 表示不可能命中
+// 没有必需字面量的规则总是候选，位置记为 0
+func (p *SensitivePolicy) candidates(text string) ([]int, bool) {
+	cand := make([]int, len(p.rules))
+	for i := range cand {
+		cand[i] = -1
+	}
+	for _, i := range p.always {
+		cand[i] = 0
+	}
+	found := len(p.always) > 0
+	if p.matcher != nil && p.matcher.Mark(text, 0, cand) > 0 {
+		found = true
+	}
+	return cand, found
+}
+
+// rescan 规则 done 从 start 开始替换文本后更新后续规则的候选
+// 字面量在 start 之前结束的位置不受替换影响，其余规则从 start 向前回退最长字面量的距离继续扫描
+func (p *SensitivePolicy) rescan(text string, start int, done int, cand []int) {
+	if p.matcher == nil {
+		return
+	}
+	for i := range cand {
+		switch {
+		case i <= done:
+			// 已执行的规则不再需要扫描
+			cand[i] = 0
+		case cand[i] > start:
+			cand[i] = -1
+		}
+	}
+	p.matcher.Mark(text, max(0, start-p.matcher.MaxLen()+1), cand)
+}
+
+// replace 按命中位置替换文本，matches 为 FindAllStringIndex 或 FindAllStringSubmatchIndex 的结果
+func (r *CompiledRule) replace(text string, matches [][]int, mask SensitiveMaskFunc) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	last := 0
+	var dst []byte
+	for _, m := range matches {
+		b.WriteString(text[last:m[0]])
+		switch {
+		case mask != nil && r.Rule.Action == model.SensitiveActionMask:
+			b.WriteString(mask(r.Rule, text[m[0]:m[1]]))
+		case r.expand:
+			dst = r.Regex.ExpandString(dst[:0], r.Rule.Replacement, text, m)
+			b.Write(dst)
+		default:
+			b.WriteString(r.Rule.Replacement)
+		}
+		last = m[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// requiredLiterals 提取正则每个匹配都必须包含其中之一的字面量集合（ASCII 大小写不敏感）
+// 优先选择最短字面量最长的集合；无法提取时返回 nil，此时规则不参与预过滤
+func requiredLiterals(re *syntax.Regexp) []string {
+	set, ok := requiredSet(re)
+	if !ok || len(set) == 0 {
+		return nil
+	}
+	for _, literal := range set {
+		if literal == "" {
+			return nil
+		}
+	}
+	return set
+}
+
+// requiredSet 返回正则每个匹配都必须包含其中之一的字面量集合
+func requiredSet(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredSet(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min < 1 {
+			return nil, false
+		}
+		return requiredSet(re.Sub[0])
+	case syntax.OpAlternate:
+		var set []string
+		for _, sub := range re.Sub {
+			subSet, ok := requiredSet(sub)
+			if !ok {
+				return nil, false
+			}
+			set = append(set, subSet...)
+			if len(set) > sensitivePrefixMaxCount {
+				return nil, false
+			}
+		}
+		return dedupe(set), true
+	case syntax.OpConcat:
+		// 候选：每个子表达式自身必须包含的字面量，以及从每个位置开始连续拼接得到的字面量
+		var best []string
+		for i, sub := range re.Sub {
+			if set, ok := requiredSet(sub); ok && betterLiterals(set, best) {
+				best = set
+			}
+			if set, _, ok := concatSet(re.Sub[i:]); ok && betterLiterals(set, best) {
+				best = set
+			}
+		}
+		return best, best != nil
+	default:
+		set, _, ok := literalSet(re)
+		return set, ok
+	}
+}
+
+// betterLiterals 判断字面量集合 a 是否比 b 更适合预过滤：最短字面量更长，其次集合更小
+func betterLiterals(a, b []string) bool {
+	if len(a) == 0 {
+		return false
+	}
+	if len(b) == 0 {
+		return true
+	}
+	minA, minB := minLen(a), minLen(b)
+	if minA != minB {
+		return minA > minB
+	}
+	return len(a) < len(b)
+}
+
+func minLen(set []string) int {
+	n := len(set[0])
+	for _, s := range set[1:] {
+		n = min(n, len(s))
+	}
+	return n
+}
+
+// literalSet 返回正则匹配内容的前缀集合；exact 表示集合恰好是正则能匹配的全部字符串，
+// 此时连接运算可以继续向后扩展
+func literalSet(re *syntax.Regexp) (set []string, exact bool, ok bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		// 预过滤器只按 ASCII 折叠大小写，有非 ASCII 大小写等价字符的（如 s 和 ſ、k 和 K）不能用于预过滤
+		if re.Flags&syntax.FoldCase != 0 {
+			for _, r := range re.Rune {
+				if !asciiFold(r) {
+					return nil, false, false
+				}
+			}
+		}
+		return []string{string(re.Rune)}, true, true
+	case syntax.OpCharClass:
+		size := 0
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			size += int(re.Rune[i+1]-re.Rune[i]) + 1
+			if size > sensitiveClassMaxSize {
+				return nil, false, false
+			}
+		}
+		set = make([]string, 0, size)
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+				set = append(set, string(r))
+			}
+		}
+		return set, true, true
+	case syntax.OpCapture:
+		return literalSet(re.Sub[0])
+	case syntax.OpPlus:
+		set, _, ok = literalSet(re.Sub[0])
+		return set, false, ok
+	case syntax.OpRepeat:
+		if re.Min < 1 {
+			return nil, false, false
+		}
+		set, _, ok = literalSet(re.Sub[0])
+		return set, false, ok
+	case syntax.OpAlternate:
+		exact = true
+		for _, sub := range re.Sub {
+			subSet, subExact, subOK := literalSet(sub)
+			if !subOK {
+				return nil, false, false
+			}
+			set = append(set, subSet...)
+			exact = exact && subExact
+			if len(set) > sensitivePrefixMaxCount {
+				return nil, false, false
+			}
+		}
+		return set, exact, true
+	case syntax.OpConcat:
+		return concatSet(re.Sub)
+	default:
+		return nil, false, false
+	}
+}
+
+// concatSet 依次拼接连续子表达式的前缀集合，遇到无法精确展开的子表达式时停止
+func concatSet(subs []*syntax.Regexp) (set []string, exact bool, ok bool) {
+	set = []string{""}
+	for _, sub := range subs {
+		switch sub.Op {
+		case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+			syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+			// 零宽断言不消耗字符
+			continue
+		}
+		subSet, subExact, subOK := literalSet(sub)
+		if !subOK || len(set)*len(subSet) > sensitivePrefixMaxCount {
+			return set, false, len(set) > 1 || set[0] != ""
+		}
+		next := make([]string, 0, len(set)*len(subSet))
+		truncated := false
+		for _, prefix := range set {
+			for _, suffix := range subSet {
+				joined := prefix + suffix
+				if len(joined) > sensitivePrefixMaxLen {
+					joined = joined[:sensitivePrefixMaxLen]
+					truncated = true
+				}
+				next = append(next, joined)
+			}
+		}
+		set = dedupe(next)
+		if !subExact || truncated {
+			return set, false, true
+		}
+	}
+	return set, true, len(set) > 1 || set[0] != ""
+}
+
+// asciiFold 判断 r 及其所有大小写等价字符是否都是 ASCII
+func asciiFold(r rune) bool {
+	if r >= utf8.RuneSelf {
+		return false
+	}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func dedupe(set []string) []string {
+	seen := make(map[string]struct{}, len(set))
+	out := set[:0]
+	for _, s := range set {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+package op
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+// 内置规则只能修改开关、方向和处理方式，未提交的字段沿用原值后再校验
+func TestSensitiveFilterRuleUpdateBuiltIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing model.SensitiveFilterRule
+		update   model.SensitiveFilterRule
+		wantErr  bool
+		want     model.SensitiveFilterRule
+	}{
+		{
+			name:     "block on both directions",
+			existing: model.SensitiveFilterRule{Direction: model.SensitiveDirectionBoth, Action: model.SensitiveActionReplace},
+			update:   model.SensitiveFilterRule{Enabled: true, Action: model.SensitiveActionBlock},
+			wantErr:  true,
+		},
+		{
+			name:     "mask on both directions",
+			existing: model.SensitiveFilterRule{Direction: model.SensitiveDirectionBoth, Action: model.SensitiveActionReplace},
+			update:   model.SensitiveFilterRule{Enabled: true, Action: model.SensitiveActionMask},
+			wantErr:  true,
+		},
+		{
+			name:     "widen a block rule to both directions",
+			existing: model.SensitiveFilterRule{Direction: model.SensitiveDirectionRequest, Action: model.SensitiveActionBlock},
+			update:   model.SensitiveFilterRule{Enabled: true, Direction: model.SensitiveDirectionBoth},
+			wantErr:  true,
+		},
+		{
+			name:     "block on request",
+			existing: model.SensitiveFilterRule{Direction: model.SensitiveDirectionBoth, Action: model.SensitiveActionReplace},
+			update:   model.SensitiveFilterRule{Enabled: true, Direction: model.SensitiveDirectionRequest, Action: model.SensitiveActionBlock},
+			want:     model.SensitiveFilterRule{Enabled: true, Direction: model.SensitiveDirectionRequest, Action: model.SensitiveActionBlock},
+		},
+		{
+			name:     "other fields ignored",
+			existing: model.SensitiveFilterRule{Direction: model.SensitiveDirectionBoth, Action: model.SensitiveActionReplace},
+			update:   model.SensitiveFilterRule{Name: "renamed", Pattern: "x", Replacement: "y", RuleSetID: 9},
+			want:     model.SensitiveFilterRule{Enabled: false, Direction: model.SensitiveDirectionBoth, Action: model.SensitiveActionReplace},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbtest.Init(t, "sensitive.db")
+			ctx := context.Background()
+			existing := tt.existing
+			existing.Name, existing.Pattern, existing.Replacement = "builtin", `sk-\w+`, "[KEY]"
+			existing.Enabled, existing.BuiltIn = true, true
+			if err := db.GetDB().Create(&existing).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			update := tt.update
+			update.ID = existing.ID
+			err := SensitiveFilterRuleUpdate(&update, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var got model.SensitiveFilterRule
+			if err := db.GetDB().First(&got, existing.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			want := existing
+			if !tt.wantErr {
+				want.Enabled, want.Direction, want.Action = tt.want.Enabled, tt.want.Direction, tt.want.Action
+				if update != want {
+					t.Errorf("returned rule = %+v, want %+v", update, want)
+				}
+			}
+			if got != want {
+				t.Errorf("stored rule = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// API Key 的规则集优先于分组的规则集，都未设置时使用默认规则集；规则集变更后立即生效
+func TestSensitivePolicyResolve(t *testing.T) {
+	dbtest.Init(t, "sensitive.db")
+	ctx := context.Background()
+
+	setA := model.SensitiveRuleSet{Name: "a"}
+	setB := model.SensitiveRuleSet{Name: "b", IncludeDefault: true}
+	for _, set := range []*model.SensitiveRuleSet{&setA, &setB} {
+		if err := SensitiveRuleSetCreate(set, ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules := []model.SensitiveFilterRule{
+		{Name: "default", Pattern: `def-\w+`, Replacement: "[DEF]"},
+		{Name: "a", Pattern: `aaa-\w+`, Replacement: "[A]", RuleSetID: setA.ID},
+		{Name: "b", Pattern: `bbb-\w+`, Replacement: "[B]", RuleSetID: setB.ID},
+	}
+	for i := range rules {
+		rules[i].Enabled = true
+		if err := SensitiveFilterRuleCreate(&rules[i], ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apiKeys := []model.APIKey{{ID: 1, Name: "k1", APIKey: "k1", SensitiveRuleSetID: setA.ID}, {ID: 2, Name: "k2", APIKey: "k2"}}
+	groups := []model.Group{{ID: 1, Name: "g1", SensitiveRuleSetID: setB.ID}, {ID: 2, Name: "g2"}}
+	if err := db.GetDB().Create(&apiKeys).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.GetDB().Create(&groups).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := apiKeyRefreshCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := groupRefreshCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	const text = "def-1 aaa-1 bbb-1"
+	check := func(apiKeyID, groupID int, want string) {
+		t.Helper()
+		var result SensitiveFilterResult
+		got := SensitivePolicyResolve(apiKeyID, groupID).Apply(text, model.SensitiveDirectionRequest, nil, &result)
+		if got != want {
+			t.Errorf("resolve(%d, %d) = %q, want %q", apiKeyID, groupID, got, want)
+		}
+	}
+
+	check(1, 1, "def-1 [A] bbb-1")   // API Key 优先，规则集 a 不包含默认规则
+	check(1, 99, "def-1 [A] bbb-1")  // 分组不存在
+	check(2, 1, "[DEF] aaa-1 [B]")   // API Key 未设置，使用分组的规则集，b 包含默认规则
+	check(99, 1, "[DEF] aaa-1 [B]")  // API Key 不存在
+	check(2, 2, "[DEF] aaa-1 bbb-1") // 都未设置
+
+	setA.IncludeDefault = true
+	if err := SensitiveRuleSetUpdate(&setA, ctx); err != nil {
+		t.Fatal(err)
+	}
+	check(1, 1, "[DEF] [A] bbb-1")
+
+	if err := SensitiveFilterRuleToggle(rules[1].ID, false, ctx); err != nil {
+		t.Fatal(err)
+	}
+	check(1, 1, "[DEF] aaa-1 bbb-1")
+
+	// 删除规则集后引用它的 API Key 改为使用分组的规则集
+	if err := SensitiveRuleSetDelete(setA.ID, ctx); err != nil {
+		t.Fatal(err)
+	}
+	check(1, 1, "[DEF] aaa-1 [B]")
+	check(1, 2, "[DEF] aaa-1 bbb-1")
+}
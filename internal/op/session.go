@@ -0,0 +1,278 @@
+package op
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/cache"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"gorm.io/gorm"
+)
+
+var authSecretCache = cache.New[uint, model.AuthSecret](1)
+var authSecretLock sync.Mutex
+
+var sessionCache = cache.New[string, model.UserSession](16)
+
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+)
+
+// AuthSecretInit 加载签名密钥，没有时生成一个
+func AuthSecretInit() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := authSecretRefreshCache(ctx); err != nil {
+		return err
+	}
+	if _, ok := AuthSecretCurrent(); ok {
+		return nil
+	}
+	_, err := AuthSecretRotate(0, ctx)
+	return err
+}
+
+// AuthSecretCurrent 返回用于签发新令牌的密钥
+func AuthSecretCurrent() (model.AuthSecret, bool) {
+	var current model.AuthSecret
+	for _, s := range authSecretCache.GetAll() {
+		if s.RetiredAt == 0 && s.ID > current.ID {
+			current = s
+		}
+	}
+	return current, current.ID != 0
+}
+
+func AuthSecretGet(id uint) (model.AuthSecret, bool) {
+	return authSecretCache.Get(id)
+}
+
+// AuthSecretRotate 生成新的签名密钥并停用当前密钥
+// 停用超过 keep 的旧密钥会被删除，由它们签名的令牌随之失效
+func AuthSecretRotate(keep time.Duration, ctx context.Context) (model.AuthSecret, error) {
+	authSecretLock.Lock()
+	defer authSecretLock.Unlock()
+	value, err := randomHex(32)
+	if err != nil {
+		return model.AuthSecret{}, err
+	}
+	now := time.Now()
+	secret := model.AuthSecret{Secret: value, CreatedAt: now.Unix()}
+	err = db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("retired_at > 0 AND retired_at <= ?", now.Add(-keep).Unix()).
+			Delete(&model.AuthSecret{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.AuthSecret{}).Where("retired_at = 0").
+			Update("retired_at", now.Unix()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&secret).Error
+	})
+	if err != nil {
+		return model.AuthSecret{}, fmt.Errorf("failed to rotate auth secret: %w", err)
+	}
+	if err := authSecretRefreshCache(ctx); err != nil {
+		return model.AuthSecret{}, err
+	}
+	return secret, nil
+}
+
+// SessionCreate 为用户创建登录会话，返回会话和刷新令牌
+func SessionCreate(userID uint, ttl time.Duration, userAgent, ip string, ctx context.Context) (model.UserSession, string, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return model.UserSession{}, "", err
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return model.UserSession{}, "", err
+	}
+	userAgent, _ = truncateUTF8(userAgent, 255)
+	now := time.Now().Unix()
+	session := model.UserSession{
+		ID:          id,
+		UserID:      userID,
+		RefreshHash: tokenHash(token),
+		UserAgent:   userAgent,
+		IP:          ip,
+		CreatedAt:   now,
+		LastUsedAt:  now,
+		ExpiresAt:   now + int64(ttl.Seconds()),
+	}
+	conn := db.Conn(ctx)
+	// 顺便清理已过期的会话
+	if err := conn.Where("expires_at <= ?", now).Delete(&model.UserSession{}).Error; err != nil {
+		return model.UserSession{}, "", fmt.Errorf("failed to prune sessions: %w", err)
+	}
+	if err := conn.Create(&session).Error; err != nil {
+		return model.UserSession{}, "", fmt.Errorf("failed to create session: %w", err)
+	}
+	sessionCache.Set(session.ID, session)
+	return session, token, nil
+}
+
+// SessionRefresh 校验刷新令牌并更换为新的刷新令牌，旧令牌随即失效
+// 会话的过期时间从登录时算起，刷新不会延长
+func SessionRefresh(refreshToken string, ctx context.Context) (model.UserSession, string, error) {
+	hash := tokenHash(refreshToken)
+	conn := db.Conn(ctx)
+	var session model.UserSession
+	if err := conn.Where("refresh_hash = ?", hash).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.UserSession{}, "", ErrRefreshTokenInvalid
+		}
+		return model.UserSession{}, "", err
+	}
+	now := time.Now().Unix()
+	if session.ExpiresAt <= now {
+		return model.UserSession{}, "", ErrRefreshTokenInvalid
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return model.UserSession{}, "", err
+	}
+	// 以旧哈希为条件更新，并发使用同一个刷新令牌时只有一个请求成功
+	result := conn.Model(&model.UserSession{}).
+		Where("id = ? AND refresh_hash = ?", session.ID, hash).
+		Updates(map[string]any{"refresh_hash": tokenHash(token), "last_used_at": now})
+	if result.Error != nil {
+		return model.UserSession{}, "", fmt.Errorf("failed to refresh session: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return model.UserSession{}, "", ErrRefreshTokenInvalid
+	}
+	session.RefreshHash = tokenHash(token)
+	session.LastUsedAt = now
+	sessionCache.Set(session.ID, session)
+	return session, token, nil
+}
+
+// SessionGet 返回未过期的会话，缓存中没有时查询数据库
+// 多实例模式下会话可能由其他实例创建或撤销，总是以数据库为准
+func SessionGet(id string, ctx context.Context) (model.UserSession, bool) {
+	session, ok := sessionCache.Get(id)
+	if !ok || ClusterEnabled() {
+		session, ok = sessionLoad(id, ctx)
+	}
+	if !ok || session.ExpiresAt <= time.Now().Unix() {
+		return model.UserSession{}, false
+	}
+	return session, true
+}
+
+// SessionList 返回用户未过期的会话，最近使用的在前
+func SessionList(userID uint) []model.UserSession {
+	now := time.Now().Unix()
+	sessions := []model.UserSession{}
+	for _, s := range sessionCache.GetAll() {
+		if s.UserID == userID && s.ExpiresAt > now {
+			sessions = append(sessions, s)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b model.UserSession) int { return cmp.Compare(b.LastUsedAt, a.LastUsedAt) })
+	return sessions
+}
+
+// SessionRevoke 撤销用户的一个会话
+func SessionRevoke(userID uint, id string, ctx context.Context) error {
+	result := db.Conn(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.UserSession{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke session: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	sessionCache.Del(id)
+	return nil
+}
+
+// SessionRevokeUser 撤销用户除 exceptID 之外的所有会话，exceptID 为空时全部撤销
+func SessionRevokeUser(userID uint, exceptID string, ctx context.Context) error {
+	if err := db.Conn(ctx).
+		Where("user_id = ? AND id <> ?", userID, exceptID).
+		Delete(&model.UserSession{}).Error; err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	cacheRetain(sessionCache, func(id string, s model.UserSession) bool {
+		return s.UserID != userID || id == exceptID
+	})
+	return nil
+}
+
+// SessionRevokeAll 撤销所有用户的会话
+func SessionRevokeAll(ctx context.Context) error {
+	if err := db.Conn(ctx).Where("1 = 1").Delete(&model.UserSession{}).Error; err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	sessionCache.Clear()
+	return nil
+}
+
+// sessionLoad 从数据库读取会话并更新缓存，会话已被删除时同时移出缓存，查询失败时视为不存在
+func sessionLoad(id string, ctx context.Context) (model.UserSession, bool) {
+	var session model.UserSession
+	err := db.Conn(ctx).Where("id = ?", id).First(&session).Error
+	switch {
+	case err == nil:
+		sessionCache.Set(id, session)
+		return session, true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sessionCache.Del(id)
+	default:
+		log.Warnf("failed to load session: %v", err)
+	}
+	return model.UserSession{}, false
+}
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func authSecretRefreshCache(ctx context.Context) error {
+	secrets := []model.AuthSecret{}
+	if err := db.Conn(ctx).Find(&secrets).Error; err != nil {
+		return err
+	}
+	cacheRetain(authSecretCache, func(id uint, _ model.AuthSecret) bool {
+		return slices.ContainsFunc(secrets, func(s model.AuthSecret) bool { return s.ID == id })
+	})
+	for _, s := range secrets {
+		authSecretCache.Set(s.ID, s)
+	}
+	return nil
+}
+
+func sessionRefreshCache(ctx context.Context) error {
+	sessions := []model.UserSession{}
+	if err := db.Conn(ctx).Where("expires_at > ?", time.Now().Unix()).Find(&sessions).Error; err != nil {
+		return err
+	}
+	cacheRetain(sessionCache, func(id string, _ model.UserSession) bool {
+		return slices.ContainsFunc(sessions, func(s model.UserSession) bool { return s.ID == id })
+	})
+	for _, s := range sessions {
+		sessionCache.Set(s.ID, s)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+// 刷新令牌每次使用后更换，旧令牌、过期和已撤销会话的令牌都不能再使用
+func TestSessionRefreshRotates(t *testing.T) {
+	dbtest.Init(t, "session.db")
+	ctx := context.Background()
+
+	session, first, err := SessionCreate(1, time.Hour, "", "", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, second, err := SessionRefresh(first, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.ID != session.ID || refreshed.ExpiresAt != session.ExpiresAt || second == first {
+		t.Errorf("refreshed session = %+v, token changed: %v", refreshed, second != first)
+	}
+	if cached, ok := SessionGet(session.ID, ctx); !ok || cached.RefreshHash != tokenHash(second) {
+		t.Errorf("cached session = %+v", cached)
+	}
+
+	expired, expiredToken, err := SessionCreate(1, -time.Second, "", "", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedToken, err := SessionCreate(1, time.Hour, "", "", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SessionRevoke(1, revoked.ID, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"old token", first, ErrRefreshTokenInvalid},
+		{"unknown token", "00", ErrRefreshTokenInvalid},
+		{"expired session", expiredToken, ErrRefreshTokenInvalid},
+		{"revoked session", revokedToken, ErrRefreshTokenInvalid},
+		{"current token", second, nil},
+	}
+	for _, tt := range tests {
+		if _, _, err := SessionRefresh(tt.token, ctx); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if _, ok := SessionGet(expired.ID, ctx); ok {
+		t.Error("expired session returned by SessionGet")
+	}
+}
+
+// 并发使用同一个刷新令牌时只有一个请求换到新令牌
+func TestSessionRefreshConcurrent(t *testing.T) {
+	dbtest.Init(t, "session.db")
+	ctx := context.Background()
+
+	_, token, err := SessionCreate(1, time.Hour, "", "", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	tokens := make([]string, n)
+	start := make(chan struct{})
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, tokens[i], errs[i] = SessionRefresh(token, ctx)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var winner string
+	for i, err := range errs {
+		switch {
+		case err == nil && winner == "":
+			winner = tokens[i]
+		case err == nil:
+			t.Errorf("refresh token used twice")
+		case !errors.Is(err, ErrRefreshTokenInvalid):
+			t.Errorf("refresh err = %v", err)
+		}
+	}
+	if winner == "" {
+		t.Fatal("no refresh succeeded")
+	}
+	if _, _, err := SessionRefresh(winner, ctx); err != nil {
+		t.Errorf("refresh with the new token: %v", err)
+	}
+}
+
+// 其他实例创建的会话在缓存中没有时从数据库读取，多实例模式下其他实例撤销的会话立即失效
+func TestSessionGetOtherInstance(t *testing.T) {
+	dbtest.Init(t, "session.db")
+	ctx := context.Background()
+	enabled := conf.AppConfig.Cluster.Enabled
+	t.Cleanup(func() { conf.AppConfig.Cluster.Enabled = enabled })
+
+	now := time.Now().Unix()
+	other := model.UserSession{ID: "other", UserID: 1, RefreshHash: "h", CreatedAt: now, ExpiresAt: now + 3600}
+	if err := db.Conn(ctx).Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := SessionGet(other.ID, ctx); !ok {
+		t.Fatal("session created by another instance not found")
+	}
+
+	conf.AppConfig.Cluster.Enabled = true
+	if err := db.Conn(ctx).Delete(&model.UserSession{ID: other.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := SessionGet(other.ID, ctx); ok {
+		t.Error("session revoked by another instance still valid")
+	}
+	if _, ok := sessionCache.Get(other.ID); ok {
+		t.Error("revoked session kept in cache")
+	}
+}
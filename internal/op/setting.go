@@ -11,18 +11,42 @@ import (
 )
 
 var settingCache = cache.New[model.SettingKey, string](16)
+var settingManagedCache = cache.New[model.SettingKey, struct{}](16)
 
 func SettingList(ctx context.Context) ([]model.Setting, error) {
 	settings := make([]model.Setting, 0, settingCache.Len())
 	for key, value := range settingCache.GetAll() {
 		settings = append(settings, model.Setting{
-			Key:   key,
-			Value: value,
+			Key:     key,
+			Value:   value,
+			Managed: SettingIsManaged(key),
 		})
 	}
 	return settings, nil
 }
 
+// SettingIsManaged 判断设置项是否由声明式配置管理
+func SettingIsManaged(key model.SettingKey) bool {
+	_, ok := settingManagedCache.Get(key)
+	return ok
+}
+
+// SettingSetManaged 设置设置项是否由声明式配置管理
+func SettingSetManaged(key model.SettingKey, managed bool, ctx context.Context) error {
+	if _, ok := settingCache.Get(key); !ok {
+		return fmt.Errorf("setting not found")
+	}
+	if err := db.Conn(ctx).Model(&model.Setting{Key: key}).Update("managed", managed).Error; err != nil {
+		return fmt.Errorf("failed to set setting: %w", err)
+	}
+	if managed {
+		settingManagedCache.Set(key, struct{}{})
+	} else {
+		settingManagedCache.Del(key)
+	}
+	return nil
+}
+
 func SettingGetString(key model.SettingKey) (string, error) {
 	setting, ok := settingCache.Get(key)
 	if !ok {
@@ -31,7 +55,7 @@ func SettingGetString(key model.SettingKey) (string, error) {
 	return setting, nil
 }
 
-func SettingSetString(key model.SettingKey, value string) error {
+func SettingSetString(key model.SettingKey, value string, ctx context.Context) error {
 	valueCache, ok := settingCache.Get(key)
 	if !ok {
 		return fmt.Errorf("setting not found")
@@ -39,7 +63,7 @@ func SettingSetString(key model.SettingKey, value string) error {
 	if valueCache == value {
 		return nil
 	}
-	result := db.GetDB().Model(&model.Setting{Key: key}).Update("Value", value)
+	result := db.Conn(ctx).Model(&model.Setting{Key: key}).Update("Value", value)
 	if result.Error != nil {
 		return fmt.Errorf("failed to set setting: %w", result.Error)
 	}
@@ -90,7 +114,7 @@ func SettingSetInt(key model.SettingKey, value int) error {
 }
 
 func settingRefreshCache(ctx context.Context) error {
-	db := db.GetDB().WithContext(ctx)
+	db := db.Conn(ctx)
 
 	var settings []model.Setting
 	if err := db.Find(&settings).Error; err != nil {
@@ -117,8 +141,12 @@ func settingRefreshCache(ctx context.Context) error {
 		}
 		settings = append(settings, missingSettings...)
 	}
+	settingManagedCache.Clear()
 	for _, setting := range settings {
 		settingCache.Set(setting.Key, setting.Value)
+		if setting.Managed {
+			settingManagedCache.Set(setting.Key, struct{}{})
+		}
 	}
 	return nil
 }
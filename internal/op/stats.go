@@ -25,292 +25,411 @@ var statsHourlyCache [24]model.StatsHourly
 var statsHourlyCacheLock sync.RWMutex
 
 var statsChannelCache = cache.New[int, model.StatsChannel](16)
-var statsChannelCacheNeedUpdate = make(map[int]struct{})
-var statsChannelCacheNeedUpdateLock sync.Mutex
-
 var statsModelCache = cache.New[int, model.StatsModel](16)
-var statsModelCacheNeedUpdate = make(map[int]struct{})
-var statsModelCacheNeedUpdateLock sync.Mutex
-
 var statsAPIKeyCache = cache.New[int, model.StatsAPIKey](16)
-var statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
-var statsAPIKeyCacheNeedUpdateLock sync.Mutex
+var statsSensitiveCache = cache.New[model.StatsSensitiveKey, model.StatsSensitive](16)
 
-func StatsSaveDBTask() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-	log.Debugf("stats save db task started")
-	startTime := time.Now()
-	defer func() {
-		log.Debugf("stats save db task finished, save time: %s", time.Since(startTime))
-	}()
-	if err := StatsSaveDB(ctx); err != nil {
-		log.Errorf("stats save db error: %v", err)
-		return
-	}
+// statsHourKey 小时统计增量的键，跨天的增量需要写入对应日期
+type statsHourKey struct {
+	Date string
+	Hour int
 }
 
-func StatsSaveDB(ctx context.Context) error {
-	statsTotalCacheLock.RLock()
-	totalSnap := statsTotalCache
-	statsTotalCacheLock.RUnlock()
-	if totalSnap.ID == 0 {
-		totalSnap.ID = 1
-	}
-
-	statsDailyCacheLock.RLock()
-	dailySnap := statsDailyCache
-	statsDailyCacheLock.RUnlock()
-
-	statsHourlyCacheLock.RLock()
-	hourlyAll := statsHourlyCache
-	statsHourlyCacheLock.RUnlock()
-
-	statsChannelCacheNeedUpdateLock.Lock()
-	channelIDs := make([]int, 0, len(statsChannelCacheNeedUpdate))
-	for id := range statsChannelCacheNeedUpdate {
-		channelIDs = append(channelIDs, id)
-	}
-	statsChannelCacheNeedUpdate = make(map[int]struct{})
-	statsChannelCacheNeedUpdateLock.Unlock()
-
-	statsModelCacheNeedUpdateLock.Lock()
-	modelIDs := make([]int, 0, len(statsModelCacheNeedUpdate))
-	for id := range statsModelCacheNeedUpdate {
-		modelIDs = append(modelIDs, id)
-	}
-	statsModelCacheNeedUpdate = make(map[int]struct{})
-	statsModelCacheNeedUpdateLock.Unlock()
+// statsDeltaSet 自上次写入数据库以来的增量
+// 写入时在数据库中累加而不是覆盖，多个实例共享数据库时各自的统计不会互相覆盖
+type statsDeltaSet struct {
+	total      model.StatsMetrics
+	daily      map[string]model.StatsMetrics
+	hourly     map[statsHourKey]model.StatsMetrics
+	channel    map[int]model.StatsMetrics
+	model      map[int]model.StatsModel
+	apiKey     map[int]model.StatsMetrics
+	sensitive  map[model.StatsSensitiveKey]model.StatsSensitive
+	channelKey map[int]float64 // 渠道密钥的费用增量
+	// journalIDs 已写入流水的请求，汇总时从流水表读取
+	journalIDs []int64
+}
 
-	statsAPIKeyCacheNeedUpdateLock.Lock()
-	apiKeyIDs := make([]int, 0, len(statsAPIKeyCacheNeedUpdate))
-	for id := range statsAPIKeyCacheNeedUpdate {
-		apiKeyIDs = append(apiKeyIDs, id)
+func newStatsDeltaSet() statsDeltaSet {
+	return statsDeltaSet{
+		daily:      make(map[string]model.StatsMetrics),
+		hourly:     make(map[statsHourKey]model.StatsMetrics),
+		channel:    make(map[int]model.StatsMetrics),
+		model:      make(map[int]model.StatsModel),
+		apiKey:     make(map[int]model.StatsMetrics),
+		sensitive:  make(map[model.StatsSensitiveKey]model.StatsSensitive),
+		channelKey: make(map[int]float64),
 	}
-	statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
-	statsAPIKeyCacheNeedUpdateLock.Unlock()
-
-	return persistStatsSnapshots(ctx, totalSnap, dailySnap, hourlyAll, channelIDs, modelIDs, apiKeyIDs)
 }
 
-func persistStatsSnapshots(
-	ctx context.Context,
-	totalSnap model.StatsTotal,
-	dailySnap model.StatsDaily,
-	hourlyAll [24]model.StatsHourly,
-	channelIDs []int,
-	modelIDs []int,
-	apiKeyIDs []int,
-) error {
-	dbConn := db.GetDB().WithContext(ctx)
-
-	if result := dbConn.Save(&totalSnap); result.Error != nil {
-		return result.Error
+// addJournal 累加一次请求的统计
+func (d *statsDeltaSet) addJournal(j model.StatsJournal) {
+	if j.ID != 0 {
+		d.journalIDs = append(d.journalIDs, j.ID)
 	}
-	if result := dbConn.Save(&dailySnap); result.Error != nil {
-		return result.Error
-	}
-
-	todayDate := time.Now().Format("20060102")
-	hourlyStats := make([]model.StatsHourly, 0, 24)
-	for hour := 0; hour < 24; hour++ {
-		if hourlyAll[hour].Date == todayDate {
-			hourlyStats = append(hourlyStats, hourlyAll[hour])
+	for ruleID, hits := range j.SensitiveHits {
+		key := model.StatsSensitiveKey{RuleID: ruleID, APIKeyID: j.APIKeyID}
+		s, ok := d.sensitive[key]
+		if !ok {
+			s = model.StatsSensitive{RuleID: ruleID, APIKeyID: j.APIKeyID}
 		}
+		s.Hits += hits
+		s.LastHitTime = max(s.LastHitTime, j.Time)
+		d.sensitive[key] = s
 	}
-	if len(hourlyStats) > 0 {
-		if result := dbConn.Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "hour"}},
-			UpdateAll: true,
-		}).Create(&hourlyStats); result.Error != nil {
-			return result.Error
-		}
+	// 只记录命中次数的流水没有用量
+	if j.StatsMetrics == (model.StatsMetrics{}) {
+		return
 	}
+	if cost := j.InputCost + j.OutputCost; j.ChannelKeyID != 0 && cost != 0 {
+		d.channelKey[j.ChannelKeyID] += cost
+	}
+	d.total.Add(j.StatsMetrics)
+	daily := d.daily[j.Date]
+	daily.Add(j.StatsMetrics)
+	d.daily[j.Date] = daily
+	key := statsHourKey{Date: j.Date, Hour: j.Hour}
+	hourly := d.hourly[key]
+	hourly.Add(j.StatsMetrics)
+	d.hourly[key] = hourly
+	channel := d.channel[j.ChannelID]
+	channel.Add(j.StatsMetrics)
+	d.channel[j.ChannelID] = channel
+	apiKey := d.apiKey[j.APIKeyID]
+	apiKey.Add(j.StatsMetrics)
+	d.apiKey[j.APIKeyID] = apiKey
+}
 
-	for _, id := range channelIDs {
-		ch, ok := statsChannelCache.Get(id)
+// merge 将写入失败的增量合并回去，等待下次写入
+func (d *statsDeltaSet) merge(o statsDeltaSet) {
+	d.journalIDs = append(d.journalIDs, o.journalIDs...)
+	d.total.Add(o.total)
+	for k, v := range o.daily {
+		m := d.daily[k]
+		m.Add(v)
+		d.daily[k] = m
+	}
+	for k, v := range o.hourly {
+		m := d.hourly[k]
+		m.Add(v)
+		d.hourly[k] = m
+	}
+	for k, v := range o.channel {
+		m := d.channel[k]
+		m.Add(v)
+		d.channel[k] = m
+	}
+	for k, v := range o.model {
+		m, ok := d.model[k]
 		if !ok {
-			continue
-		}
-		if result := dbConn.Save(&ch); result.Error != nil {
-			return result.Error
+			m = model.StatsModel{ID: v.ID, Name: v.Name, ChannelID: v.ChannelID}
 		}
+		m.StatsMetrics.Add(v.StatsMetrics)
+		d.model[k] = m
 	}
-
-	for _, id := range modelIDs {
-		m, ok := statsModelCache.Get(id)
+	for k, v := range o.apiKey {
+		m := d.apiKey[k]
+		m.Add(v)
+		d.apiKey[k] = m
+	}
+	for k, v := range o.sensitive {
+		m, ok := d.sensitive[k]
 		if !ok {
-			continue
-		}
-		if result := dbConn.Save(&m); result.Error != nil {
-			return result.Error
+			m = model.StatsSensitive{RuleID: v.RuleID, APIKeyID: v.APIKeyID}
 		}
+		m.Hits += v.Hits
+		m.LastHitTime = max(m.LastHitTime, v.LastHitTime)
+		d.sensitive[k] = m
+	}
+	for k, v := range o.channelKey {
+		d.channelKey[k] += v
 	}
+}
 
-	for _, id := range apiKeyIDs {
-		ak, ok := statsAPIKeyCache.Get(id)
-		if !ok {
-			continue
-		}
-		if result := dbConn.Save(&ak); result.Error != nil {
-			return result.Error
+// statsDelta 只在内存中的增量，汇总时直接累加到统计表
+// statsJournalPending 已写入流水但尚未汇总的增量，只用于刷新缓存，汇总时以流水表为准
+// 更新缓存和记录增量在同一把锁内完成，刷新缓存时可以得到一致的 数据库值 + 增量
+var statsDelta = newStatsDeltaSet()
+var statsJournalPending = newStatsDeltaSet()
+var statsDeltaLock sync.Mutex
+
+// statsMetricColumns StatsMetrics 的列，写入时逐列累加
+var statsMetricColumns = []string{
+	"input_token", "output_token", "input_cost", "output_cost", "wait_time", "request_success", "request_failed",
+}
+
+func statsMetricValues(m model.StatsMetrics) []any {
+	return []any{m.InputToken, m.OutputToken, m.InputCost, m.OutputCost, m.WaitTime, m.RequestSuccess, m.RequestFailed}
+}
+
+// statsAddAssignments 生成 列 = 表.列 + 增量 的赋值
+func statsAddAssignments(table string, m model.StatsMetrics) []clause.Assignment {
+	values := statsMetricValues(m)
+	assignments := make([]clause.Assignment, len(statsMetricColumns))
+	for i, col := range statsMetricColumns {
+		assignments[i] = clause.Assignment{
+			Column: clause.Column{Name: col},
+			Value:  gorm.Expr("? + ?", clause.Column{Table: table, Name: col}, values[i]),
 		}
 	}
+	return assignments
+}
 
-	return nil
+// statsUpsertAdd 插入增量行，主键已存在时累加到已有行
+func statsUpsertAdd(tx *gorm.DB, row any, table string, keys []clause.Column, assignments []clause.Assignment) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   keys,
+		DoUpdates: clause.Set(assignments),
+	}).Create(row).Error
 }
 
-func statsSaveDBWithDailyOverride(ctx context.Context, dailyOverride model.StatsDaily) error {
-	statsTotalCacheLock.RLock()
-	totalSnap := statsTotalCache
-	statsTotalCacheLock.RUnlock()
-	if totalSnap.ID == 0 {
-		totalSnap.ID = 1
+func StatsSaveDBTask(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	log.Debugf("stats save db task started")
+	startTime := time.Now()
+	defer func() {
+		log.Debugf("stats save db task finished, save time: %s", time.Since(startTime))
+	}()
+	if err := StatsSaveDB(ctx); err != nil {
+		return fmt.Errorf("stats save db: %w", err)
+	}
+	// 多实例部署时由主节点汇总已退出实例遗留的流水，并重新加载数据库中的汇总，包含其他实例写入的统计
+	if ClusterEnabled() {
+		if ClusterIsLeader() {
+			if err := statsJournalSweep(ctx); err != nil {
+				return fmt.Errorf("stats journal sweep: %w", err)
+			}
+		}
+		if err := statsRefreshCache(ctx); err != nil {
+			return fmt.Errorf("stats refresh cache: %w", err)
+		}
 	}
+	return nil
+}
 
-	statsHourlyCacheLock.RLock()
-	hourlyAll := statsHourlyCache
-	statsHourlyCacheLock.RUnlock()
+// StatsSaveDB 将增量和本实例的流水累加写入数据库，失败时增量保留到下次写入
+func StatsSaveDB(ctx context.Context) error {
+	statsDeltaLock.Lock()
+	delta, journal := statsDelta, statsJournalPending
+	statsDelta, statsJournalPending = newStatsDeltaSet(), newStatsDeltaSet()
+	statsDeltaLock.Unlock()
+
+	err := db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := persistStatsDelta(tx, delta); err != nil {
+			return err
+		}
+		return statsJournalFoldIDs(tx, journal.journalIDs)
+	})
+	if err != nil {
+		statsDeltaLock.Lock()
+		statsDelta.merge(delta)
+		statsJournalPending.merge(journal)
+		statsDeltaLock.Unlock()
+	}
+	statsFlushRecord(err)
+	return err
+}
 
-	statsChannelCacheNeedUpdateLock.Lock()
-	channelIDs := make([]int, 0, len(statsChannelCacheNeedUpdate))
-	for id := range statsChannelCacheNeedUpdate {
-		channelIDs = append(channelIDs, id)
-	}
-	statsChannelCacheNeedUpdate = make(map[int]struct{})
-	statsChannelCacheNeedUpdateLock.Unlock()
+var (
+	statsFlushLock   sync.Mutex
+	statsFlushStatus model.StatsFlushStatus
+)
 
-	statsModelCacheNeedUpdateLock.Lock()
-	modelIDs := make([]int, 0, len(statsModelCacheNeedUpdate))
-	for id := range statsModelCacheNeedUpdate {
-		modelIDs = append(modelIDs, id)
+func statsFlushRecord(err error) {
+	statsFlushLock.Lock()
+	defer statsFlushLock.Unlock()
+	if err != nil {
+		statsFlushStatus.LastError = err.Error()
+		return
 	}
-	statsModelCacheNeedUpdate = make(map[int]struct{})
-	statsModelCacheNeedUpdateLock.Unlock()
+	statsFlushStatus.LastFlush = time.Now().Unix()
+	statsFlushStatus.LastError = ""
+}
 
-	statsAPIKeyCacheNeedUpdateLock.Lock()
-	apiKeyIDs := make([]int, 0, len(statsAPIKeyCacheNeedUpdate))
-	for id := range statsAPIKeyCacheNeedUpdate {
-		apiKeyIDs = append(apiKeyIDs, id)
-	}
-	statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
-	statsAPIKeyCacheNeedUpdateLock.Unlock()
+// StatsFlushInfo 返回统计数据最近一次写入数据库的状态
+func StatsFlushInfo() model.StatsFlushStatus {
+	statsFlushLock.Lock()
+	status := statsFlushStatus
+	statsFlushLock.Unlock()
 
-	return persistStatsSnapshots(ctx, totalSnap, dailyOverride, hourlyAll, channelIDs, modelIDs, apiKeyIDs)
+	statsDeltaLock.Lock()
+	status.PendingJournal = len(statsJournalPending.journalIDs)
+	statsDeltaLock.Unlock()
+	return status
 }
 
-func StatsDailyUpdate(ctx context.Context, metrics model.StatsMetrics) error {
-	today := time.Now().Format("20060102")
-
-	statsDailyCacheLock.Lock()
-	if statsDailyCache.Date == today {
-		statsDailyCache.StatsMetrics.Add(metrics)
-		statsDailyCacheLock.Unlock()
-		return nil
+func persistStatsDelta(tx *gorm.DB, delta statsDeltaSet) error {
+	if delta.total != (model.StatsMetrics{}) {
+		row := model.StatsTotal{ID: 1, StatsMetrics: delta.total}
+		if err := statsUpsertAdd(tx, &row, "stats_totals", []clause.Column{{Name: "id"}}, statsAddAssignments("stats_totals", delta.total)); err != nil {
+			return err
+		}
 	}
 
-	prevDaily := statsDailyCache
-	statsDailyCache = model.StatsDaily{Date: today}
-	statsDailyCache.StatsMetrics.Add(metrics)
-	statsDailyCacheLock.Unlock()
+	for date, m := range delta.daily {
+		row := model.StatsDaily{Date: date, StatsMetrics: m}
+		if err := statsUpsertAdd(tx, &row, "stats_dailies", []clause.Column{{Name: "date"}}, statsAddAssignments("stats_dailies", m)); err != nil {
+			return err
+		}
+	}
 
-	return statsSaveDBWithDailyOverride(ctx, prevDaily)
-}
+	// 小时统计每小时只有一行，已有数据的日期较早时直接替换，较晚时说明增量已过期，保留已有数据
+	for key, m := range delta.hourly {
+		values := statsMetricValues(m)
+		dateCol := clause.Column{Table: "stats_hourlies", Name: "date"}
+		assignments := make([]clause.Assignment, 0, len(statsMetricColumns)+1)
+		for i, col := range statsMetricColumns {
+			current := clause.Column{Table: "stats_hourlies", Name: col}
+			assignments = append(assignments, clause.Assignment{
+				Column: clause.Column{Name: col},
+				Value: gorm.Expr("CASE WHEN ? = ? THEN ? + ? WHEN ? < ? THEN ? ELSE ? END",
+					dateCol, key.Date, current, values[i], dateCol, key.Date, values[i], current),
+			})
+		}
+		// MySQL 按顺序执行赋值，日期必须最后更新
+		assignments = append(assignments, clause.Assignment{
+			Column: clause.Column{Name: "date"},
+			Value:  gorm.Expr("CASE WHEN ? < ? THEN ? ELSE ? END", dateCol, key.Date, key.Date, dateCol),
+		})
+		row := model.StatsHourly{Hour: key.Hour, Date: key.Date, StatsMetrics: m}
+		if err := statsUpsertAdd(tx, &row, "stats_hourlies", []clause.Column{{Name: "hour"}}, assignments); err != nil {
+			return err
+		}
+	}
 
-func StatsTotalUpdate(metrics model.StatsMetrics) error {
-	statsTotalCacheLock.Lock()
-	defer statsTotalCacheLock.Unlock()
-	if statsTotalCache.ID == 0 {
-		statsTotalCache.ID = 1
+	for id, m := range delta.channel {
+		row := model.StatsChannel{ChannelID: id, StatsMetrics: m}
+		if err := statsUpsertAdd(tx, &row, "stats_channels", []clause.Column{{Name: "channel_id"}}, statsAddAssignments("stats_channels", m)); err != nil {
+			return err
+		}
 	}
-	statsTotalCache.StatsMetrics.Add(metrics)
-	return nil
-}
 
-func StatsChannelUpdate(channelID int, metrics model.StatsMetrics) error {
-	channelCache, ok := statsChannelCache.Get(channelID)
-	if !ok {
-		channelCache = model.StatsChannel{
-			ChannelID: channelID,
+	for _, m := range delta.model {
+		row := m
+		if err := statsUpsertAdd(tx, &row, "stats_models", []clause.Column{{Name: "id"}}, statsAddAssignments("stats_models", m.StatsMetrics)); err != nil {
+			return err
 		}
 	}
-	channelCache.StatsMetrics.Add(metrics)
-	statsChannelCache.Set(channelID, channelCache)
-	statsChannelCacheNeedUpdateLock.Lock()
-	statsChannelCacheNeedUpdate[channelID] = struct{}{}
-	statsChannelCacheNeedUpdateLock.Unlock()
-	return nil
-}
 
-func StatsHourlyUpdate(metrics model.StatsMetrics) error {
-	now := time.Now()
-	nowHour := now.Hour()
-	todayDate := time.Now().Format("20060102")
+	for id, m := range delta.apiKey {
+		row := model.StatsAPIKey{APIKeyID: id, StatsMetrics: m}
+		if err := statsUpsertAdd(tx, &row, "stats_api_keys", []clause.Column{{Name: "api_key_id"}}, statsAddAssignments("stats_api_keys", m)); err != nil {
+			return err
+		}
+	}
 
-	statsHourlyCacheLock.Lock()
-	defer statsHourlyCacheLock.Unlock()
+	for _, s := range delta.sensitive {
+		row := s
+		lastHit := clause.Column{Table: "stats_sensitives", Name: "last_hit_time"}
+		assignments := []clause.Assignment{
+			{Column: clause.Column{Name: "hits"}, Value: gorm.Expr("? + ?", clause.Column{Table: "stats_sensitives", Name: "hits"}, s.Hits)},
+			{Column: clause.Column{Name: "last_hit_time"}, Value: gorm.Expr("CASE WHEN ? > ? THEN ? ELSE ? END", lastHit, s.LastHitTime, lastHit, s.LastHitTime)},
+		}
+		if err := statsUpsertAdd(tx, &row, "stats_sensitives", []clause.Column{{Name: "rule_id"}, {Name: "api_key_id"}}, assignments); err != nil {
+			return err
+		}
+	}
 
-	if statsHourlyCache[nowHour].Date != todayDate {
-		statsHourlyCache[nowHour] = model.StatsHourly{
-			Hour: nowHour,
-			Date: todayDate,
+	// 已删除的密钥没有对应的行，费用增量直接丢弃
+	for id, cost := range delta.channelKey {
+		if err := tx.Model(&model.ChannelKey{}).Where("id = ?", id).
+			UpdateColumn("total_cost", gorm.Expr("total_cost + ?", cost)).Error; err != nil {
+			return err
 		}
 	}
 
-	statsHourlyCache[nowHour].StatsMetrics.Add(metrics)
 	return nil
 }
 
+// statsPendingChannelKeyCost 尚未写入数据库的渠道密钥费用增量，调用方需持有 statsDeltaLock
+func statsPendingChannelKeyCost() map[int]float64 {
+	pending := make(map[int]float64, len(statsDelta.channelKey)+len(statsJournalPending.channelKey))
+	for id, cost := range statsDelta.channelKey {
+		pending[id] += cost
+	}
+	for id, cost := range statsJournalPending.channelKey {
+		pending[id] += cost
+	}
+	return pending
+}
+
+// StatsModelUpdate 累加模型统计，增量只在内存中，不经过流水；目前没有调用方
 func StatsModelUpdate(stats model.StatsModel) error {
+	statsDeltaLock.Lock()
+	defer statsDeltaLock.Unlock()
+	d, ok := statsDelta.model[stats.ID]
+	if !ok {
+		d = model.StatsModel{ID: stats.ID, Name: stats.Name, ChannelID: stats.ChannelID}
+	}
+	d.StatsMetrics.Add(stats.StatsMetrics)
+	statsDelta.model[stats.ID] = d
+
 	modelCache, ok := statsModelCache.Get(stats.ID)
 	if !ok {
 		modelCache = model.StatsModel{
-			ID: stats.ID,
+			ID:        stats.ID,
+			Name:      stats.Name,
+			ChannelID: stats.ChannelID,
 		}
 	}
 	modelCache.StatsMetrics.Add(stats.StatsMetrics)
 	statsModelCache.Set(stats.ID, modelCache)
-	statsModelCacheNeedUpdateLock.Lock()
-	statsModelCacheNeedUpdate[stats.ID] = struct{}{}
-	statsModelCacheNeedUpdateLock.Unlock()
 	return nil
 }
 
-func StatsAPIKeyUpdate(apiKeyID int, metrics model.StatsMetrics) error {
-	apiKeyCache, ok := statsAPIKeyCache.Get(apiKeyID)
-	if !ok {
-		apiKeyCache = model.StatsAPIKey{
-			APIKeyID: apiKeyID,
-		}
+// StatsSensitiveUpdate 累加一次请求中各规则的命中次数，和用量一样先写入流水
+func StatsSensitiveUpdate(apiKeyID int, hits map[int]int64, ctx context.Context) {
+	if len(hits) == 0 {
+		return
 	}
-	apiKeyCache.StatsMetrics.Add(metrics)
-	statsAPIKeyCache.Set(apiKeyID, apiKeyCache)
-	statsAPIKeyCacheNeedUpdateLock.Lock()
-	statsAPIKeyCacheNeedUpdate[apiKeyID] = struct{}{}
-	statsAPIKeyCacheNeedUpdateLock.Unlock()
-	return nil
+	StatsRecord(ctx, model.StatsJournal{APIKeyID: apiKeyID, SensitiveHits: hits})
 }
 
-func StatsChannelDel(id int) error {
+func StatsChannelDel(id int, ctx context.Context) error {
+	statsDeltaLock.Lock()
+	defer statsDeltaLock.Unlock()
 	if _, ok := statsChannelCache.Get(id); !ok {
 		return nil
 	}
 	statsChannelCache.Del(id)
-	statsChannelCacheNeedUpdateLock.Lock()
-	delete(statsChannelCacheNeedUpdate, id)
-	statsChannelCacheNeedUpdateLock.Unlock()
-	return db.GetDB().Delete(&model.StatsChannel{}, id).Error
+	delete(statsDelta.channel, id)
+	delete(statsJournalPending.channel, id)
+	return db.Conn(ctx).Delete(&model.StatsChannel{}, id).Error
 }
 
-func StatsAPIKeyDel(id int) error {
+func StatsAPIKeyDel(id int, ctx context.Context) error {
+	statsDeltaLock.Lock()
+	defer statsDeltaLock.Unlock()
 	if _, ok := statsAPIKeyCache.Get(id); !ok {
 		return nil
 	}
 	statsAPIKeyCache.Del(id)
-	statsAPIKeyCacheNeedUpdateLock.Lock()
-	delete(statsAPIKeyCacheNeedUpdate, id)
-	statsAPIKeyCacheNeedUpdateLock.Unlock()
-	return db.GetDB().Delete(&model.StatsAPIKey{}, id).Error
+	delete(statsDelta.apiKey, id)
+	delete(statsJournalPending.apiKey, id)
+	return db.Conn(ctx).Delete(&model.StatsAPIKey{}, id).Error
+}
+
+// StatsSensitiveRuleDel 删除规则的命中统计
+func StatsSensitiveRuleDel(ruleID int) error {
+	statsDeltaLock.Lock()
+	for key := range statsSensitiveCache.GetAll() {
+		if key.RuleID == ruleID {
+			statsSensitiveCache.Del(key)
+		}
+	}
+	for key := range statsDelta.sensitive {
+		if key.RuleID == ruleID {
+			delete(statsDelta.sensitive, key)
+		}
+	}
+	for key := range statsJournalPending.sensitive {
+		if key.RuleID == ruleID {
+			delete(statsJournalPending.sensitive, key)
+		}
+	}
+	statsDeltaLock.Unlock()
+	return db.GetDB().Where("rule_id = ?", ruleID).Delete(&model.StatsSensitive{}).Error
 }
 
 func StatsTotalGet() model.StatsTotal {
@@ -328,14 +447,7 @@ func StatsTodayGet() model.StatsDaily {
 func StatsChannelGet(id int) model.StatsChannel {
 	stats, ok := statsChannelCache.Get(id)
 	if !ok {
-		tmp := model.StatsChannel{
-			ChannelID: id,
-		}
-		statsChannelCache.Set(id, tmp)
-		statsChannelCacheNeedUpdateLock.Lock()
-		statsChannelCacheNeedUpdate[id] = struct{}{}
-		statsChannelCacheNeedUpdateLock.Unlock()
-		return tmp
+		return model.StatsChannel{ChannelID: id}
 	}
 	return stats
 }
@@ -343,14 +455,7 @@ func StatsChannelGet(id int) model.StatsChannel {
 func StatsAPIKeyGet(id int) model.StatsAPIKey {
 	stats, ok := statsAPIKeyCache.Get(id)
 	if !ok {
-		tmp := model.StatsAPIKey{
-			APIKeyID: id,
-		}
-		statsAPIKeyCache.Set(id, tmp)
-		statsAPIKeyCacheNeedUpdateLock.Lock()
-		statsAPIKeyCacheNeedUpdate[id] = struct{}{}
-		statsAPIKeyCacheNeedUpdateLock.Unlock()
-		return tmp
+		return model.StatsAPIKey{APIKeyID: id}
 	}
 	return stats
 }
@@ -363,6 +468,14 @@ func StatsAPIKeyList() []model.StatsAPIKey {
 	return apiKeys
 }
 
+func StatsSensitiveList() []model.StatsSensitive {
+	stats := make([]model.StatsSensitive, 0, statsSensitiveCache.Len())
+	for _, v := range statsSensitiveCache.GetAll() {
+		stats = append(stats, v)
+	}
+	return stats
+}
+
 func StatsHourlyGet() []model.StatsHourly {
 	now := time.Now()
 	currentHour := now.Hour()
@@ -389,23 +502,29 @@ func StatsHourlyGet() []model.StatsHourly {
 
 func StatsGetDaily(ctx context.Context) ([]model.StatsDaily, error) {
 	var statsDaily []model.StatsDaily
-	result := db.GetDB().WithContext(ctx).Find(&statsDaily)
+	result := db.Conn(ctx).Find(&statsDaily)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return statsDaily, nil
 }
 
+// statsRefreshCache 从数据库加载统计，并叠加尚未写入的增量
 func statsRefreshCache(ctx context.Context) error {
-	dbConn := db.GetDB().WithContext(ctx)
+	// 读取数据库和合并增量期间都持有增量锁，避免期间写入数据库的增量既不在读到的值里也不在待写增量里；
+	// 锁内统计更新被阻塞，缓存 = 数据库值 + 未写入的增量
+	statsDeltaLock.Lock()
+	defer statsDeltaLock.Unlock()
+
+	dbConn := db.Conn(ctx)
 	today := time.Now().Format("20060102")
 
 	var loadedDaily model.StatsDaily
-	result := dbConn.Last(&loadedDaily)
-	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+	result := dbConn.Where("date = ?", today).Limit(1).Find(&loadedDaily)
+	if result.Error != nil {
 		return fmt.Errorf("failed to get daily stats: %v", result.Error)
 	}
-	if result.RowsAffected == 0 || loadedDaily.Date != today {
+	if result.RowsAffected == 0 {
 		loadedDaily = model.StatsDaily{Date: today}
 	}
 
@@ -432,35 +551,67 @@ func statsRefreshCache(ctx context.Context) error {
 		return fmt.Errorf("failed to get hourly stats: %v", result.Error)
 	}
 
+	var loadedAPIKeys []model.StatsAPIKey
+	result = dbConn.Find(&loadedAPIKeys)
+	if result.Error != nil {
+		return fmt.Errorf("failed to get api key stats: %v", result.Error)
+	}
+
+	var loadedSensitive []model.StatsSensitive
+	result = dbConn.Find(&loadedSensitive)
+	if result.Error != nil {
+		return fmt.Errorf("failed to get sensitive stats: %v", result.Error)
+	}
+
+	pending := newStatsDeltaSet()
+	pending.merge(statsDelta)
+	pending.merge(statsJournalPending)
+
+	loadedDaily.StatsMetrics.Add(pending.daily[today])
 	statsDailyCacheLock.Lock()
 	statsDailyCache = loadedDaily
 	statsDailyCacheLock.Unlock()
 
+	loadedTotal.StatsMetrics.Add(pending.total)
 	statsTotalCacheLock.Lock()
 	statsTotalCache = loadedTotal
 	statsTotalCacheLock.Unlock()
 
 	statsChannelCache.Clear()
-	statsChannelCacheNeedUpdateLock.Lock()
-	statsChannelCacheNeedUpdate = make(map[int]struct{})
-	statsChannelCacheNeedUpdateLock.Unlock()
 	for _, v := range loadedChannels {
 		statsChannelCache.Set(v.ChannelID, v)
 	}
-
-	var loadedAPIKeys []model.StatsAPIKey
-	result = dbConn.Find(&loadedAPIKeys)
-	if result.Error != nil {
-		return fmt.Errorf("failed to get api key stats: %v", result.Error)
+	for id, m := range pending.channel {
+		v, _ := statsChannelCache.Get(id)
+		v.ChannelID = id
+		v.StatsMetrics.Add(m)
+		statsChannelCache.Set(id, v)
 	}
 
 	statsAPIKeyCache.Clear()
-	statsAPIKeyCacheNeedUpdateLock.Lock()
-	statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
-	statsAPIKeyCacheNeedUpdateLock.Unlock()
 	for _, v := range loadedAPIKeys {
 		statsAPIKeyCache.Set(v.APIKeyID, v)
 	}
+	for id, m := range pending.apiKey {
+		v, _ := statsAPIKeyCache.Get(id)
+		v.APIKeyID = id
+		v.StatsMetrics.Add(m)
+		statsAPIKeyCache.Set(id, v)
+	}
+
+	statsSensitiveCache.Clear()
+	for _, v := range loadedSensitive {
+		statsSensitiveCache.Set(model.StatsSensitiveKey{RuleID: v.RuleID, APIKeyID: v.APIKeyID}, v)
+	}
+	for key, d := range pending.sensitive {
+		v, ok := statsSensitiveCache.Get(key)
+		if !ok {
+			v = model.StatsSensitive{RuleID: key.RuleID, APIKeyID: key.APIKeyID}
+		}
+		v.Hits += d.Hits
+		v.LastHitTime = max(v.LastHitTime, d.LastHitTime)
+		statsSensitiveCache.Set(key, v)
+	}
 
 	statsHourlyCacheLock.Lock()
 	statsHourlyCache = [24]model.StatsHourly{}
@@ -469,6 +620,15 @@ func statsRefreshCache(ctx context.Context) error {
 			statsHourlyCache[v.Hour] = v
 		}
 	}
+	for key, m := range pending.hourly {
+		if key.Hour < 0 || key.Hour >= 24 {
+			continue
+		}
+		if statsHourlyCache[key.Hour].Date != key.Date {
+			statsHourlyCache[key.Hour] = model.StatsHourly{Hour: key.Hour, Date: key.Date}
+		}
+		statsHourlyCache[key.Hour].StatsMetrics.Add(m)
+	}
 	statsHourlyCacheLock.Unlock()
 
 	return nil
@@ -0,0 +1,213 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"gorm.io/gorm"
+)
+
+const statsJournalBatchSize = 500
+
+// errStatsJournalConflict 流水已被其他事务汇总
+var errStatsJournalConflict = errors.New("stats journal rows already folded")
+
+// StatsRecord 记录一次请求的统计：先写入流水再更新缓存，流水写入后即使进程异常退出也不会丢失
+// j 由调用方填写渠道、渠道密钥、API Key、用量和敏感信息命中次数，实例和时间在这里填写
+// 流水写入失败时统计只保存在内存中，随下次汇总写入
+func StatsRecord(ctx context.Context, j model.StatsJournal) {
+	now := time.Now()
+	j.Node = statsJournalNode()
+	j.Time = now.Unix()
+	j.Date = now.Format("20060102")
+	j.Hour = now.Hour()
+	err := db.Conn(ctx).Create(&j).Error
+	if err != nil {
+		log.Warnf("stats journal write failed, keeping usage in memory: %v", err)
+		j.ID = 0
+	}
+
+	statsDeltaLock.Lock()
+	defer statsDeltaLock.Unlock()
+	if j.ID != 0 {
+		statsJournalPending.addJournal(j)
+	} else {
+		statsDelta.addJournal(j)
+	}
+	statsCacheAdd(j)
+}
+
+// statsCacheAdd 将一次请求的统计累加到缓存，调用方需持有 statsDeltaLock
+func statsCacheAdd(j model.StatsJournal) {
+	for ruleID, hits := range j.SensitiveHits {
+		key := model.StatsSensitiveKey{RuleID: ruleID, APIKeyID: j.APIKeyID}
+		stats, ok := statsSensitiveCache.Get(key)
+		if !ok {
+			stats = model.StatsSensitive{RuleID: ruleID, APIKeyID: j.APIKeyID}
+		}
+		stats.Hits += hits
+		stats.LastHitTime = max(stats.LastHitTime, j.Time)
+		statsSensitiveCache.Set(key, stats)
+	}
+	if j.StatsMetrics == (model.StatsMetrics{}) {
+		return
+	}
+	if cost := j.InputCost + j.OutputCost; j.ChannelKeyID != 0 && cost != 0 {
+		channelKeyAddCost(j.ChannelKeyID, cost)
+	}
+
+	statsTotalCacheLock.Lock()
+	if statsTotalCache.ID == 0 {
+		statsTotalCache.ID = 1
+	}
+	statsTotalCache.StatsMetrics.Add(j.StatsMetrics)
+	statsTotalCacheLock.Unlock()
+
+	statsDailyCacheLock.Lock()
+	if statsDailyCache.Date < j.Date {
+		statsDailyCache = model.StatsDaily{Date: j.Date}
+	}
+	if statsDailyCache.Date == j.Date {
+		statsDailyCache.StatsMetrics.Add(j.StatsMetrics)
+	}
+	statsDailyCacheLock.Unlock()
+
+	statsHourlyCacheLock.Lock()
+	if statsHourlyCache[j.Hour].Date < j.Date {
+		statsHourlyCache[j.Hour] = model.StatsHourly{Hour: j.Hour, Date: j.Date}
+	}
+	if statsHourlyCache[j.Hour].Date == j.Date {
+		statsHourlyCache[j.Hour].StatsMetrics.Add(j.StatsMetrics)
+	}
+	statsHourlyCacheLock.Unlock()
+
+	channel, ok := statsChannelCache.Get(j.ChannelID)
+	if !ok {
+		channel = model.StatsChannel{ChannelID: j.ChannelID}
+	}
+	channel.StatsMetrics.Add(j.StatsMetrics)
+	statsChannelCache.Set(j.ChannelID, channel)
+
+	apiKey, ok := statsAPIKeyCache.Get(j.APIKeyID)
+	if !ok {
+		apiKey = model.StatsAPIKey{APIKeyID: j.APIKeyID}
+	}
+	apiKey.StatsMetrics.Add(j.StatsMetrics)
+	statsAPIKeyCache.Set(j.APIKeyID, apiKey)
+}
+
+// StatsJournalReplay 汇总上次运行遗留的流水，需要在加载统计缓存之前调用
+// 多实例部署时只处理本实例的流水，其他实例遗留的流水由主节点在超时后汇总
+func StatsJournalReplay(ctx context.Context) error {
+	n, err := statsJournalFoldAll(ctx, func(tx *gorm.DB) *gorm.DB {
+		if ClusterEnabled() {
+			return tx.Where("node = ?", statsJournalNode())
+		}
+		return tx
+	})
+	if err != nil {
+		return fmt.Errorf("replay stats journal: %w", err)
+	}
+	if n > 0 {
+		log.Infof("replayed %d stats journal entries", n)
+	}
+	return nil
+}
+
+// statsJournalSweep 汇总超过两个保存间隔仍未汇总的流水，通常来自异常退出的实例
+func statsJournalSweep(ctx context.Context) error {
+	minutes, err := SettingGetInt(model.SettingKeyStatsSaveInterval)
+	if err != nil {
+		return err
+	}
+	timeout := 2*time.Duration(minutes)*time.Minute + clusterLeaseTTL()
+	cutoff := time.Now().Add(-timeout).Unix()
+	n, err := statsJournalFoldAll(ctx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("time < ?", cutoff)
+	})
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Infof("folded %d stale stats journal entries", n)
+	}
+	return nil
+}
+
+// statsJournalFoldAll 按批次汇总满足条件的所有流水，返回汇总的条数
+func statsJournalFoldAll(ctx context.Context, scope func(*gorm.DB) *gorm.DB) (int, error) {
+	total, conflicts := 0, 0
+	for {
+		var n int
+		err := db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+			var rows []model.StatsJournal
+			if err := tx.Scopes(scope).Order("id").Limit(statsJournalBatchSize).Find(&rows).Error; err != nil {
+				return err
+			}
+			n = len(rows)
+			return statsJournalFold(tx, rows)
+		})
+		switch {
+		case errors.Is(err, errStatsJournalConflict) && conflicts < 3:
+			conflicts++
+			continue
+		case err != nil:
+			return total, err
+		}
+		total += n
+		if n < statsJournalBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// statsJournalFoldIDs 汇总本实例记录的流水，已被其他事务汇总的流水会被跳过
+func statsJournalFoldIDs(tx *gorm.DB, ids []int64) error {
+	for start := 0; start < len(ids); start += statsJournalBatchSize {
+		chunk := ids[start:min(start+statsJournalBatchSize, len(ids))]
+		var rows []model.StatsJournal
+		if err := tx.Where("id IN ?", chunk).Find(&rows).Error; err != nil {
+			return err
+		}
+		if err := statsJournalFold(tx, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statsJournalFold 在同一事务中将流水累加到统计表并删除
+// 删除的行数与读取的不一致时说明有其他事务同时汇总了这些流水，返回错误使事务回滚，避免重复累加
+func statsJournalFold(tx *gorm.DB, rows []model.StatsJournal) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	delta := newStatsDeltaSet()
+	for _, r := range rows {
+		delta.addJournal(r)
+	}
+	if err := persistStatsDelta(tx, delta); err != nil {
+		return err
+	}
+	result := tx.Where("id IN ?", delta.journalIDs).Delete(&model.StatsJournal{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(rows)) {
+		return errStatsJournalConflict
+	}
+	return nil
+}
+
+// statsJournalNode 流水所属的实例，单实例模式下为空
+func statsJournalNode() string {
+	if !ClusterEnabled() {
+		return ""
+	}
+	return ClusterNodeID()
+}
@@ -0,0 +1,225 @@
+package op
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm"
+)
+
+// 两个实例先后写入同一行时应当累加，而不是互相覆盖
+func TestPersistStatsDeltaAdds(t *testing.T) {
+	conn := dbtest.Open(t, "stats.db")
+
+	write := func(date string, m model.StatsMetrics, hits int64, lastHit int64) {
+		t.Helper()
+		d := newStatsDeltaSet()
+		d.total = m
+		d.daily[date] = m
+		d.hourly[statsHourKey{Date: date, Hour: 3}] = m
+		d.channel[1] = m
+		d.model[2] = model.StatsModel{ID: 2, Name: "gpt", ChannelID: 1, StatsMetrics: m}
+		d.apiKey[4] = m
+		key := model.StatsSensitiveKey{RuleID: 5, APIKeyID: 4}
+		d.sensitive[key] = model.StatsSensitive{RuleID: 5, APIKeyID: 4, Hits: hits, LastHitTime: lastHit}
+		if err := persistStatsDelta(conn, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("20260101", model.StatsMetrics{InputToken: 10, OutputCost: 0.5, RequestSuccess: 1}, 2, 200)
+	write("20260101", model.StatsMetrics{InputToken: 5, OutputCost: 0.25, RequestFailed: 1}, 3, 100)
+
+	want := model.StatsMetrics{InputToken: 15, OutputCost: 0.75, RequestSuccess: 1, RequestFailed: 1}
+	var total model.StatsTotal
+	var daily model.StatsDaily
+	var hourly model.StatsHourly
+	var channel model.StatsChannel
+	var modelStats model.StatsModel
+	var apiKey model.StatsAPIKey
+	for _, dst := range []any{&total, &daily, &hourly, &channel, &modelStats, &apiKey} {
+		if err := conn.First(dst).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, got := range map[string]model.StatsMetrics{
+		"total": total.StatsMetrics, "daily": daily.StatsMetrics, "hourly": hourly.StatsMetrics,
+		"channel": channel.StatsMetrics, "model": modelStats.StatsMetrics, "api_key": apiKey.StatsMetrics,
+	} {
+		if got != want {
+			t.Errorf("%s = %+v, want %+v", name, got, want)
+		}
+	}
+
+	var sensitive model.StatsSensitive
+	if err := conn.First(&sensitive).Error; err != nil {
+		t.Fatal(err)
+	}
+	if sensitive.Hits != 5 || sensitive.LastHitTime != 200 {
+		t.Errorf("sensitive = %+v, want 5 hits at 200", sensitive)
+	}
+
+	// 同一小时跨天后替换前一天的数据
+	next := model.StatsMetrics{InputToken: 7}
+	write("20260102", next, 1, 300)
+	if err := conn.Where("hour = ?", 3).First(&hourly).Error; err != nil {
+		t.Fatal(err)
+	}
+	if hourly.Date != "20260102" || hourly.StatsMetrics != next {
+		t.Errorf("hourly after rollover = %+v", hourly)
+	}
+
+	// 迟到的前一天流水不能覆盖当天的小时统计
+	write("20260101", model.StatsMetrics{InputToken: 100}, 1, 300)
+	if err := conn.Where("hour = ?", 3).First(&hourly).Error; err != nil {
+		t.Fatal(err)
+	}
+	if hourly.Date != "20260102" || hourly.StatsMetrics != next {
+		t.Errorf("hourly after late write = %+v", hourly)
+	}
+}
+
+// 流水汇总后删除，重复汇总同一批流水不会重复累加
+func TestStatsJournalFold(t *testing.T) {
+	conn := dbtest.Open(t, "stats.db")
+	rows := []model.StatsJournal{
+		{Date: "20260101", Hour: 1, ChannelID: 1, APIKeyID: 1, StatsMetrics: model.StatsMetrics{InputToken: 3, RequestSuccess: 1}},
+		{Date: "20260101", Hour: 1, ChannelID: 2, APIKeyID: 1, StatsMetrics: model.StatsMetrics{InputToken: 4, RequestFailed: 1}},
+	}
+	if err := conn.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	ids := []int64{rows[0].ID, rows[1].ID}
+	for range 2 {
+		if err := conn.Transaction(func(tx *gorm.DB) error { return statsJournalFoldIDs(tx, ids) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var total model.StatsTotal
+	if err := conn.First(&total).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := (model.StatsMetrics{InputToken: 7, RequestSuccess: 1, RequestFailed: 1}); total.StatsMetrics != want {
+		t.Errorf("total = %+v, want %+v", total.StatsMetrics, want)
+	}
+	var apiKey model.StatsAPIKey
+	if err := conn.First(&apiKey, "api_key_id = ?", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if apiKey.InputToken != 7 {
+		t.Errorf("api key input tokens = %d, want 7", apiKey.InputToken)
+	}
+	var left int64
+	if err := conn.Model(&model.StatsJournal{}).Count(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d journal rows left after fold", left)
+	}
+}
+
+// 进程异常退出后重放流水，用量、渠道密钥费用和敏感信息命中次数都不丢失
+func TestStatsJournalReplayAfterCrash(t *testing.T) {
+	dbtest.Init(t, "stats.db")
+	ctx := context.Background()
+	statsDelta, statsJournalPending = newStatsDeltaSet(), newStatsDeltaSet()
+
+	channel := model.Channel{Name: "c1", Keys: []model.ChannelKey{{ChannelKey: "sk-1", Enabled: true, TotalCost: 1}}}
+	if err := db.Conn(ctx).Create(&channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	keyID := channel.Keys[0].ID
+	StatsRecord(ctx, model.StatsJournal{
+		ChannelID:    channel.ID,
+		ChannelKeyID: keyID,
+		APIKeyID:     3,
+		StatsMetrics: model.StatsMetrics{InputToken: 10, InputCost: 0.5, RequestSuccess: 1},
+	})
+	StatsSensitiveUpdate(3, map[int]int64{7: 2}, ctx)
+	StatsSensitiveUpdate(3, map[int]int64{7: 1, 8: 1}, ctx)
+
+	// 模拟崩溃：内存中的增量全部丢失
+	statsDelta, statsJournalPending = newStatsDeltaSet(), newStatsDeltaSet()
+	if err := StatsJournalReplay(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var total model.StatsTotal
+	if err := db.Conn(ctx).First(&total).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := (model.StatsMetrics{InputToken: 10, InputCost: 0.5, RequestSuccess: 1}); total.StatsMetrics != want {
+		t.Errorf("total = %+v, want %+v", total.StatsMetrics, want)
+	}
+	var key model.ChannelKey
+	if err := db.Conn(ctx).First(&key, keyID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if key.TotalCost != 1.5 {
+		t.Errorf("key total cost = %v, want 1.5", key.TotalCost)
+	}
+	var sensitive []model.StatsSensitive
+	if err := db.Conn(ctx).Order("rule_id").Find(&sensitive).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(sensitive) != 2 || sensitive[0].Hits != 3 || sensitive[1].Hits != 1 || sensitive[0].APIKeyID != 3 || sensitive[0].LastHitTime == 0 {
+		t.Errorf("sensitive stats = %+v", sensitive)
+	}
+	// 只记录命中次数的流水不产生用量
+	var channels int64
+	if err := db.Conn(ctx).Model(&model.StatsChannel{}).Count(&channels).Error; err != nil {
+		t.Fatal(err)
+	}
+	if channels != 1 {
+		t.Errorf("%d channel stats rows, want 1", channels)
+	}
+}
+
+// 规则命中次数立即计入缓存，写入数据库后与已有的行累加
+func TestStatsSensitiveUpdatePersist(t *testing.T) {
+	dbtest.Init(t, "stats.db")
+	ctx := context.Background()
+	statsDelta, statsJournalPending = newStatsDeltaSet(), newStatsDeltaSet()
+	statsSensitiveCache.Clear()
+
+	StatsSensitiveUpdate(3, map[int]int64{7: 2}, ctx)
+	StatsSensitiveUpdate(3, nil, ctx)
+	StatsSensitiveUpdate(4, map[int]int64{7: 1}, ctx)
+	if err := StatsSaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+	StatsSensitiveUpdate(3, map[int]int64{7: 1}, ctx)
+	if err := StatsSaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[model.StatsSensitiveKey]int64{{RuleID: 7, APIKeyID: 3}: 3, {RuleID: 7, APIKeyID: 4}: 1}
+	var rows []model.StatsSensitive
+	if err := db.Conn(ctx).Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("sensitive stats rows = %+v", rows)
+	}
+	for _, row := range rows {
+		if row.Hits != want[model.StatsSensitiveKey{RuleID: row.RuleID, APIKeyID: row.APIKeyID}] || row.LastHitTime == 0 {
+			t.Errorf("row = %+v", row)
+		}
+	}
+	for _, s := range StatsSensitiveList() {
+		if s.Hits != want[model.StatsSensitiveKey{RuleID: s.RuleID, APIKeyID: s.APIKeyID}] {
+			t.Errorf("cached = %+v", s)
+		}
+	}
+	var journal int64
+	if err := db.Conn(ctx).Model(&model.StatsJournal{}).Count(&journal).Error; err != nil {
+		t.Fatal(err)
+	}
+	if journal != 0 {
+		t.Errorf("%d journal rows left after save", journal)
+	}
+}
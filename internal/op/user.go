@@ -1,69 +1,286 @@
 package op
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/bestruirui/octopus/internal/db"
 	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/cache"
 	"github.com/bestruirui/octopus/internal/utils/log"
 )
 
-var userCache model.User
+var userCache = cache.New[uint, model.User](16)
 
+// userLock 串行化用户的增删改，保证始终至少保留一个 owner
+var userLock sync.Mutex
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUserLastOwner = errors.New("at least one owner is required")
+)
+
+// UserInit 没有任何用户时创建默认的 owner 用户 admin
 func UserInit() error {
-	if err := db.GetDB().First(&userCache).Error; err == nil {
+	if err := userRefreshCache(context.Background()); err != nil {
+		return err
+	}
+	if userCache.Len() > 0 {
 		return nil
 	}
-	userCache.Username = "admin"
-	userCache.Password = "admin"
-	if err := userCache.HashPassword(); err != nil {
+	user := model.User{Username: "admin", Password: "admin", Role: model.UserRoleOwner}
+	if err := user.HashPassword(); err != nil {
 		return err
 	}
-	if err := db.GetDB().Create(&userCache).Error; err != nil {
+	if err := db.GetDB().Create(&user).Error; err != nil {
 		return err
 	}
+	userCache.Set(user.ID, user)
 	log.Infof("initial user: admin,password: admin")
 	return nil
 }
 
-func UserChangePassword(oldPassword, newPassword string) error {
-	if err := userCache.ComparePassword(oldPassword); err != nil {
+// UserList 按 ID 返回所有用户
+func UserList() []model.User {
+	users := make([]model.User, 0, userCache.Len())
+	for _, u := range userCache.GetAll() {
+		u.SSO = u.OIDCSubject != ""
+		users = append(users, u)
+	}
+	slices.SortFunc(users, func(a, b model.User) int { return int(a.ID) - int(b.ID) })
+	return users
+}
+
+func UserGet(id uint) (model.User, bool) {
+	return userCache.Get(id)
+}
+
+// UserGetByName 按用户名查找用户
+func UserGetByName(username string) (model.User, bool) {
+	for _, u := range userCache.GetAll() {
+		if u.Username == username {
+			return u, true
+		}
+	}
+	return model.User{}, false
+}
+
+func UserCreate(req *model.UserCreateRequest, ctx context.Context) (*model.User, error) {
+	username := strings.TrimSpace(req.Username)
+	if username == "" || req.Password == "" {
+		return nil, fmt.Errorf("username and password are required")
+	}
+	if !req.Role.Valid() {
+		return nil, fmt.Errorf("invalid role: %s", req.Role)
+	}
+
+	userLock.Lock()
+	defer userLock.Unlock()
+	if _, exists := UserGetByName(username); exists {
+		return nil, fmt.Errorf("username %s already exists", username)
+	}
+	user := model.User{Username: username, Password: req.Password, Role: req.Role}
+	if err := user.HashPassword(); err != nil {
+		return nil, err
+	}
+	if err := db.Conn(ctx).Create(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	userCache.Set(user.ID, user)
+	return &user, nil
+}
+
+// UserUpdate 修改用户的角色或重置密码，不能把最后一个 owner 改为其他角色
+func UserUpdate(req *model.UserUpdateRequest, ctx context.Context) (*model.User, error) {
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userCache.Get(req.ID)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	updates := map[string]any{}
+	if req.Role != nil && *req.Role != user.Role {
+		if !req.Role.Valid() {
+			return nil, fmt.Errorf("invalid role: %s", *req.Role)
+		}
+		if user.Role == model.UserRoleOwner && userOwnerCount() <= 1 {
+			return nil, ErrUserLastOwner
+		}
+		user.Role = *req.Role
+		updates["role"] = user.Role
+	}
+	if req.Password != nil {
+		if *req.Password == "" {
+			return nil, fmt.Errorf("password is required")
+		}
+		user.Password = *req.Password
+		if err := user.HashPassword(); err != nil {
+			return nil, err
+		}
+		updates["password"] = user.Password
+	}
+	// 用户丢失验证器时由 owner 关闭其两步验证
+	if req.DisableTOTP && user.TOTPEnabled {
+		if err := userTOTPClear(&user, ctx); err != nil {
+			return nil, err
+		}
+	}
+	if len(updates) == 0 {
+		return &user, nil
+	}
+	if err := db.Conn(ctx).Model(&model.User{ID: user.ID}).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	userCache.Set(user.ID, user)
+	// 重置密码后该用户需要重新登录
+	if req.Password != nil {
+		if err := SessionRevokeUser(user.ID, "", ctx); err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+// UserDelete 删除用户，不能删除最后一个 owner
+func UserDelete(id uint, ctx context.Context) error {
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userCache.Get(id)
+	if !ok {
+		return ErrUserNotFound
+	}
+	if user.Role == model.UserRoleOwner && userOwnerCount() <= 1 {
+		return ErrUserLastOwner
+	}
+	if err := db.Conn(ctx).Delete(&model.User{ID: id}).Error; err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	userCache.Del(id)
+	if err := AccessTokenDeleteUser(id, ctx); err != nil {
+		return err
+	}
+	return SessionRevokeUser(id, "", ctx)
+}
+
+func UserChangePassword(id uint, oldPassword, newPassword string) error {
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userCache.Get(id)
+	if !ok {
+		return ErrUserNotFound
+	}
+	if err := user.ComparePassword(oldPassword); err != nil {
 		return fmt.Errorf("incorrect old password: %w", err)
 	}
 
-	userCache.Password = newPassword
-	if err := userCache.HashPassword(); err != nil {
+	user.Password = newPassword
+	if err := user.HashPassword(); err != nil {
 		return fmt.Errorf("failed to hash new password: %w", err)
 	}
 
-	if err := db.GetDB().Model(&userCache).Update("password", userCache.Password).Error; err != nil {
+	if err := db.GetDB().Model(&user).Update("password", user.Password).Error; err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
-
+	userCache.Set(user.ID, user)
 	return nil
 }
 
-func UserChangeUsername(newUsername string) error {
-	if userCache.Username == newUsername {
+func UserChangeUsername(id uint, newUsername string) error {
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userCache.Get(id)
+	if !ok {
+		return ErrUserNotFound
+	}
+	newUsername = strings.TrimSpace(newUsername)
+	if newUsername == "" {
+		return fmt.Errorf("username is required")
+	}
+	if user.Username == newUsername {
 		return fmt.Errorf("new username is the same as the old username")
 	}
-	userCache.Username = newUsername
-	if err := db.GetDB().Model(&userCache).Update("username", userCache.Username).Error; err != nil {
+	if _, exists := UserGetByName(newUsername); exists {
+		return fmt.Errorf("username %s already exists", newUsername)
+	}
+	user.Username = newUsername
+	if err := db.GetDB().Model(&user).Update("username", user.Username).Error; err != nil {
 		return fmt.Errorf("failed to update username: %w", err)
 	}
+	userCache.Set(user.ID, user)
 	return nil
 }
 
-func UserVerify(username, password string) error {
-	if username != userCache.Username {
-		return fmt.Errorf("incorrect username")
+// UserVerify 校验用户名和密码，返回对应的用户
+func UserVerify(username, password string) (model.User, error) {
+	user, ok := UserGetByName(username)
+	if !ok {
+		return model.User{}, fmt.Errorf("incorrect username")
 	}
-	if err := userCache.ComparePassword(password); err != nil {
-		return fmt.Errorf("incorrect password")
+	if err := user.ComparePassword(password); err != nil {
+		return model.User{}, fmt.Errorf("incorrect password")
 	}
-	return nil
+	return user, nil
 }
 
-func UserGet() model.User {
-	return userCache
+// UserResetPassword 不校验旧密码直接重置密码，用于忘记密码时通过命令行恢复
+// username 为空时重置第一个 owner 的密码
+func UserResetPassword(username, newPassword string) (model.User, error) {
+	if err := UserInit(); err != nil {
+		return model.User{}, err
+	}
+	var user model.User
+	var ok bool
+	if username != "" {
+		user, ok = UserGetByName(username)
+	} else {
+		for _, u := range UserList() {
+			if u.Role == model.UserRoleOwner {
+				user, ok = u, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return model.User{}, ErrUserNotFound
+	}
+	user.Password = newPassword
+	if err := user.HashPassword(); err != nil {
+		return model.User{}, fmt.Errorf("failed to hash new password: %w", err)
+	}
+	if err := db.GetDB().Model(&user).Update("password", user.Password).Error; err != nil {
+		return model.User{}, fmt.Errorf("failed to update password: %w", err)
+	}
+	userCache.Set(user.ID, user)
+	if err := SessionRevokeUser(user.ID, "", context.Background()); err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+func userOwnerCount() int {
+	n := 0
+	for _, u := range userCache.GetAll() {
+		if u.Role == model.UserRoleOwner {
+			n++
+		}
+	}
+	return n
+}
+
+func userRefreshCache(ctx context.Context) error {
+	users := []model.User{}
+	if err := db.Conn(ctx).Find(&users).Error; err != nil {
+		return err
+	}
+	cacheRetain(userCache, func(id uint, _ model.User) bool {
+		return slices.ContainsFunc(users, func(u model.User) bool { return u.ID == id })
+	})
+	for _, u := range users {
+		userCache.Set(u.ID, u)
+	}
+	return nil
 }
@@ -0,0 +1,95 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/log"
+)
+
+var (
+	ErrOIDCUserNotAllowed = errors.New("user is not allowed to sign in with single sign-on")
+	ErrOIDCUserNotLinked  = errors.New("user already exists and is not linked to single sign-on")
+	ErrOIDCUserNotFound   = errors.New("user does not exist")
+)
+
+// UserOIDCLogin 单点登录成功后查找 subject 关联的用户，没有时按配置关联同名用户或自动创建
+// role 为声明映射得到的角色，为空表示该身份没有权限，即使已关联用户也拒绝登录
+// linkable 为用户名是否可信到可以关联已有用户，由调用方按声明来源判断
+func UserOIDCLogin(subject, username string, role model.UserRole, linkable bool, ctx context.Context) (model.User, error) {
+	cfg := conf.AppConfig.OIDC
+	username = strings.TrimSpace(username)
+	if role == "" {
+		return model.User{}, ErrOIDCUserNotAllowed
+	}
+
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userGetByOIDCSubject(subject)
+	if !ok {
+		if username == "" {
+			return model.User{}, fmt.Errorf("identity has no username")
+		}
+		existing, exists := UserGetByName(username)
+		switch {
+		case exists && (!cfg.LinkExisting || !linkable):
+			return model.User{}, fmt.Errorf("%w: %s", ErrOIDCUserNotLinked, username)
+		case exists:
+			user = existing
+			user.OIDCSubject = subject
+			if err := userSave(&user, ctx, "oidc_subject"); err != nil {
+				return model.User{}, err
+			}
+			log.Infof("linked user %s to single sign-on", username)
+		case !cfg.AutoCreate:
+			return model.User{}, fmt.Errorf("%w: %s", ErrOIDCUserNotFound, username)
+		default:
+			return userOIDCCreate(subject, username, role, ctx)
+		}
+	}
+	if !cfg.SyncRole || role == user.Role {
+		return user, nil
+	}
+	// 映射结果会让系统失去最后一个 owner 时保留原角色
+	if user.Role == model.UserRoleOwner && userOwnerCount() <= 1 {
+		log.Warnf("single sign-on maps the last owner %s to %s, keeping owner", user.Username, role)
+		return user, nil
+	}
+	user.Role = role
+	if err := userSave(&user, ctx, "role"); err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// userOIDCCreate 创建单点登录用户，密码为随机值，需要由 owner 重置后才能使用密码登录
+func userOIDCCreate(subject, username string, role model.UserRole, ctx context.Context) (model.User, error) {
+	password, err := randomHex(32)
+	if err != nil {
+		return model.User{}, err
+	}
+	user := model.User{Username: username, Password: password, Role: role, OIDCSubject: subject}
+	if err := user.HashPassword(); err != nil {
+		return model.User{}, err
+	}
+	if err := db.Conn(ctx).Create(&user).Error; err != nil {
+		return model.User{}, fmt.Errorf("failed to create user: %w", err)
+	}
+	userCache.Set(user.ID, user)
+	log.Infof("created user %s with role %s from single sign-on", username, role)
+	return user, nil
+}
+
+func userGetByOIDCSubject(subject string) (model.User, bool) {
+	for _, u := range userCache.GetAll() {
+		if u.OIDCSubject == subject {
+			return u, true
+		}
+	}
+	return model.User{}, false
+}
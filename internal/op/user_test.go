@@ -0,0 +1,68 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+// 最后一个 owner 不能被降级或删除，还有其他 owner 时可以
+func TestUserLastOwnerGuard(t *testing.T) {
+	dbtest.Init(t, "user.db")
+	ctx := context.Background()
+	if err := userRefreshCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(name string, role model.UserRole) *model.User {
+		t.Helper()
+		u, err := UserCreate(&model.UserCreateRequest{Username: name, Password: "pw", Role: role}, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	role := func(r model.UserRole) *model.UserRole { return &r }
+
+	owner := create("owner", model.UserRoleOwner)
+	viewer := create("viewer", model.UserRoleViewer)
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{"demote last owner", func() error {
+			_, err := UserUpdate(&model.UserUpdateRequest{ID: owner.ID, Role: role(model.UserRoleOperator)}, ctx)
+			return err
+		}, ErrUserLastOwner},
+		{"delete last owner", func() error { return UserDelete(owner.ID, ctx) }, ErrUserLastOwner},
+		{"keep owner role", func() error {
+			_, err := UserUpdate(&model.UserUpdateRequest{ID: owner.ID, Role: role(model.UserRoleOwner)}, ctx)
+			return err
+		}, nil},
+		{"promote viewer", func() error {
+			_, err := UserUpdate(&model.UserUpdateRequest{ID: viewer.ID, Role: role(model.UserRoleOwner)}, ctx)
+			return err
+		}, nil},
+		// 有两个 owner 后可以降级其中一个，剩下的一个仍受保护
+		{"demote one of two owners", func() error {
+			_, err := UserUpdate(&model.UserUpdateRequest{ID: owner.ID, Role: role(model.UserRoleViewer)}, ctx)
+			return err
+		}, nil},
+		{"delete remaining owner", func() error { return UserDelete(viewer.ID, ctx) }, ErrUserLastOwner},
+		{"delete non-owner", func() error { return UserDelete(owner.ID, ctx) }, nil},
+		{"delete missing user", func() error { return UserDelete(owner.ID, ctx) }, ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		if err := tt.run(); !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if n := userOwnerCount(); n != 1 {
+		t.Errorf("owners = %d, want 1", n)
+	}
+}
@@ -0,0 +1,187 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/totp"
+)
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+var (
+	ErrTOTPEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnabled = errors.New("two-factor authentication is not enabled")
+	ErrTOTPInvalid    = errors.New("invalid two-factor code")
+	ErrWrongPassword  = errors.New("incorrect password")
+)
+
+// UserTOTPSetup 为用户生成待验证的两步验证密钥，确认验证码后才会启用
+func UserTOTPSetup(id uint, ctx context.Context) (model.UserTOTPSetupResponse, error) {
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userCache.Get(id)
+	if !ok {
+		return model.UserTOTPSetupResponse{}, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return model.UserTOTPSetupResponse{}, ErrTOTPEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return model.UserTOTPSetupResponse{}, err
+	}
+	user.TOTPSecret = secret
+	if err := userSave(&user, ctx, "totp_secret"); err != nil {
+		return model.UserTOTPSetupResponse{}, err
+	}
+	return model.UserTOTPSetupResponse{Secret: secret, URI: totp.URI(conf.APP_NAME, user.Username, secret)}, nil
+}
+
+// UserTOTPEnable 校验验证码后启用两步验证，返回新的恢复码
+func UserTOTPEnable(id uint, code string, ctx context.Context) ([]string, error) {
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userCache.Get(id)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, fmt.Errorf("two-factor setup has not been started")
+	}
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrTOTPInvalid
+	}
+	codes, hashes, err := recoveryCodesGenerate()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled, user.TOTPLastStep, user.RecoveryCodes = true, step, hashes
+	if err := userSave(&user, ctx, "totp_enabled", "totp_last_step", "recovery_codes"); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// UserTOTPDisable 确认密码后关闭两步验证
+func UserTOTPDisable(id uint, password string, ctx context.Context) error {
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userCache.Get(id)
+	if !ok {
+		return ErrUserNotFound
+	}
+	if err := user.ComparePassword(password); err != nil {
+		return ErrWrongPassword
+	}
+	return userTOTPClear(&user, ctx)
+}
+
+// UserTOTPReset 不校验密码直接关闭两步验证，用于所有者或命令行恢复丢失验证器的用户
+func UserTOTPReset(id uint, ctx context.Context) error {
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userCache.Get(id)
+	if !ok {
+		return ErrUserNotFound
+	}
+	return userTOTPClear(&user, ctx)
+}
+
+// UserRecoveryCodesRegenerate 确认密码后重新生成恢复码，旧恢复码全部失效
+func UserRecoveryCodesRegenerate(id uint, password string, ctx context.Context) ([]string, error) {
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userCache.Get(id)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if err := user.ComparePassword(password); err != nil {
+		return nil, ErrWrongPassword
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTOTPNotEnabled
+	}
+	codes, hashes, err := recoveryCodesGenerate()
+	if err != nil {
+		return nil, err
+	}
+	user.RecoveryCodes = hashes
+	if err := userSave(&user, ctx, "recovery_codes"); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// UserTOTPVerify 登录时校验验证码或恢复码，验证码不能重复使用，恢复码使用后删除
+func UserTOTPVerify(id uint, code, recoveryCode string, ctx context.Context) error {
+	userLock.Lock()
+	defer userLock.Unlock()
+	user, ok := userCache.Get(id)
+	if !ok {
+		return ErrUserNotFound
+	}
+	if !user.TOTPEnabled {
+		return nil
+	}
+	if recoveryCode != "" {
+		hash := tokenHash(recoveryCodeNormalize(recoveryCode))
+		i := slices.Index(user.RecoveryCodes, hash)
+		if i < 0 {
+			return ErrTOTPInvalid
+		}
+		user.RecoveryCodes = slices.Delete(slices.Clone(user.RecoveryCodes), i, i+1)
+		return userSave(&user, ctx, "recovery_codes")
+	}
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return ErrTOTPInvalid
+	}
+	user.TOTPLastStep = step
+	return userSave(&user, ctx, "totp_last_step")
+}
+
+func userTOTPClear(user *model.User, ctx context.Context) error {
+	user.TOTPEnabled, user.TOTPSecret, user.TOTPLastStep, user.RecoveryCodes = false, "", 0, []string{}
+	return userSave(user, ctx, "totp_enabled", "totp_secret", "totp_last_step", "recovery_codes")
+}
+
+// userSave 写入用户的指定字段并更新缓存，调用方需持有 userLock
+func userSave(user *model.User, ctx context.Context, columns ...string) error {
+	if err := db.Conn(ctx).Model(&model.User{ID: user.ID}).Select(columns).Updates(user).Error; err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	userCache.Set(user.ID, *user)
+	return nil
+}
+
+// recoveryCodesGenerate 生成恢复码，返回明文和用于保存的哈希
+func recoveryCodesGenerate() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		value, err := randomHex(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = value[:5] + "-" + value[5:]
+		hashes[i] = tokenHash(value)
+	}
+	return codes, hashes, nil
+}
+
+func recoveryCodeNormalize(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
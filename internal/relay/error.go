@@ -0,0 +1,272 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/transformer/inbound"
+	"github.com/bestruirui/octopus/internal/transformer/inbound/anthropic"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/gin-gonic/gin"
+)
+
+// maxUpstreamErrorMessage 上游错误信息记录到日志的最大长度
+const maxUpstreamErrorMessage = 1024
+
+// upstreamError 上游返回的非 2xx 响应
+type upstreamError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("upstream error: %d: %s", e.StatusCode, string(e.Body))
+}
+
+// Message 尝试从上游响应体中提取可读的错误信息
+func (e *upstreamError) Message() string {
+	var payload struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(e.Body, &payload); err == nil {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		if len(payload.Error) > 0 && json.Unmarshal(payload.Error, &detail) == nil && detail.Message != "" {
+			return detail.Message
+		}
+		var text string
+		if len(payload.Error) > 0 && json.Unmarshal(payload.Error, &text) == nil && text != "" {
+			return text
+		}
+		if payload.Message != "" {
+			return payload.Message
+		}
+	}
+	msg := strings.TrimSpace(string(e.Body))
+	if msg == "" {
+		return http.StatusText(e.StatusCode)
+	}
+	if len(msg) > maxUpstreamErrorMessage {
+		msg = msg[:maxUpstreamErrorMessage]
+	}
+	return msg
+}
+
+// clientStatus 将上游状态码映射为返回给客户端的状态码
+// 上游鉴权失败属于渠道配置问题，不应让客户端误以为自己的密钥无效
+func (e *upstreamError) clientStatus() int {
+	switch e.StatusCode {
+	case http.StatusBadRequest,
+		http.StatusNotFound,
+		http.StatusRequestEntityTooLarge,
+		http.StatusUnprocessableEntity,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+		529:
+		return e.StatusCode
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// relayFailure 根据最后一次失败构造返回给客户端的状态码和错误信息
+// 错误详情可能包含渠道名称、上游地址等内部信息，只记录到日志，客户端只收到按状态码区分的通用信息
+func relayFailure(lastErr error) (int, string) {
+	statusCode := http.StatusBadGateway
+	var upErr *upstreamError
+	if errors.As(lastErr, &upErr) {
+		statusCode = upErr.clientStatus()
+		log.Warnf("all channels failed, last upstream error %d: %s", upErr.StatusCode, upErr.Message())
+	} else if lastErr != nil {
+		log.Warnf("all channels failed: %v", lastErr)
+	}
+	return statusCode, relayErrorMessage(statusCode)
+}
+
+// relayErrorMessage 返回给客户端的通用错误信息
+func relayErrorMessage(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return "the upstream rejected the request"
+	case http.StatusNotFound:
+		return "the model was not found upstream"
+	case http.StatusRequestEntityTooLarge:
+		return "the request is too large"
+	case http.StatusTooManyRequests:
+		return "upstream rate limit exceeded, please retry later"
+	case http.StatusServiceUnavailable, 529:
+		return "the upstream service is overloaded, please retry later"
+	case http.StatusGatewayTimeout:
+		return "the upstream service timed out"
+	}
+	if statusCode >= 400 && statusCode < 500 {
+		return "the request could not be processed"
+	}
+	return "all channels failed"
+}
+
+// writeError 以入站协议对应的格式返回错误
+// 流式响应已开始时以 SSE 错误事件的形式写入，否则返回带状态码的 JSON
+func writeError(c *gin.Context, inboundType inbound.InboundType, statusCode int, message string) {
+	body := errorBody(inboundType, statusCode, message)
+	if c.Writer.Written() {
+		c.Writer.Write(errorEvent(inboundType, body))
+		c.Writer.Flush()
+		c.Abort()
+		return
+	}
+	// 流式处理可能已设置 SSE 响应头，需要重置
+	c.Writer.Header().Del("Cache-Control")
+	c.Writer.Header().Del("Connection")
+	c.Writer.Header().Del("X-Accel-Buffering")
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.AbortWithStatus(statusCode)
+	c.Writer.Write(body)
+}
+
+// errorBody 构造入站协议格式的错误响应体
+func errorBody(inboundType inbound.InboundType, statusCode int, message string) []byte {
+	var payload any
+	switch inboundType {
+	case inbound.InboundTypeAnthropic:
+		payload = anthropic.AnthropicError{
+			Type: "error",
+			Error: anthropic.ErrorDetail{
+				Type:    anthropicErrorType(statusCode),
+				Message: message,
+			},
+		}
+	case inbound.InboundTypeGemini:
+		payload = geminiError{
+			Error: geminiErrorDetail{
+				Code:    statusCode,
+				Message: message,
+				Status:  geminiErrorStatus(statusCode),
+			},
+		}
+	default:
+		payload = model.ResponseError{
+			Detail: model.ErrorDetail{
+				Code:    openaiErrorCode(statusCode),
+				Message: message,
+				Type:    openaiErrorType(statusCode),
+			},
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return []byte(`{"error":{"message":"internal error","type":"server_error"}}`)
+	}
+	return body
+}
+
+// errorEvent 将错误响应体包装为入站协议的 SSE 错误事件
+func errorEvent(inboundType inbound.InboundType, body []byte) []byte {
+	switch inboundType {
+	case inbound.InboundTypeAnthropic:
+		return []byte("event: error\ndata: " + string(body) + "\n\n")
+	case inbound.InboundTypeOpenAIResponse:
+		var payload model.ResponseError
+		_ = json.Unmarshal(body, &payload)
+		event, _ := json.Marshal(map[string]any{
+			"type":    "error",
+			"code":    payload.Detail.Code,
+			"message": payload.Detail.Message,
+			"param":   nil,
+		})
+		return []byte("event: error\ndata: " + string(event) + "\n\n")
+	default:
+		return []byte("data: " + string(body) + "\n\n")
+	}
+}
+
+type geminiError struct {
+	Error geminiErrorDetail `json:"error"`
+}
+
+type geminiErrorDetail struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func openaiErrorType(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	}
+	if statusCode >= 500 {
+		return "server_error"
+	}
+	return "invalid_request_error"
+}
+
+func openaiErrorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return "invalid_api_key"
+	case http.StatusNotFound:
+		return "model_not_found"
+	case http.StatusTooManyRequests:
+		return "rate_limit_exceeded"
+	}
+	return ""
+}
+
+func anthropicErrorType(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable, 529:
+		return "overloaded_error"
+	}
+	if statusCode >= 500 {
+		return "api_error"
+	}
+	return "invalid_request_error"
+}
+
+func geminiErrorStatus(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusServiceUnavailable, 529:
+		return "UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		return "DEADLINE_EXCEEDED"
+	}
+	if statusCode >= 500 {
+		return "INTERNAL"
+	}
+	return "FAILED_PRECONDITION"
+}
@@ -0,0 +1,121 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/transformer/inbound"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRelayFailureHidesDetails(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"disabled", fmt.Errorf("channel %s is disabled", "secret-channel"), http.StatusBadGateway},
+		{"transport", fmt.Errorf("channel a failed: %w", errors.New(`Post "https://internal.example:8443/v1": dial tcp: refused`)), http.StatusBadGateway},
+		{"rate limit", fmt.Errorf("channel a failed: %w", &upstreamError{StatusCode: 429, Body: []byte(`{"error":{"message":"org-123 quota"}}`)}), http.StatusTooManyRequests},
+		{"upstream auth", fmt.Errorf("channel a failed: %w", &upstreamError{StatusCode: 401, Body: []byte(`invalid key sk-abc`)}), http.StatusBadGateway},
+		{"none", nil, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		status, message := relayFailure(tt.err)
+		if status != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.status)
+		}
+		for _, leak := range []string{"secret-channel", "internal.example", "org-123", "sk-abc"} {
+			if strings.Contains(message, leak) {
+				t.Errorf("%s: message %q leaks %q", tt.name, message, leak)
+			}
+		}
+		if message != relayErrorMessage(status) {
+			t.Errorf("%s: message = %q, want %q", tt.name, message, relayErrorMessage(status))
+		}
+	}
+}
+
+func TestWriteErrorEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		typ    inbound.InboundType
+		status int
+		want   string
+	}{
+		{"openai", inbound.InboundTypeOpenAIChat, 429,
+			`{"error":{"code":"rate_limit_exceeded","message":"m","type":"rate_limit_error"}}`},
+		{"openai server", inbound.InboundTypeOpenAIChat, 502,
+			`{"error":{"message":"m","type":"server_error"}}`},
+		{"anthropic", inbound.InboundTypeAnthropic, 529,
+			`{"type":"error","request_id":"","error":{"type":"overloaded_error","message":"m"}}`},
+		{"anthropic invalid", inbound.InboundTypeAnthropic, 400,
+			`{"type":"error","request_id":"","error":{"type":"invalid_request_error","message":"m"}}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		writeError(c, tt.typ, tt.status, "m")
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: content type = %q", tt.name, ct)
+		}
+		if !jsonEqual(t, w.Body.String(), tt.want) {
+			t.Errorf("%s: body = %s, want %s", tt.name, w.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestWriteErrorStreamEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		typ    inbound.InboundType
+		prefix string
+		want   string
+	}{
+		{"openai", inbound.InboundTypeOpenAIChat, "data: ",
+			`{"error":{"message":"m","type":"server_error"}}`},
+		{"anthropic", inbound.InboundTypeAnthropic, "event: error\ndata: ",
+			`{"type":"error","request_id":"","error":{"type":"api_error","message":"m"}}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Writer.WriteHeader(http.StatusOK)
+		c.Writer.WriteString("data: {}\n\n")
+		writeError(c, tt.typ, http.StatusBadGateway, "m")
+		event, ok := strings.CutPrefix(w.Body.String(), "data: {}\n\n")
+		if !ok || !strings.HasPrefix(event, tt.prefix) || !strings.HasSuffix(event, "\n\n") {
+			t.Fatalf("%s: unexpected stream %q", tt.name, w.Body.String())
+		}
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status changed to %d mid-stream", tt.name, w.Code)
+		}
+		if data := strings.TrimSuffix(strings.TrimPrefix(event, tt.prefix), "\n\n"); !jsonEqual(t, data, tt.want) {
+			t.Errorf("%s: event = %s, want %s", tt.name, data, tt.want)
+		}
+	}
+}
+
+func jsonEqual(t *testing.T, got, want string) bool {
+	t.Helper()
+	var a, b any
+	if err := json.Unmarshal([]byte(got), &a); err != nil {
+		t.Fatalf("invalid json %q: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &b); err != nil {
+		t.Fatal(err)
+	}
+	ga, _ := json.Marshal(a)
+	gb, _ := json.Marshal(b)
+	return string(ga) == string(gb)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/price"
+	"github.com/bestruirui/octopus/internal/transformer/inbound"
 	transformerModel "github.com/bestruirui/octopus/internal/transformer/model"
 	"github.com/bestruirui/octopus/internal/utils/log"
 )
@@ -18,13 +19,20 @@ import (
 type RelayMetrics struct {
 	// 基础信息
 	ChannelID      int
+	ChannelKeyID   int // 成功请求使用的渠道密钥，费用同时累加到该密钥
 	APIKeyID       int
 	ChannelName    string // 渠道名称
 	RequestModel   string // 请求的模型名称
 	ActualModel    string // 实际使用的模型名称
+	InboundType    inbound.InboundType
+	Stream         bool
+	StatusCode     int // 返回给客户端的 HTTP 状态码
 	StartTime      time.Time
 	FirstTokenTime time.Time // 首个 Token 时间（流式场景）
 
+	// 按顺序记录的上游尝试
+	Attempts []model.RelayAttempt
+
 	// 请求和响应内容
 	InternalRequest  *transformerModel.InternalLLMRequest
 	InternalResponse *transformerModel.InternalLLMResponse
@@ -34,8 +42,9 @@ type RelayMetrics struct {
 }
 
 // NewRelayMetrics 创建新的 RelayMetrics
-func NewRelayMetrics(requestModel string) *RelayMetrics {
+func NewRelayMetrics(inboundType inbound.InboundType, requestModel string) *RelayMetrics {
 	return &RelayMetrics{
+		InboundType:  inboundType,
 		RequestModel: requestModel,
 		StartTime:    time.Now(),
 	}
@@ -45,6 +54,11 @@ func (m *RelayMetrics) SetAPIKeyID(apiKeyID int) {
 	m.APIKeyID = apiKeyID
 }
 
+// SetStatusCode 设置返回给客户端的 HTTP 状态码
+func (m *RelayMetrics) SetStatusCode(statusCode int) {
+	m.StatusCode = statusCode
+}
+
 // SetChannel 设置通道信息
 func (m *RelayMetrics) SetChannel(channelID int, channelName string, actualModel string) {
 	m.ChannelID = channelID
@@ -52,6 +66,16 @@ func (m *RelayMetrics) SetChannel(channelID int, channelName string, actualModel
 	m.ActualModel = actualModel
 }
 
+// SetChannelKeyID 设置成功请求使用的渠道密钥
+func (m *RelayMetrics) SetChannelKeyID(channelKeyID int) {
+	m.ChannelKeyID = channelKeyID
+}
+
+// AddAttempt 记录一次上游尝试
+func (m *RelayMetrics) AddAttempt(attempt model.RelayAttempt) {
+	m.Attempts = append(m.Attempts, attempt)
+}
+
 // SetFirstTokenTime 设置首个 Token 时间
 func (m *RelayMetrics) SetFirstTokenTime(t time.Time) {
 	m.FirstTokenTime = t
@@ -60,6 +84,7 @@ func (m *RelayMetrics) SetFirstTokenTime(t time.Time) {
 // SetInternalRequest 设置内部请求
 func (m *RelayMetrics) SetInternalRequest(req *transformerModel.InternalLLMRequest) {
 	m.InternalRequest = req
+	m.Stream = req != nil && req.Stream != nil && *req.Stream
 }
 
 // SetInternalResponse 设置内部响应并计算费用
@@ -117,11 +142,13 @@ func (m *RelayMetrics) saveStats(success bool, duration time.Duration) {
 	}
 	m.Stats.WaitTime = duration.Milliseconds()
 
-	op.StatsChannelUpdate(m.ChannelID, m.Stats)
-	op.StatsTotalUpdate(m.Stats)
-	op.StatsHourlyUpdate(m.Stats)
-	op.StatsDailyUpdate(context.Background(), m.Stats)
-	op.StatsAPIKeyUpdate(m.APIKeyID, m.Stats)
+	// 客户端断开后请求上下文已取消，统计仍需写入
+	op.StatsRecord(context.Background(), model.StatsJournal{
+		ChannelID:    m.ChannelID,
+		ChannelKeyID: m.ChannelKeyID,
+		APIKeyID:     m.APIKeyID,
+		StatsMetrics: m.Stats,
+	})
 
 	log.Infof("channel: %d, model: %s, success: %t, wait time: %d, input token: %d, output token: %d, input cost: %f, output cost: %f total cost: %f",
 		m.ChannelID, m.ActualModel, success, m.Stats.WaitTime,
@@ -133,11 +160,17 @@ func (m *RelayMetrics) saveStats(success bool, duration time.Duration) {
 func (m *RelayMetrics) saveLog(ctx context.Context, err error, duration time.Duration) {
 	relayLog := model.RelayLog{
 		Time:             m.StartTime.Unix(),
+		APIKeyID:         m.APIKeyID,
+		InboundType:      int(m.InboundType),
+		Stream:           m.Stream,
+		StatusCode:       m.StatusCode,
 		RequestModelName: m.RequestModel,
 		ChannelName:      m.ChannelName,
 		ChannelId:        m.ChannelID,
 		ActualModelName:  m.ActualModel,
 		UseTime:          int(duration.Milliseconds()),
+		AttemptCount:     len(m.Attempts),
+		Attempts:         m.Attempts,
 	}
 
 	// 设置首字时间（流式场景）
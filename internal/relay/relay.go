@@ -2,6 +2,7 @@ package relay
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,9 +11,9 @@ import (
 	"time"
 
 	"github.com/bestruirui/octopus/internal/helper"
+	dbmodel "github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/relay/balancer"
-	"github.com/bestruirui/octopus/internal/server/resp"
 	"github.com/bestruirui/octopus/internal/transformer/inbound"
 	"github.com/bestruirui/octopus/internal/transformer/model"
 	"github.com/bestruirui/octopus/internal/transformer/outbound"
@@ -32,41 +33,33 @@ func Handler(inboundType inbound.InboundType, c *gin.Context) {
 	if supportedModels != "" {
 		supportedModelsArray := strings.Split(supportedModels, ",")
 		if !slices.Contains(supportedModelsArray, internalRequest.Model) {
-			resp.Error(c, http.StatusBadRequest, "model not supported")
+			writeError(c, inboundType, http.StatusBadRequest, "model not supported")
 			return
 		}
 	}
 
 	// 初始化统计和日志
 	apiKeyID := c.GetInt("api_key_id")
-	metrics := NewRelayMetrics(internalRequest.Model)
-	// 过滤敏感信息
-	for i := range internalRequest.Messages {
-		msg := &internalRequest.Messages[i]
-		// 处理简单文本内容
-		if msg.Content.Content != nil {
-			filtered, count := op.SensitiveFilterText(*msg.Content.Content)
-			if count > 0 {
-				msg.Content.Content = &filtered
-			}
-		}
-		// 处理多模态内容中的文本部分
-		for j := range msg.Content.MultipleContent {
-			if msg.Content.MultipleContent[j].Type == "text" && msg.Content.MultipleContent[j].Text != nil {
-				filtered, count := op.SensitiveFilterText(*msg.Content.MultipleContent[j].Text)
-				if count > 0 {
-					msg.Content.MultipleContent[j].Text = &filtered
-				}
-			}
-		}
+	metrics := NewRelayMetrics(inboundType, internalRequest.Model)
+	// 获取通道分组
+	group, err := op.GroupGetMap(internalRequest.Model, c.Request.Context())
+	if err != nil {
+		writeError(c, inboundType, http.StatusNotFound, "model not found")
+		return
 	}
 
+	// 按 API Key 和分组的规则集过滤敏感信息
+	sensitive := newSensitiveFilter(apiKeyID, op.SensitivePolicyResolve(apiKeyID, group.ID))
+	defer sensitive.commit()
+	sensitive.filterRequest(internalRequest)
+
 	metrics.SetInternalRequest(internalRequest)
 	metrics.SetAPIKeyID(apiKeyID)
-	// 获取通道分组
-	group, err := op.GroupGetMap(internalRequest.Model, c.Request.Context())
-	if err != nil {
-		resp.Error(c, http.StatusNotFound, "model not found")
+	if rule := sensitive.blocked(); rule != nil {
+		message := fmt.Sprintf("request blocked by sensitive filter rule: %s", rule.Name)
+		writeError(c, inboundType, http.StatusBadRequest, message)
+		metrics.SetStatusCode(http.StatusBadRequest)
+		metrics.Save(c.Request.Context(), false, errors.New(message))
 		return
 	}
 
@@ -77,7 +70,7 @@ func Handler(inboundType inbound.InboundType, c *gin.Context) {
 	for round := 0; round < maxRounds; round++ {
 		item := b.Select(group.Items)
 		if item == nil {
-			resp.Error(c, http.StatusServiceUnavailable, "no available channel")
+			writeError(c, inboundType, http.StatusServiceUnavailable, "no available channel")
 			return
 		}
 
@@ -123,51 +116,61 @@ func Handler(inboundType inbound.InboundType, c *gin.Context) {
 				internalRequest:      internalRequest,
 				channel:              channel,
 				metrics:              metrics,
+				sensitive:            sensitive,
 				usedKey:              channel.GetChannelKey(),
 				firstTokenTimeOutSec: group.FirstTokenTimeOut,
 			}
 
-			if statusCode, err := rc.forward(); err == nil {
+			attemptStart := time.Now()
+			statusCode, err := rc.forward()
+			rc.recordAttempt(item.ModelName, statusCode, time.Since(attemptStart), err)
+			if err == nil {
 				rc.collectResponse()
 				rc.usedKey.StatusCode = statusCode
 				rc.usedKey.LastUseTimeStamp = time.Now().Unix()
-				rc.usedKey.TotalCost += metrics.Stats.InputCost + metrics.Stats.OutputCost
 				op.ChannelKeyUpdate(rc.usedKey)
+				metrics.SetChannelKeyID(rc.usedKey.ID)
+				metrics.SetStatusCode(c.Writer.Status())
 				metrics.Save(c.Request.Context(), true, nil)
 				return
-			} else {
-				rc.usedKey.StatusCode = statusCode
-				rc.usedKey.LastUseTimeStamp = time.Now().Unix()
-				op.ChannelKeyUpdate(rc.usedKey)
-				if c.Writer.Written() {
-					// Streaming responses may have already started; retrying would corrupt the client stream.
-					rc.collectResponse()
-					metrics.Save(c.Request.Context(), false, err)
-					return
-				}
-				lastErr = fmt.Errorf("channel %s failed: %v", channel.Name, err)
 			}
+			rc.usedKey.StatusCode = statusCode
+			rc.usedKey.LastUseTimeStamp = time.Now().Unix()
+			op.ChannelKeyUpdate(rc.usedKey)
+			if c.Writer.Written() {
+				// Streaming responses may have already started; retrying would corrupt the client stream.
+				rc.collectResponse()
+				log.Warnf("channel %s failed after the response started: %v", channel.Name, err)
+				writeError(c, inboundType, http.StatusBadGateway, relayErrorMessage(http.StatusBadGateway))
+				metrics.SetStatusCode(c.Writer.Status())
+				metrics.Save(c.Request.Context(), false, err)
+				return
+			}
+			lastErr = fmt.Errorf("channel %s failed: %w", channel.Name, err)
 			item = b.Next(group.Items, item)
 		}
 	}
 
 	// 所有通道都失败
+	statusCode, message := relayFailure(lastErr)
+	writeError(c, inboundType, statusCode, message)
+	metrics.SetStatusCode(statusCode)
 	metrics.Save(c.Request.Context(), false, lastErr)
-	resp.Error(c, http.StatusBadGateway, "all channels failed")
 }
 
 // parseRequest 解析并验证入站请求
 func parseRequest(inboundType inbound.InboundType, c *gin.Context) (*model.InternalLLMRequest, model.Inbound, error) {
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		resp.Error(c, http.StatusInternalServerError, err.Error())
+		log.Warnf("failed to read request body: %v", err)
+		writeError(c, inboundType, http.StatusInternalServerError, "failed to read request body")
 		return nil, nil, err
 	}
 
 	inAdapter := inbound.Get(inboundType)
 	internalRequest, err := inAdapter.TransformRequest(c.Request.Context(), body)
 	if err != nil {
-		resp.Error(c, http.StatusInternalServerError, err.Error())
+		writeError(c, inboundType, http.StatusBadRequest, err.Error())
 		return nil, nil, err
 	}
 
@@ -175,7 +178,7 @@ func parseRequest(inboundType inbound.InboundType, c *gin.Context) (*model.Inter
 	internalRequest.Query = c.Request.URL.Query()
 
 	if err := internalRequest.Validate(); err != nil {
-		resp.Error(c, http.StatusBadRequest, err.Error())
+		writeError(c, inboundType, http.StatusBadRequest, err.Error())
 		return nil, nil, err
 	}
 
@@ -200,6 +203,7 @@ func (rc *relayContext) forward() (int, error) {
 
 	// 复制请求头
 	rc.copyHeaders(outboundRequest)
+	rc.sensitive.resetStream()
 
 	// 发送请求
 	response, err := rc.sendRequest(outboundRequest)
@@ -214,7 +218,7 @@ func (rc *relayContext) forward() (int, error) {
 		if err != nil {
 			return 0, fmt.Errorf("failed to read response body: %w", err)
 		}
-		return 0, fmt.Errorf("upstream error: %d: %s", response.StatusCode, string(body))
+		return response.StatusCode, &upstreamError{StatusCode: response.StatusCode, Body: body}
 	}
 
 	// 处理响应
@@ -366,6 +370,19 @@ func (rc *relayContext) transformStreamData(ctx context.Context, data string) ([
 		return nil, nil
 	}
 
+	// 流结束前输出敏感信息过滤暂存的内容
+	var pending []byte
+	if internalStream.Object == "[DONE]" {
+		if flushed := rc.sensitive.flushStream(); flushed != nil {
+			if pending, err = rc.inAdapter.TransformStream(ctx, flushed); err != nil {
+				log.Warnf("failed to transform stream: %v", err)
+				return nil, err
+			}
+		}
+	} else {
+		rc.sensitive.filterStream(internalStream)
+	}
+
 	// 内部格式 → 入站格式
 	inStream, err := rc.inAdapter.TransformStream(ctx, internalStream)
 	if err != nil {
@@ -373,7 +390,7 @@ func (rc *relayContext) transformStreamData(ctx context.Context, data string) ([
 		return nil, err
 	}
 
-	return inStream, nil
+	return append(pending, inStream...), nil
 }
 
 // handleResponse 处理非流式响应
@@ -384,6 +401,7 @@ func (rc *relayContext) handleResponse(ctx context.Context, response *http.Respo
 		log.Warnf("failed to transform response: %v", err)
 		return fmt.Errorf("failed to transform outbound response: %w", err)
 	}
+	rc.sensitive.filterResponse(internalResponse)
 
 	// 内部格式 → 入站格式
 	inResponse, err := rc.inAdapter.TransformResponse(ctx, internalResponse)
@@ -396,6 +414,23 @@ func (rc *relayContext) handleResponse(ctx context.Context, response *http.Respo
 	return nil
 }
 
+// recordAttempt 记录本次上游尝试
+func (rc *relayContext) recordAttempt(modelName string, statusCode int, latency time.Duration, err error) {
+	attempt := dbmodel.RelayAttempt{
+		ChannelID:   rc.channel.ID,
+		ChannelName: rc.channel.Name,
+		KeyID:       rc.usedKey.ID,
+		BaseUrl:     rc.channel.GetBaseUrl(),
+		ModelName:   modelName,
+		StatusCode:  statusCode,
+		Latency:     int(latency.Milliseconds()),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	rc.metrics.AddAttempt(attempt)
+}
+
 // collectResponse 收集响应信息
 func (rc *relayContext) collectResponse() {
 	internalResponse, err := rc.inAdapter.GetInternalResponse(rc.c.Request.Context())
@@ -0,0 +1,400 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+)
+
+// sensitiveStreamHoldback 流式场景中暂存的尾部字节数，
+// 用于匹配被拆分到多个 SSE 事件中的敏感信息，需不小于常见密钥的长度
+const sensitiveStreamHoldback = 256
+
+// sensitiveMaskPrefix 掩码占位符前缀，完整格式为 __MASKED_SECRET_<序号>__
+const sensitiveMaskPrefix = "__MASKED_SECRET_"
+
+var sensitiveMaskRegex = regexp.MustCompile(`__MASKED_SECRET_\d+__`)
+
+// sensitiveFilter 对单个请求的请求体和响应体进行敏感信息过滤
+// 推理内容(reasoning)不参与过滤：修改后会导致上游的思维链签名校验失败
+type sensitiveFilter struct {
+	// streams 流式响应中每个字段尚未输出的尾部内容
+	streams map[sensitiveStreamKey]*string
+	// secrets 掩码占位符到原始值的映射，仅在当前请求内有效
+	secrets map[string]string
+	// placeholders 原始值到掩码占位符的映射，保证同一请求内相同的值使用相同的占位符
+	placeholders map[string]string
+	// result 请求和响应中各规则的命中情况
+	result   op.SensitiveFilterResult
+	policy   *op.SensitivePolicy
+	apiKeyID int
+}
+
+// sensitiveStreamKey 标识流式响应中的一个可累积字段
+type sensitiveStreamKey struct {
+	choice   int
+	field    string // content 或 tool_call
+	toolCall int
+}
+
+func newSensitiveFilter(apiKeyID int, policy *op.SensitivePolicy) *sensitiveFilter {
+	return &sensitiveFilter{apiKeyID: apiKeyID, policy: policy}
+}
+
+// blocked 返回命中的拦截规则
+func (f *sensitiveFilter) blocked() *dbmodel.SensitiveFilterRule {
+	return f.result.Blocked
+}
+
+// commit 将本次请求的规则命中次数计入统计
+func (f *sensitiveFilter) commit() {
+	// 客户端断开后请求上下文已取消，统计仍需写入
+	op.StatsSensitiveUpdate(f.apiKeyID, f.result.Hits, context.Background())
+}
+
+// SensitiveFilterTest 使用规则集的当前规则过滤一段示例文本，不计入命中统计
+func SensitiveFilterTest(text string, direction dbmodel.SensitiveDirection, ruleSetID int) dbmodel.SensitiveTestResult {
+	if direction == "" {
+		direction = dbmodel.SensitiveDirectionRequest
+	}
+	f := newSensitiveFilter(0, op.SensitivePolicyGet(ruleSetID))
+	f.filterText(&text, direction)
+
+	result := dbmodel.SensitiveTestResult{Text: text, Hits: []dbmodel.SensitiveRuleHit{}}
+	if f.result.Blocked != nil {
+		result.Blocked = true
+		result.BlockedRule = f.result.Blocked.Name
+	}
+	for id, count := range f.result.Hits {
+		hit := dbmodel.SensitiveRuleHit{RuleID: id, Count: count}
+		if rule := f.policy.Rule(id); rule != nil {
+			hit.Name = rule.Name
+			hit.Action = rule.Action
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	sort.Slice(result.Hits, func(i, j int) bool { return result.Hits[i].RuleID < result.Hits[j].RuleID })
+	return result
+}
+
+// mask 为命中掩码规则的值生成占位符
+func (f *sensitiveFilter) mask(rule *dbmodel.SensitiveFilterRule, match string) string {
+	// 已经是占位符的内容不再嵌套掩码
+	if _, ok := f.secrets[match]; ok {
+		return match
+	}
+	if placeholder, ok := f.placeholders[match]; ok {
+		return placeholder
+	}
+	if f.secrets == nil {
+		f.secrets = make(map[string]string)
+		f.placeholders = make(map[string]string)
+	}
+	placeholder := fmt.Sprintf("%s%d__", sensitiveMaskPrefix, len(f.secrets)+1)
+	f.secrets[placeholder] = match
+	f.placeholders[match] = placeholder
+	return placeholder
+}
+
+// restore 将文本中的掩码占位符还原为原始值，jsonEscape 为 true 时按 JSON 字符串转义
+func (f *sensitiveFilter) restore(text string, jsonEscape bool) string {
+	if len(f.secrets) == 0 || !strings.Contains(text, sensitiveMaskPrefix) {
+		return text
+	}
+	return sensitiveMaskRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		secret, ok := f.secrets[placeholder]
+		if !ok {
+			return placeholder
+		}
+		if jsonEscape {
+			if b, err := json.Marshal(secret); err == nil {
+				return string(b[1 : len(b)-1])
+			}
+		}
+		return secret
+	})
+}
+
+// active 判断指定方向是否需要处理：存在生效的规则，或响应中有待还原的掩码
+func (f *sensitiveFilter) active(direction dbmodel.SensitiveDirection) bool {
+	if direction == dbmodel.SensitiveDirectionResponse && len(f.secrets) > 0 {
+		return true
+	}
+	return f.policy.HasRules(direction)
+}
+
+// filterText 按方向过滤一段文本，响应方向先还原掩码
+func (f *sensitiveFilter) filterText(text *string, direction dbmodel.SensitiveDirection) {
+	if text == nil || *text == "" {
+		return
+	}
+	if direction == dbmodel.SensitiveDirectionResponse {
+		*text = f.restore(*text, false)
+	}
+	*text = f.policy.Apply(*text, direction, f.mask, &f.result)
+}
+
+// filterJSON 过滤 JSON 文本，替换后不再是合法 JSON 时改为逐个过滤字符串值
+func (f *sensitiveFilter) filterJSON(raw string, direction dbmodel.SensitiveDirection) string {
+	if raw == "" {
+		return raw
+	}
+	if direction == dbmodel.SensitiveDirectionResponse {
+		raw = f.restore(raw, true)
+	}
+	// 先整体替换，结果不是合法 JSON 时丢弃本次命中，改为逐个过滤字符串值
+	var result op.SensitiveFilterResult
+	filtered := f.policy.Apply(raw, direction, f.mask, &result)
+	if filtered == raw || json.Valid([]byte(filtered)) || !json.Valid([]byte(raw)) {
+		f.result.Merge(&result)
+		return filtered
+	}
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		f.result.Merge(&result)
+		return filtered
+	}
+	out, err := json.Marshal(f.filterJSONValue(value, direction))
+	if err != nil {
+		f.result.Merge(&result)
+		return filtered
+	}
+	return string(out)
+}
+
+func (f *sensitiveFilter) filterJSONValue(value any, direction dbmodel.SensitiveDirection) any {
+	switch v := value.(type) {
+	case string:
+		return f.policy.Apply(v, direction, f.mask, &f.result)
+	case []any:
+		for i := range v {
+			v[i] = f.filterJSONValue(v[i], direction)
+		}
+		return v
+	case map[string]any:
+		for k := range v {
+			v[k] = f.filterJSONValue(v[k], direction)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// filterMessage 过滤消息中的文本、拒绝信息和工具调用参数
+func (f *sensitiveFilter) filterMessage(msg *model.Message, direction dbmodel.SensitiveDirection) {
+	if msg == nil {
+		return
+	}
+	f.filterText(msg.Content.Content, direction)
+	for j := range msg.Content.MultipleContent {
+		if msg.Content.MultipleContent[j].Type == "text" {
+			f.filterText(msg.Content.MultipleContent[j].Text, direction)
+		}
+	}
+	f.filterText(&msg.Refusal, direction)
+	for j := range msg.ToolCalls {
+		msg.ToolCalls[j].Function.Arguments = f.filterJSON(msg.ToolCalls[j].Function.Arguments, direction)
+	}
+}
+
+// filterRequest 过滤请求中的消息、工具定义和响应格式
+func (f *sensitiveFilter) filterRequest(req *model.InternalLLMRequest) {
+	const direction = dbmodel.SensitiveDirectionRequest
+	if !f.policy.HasRules(direction) {
+		return
+	}
+	for i := range req.Messages {
+		f.filterMessage(&req.Messages[i], direction)
+	}
+	for i := range req.Tools {
+		fn := &req.Tools[i].Function
+		f.filterText(&fn.Description, direction)
+		if len(fn.Parameters) > 0 {
+			fn.Parameters = json.RawMessage(f.filterJSON(string(fn.Parameters), direction))
+		}
+	}
+	if req.ResponseFormat != nil && len(req.ResponseFormat.JSONSchema) > 0 {
+		req.ResponseFormat.JSONSchema = json.RawMessage(f.filterJSON(string(req.ResponseFormat.JSONSchema), direction))
+	}
+}
+
+// filterResponse 过滤非流式响应
+func (f *sensitiveFilter) filterResponse(resp *model.InternalLLMResponse) {
+	const direction = dbmodel.SensitiveDirectionResponse
+	if resp == nil || !f.active(direction) {
+		return
+	}
+	for i := range resp.Choices {
+		f.filterMessage(resp.Choices[i].Message, direction)
+	}
+}
+
+// resetStream 清空流式暂存内容，切换渠道重试时调用
+func (f *sensitiveFilter) resetStream() {
+	f.streams = nil
+}
+
+// filterStream 过滤流式响应块
+// 每个字段暂存尾部内容直到确认其中不包含被拆分的敏感信息，choice 结束时输出全部剩余内容
+func (f *sensitiveFilter) filterStream(chunk *model.InternalLLMResponse) {
+	const direction = dbmodel.SensitiveDirectionResponse
+	if chunk == nil || (f.streams == nil && !f.active(direction)) {
+		return
+	}
+	if f.streams == nil {
+		f.streams = make(map[sensitiveStreamKey]*string)
+	}
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		final := choice.FinishReason != nil
+		if choice.Delta == nil {
+			if !final || !f.hasPending(choice.Index) {
+				continue
+			}
+			choice.Delta = &model.Message{}
+		}
+		delta := choice.Delta
+
+		key := sensitiveStreamKey{choice: choice.Index, field: "content"}
+		var text string
+		if delta.Content.Content != nil {
+			text = *delta.Content.Content
+		}
+		if out := f.streamAppend(key, text, final, direction); out != "" || delta.Content.Content != nil {
+			delta.Content.Content = &out
+		}
+
+		for j := range delta.ToolCalls {
+			tc := &delta.ToolCalls[j]
+			key := sensitiveStreamKey{choice: choice.Index, field: "tool_call", toolCall: tc.Index}
+			tc.Function.Arguments = f.streamAppend(key, tc.Function.Arguments, final, direction)
+		}
+		if final {
+			// 输出没有在本块中出现的工具调用的剩余参数
+			for _, key := range f.pendingKeys(choice.Index) {
+				delta.ToolCalls = append(delta.ToolCalls, model.ToolCall{
+					Index:    key.toolCall,
+					Function: model.FunctionCall{Arguments: f.streamAppend(key, "", true, direction)},
+				})
+			}
+		}
+	}
+}
+
+// flushStream 在流结束但 choice 未给出结束原因时，输出所有暂存内容
+func (f *sensitiveFilter) flushStream() *model.InternalLLMResponse {
+	if len(f.streams) == 0 {
+		return nil
+	}
+	choiceSet := make(map[int]struct{})
+	for key := range f.streams {
+		choiceSet[key.choice] = struct{}{}
+	}
+	indexes := make([]int, 0, len(choiceSet))
+	for index := range choiceSet {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	chunk := &model.InternalLLMResponse{Object: "chat.completion.chunk"}
+	for _, index := range indexes {
+		delta := &model.Message{}
+		key := sensitiveStreamKey{choice: index, field: "content"}
+		if _, ok := f.streams[key]; ok {
+			out := f.streamAppend(key, "", true, dbmodel.SensitiveDirectionResponse)
+			delta.Content.Content = &out
+		}
+		for _, key := range f.pendingKeys(index) {
+			delta.ToolCalls = append(delta.ToolCalls, model.ToolCall{
+				Index:    key.toolCall,
+				Function: model.FunctionCall{Arguments: f.streamAppend(key, "", true, dbmodel.SensitiveDirectionResponse)},
+			})
+		}
+		chunk.Choices = append(chunk.Choices, model.Choice{Index: index, Delta: delta})
+	}
+	return chunk
+}
+
+func (f *sensitiveFilter) hasPending(choice int) bool {
+	for key := range f.streams {
+		if key.choice == choice {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingKeys 返回指定 choice 中仍有暂存内容的工具调用，按工具调用序号排序
+func (f *sensitiveFilter) pendingKeys(choice int) []sensitiveStreamKey {
+	var keys []sensitiveStreamKey
+	for key := range f.streams {
+		if key.choice == choice && key.field == "tool_call" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].toolCall < keys[j].toolCall })
+	return keys
+}
+
+// streamAppend 追加新内容并返回可以安全输出的已过滤内容
+func (f *sensitiveFilter) streamAppend(key sensitiveStreamKey, text string, final bool, direction dbmodel.SensitiveDirection) string {
+	buf := text
+	if pending, ok := f.streams[key]; ok {
+		buf = *pending + text
+	}
+	if buf == "" {
+		delete(f.streams, key)
+		return ""
+	}
+
+	cut := len(buf)
+	if !final {
+		matches := f.policy.Matches(buf, direction)
+		if len(f.secrets) > 0 {
+			matches = append(matches, sensitiveMaskRegex.FindAllStringIndex(buf, -1)...)
+		}
+		cut = safeStreamCut(buf, matches)
+	}
+	if cut < len(buf) {
+		rest := buf[cut:]
+		f.streams[key] = &rest
+	} else {
+		delete(f.streams, key)
+	}
+
+	// 工具调用参数是 JSON，还原的值需要转义
+	out := f.restore(buf[:cut], key.field == "tool_call")
+	out = f.policy.Apply(out, direction, f.mask, &f.result)
+	return out
+}
+
+// safeStreamCut 计算可以输出的前缀长度：保留最后 sensitiveStreamHoldback 字节，
+// 并且不切断任何已命中的匹配
+func safeStreamCut(buf string, matches [][]int) int {
+	cut := len(buf) - sensitiveStreamHoldback
+	if cut <= 0 {
+		return 0
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, m := range matches {
+			if m[0] < cut && m[1] > cut {
+				cut = m[0]
+				changed = true
+			}
+		}
+	}
+	for cut > 0 && !utf8.RuneStart(buf[cut]) {
+		cut--
+	}
+	return cut
+}
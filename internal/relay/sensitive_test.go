@@ -0,0 +1,445 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/inbound"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/outbound"
+	"github.com/gin-gonic/gin"
+)
+
+// testSensitivePolicy 将规则写入临时数据库，返回默认规则集生效的策略
+func testSensitivePolicy(t *testing.T, rules ...dbmodel.SensitiveFilterRule) *op.SensitivePolicy {
+	t.Helper()
+	dbtest.Init(t, "sensitive.db")
+	for i := range rules {
+		rules[i].Enabled = true
+		if err := db.GetDB().Create(&rules[i]).Error; err != nil {
+			t.Fatalf("create rule %s: %v", rules[i].Name, err)
+		}
+	}
+	op.SensitiveFilterRefresh()
+	return op.SensitivePolicyGet(0)
+}
+
+// testKeyRule 响应方向的密钥替换规则
+func testKeyRule() dbmodel.SensitiveFilterRule {
+	return dbmodel.SensitiveFilterRule{
+		Name:        "key",
+		Pattern:     `sk-[A-Za-z0-9]{32,}`,
+		Replacement: "[KEY]",
+		Direction:   dbmodel.SensitiveDirectionResponse,
+		Action:      dbmodel.SensitiveActionReplace,
+	}
+}
+
+func textChunk(text string) *model.InternalLLMResponse {
+	return &model.InternalLLMResponse{Choices: []model.Choice{{
+		Delta: &model.Message{Content: model.MessageContent{Content: &text}},
+	}}}
+}
+
+func toolChunk(index int, args string) *model.InternalLLMResponse {
+	return &model.InternalLLMResponse{Choices: []model.Choice{{
+		Delta: &model.Message{ToolCalls: []model.ToolCall{{Index: index, Function: model.FunctionCall{Arguments: args}}}},
+	}}}
+}
+
+// finishChunk 只带结束原因、没有内容的块
+func finishChunk() *model.InternalLLMResponse {
+	reason := "stop"
+	return &model.InternalLLMResponse{Choices: []model.Choice{{FinishReason: &reason}}}
+}
+
+// streamOutput 客户端实际收到的流式内容
+type streamOutput struct {
+	content string
+	tools   map[int]string
+	deltas  []string // 每次输出的非空片段
+}
+
+func (o *streamOutput) add(chunk *model.InternalLLMResponse) {
+	if chunk == nil {
+		return
+	}
+	for _, choice := range chunk.Choices {
+		if choice.Delta == nil {
+			continue
+		}
+		if c := choice.Delta.Content.Content; c != nil && *c != "" {
+			o.content += *c
+			o.deltas = append(o.deltas, *c)
+		}
+		for _, tc := range choice.Delta.ToolCalls {
+			if tc.Function.Arguments == "" {
+				continue
+			}
+			if o.tools == nil {
+				o.tools = make(map[int]string)
+			}
+			o.tools[tc.Index] += tc.Function.Arguments
+			o.deltas = append(o.deltas, tc.Function.Arguments)
+		}
+	}
+}
+
+// runStream 依次过滤流式块，done 为 true 时模拟没有结束原因的 [DONE]
+func runStream(f *sensitiveFilter, chunks []*model.InternalLLMResponse, done bool) *streamOutput {
+	out := &streamOutput{}
+	for _, chunk := range chunks {
+		f.filterStream(chunk)
+		out.add(chunk)
+	}
+	if done {
+		out.add(f.flushStream())
+	}
+	return out
+}
+
+func TestSensitiveFilterStream(t *testing.T) {
+	pad := strings.Repeat("x", 300)
+	secret := "sk-ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789abcdef"
+	cjk := strings.Repeat("中", 100)
+
+	tests := []struct {
+		name        string
+		chunks      []*model.InternalLLMResponse
+		done        bool
+		wantContent string
+		wantTools   map[int]string
+		forbid      string // 任何一次输出都不能包含的内容
+	}{
+		{
+			name:        "secret split across chunks",
+			chunks:      []*model.InternalLLMResponse{textChunk(pad + " key " + secret[:13]), textChunk(secret[13:] + " end"), finishChunk()},
+			wantContent: pad + " key [KEY] end",
+			forbid:      "sk-",
+		},
+		{
+			name:        "secret split in every chunk",
+			chunks:      []*model.InternalLLMResponse{textChunk("a " + secret[:4]), textChunk(secret[4:20]), textChunk(secret[20:]), textChunk(" b"), finishChunk()},
+			wantContent: "a [KEY] b",
+			forbid:      "sk-",
+		},
+		{
+			name:        "holdback flushed on finish_reason",
+			chunks:      []*model.InternalLLMResponse{textChunk("token " + secret), finishChunk()},
+			wantContent: "token [KEY]",
+			forbid:      "sk-",
+		},
+		{
+			name:        "holdback flushed on done",
+			chunks:      []*model.InternalLLMResponse{textChunk(pad), textChunk(" token " + secret)},
+			done:        true,
+			wantContent: pad + " token [KEY]",
+			forbid:      "sk-",
+		},
+		{
+			name: "tool call arguments buffered",
+			chunks: []*model.InternalLLMResponse{
+				toolChunk(0, `{"key":"`+secret[:10]), toolChunk(1, `{"q":1}`), toolChunk(0, secret[10:]+`"}`), finishChunk(),
+			},
+			wantTools: map[int]string{0: `{"key":"[KEY]"}`, 1: `{"q":1}`},
+			forbid:    "sk-",
+		},
+		{
+			name:      "tool call arguments flushed on done",
+			chunks:    []*model.InternalLLMResponse{toolChunk(0, `{"key":"`+secret+`"`), toolChunk(0, `}`)},
+			done:      true,
+			wantTools: map[int]string{0: `{"key":"[KEY]"}`},
+			forbid:    "sk-",
+		},
+		{
+			name:        "multi-byte characters at the cut point",
+			chunks:      []*model.InternalLLMResponse{textChunk(cjk[:150]), textChunk(cjk[150:] + cjk), finishChunk()},
+			wantContent: cjk + cjk,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSensitiveFilter(0, testSensitivePolicy(t, testKeyRule()))
+			out := runStream(f, tt.chunks, tt.done)
+			if out.content != tt.wantContent {
+				t.Errorf("content = %q, want %q", out.content, tt.wantContent)
+			}
+			if len(out.tools) != len(tt.wantTools) {
+				t.Errorf("tool calls = %v, want %v", out.tools, tt.wantTools)
+			}
+			for index, want := range tt.wantTools {
+				if out.tools[index] != want {
+					t.Errorf("tool call %d = %q, want %q", index, out.tools[index], want)
+				}
+			}
+			for _, delta := range out.deltas {
+				if !utf8.ValidString(delta) {
+					t.Errorf("delta %q is not valid UTF-8", delta)
+				}
+				if tt.forbid != "" && strings.Contains(delta, tt.forbid) {
+					t.Errorf("delta %q contains %q", delta, tt.forbid)
+				}
+			}
+			if len(f.streams) != 0 {
+				t.Errorf("pending streams = %d after finish", len(f.streams))
+			}
+		})
+	}
+}
+
+// 每个块只输出暂存 sensitiveStreamHoldback 字节之前的内容，剩余部分在流结束时输出
+func TestSensitiveFilterStreamHoldback(t *testing.T) {
+	f := newSensitiveFilter(0, testSensitivePolicy(t, testKeyRule()))
+	pad := strings.Repeat("x", 300)
+
+	chunk := textChunk(pad)
+	f.filterStream(chunk)
+	if got := *chunk.Choices[0].Delta.Content.Content; got != pad[:300-sensitiveStreamHoldback] {
+		t.Fatalf("first chunk emitted %d bytes, want %d", len(got), 300-sensitiveStreamHoldback)
+	}
+	chunk = textChunk("y")
+	f.filterStream(chunk)
+	if got := *chunk.Choices[0].Delta.Content.Content; got != "x" {
+		t.Fatalf("second chunk emitted %q, want %q", got, "x")
+	}
+	if flushed := f.flushStream(); flushed == nil || *flushed.Choices[0].Delta.Content.Content != pad[:sensitiveStreamHoldback-1]+"y" {
+		t.Fatalf("flush did not emit the held back tail")
+	}
+	if f.flushStream() != nil {
+		t.Fatalf("second flush should be empty")
+	}
+}
+
+func TestSafeStreamCut(t *testing.T) {
+	ascii := strings.Repeat("a", 300)
+	cjk := strings.Repeat("中", 100) // 300 字节，300-256=44 落在字符中间
+
+	tests := []struct {
+		name    string
+		buf     string
+		matches [][]int
+		want    int
+	}{
+		{"shorter than holdback", ascii[:200], nil, 0},
+		{"exactly holdback", ascii[:sensitiveStreamHoldback], nil, 0},
+		{"plain", ascii, nil, 44},
+		{"match before cut", ascii, [][]int{{10, 20}}, 44},
+		{"match across cut", ascii, [][]int{{40, 50}}, 40},
+		{"overlapping matches", ascii, [][]int{{35, 42}, {40, 50}}, 35},
+		{"match ending at cut", ascii, [][]int{{30, 44}}, 44},
+		{"multi-byte boundary", cjk, nil, 42},
+		{"multi-byte with match", "ab" + cjk, [][]int{{2, 47}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := safeStreamCut(tt.buf, tt.matches)
+			if got != tt.want {
+				t.Fatalf("safeStreamCut = %d, want %d", got, tt.want)
+			}
+			if !utf8.ValidString(tt.buf[:got]) || !utf8.ValidString(tt.buf[got:]) {
+				t.Fatalf("cut %d splits a character", got)
+			}
+		})
+	}
+}
+
+// testMaskFilter 使用两条请求方向的掩码规则过滤请求，返回保存了占位符映射的过滤器
+// 请求中依次出现 key、password、key，key 对应 __MASKED_SECRET_1__，password 对应 __MASKED_SECRET_2__
+func testMaskFilter(t *testing.T, key, password string) *sensitiveFilter {
+	t.Helper()
+	policy := testSensitivePolicy(t,
+		dbmodel.SensitiveFilterRule{Name: "key", Pattern: `sk-[A-Za-z0-9]{32,}`, Replacement: "[KEY]",
+			Direction: dbmodel.SensitiveDirectionRequest, Action: dbmodel.SensitiveActionMask},
+		dbmodel.SensitiveFilterRule{Name: "password", Pattern: `pw:\S+`, Replacement: "[PASSWORD]",
+			Direction: dbmodel.SensitiveDirectionRequest, Action: dbmodel.SensitiveActionMask},
+	)
+	f := newSensitiveFilter(0, policy)
+
+	text := "use " + key + " and " + password
+	again := "again " + key
+	args, _ := json.Marshal(map[string]string{"key": key})
+	req := &model.InternalLLMRequest{Messages: []model.Message{
+		{Role: "user", Content: model.MessageContent{Content: &text}},
+		{Role: "assistant", ToolCalls: []model.ToolCall{{Function: model.FunctionCall{Arguments: string(args)}}}},
+		{Role: "user", Content: model.MessageContent{Content: &again}},
+	}}
+	f.filterRequest(req)
+
+	if want := "use __MASKED_SECRET_1__ and __MASKED_SECRET_2__"; *req.Messages[0].Content.Content != want {
+		t.Fatalf("request content = %q, want %q", *req.Messages[0].Content.Content, want)
+	}
+	// 同一请求内相同的值使用相同的占位符
+	if want := `{"key":"__MASKED_SECRET_1__"}`; req.Messages[1].ToolCalls[0].Function.Arguments != want {
+		t.Fatalf("request tool call = %q, want %q", req.Messages[1].ToolCalls[0].Function.Arguments, want)
+	}
+	if want := "again __MASKED_SECRET_1__"; *req.Messages[2].Content.Content != want {
+		t.Fatalf("request content = %q, want %q", *req.Messages[2].Content.Content, want)
+	}
+	if len(f.secrets) != 2 {
+		t.Fatalf("secrets = %v, want 2 placeholders", f.secrets)
+	}
+	return f
+}
+
+func TestSensitiveMaskRestore(t *testing.T) {
+	key := "sk-ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789abcdef"
+	password := `pw:a"b\c`
+	pad := strings.Repeat("x", 300)
+
+	t.Run("response", func(t *testing.T) {
+		f := testMaskFilter(t, key, password)
+		content := "got __MASKED_SECRET_1__, __MASKED_SECRET_2__ and __MASKED_SECRET_9__"
+		resp := &model.InternalLLMResponse{Choices: []model.Choice{{Message: &model.Message{
+			Content:   model.MessageContent{Content: &content},
+			ToolCalls: []model.ToolCall{{Function: model.FunctionCall{Arguments: `{"cmd":"login __MASKED_SECRET_2__","key":"__MASKED_SECRET_1__"}`}}},
+		}}}}
+		f.filterResponse(resp)
+
+		msg := resp.Choices[0].Message
+		// 未知的占位符保持原样
+		if want := "got " + key + ", " + password + " and __MASKED_SECRET_9__"; *msg.Content.Content != want {
+			t.Errorf("content = %q, want %q", *msg.Content.Content, want)
+		}
+		var args map[string]string
+		if err := json.Unmarshal([]byte(msg.ToolCalls[0].Function.Arguments), &args); err != nil {
+			t.Fatalf("tool call arguments %q are not valid JSON: %v", msg.ToolCalls[0].Function.Arguments, err)
+		}
+		if args["cmd"] != "login "+password || args["key"] != key {
+			t.Errorf("tool call arguments = %v", args)
+		}
+	})
+
+	streams := []struct {
+		name        string
+		chunks      []*model.InternalLLMResponse
+		done        bool
+		wantContent string
+		wantArgs    map[string]string
+	}{
+		{
+			name:        "stream",
+			chunks:      []*model.InternalLLMResponse{textChunk("got __MASKED_SECRET_1__ and "), textChunk("__MASKED_SECRET_2__"), finishChunk()},
+			wantContent: "got " + key + " and " + password,
+		},
+		{
+			name:        "stream placeholder split across chunks",
+			chunks:      []*model.InternalLLMResponse{textChunk(pad + " got __MASKED_SEC"), textChunk("RET_1__ and __MASKED_SECRET_"), textChunk("2__ end"), finishChunk()},
+			wantContent: pad + " got " + key + " and " + password + " end",
+		},
+		{
+			name:        "stream placeholder split at done",
+			chunks:      []*model.InternalLLMResponse{textChunk(pad + " __MASKED_"), textChunk("SECRET_1__")},
+			done:        true,
+			wantContent: pad + " " + key,
+		},
+		{
+			name: "stream tool call arguments",
+			chunks: []*model.InternalLLMResponse{
+				toolChunk(0, `{"cmd":"`+pad+` login __MASKED_SECRET`), toolChunk(0, `_2__","key":"__MASKED_`), toolChunk(0, `SECRET_1__"}`), finishChunk(),
+			},
+			wantArgs: map[string]string{"cmd": pad + " login " + password, "key": key},
+		},
+	}
+	for _, tt := range streams {
+		t.Run(tt.name, func(t *testing.T) {
+			f := testMaskFilter(t, key, password)
+			out := runStream(f, tt.chunks, tt.done)
+			if out.content != tt.wantContent {
+				t.Errorf("content = %q, want %q", out.content, tt.wantContent)
+			}
+			if tt.wantArgs != nil {
+				var args map[string]string
+				if err := json.Unmarshal([]byte(out.tools[0]), &args); err != nil {
+					t.Fatalf("tool call arguments %q are not valid JSON: %v", out.tools[0], err)
+				}
+				for k, want := range tt.wantArgs {
+					if args[k] != want {
+						t.Errorf("tool call argument %s = %q, want %q", k, args[k], want)
+					}
+				}
+			}
+			for _, delta := range out.deltas {
+				if strings.Contains(delta, "__MASKED") || strings.Contains(delta, "SECRET_") {
+					t.Errorf("delta %q leaks a placeholder fragment", delta)
+				}
+			}
+		})
+	}
+}
+
+// 命中拦截规则的请求返回 400 且不转发到上游，命中次数计入统计
+func TestHandlerSensitiveBlock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var upstream atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"1","object":"chat.completion","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	block := dbmodel.SensitiveFilterRule{Name: "forbidden", Pattern: `forbidden-\w+`, Replacement: "[BLOCKED]",
+		Direction: dbmodel.SensitiveDirectionRequest, Action: dbmodel.SensitiveActionBlock}
+	testSensitivePolicy(t, block)
+	channel := dbmodel.Channel{Name: "c", Type: outbound.OutboundTypeOpenAIChat, BaseUrls: []dbmodel.BaseUrl{{URL: server.URL}},
+		Keys: []dbmodel.ChannelKey{{ChannelKey: "sk-upstream", Enabled: true}}}
+	if err := db.GetDB().Create(&channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	group := dbmodel.Group{Name: "m", Items: []dbmodel.GroupItem{{ChannelID: channel.ID, ModelName: "m"}}}
+	if err := db.GetDB().Create(&group).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := op.InitCache(); err != nil {
+		t.Fatal(err)
+	}
+	var ruleID int
+	if err := db.GetDB().Model(&dbmodel.SensitiveFilterRule{}).Select("id").Where("name = ?", block.Name).Scan(&ruleID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(content string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := fmt.Sprintf(`{"model":"m","messages":[{"role":"user","content":%q}]}`, content)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		c.Set("api_key_id", 5)
+		Handler(inbound.InboundTypeOpenAIChat, c)
+		return w
+	}
+
+	if w := send("hello"); w.Code != http.StatusOK || upstream.Load() != 1 {
+		t.Fatalf("allowed request: status = %d, upstream calls = %d", w.Code, upstream.Load())
+	}
+	w := send("please use forbidden-word")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("blocked request: status = %d, want 400", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), block.Name) {
+		t.Errorf("blocked request body = %s, want rule name", w.Body.String())
+	}
+	if upstream.Load() != 1 {
+		t.Errorf("blocked request reached the upstream")
+	}
+
+	var hits int64
+	for _, s := range op.StatsSensitiveList() {
+		if s.RuleID == ruleID && s.APIKeyID == 5 {
+			hits = s.Hits
+		}
+	}
+	if hits != 1 {
+		t.Errorf("rule hits = %d, want 1", hits)
+	}
+}
@@ -46,6 +46,7 @@ type relayContext struct {
 	internalRequest *model.InternalLLMRequest
 	channel         *dbmodel.Channel
 	metrics         *RelayMetrics
+	sensitive       *sensitiveFilter
 
 	usedKey dbmodel.ChannelKey
 
@@ -1,60 +1,133 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
-	"math/big"
+	"encoding/hex"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJWTToken(expiresMin int) (string, string, error) {
+// AccessTokenTTL 访问令牌的有效期，过期后客户端使用刷新令牌换取新令牌
+// 轮换签名密钥后旧密钥也只保留这么久
+const AccessTokenTTL = 15 * time.Minute
+
+// Claims 管理后台访问令牌携带的用户信息，权限以服务端当前的角色为准
+type Claims struct {
+	UserID    uint           `json:"uid"`
+	Username  string         `json:"username"`
+	Role      model.UserRole `json:"role"`
+	SessionID string         `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// SessionTTL 按登录时的 expire（分钟）计算会话有效期，0 为 15 分钟，-1 为 30 天
+func SessionTTL(expiresMin int) time.Duration {
+	switch {
+	case expiresMin > 0:
+		return time.Duration(expiresMin) * time.Minute
+	case expiresMin == -1:
+		return 30 * 24 * time.Hour
+	default:
+		return 15 * time.Minute
+	}
+}
+
+// GenerateJWTToken 为会话签发访问令牌，有效期不超过会话本身
+func GenerateJWTToken(user model.User, session model.UserSession) (string, string, error) {
+	secret, ok := op.AuthSecretCurrent()
+	if !ok {
+		return "", "", fmt.Errorf("auth secret not initialized")
+	}
 	now := time.Now()
-	claims := &jwt.RegisteredClaims{
-		IssuedAt:  jwt.NewNumericDate(now),
-		NotBefore: jwt.NewNumericDate(now),
-		Issuer:    conf.APP_NAME,
-	}
-	if expiresMin == 0 {
-		claims.ExpiresAt = jwt.NewNumericDate(now.Add(time.Duration(15) * time.Minute))
-	} else if expiresMin > 0 {
-		claims.ExpiresAt = jwt.NewNumericDate(now.Add(time.Duration(expiresMin) * time.Minute))
-	} else if expiresMin == -1 {
-		claims.ExpiresAt = jwt.NewNumericDate(now.Add(time.Duration(30) * 24 * time.Hour))
-	}
-	user := op.UserGet()
-	secret := user.Username + user.Password
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	expiresAt := now.Add(AccessTokenTTL)
+	if sessionEnd := time.Unix(session.ExpiresAt, 0); sessionEnd.Before(expiresAt) {
+		expiresAt = sessionEnd
+	}
+	claims := &Claims{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		SessionID: session.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    conf.APP_NAME,
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = strconv.FormatUint(uint64(secret.ID), 10)
+	signed, err := token.SignedString([]byte(secret.Secret))
 	if err != nil {
 		return "", "", err
 	}
-	return token, claims.ExpiresAt.Format(time.RFC3339), nil
+	return signed, claims.ExpiresAt.Format(time.RFC3339), nil
 }
 
-func VerifyJWTToken(token string) bool {
-	jwtToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		user := op.UserGet()
-		secret := user.Username + user.Password
-		return []byte(secret), nil
-	})
+// VerifyJWTToken 校验访问令牌，返回对应的用户和会话，会话被撤销后令牌立即失效
+func VerifyJWTToken(token string, ctx context.Context) (model.User, model.UserSession, bool) {
+	claims := &Claims{}
+	jwtToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		id, err := strconv.ParseUint(kid, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kid")
+		}
+		secret, ok := op.AuthSecretGet(uint(id))
+		if !ok || (secret.RetiredAt > 0 && time.Since(time.Unix(secret.RetiredAt, 0)) > AccessTokenTTL) {
+			return nil, fmt.Errorf("unknown signing key")
+		}
+		return []byte(secret.Secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
 	if err != nil || !jwtToken.Valid {
-		return false
+		return model.User{}, model.UserSession{}, false
 	}
-	return true
+	user, ok := op.UserGet(claims.UserID)
+	if !ok {
+		return model.User{}, model.UserSession{}, false
+	}
+	session, ok := op.SessionGet(claims.SessionID, ctx)
+	if !ok || session.UserID != user.ID {
+		return model.User{}, model.UserSession{}, false
+	}
+	return user, session, true
 }
 
-func GenerateAPIKey() string {
-	const keyChars = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	b := make([]byte, 48)
-	maxI := big.NewInt(int64(len(keyChars)))
-	for i := range b {
-		n, err := rand.Int(rand.Reader, maxI)
-		if err != nil {
-			return ""
-		}
-		b[i] = keyChars[n.Int64()]
+// RotateSecret 轮换签名密钥，已签发的访问令牌在过期前仍然有效
+func RotateSecret(ctx context.Context) error {
+	_, err := op.AuthSecretRotate(AccessTokenTTL, ctx)
+	return err
+}
+
+// AccessTokenPrefix 个人访问令牌的前缀，用于和登录令牌区分
+const AccessTokenPrefix = "pat-" + conf.APP_NAME + "-"
+
+// GenerateAccessToken 生成个人访问令牌
+func GenerateAccessToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return AccessTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// VerifyAccessToken 校验个人访问令牌，返回所属用户和令牌
+func VerifyAccessToken(token, ip string, ctx context.Context) (model.User, model.AccessToken, bool) {
+	accessToken, ok := op.AccessTokenVerify(token, ip, ctx)
+	if !ok {
+		return model.User{}, model.AccessToken{}, false
+	}
+	user, ok := op.UserGet(accessToken.UserID)
+	if !ok {
+		return model.User{}, model.AccessToken{}, false
 	}
-	return "sk-" + conf.APP_NAME + "-" + string(b)
+	return user, accessToken, true
 }
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 轮换密钥后旧令牌在 AccessTokenTTL 内仍然有效，停用更久的密钥和未知的 kid 签名的令牌被拒绝
+func TestVerifyJWTTokenRetiredKey(t *testing.T) {
+	dbtest.Init(t, "auth.db")
+	ctx := context.Background()
+	if err := op.AuthSecretInit(); err != nil {
+		t.Fatal(err)
+	}
+	user, err := op.UserCreate(&model.UserCreateRequest{Username: "u", Password: "pw", Role: model.UserRoleViewer}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, _, err := op.SessionCreate(user.ID, time.Hour, "", "", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func() string {
+		t.Helper()
+		token, _, err := GenerateJWTToken(*user, session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	oldSecret, _ := op.AuthSecretCurrent()
+	oldToken := sign()
+	if err := RotateSecret(ctx); err != nil {
+		t.Fatal(err)
+	}
+	newToken := sign()
+
+	forged := func(kid any) string {
+		claims := &Claims{UserID: user.ID, SessionID: session.ID, RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}}
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["kid"] = kid
+		signed, _ := token.SignedString([]byte(oldSecret.Secret))
+		return signed
+	}
+
+	check := func(name, token string, want bool) {
+		t.Helper()
+		u, s, ok := VerifyJWTToken(token, ctx)
+		if ok != want {
+			t.Errorf("%s: ok = %v, want %v", name, ok, want)
+		}
+		if ok && (u.ID != user.ID || s.ID != session.ID) {
+			t.Errorf("%s: user %d session %s", name, u.ID, s.ID)
+		}
+	}
+	check("token from retired key within TTL", oldToken, true)
+	check("token from current key", newToken, true)
+	check("unknown kid", forged("999"), false)
+	check("non-numeric kid", forged("x"), false)
+	check("missing kid", forged(nil), false)
+
+	// 密钥停用超过 AccessTokenTTL 后，即使还在缓存中也不再接受
+	retiredAt := time.Now().Add(-AccessTokenTTL - time.Minute).Unix()
+	if err := db.Conn(ctx).Model(&model.AuthSecret{}).Where("id = ?", oldSecret.ID).
+		Update("retired_at", retiredAt).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := op.AuthSecretInit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := op.AuthSecretGet(oldSecret.ID); !ok {
+		t.Fatal("retired secret not cached")
+	}
+	check("token from key retired longer than TTL", oldToken, false)
+	check("token from current key after expiry of old key", newToken, true)
+
+	// 会话撤销后令牌立即失效
+	if err := op.SessionRevoke(user.ID, session.ID, ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("revoked session", newToken, false)
+}
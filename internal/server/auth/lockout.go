@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/utils/log"
+)
+
+// 登录失败次数限制：窗口期内同一用户名或同一 IP 失败次数达到上限后锁定一段时间
+// 计数保存在内存中，多实例部署时每个实例分别计数
+const (
+	loginWindow          = 15 * time.Minute
+	loginLockout         = 15 * time.Minute
+	loginMaxUserFailures = 5
+	loginMaxIPFailures   = 20
+	loginMaxEntries      = 10000
+)
+
+type loginFailures struct {
+	count       int
+	first       time.Time
+	lockedUntil time.Time
+}
+
+var (
+	loginLock   sync.Mutex
+	loginByUser = make(map[string]*loginFailures)
+	loginByIP   = make(map[string]*loginFailures)
+)
+
+// LoginLocked 用户名或 IP 是否处于锁定中，返回剩余的锁定时间
+func LoginLocked(username, ip string) (time.Duration, bool) {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+	now := time.Now()
+	var wait time.Duration
+	for _, f := range []*loginFailures{loginByUser[username], loginByIP[ip]} {
+		if f != nil && f.lockedUntil.After(now) {
+			wait = max(wait, f.lockedUntil.Sub(now))
+		}
+	}
+	return wait, wait > 0
+}
+
+// LoginFailed 记录一次失败的登录，用户名不存在时同样计数
+func LoginFailed(username, ip string) {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+	now := time.Now()
+	if loginRecord(loginByUser, username, loginMaxUserFailures, now) {
+		log.Warnf("login locked for user %s after %d failures", username, loginMaxUserFailures)
+	}
+	if loginRecord(loginByIP, ip, loginMaxIPFailures, now) {
+		log.Warnf("login locked for ip %s after %d failures", ip, loginMaxIPFailures)
+	}
+}
+
+// LoginSucceeded 登录成功后清除该用户名的失败计数，IP 的计数保留到窗口期结束
+func LoginSucceeded(username string) {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+	delete(loginByUser, username)
+}
+
+// loginRecord 增加失败次数，达到上限时锁定并返回 true
+func loginRecord(m map[string]*loginFailures, key string, limit int, now time.Time) bool {
+	if len(m) >= loginMaxEntries {
+		loginPrune(m, now)
+	}
+	f := m[key]
+	if f == nil {
+		f = &loginFailures{}
+		m[key] = f
+	}
+	if now.Sub(f.first) > loginWindow {
+		f.count, f.first = 0, now
+	}
+	f.count++
+	if f.count < limit {
+		return false
+	}
+	f.count, f.first, f.lockedUntil = 0, now, now.Add(loginLockout)
+	return true
+}
+
+// loginPrune 删除窗口期和锁定都已结束的记录
+func loginPrune(m map[string]*loginFailures, now time.Time) {
+	for k, f := range m {
+		if now.Sub(f.first) > loginWindow && !f.lockedUntil.After(now) {
+			delete(m, k)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func resetLogin() {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+	loginByUser = make(map[string]*loginFailures)
+	loginByIP = make(map[string]*loginFailures)
+}
+
+func TestLoginLockoutThresholds(t *testing.T) {
+	tests := []struct {
+		name string
+		// fail 依次记录失败的用户名和 IP
+		fail       func()
+		username   string
+		ip         string
+		wantLocked bool
+	}{
+		{"below user limit", func() {
+			for range loginMaxUserFailures - 1 {
+				LoginFailed("alice", "10.0.0.1")
+			}
+		}, "alice", "10.0.0.9", false},
+		{"user limit", func() {
+			for range loginMaxUserFailures {
+				LoginFailed("alice", "10.0.0.1")
+			}
+		}, "alice", "10.0.0.9", true},
+		{"user limit does not lock other users", func() {
+			for range loginMaxUserFailures {
+				LoginFailed("alice", "10.0.0.1")
+			}
+		}, "bob", "10.0.0.9", false},
+		{"success clears user failures", func() {
+			for range loginMaxUserFailures - 1 {
+				LoginFailed("alice", "10.0.0.1")
+			}
+			LoginSucceeded("alice")
+			LoginFailed("alice", "10.0.0.1")
+		}, "alice", "10.0.0.9", false},
+		{"below ip limit", func() {
+			for i := range loginMaxIPFailures - 1 {
+				LoginFailed("user"+strconv.Itoa(i), "10.0.0.1")
+			}
+		}, "carol", "10.0.0.1", false},
+		{"ip limit locks every user", func() {
+			for i := range loginMaxIPFailures {
+				LoginFailed("user"+strconv.Itoa(i), "10.0.0.1")
+			}
+		}, "carol", "10.0.0.1", true},
+		{"success keeps ip failures", func() {
+			for i := range loginMaxIPFailures - 1 {
+				LoginFailed("user"+strconv.Itoa(i), "10.0.0.1")
+			}
+			LoginSucceeded("user0")
+			LoginFailed("user0", "10.0.0.1")
+		}, "carol", "10.0.0.1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetLogin()
+			t.Cleanup(resetLogin)
+			tt.fail()
+			wait, locked := LoginLocked(tt.username, tt.ip)
+			if locked != tt.wantLocked {
+				t.Fatalf("locked = %v, want %v", locked, tt.wantLocked)
+			}
+			if locked && (wait <= loginLockout-time.Minute || wait > loginLockout) {
+				t.Errorf("wait = %v, want about %v", wait, loginLockout)
+			}
+		})
+	}
+}
+
+// 超过窗口期的失败不再累计，锁定结束后重新计数
+func TestLoginRecordWindow(t *testing.T) {
+	m := make(map[string]*loginFailures)
+	now := time.Now()
+	for i := range 3 {
+		if loginRecord(m, "k", 3, now.Add(time.Duration(i)*(loginWindow/2+time.Second))) {
+			t.Fatalf("failure %d locked across windows", i+1)
+		}
+	}
+
+	start := now.Add(3 * loginWindow)
+	for i := range 3 {
+		locked := loginRecord(m, "k", 3, start.Add(time.Duration(i)*time.Second))
+		if locked != (i == 2) {
+			t.Fatalf("failure %d: locked = %v", i+1, locked)
+		}
+	}
+	if f := m["k"]; f.count != 0 || !f.lockedUntil.Equal(start.Add(2*time.Second+loginLockout)) {
+		t.Errorf("after lock = %+v", f)
+	}
+
+	loginPrune(m, start.Add(loginLockout))
+	if _, ok := m["k"]; !ok {
+		t.Error("pruned a locked entry")
+	}
+	loginPrune(m, start.Add(loginWindow+loginLockout+3*time.Second))
+	if _, ok := m["k"]; ok {
+		t.Error("expired entry not pruned")
+	}
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/utils/oidc"
+)
+
+// OIDCStateTTL 从跳转到提供方到回调的最长时间
+const OIDCStateTTL = 10 * time.Minute
+
+// oidcRoleOrder 多个映射匹配时按此顺序取权限最高的角色
+var oidcRoleOrder = []model.UserRole{model.UserRoleOwner, model.UserRoleOperator, model.UserRoleFinance, model.UserRoleViewer}
+
+var (
+	oidcLock     sync.Mutex
+	oidcProvider *oidc.Provider
+)
+
+var (
+	ErrOIDCStateInvalid = errors.New("invalid login state")
+	ErrOIDCStateExpired = errors.New("login expired, please try again")
+)
+
+// OIDCState 登录跳转时保存在签名 Cookie 中的状态，回调可以由集群中任意实例处理
+type OIDCState struct {
+	State     string `json:"s"`
+	Nonce     string `json:"n"`
+	Verifier  string `json:"v"`
+	Expire    int    `json:"e"` // 会话有效期（分钟），同密码登录的 expire
+	ExpiresAt int64  `json:"x"`
+}
+
+// OIDCEnabled 是否配置了单点登录
+func OIDCEnabled() bool {
+	cfg := conf.AppConfig.OIDC
+	return cfg.Enabled && cfg.Issuer != "" && cfg.ClientID != ""
+}
+
+// OIDCConfig 返回客户端配置，redirectURL 为未配置回调地址时按请求生成的地址
+func OIDCConfig(redirectURL string) oidc.Config {
+	cfg := conf.AppConfig.OIDC
+	if cfg.RedirectURL != "" {
+		redirectURL = cfg.RedirectURL
+	}
+	return oidc.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       cfg.Scopes,
+	}
+}
+
+// OIDCProvider 返回提供方信息，首次使用时读取发现文档，失败后下次请求重试
+func OIDCProvider(ctx context.Context) (*oidc.Provider, error) {
+	oidcLock.Lock()
+	defer oidcLock.Unlock()
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+	p, err := oidc.Discover(ctx, conf.AppConfig.OIDC.Issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	oidcProvider = p
+	return p, nil
+}
+
+// OIDCLogin 用授权码换取并校验身份，按声明映射角色后返回对应的用户
+func OIDCLogin(ctx context.Context, redirectURL, code string, state OIDCState) (model.User, error) {
+	p, err := OIDCProvider(ctx)
+	if err != nil {
+		return model.User{}, err
+	}
+	cfg := OIDCConfig(redirectURL)
+	token, err := p.Exchange(ctx, cfg, code, state.Verifier)
+	if err != nil {
+		return model.User{}, err
+	}
+	claims, err := p.VerifyIDToken(ctx, cfg.ClientID, token.IDToken, state.Nonce)
+	if err != nil {
+		return model.User{}, err
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return model.User{}, errors.New("id_token has no subject")
+	}
+	// ID Token 中没有角色声明时从 userinfo 补充，userinfo 的 sub 必须一致
+	if claimLookup(claims, conf.AppConfig.OIDC.RoleClaim) == nil && token.AccessToken != "" && p.UserInfoURL != "" {
+		info, err := p.UserInfo(ctx, token.AccessToken)
+		if err != nil {
+			return model.User{}, err
+		}
+		if info["sub"] != sub {
+			return model.User{}, errors.New("userinfo subject does not match id_token")
+		}
+		for k, v := range info {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+	username, linkable := oidcUsername(claims)
+	return op.UserOIDCLogin(p.Issuer+"|"+sub, username, OIDCRole(claims), linkable, ctx)
+}
+
+// OIDCRole 按配置的映射计算角色，没有匹配时使用默认角色，都没有时返回空
+func OIDCRole(claims map[string]any) model.UserRole {
+	cfg := conf.AppConfig.OIDC
+	values := claimStrings(claimLookup(claims, cfg.RoleClaim))
+	best := -1
+	for _, m := range cfg.RoleMappings {
+		i := slices.Index(oidcRoleOrder, model.UserRole(m.Role))
+		if i < 0 || !slices.Contains(values, m.Value) {
+			continue
+		}
+		if best < 0 || i < best {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return oidcRoleOrder[best]
+	}
+	if role := model.UserRole(cfg.DefaultRole); role.Valid() {
+		return role
+	}
+	return ""
+}
+
+// OIDCStateEncode 用当前签名密钥对状态签名
+func OIDCStateEncode(state OIDCState) (string, error) {
+	secret, ok := op.AuthSecretCurrent()
+	if !ok {
+		return "", fmt.Errorf("auth secret not initialized")
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + oidcSign(secret.Secret, payload), nil
+}
+
+// OIDCStateDecode 校验签名和有效期后返回状态
+func OIDCStateDecode(value string) (OIDCState, error) {
+	var state OIDCState
+	secret, ok := op.AuthSecretCurrent()
+	if !ok {
+		return state, fmt.Errorf("auth secret not initialized")
+	}
+	payload, sig, found := strings.Cut(value, ".")
+	if !found || !hmac.Equal([]byte(sig), []byte(oidcSign(secret.Secret, payload))) {
+		return state, ErrOIDCStateInvalid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return state, ErrOIDCStateInvalid
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, ErrOIDCStateInvalid
+	}
+	if time.Now().Unix() > state.ExpiresAt {
+		return state, ErrOIDCStateExpired
+	}
+	return state, nil
+}
+
+func oidcSign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte("oidc-state:"+secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// oidcUsername 依次使用配置的用户名声明、email 和 sub，并返回该用户名能否用于关联同名的已有用户
+// 只有来自配置的用户名声明时才能关联，该声明为 email 时还要求 email_verified，
+// 避免他人在提供方把用户名或未验证的邮箱改成已有用户的名字后接管该用户
+func oidcUsername(claims map[string]any) (string, bool) {
+	claim := conf.AppConfig.OIDC.UsernameClaim
+	for _, key := range []string{claim, "email", "sub"} {
+		if v, ok := claimLookup(claims, key).(string); ok && strings.TrimSpace(v) != "" {
+			return v, key == claim && (key != "email" || claimTrue(claims["email_verified"]))
+		}
+	}
+	return "", false
+}
+
+// claimTrue 声明值是否为 true，部分提供方以字符串 "true" 返回布尔声明
+func claimTrue(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
+
+// claimLookup 读取声明，支持 realm_access.roles 这样的嵌套路径
+func claimLookup(claims map[string]any, path string) any {
+	if path == "" {
+		return nil
+	}
+	if v, ok := claims[path]; ok {
+		return v
+	}
+	var cur any = claims
+	for _, key := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[key]
+	}
+	return cur
+}
+
+func claimStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/conf"
+)
+
+// 只有来自配置声明的用户名能关联已有用户，email 还需要已验证
+func TestOIDCUsernameLinkable(t *testing.T) {
+	claim := conf.AppConfig.OIDC.UsernameClaim
+	t.Cleanup(func() { conf.AppConfig.OIDC.UsernameClaim = claim })
+
+	tests := []struct {
+		name     string
+		claim    string
+		claims   map[string]any
+		want     string
+		linkable bool
+	}{
+		{"configured claim", "preferred_username", map[string]any{"preferred_username": "alice", "email": "a@x"}, "alice", true},
+		{"email fallback", "preferred_username", map[string]any{"email": "a@x", "email_verified": true}, "a@x", false},
+		{"sub fallback", "preferred_username", map[string]any{"sub": "123"}, "123", false},
+		{"blank configured claim", "preferred_username", map[string]any{"preferred_username": " ", "sub": "123"}, "123", false},
+		{"verified email", "email", map[string]any{"email": "a@x", "email_verified": true}, "a@x", true},
+		{"verified email as string", "email", map[string]any{"email": "a@x", "email_verified": "true"}, "a@x", true},
+		{"unverified email", "email", map[string]any{"email": "a@x", "email_verified": false}, "a@x", false},
+		{"email without email_verified", "email", map[string]any{"email": "a@x"}, "a@x", false},
+		{"no username", "preferred_username", map[string]any{}, "", false},
+	}
+	for _, tt := range tests {
+		conf.AppConfig.OIDC.UsernameClaim = tt.claim
+		got, linkable := oidcUsername(tt.claims)
+		if got != tt.want || linkable != tt.linkable {
+			t.Errorf("%s: oidcUsername = %q, %v, want %q, %v", tt.name, got, linkable, tt.want, tt.linkable)
+		}
+	}
+}
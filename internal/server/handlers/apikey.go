@@ -7,7 +7,6 @@ import (
 
 	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
-	"github.com/bestruirui/octopus/internal/server/auth"
 	"github.com/bestruirui/octopus/internal/server/middleware"
 	"github.com/bestruirui/octopus/internal/server/resp"
 	"github.com/bestruirui/octopus/internal/server/router"
@@ -17,10 +16,12 @@ import (
 
 func init() {
 	router.NewGroupRouter("/api/v1/apikey").
+		Require(model.PermAPIKeyRead).
 		Use(middleware.Auth()).
 		Use(middleware.RequireJSON()).
 		AddRoute(
 			router.NewRoute("/create", http.MethodPost).
+				Require(model.PermAPIKeyWrite).
 				Handle(createAPIKey),
 		).
 		AddRoute(
@@ -29,10 +30,12 @@ func init() {
 		).
 		AddRoute(
 			router.NewRoute("/update", http.MethodPost).
+				Require(model.PermAPIKeyWrite).
 				Handle(updateAPIKey),
 		).
 		AddRoute(
 			router.NewRoute("/delete/:id", http.MethodDelete).
+				Require(model.PermAPIKeyWrite).
 				Handle(deleteAPIKey),
 		)
 	router.NewGroupRouter("/api/v1/apikey").
@@ -53,7 +56,13 @@ func createAPIKey(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
-	req.APIKey = auth.GenerateAPIKey()
+	key, err := op.APIKeyGenerate()
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, resp.ErrInternalServer)
+		return
+	}
+	req.APIKey = key
+	req.Managed = false
 	if err := op.APIKeyCreate(&req, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -67,15 +76,34 @@ func listAPIKey(c *gin.Context) {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	// 只读角色（如财务）只能看到 Key 的末尾几位，不能拿到可用于转发的完整 Key
+	if !middleware.Can(c, model.PermAPIKeyWrite) {
+		for i := range apiKeys {
+			apiKeys[i].APIKey = maskAPIKey(apiKeys[i].APIKey)
+		}
+	}
 	resp.Success(c, apiKeys)
 }
 
+// maskAPIKey 保留前缀和末尾 4 位，其余替换为 *
+func maskAPIKey(key string) string {
+	rest := strings.TrimPrefix(key, op.APIKeyPrefix)
+	if len(rest) <= 4 {
+		return op.APIKeyPrefix + strings.Repeat("*", len(rest))
+	}
+	return op.APIKeyPrefix + "****" + rest[len(rest)-4:]
+}
+
 func updateAPIKey(c *gin.Context) {
 	var req model.APIKey
 	if err := c.ShouldBindJSON(&req); err != nil {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if apiKeyManaged(req.ID, c) {
+		return
+	}
+	req.Managed = false
 	if err := op.APIKeyUpdate(&req, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -90,6 +118,9 @@ func deleteAPIKey(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
 		return
 	}
+	if apiKeyManaged(idNum, c) {
+		return
+	}
 	if err := op.APIKeyDelete(idNum, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -97,6 +128,16 @@ func deleteAPIKey(c *gin.Context) {
 	resp.Success(c, nil)
 }
 
+// apiKeyManaged API Key 由声明式配置管理时返回错误响应
+func apiKeyManaged(id int, c *gin.Context) bool {
+	apiKey, err := op.APIKeyGet(id, c.Request.Context())
+	if err != nil || !apiKey.Managed {
+		return false
+	}
+	resp.Error(c, http.StatusForbidden, resp.ErrManagedResource)
+	return true
+}
+
 func getStatsAPIKeyById(c *gin.Context) {
 	id := c.GetInt("api_key_id")
 	stats := op.StatsAPIKeyGet(id)
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/middleware"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/server/router"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/backup").
+		Require(model.PermBackupRead).
+		Use(middleware.Auth()).
+		AddRoute(
+			router.NewRoute("/list", http.MethodGet).
+				Handle(listBackup),
+		).
+		AddRoute(
+			router.NewRoute("/create", http.MethodPost).
+				Require(model.PermBackupWrite).
+				Handle(createBackup),
+		).
+		AddRoute(
+			router.NewRoute("/restore", http.MethodPost).
+				Require(model.PermBackupWrite).
+				Use(middleware.RequireJSON()).
+				Handle(restoreBackup),
+		).
+		AddRoute(
+			router.NewRoute("/download/:name", http.MethodGet).
+				Handle(downloadBackup),
+		).
+		AddRoute(
+			router.NewRoute("/delete/:name", http.MethodDelete).
+				Require(model.PermBackupWrite).
+				Handle(deleteBackup),
+		)
+}
+
+func listBackup(c *gin.Context) {
+	list, err := op.BackupList()
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, list)
+}
+
+func createBackup(c *gin.Context) {
+	file, err := op.BackupCreate(c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, file)
+}
+
+func restoreBackup(c *gin.Context) {
+	var req struct {
+		Name   string             `json:"name"`
+		Mode   model.DBImportMode `json:"mode"`
+		DryRun bool               `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	result, err := op.BackupRestore(c.Request.Context(), req.Name, model.DBImportOptions{Mode: req.Mode, DryRun: req.DryRun})
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	reloadAfterImport(c, result)
+
+	resp.Success(c, result)
+}
+
+func downloadBackup(c *gin.Context) {
+	name := c.Param("name")
+	path, err := op.BackupPath(name)
+	if err != nil {
+		resp.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	c.FileAttachment(path, name)
+}
+
+func deleteBackup(c *gin.Context) {
+	if err := op.BackupDelete(c.Param("name")); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
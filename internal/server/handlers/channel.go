@@ -19,6 +19,7 @@ import (
 
 func init() {
 	router.NewGroupRouter("/api/v1/channel").
+		Require(model.PermChannelRead).
 		Use(middleware.Auth()).
 		Use(middleware.RequireJSON()).
 		AddRoute(
@@ -27,28 +28,35 @@ func init() {
 		).
 		AddRoute(
 			router.NewRoute("/create", http.MethodPost).
+				Require(model.PermChannelWrite).
 				Handle(createChannel),
 		).
 		AddRoute(
 			router.NewRoute("/update", http.MethodPost).
+				Require(model.PermChannelWrite).
 				Handle(updateChannel),
 		).
 		AddRoute(
 			router.NewRoute("/enable", http.MethodPost).
+				Require(model.PermChannelWrite).
 				Handle(enableChannel),
 		).
 		AddRoute(
 			router.NewRoute("/delete/:id", http.MethodDelete).
+				Require(model.PermChannelWrite).
 				Handle(deleteChannel),
 		).
 		AddRoute(
 			router.NewRoute("/fetch-model", http.MethodPost).
+				Require(model.PermChannelWrite).
 				Handle(fetchModel),
 		)
 	router.NewGroupRouter("/api/v1/channel").
+		Require(model.PermChannelRead).
 		Use(middleware.Auth()).
 		AddRoute(
 			router.NewRoute("/sync", http.MethodPost).
+				Require(model.PermChannelWrite).
 				Handle(syncChannel),
 		).
 		AddRoute(
@@ -76,6 +84,7 @@ func createChannel(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	channel.Managed = false
 	if err := op.ChannelCreate(&channel, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -100,6 +109,9 @@ func updateChannel(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if channelManaged(req.ID, c) {
+		return
+	}
 	channel, err := op.ChannelUpdate(&req, c.Request.Context())
 	if err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
@@ -128,6 +140,9 @@ func enableChannel(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if channelManaged(request.ID, c) {
+		return
+	}
 	if err := op.ChannelEnabled(request.ID, request.Enabled, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -142,12 +157,25 @@ func deleteChannel(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
 		return
 	}
+	if channelManaged(idNum, c) {
+		return
+	}
 	if err := op.ChannelDel(idNum, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 	resp.Success(c, nil)
 }
+
+// channelManaged 渠道由声明式配置管理时返回错误响应
+func channelManaged(id int, c *gin.Context) bool {
+	channel, err := op.ChannelGet(id, c.Request.Context())
+	if err != nil || !channel.Managed {
+		return false
+	}
+	resp.Error(c, http.StatusForbidden, resp.ErrManagedResource)
+	return true
+}
 func fetchModel(c *gin.Context) {
 	var request model.Channel
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -163,7 +191,10 @@ func fetchModel(c *gin.Context) {
 }
 
 func syncChannel(c *gin.Context) {
-	task.SyncModelsTask()
+	if err := task.SyncModelsTask(c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
 	resp.Success(c, nil)
 }
 
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/bestruirui/octopus/internal/gitops"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/server/middleware"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/server/router"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/gitops").
+		Require(model.PermGitOpsRead).
+		Use(middleware.Auth()).
+		AddRoute(
+			router.NewRoute("/status", http.MethodGet).
+				Handle(getGitOpsStatus),
+		).
+		AddRoute(
+			router.NewRoute("/plan", http.MethodPost).
+				Handle(planGitOps),
+		).
+		AddRoute(
+			router.NewRoute("/apply", http.MethodPost).
+				Require(model.PermGitOpsWrite).
+				Handle(applyGitOps),
+		)
+}
+
+func getGitOpsStatus(c *gin.Context) {
+	resp.Success(c, gitops.Status())
+}
+
+func planGitOps(c *gin.Context) {
+	plan, err := gitops.Sync(c.Request.Context(), true)
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp.Success(c, plan)
+}
+
+func applyGitOps(c *gin.Context) {
+	plan, err := gitops.Sync(c.Request.Context(), false)
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp.Success(c, plan)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/gitops"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/transformer/outbound"
+	"github.com/gin-gonic/gin"
+)
+
+func TestManagedResourceReadOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbtest.Init(t, "gitops.db")
+	if err := op.InitCache(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	cfg := &gitops.Config{
+		Settings: map[string]string{string(model.SettingKeyRelayLogBodyMaxSize): "1024"},
+		Channels: []gitops.Channel{{Name: "managed", Type: outbound.OutboundTypeOpenAIChat}},
+		Groups:   []gitops.Group{{Name: "managed", Mode: model.GroupModeRoundRobin}},
+		APIKeys:  []gitops.APIKey{{Name: "managed"}},
+	}
+	if _, err := gitops.Reconcile(ctx, cfg, false, false); err != nil {
+		t.Fatal(err)
+	}
+	unmanaged := model.Channel{Name: "unmanaged", Type: outbound.OutboundTypeOpenAIChat}
+	if err := op.ChannelCreate(&unmanaged, ctx); err != nil {
+		t.Fatal(err)
+	}
+	id := func(ids []int) int {
+		if len(ids) != 1 {
+			t.Fatalf("ids = %v", ids)
+		}
+		return ids[0]
+	}
+	var channelID, groupID, keyID []int
+	channels, _ := op.ChannelList(ctx)
+	for _, ch := range channels {
+		if ch.Managed {
+			channelID = append(channelID, ch.ID)
+		}
+	}
+	groups, _ := op.GroupList(ctx)
+	for _, g := range groups {
+		groupID = append(groupID, g.ID)
+	}
+	keys, _ := op.APIKeyList(ctx)
+	for _, k := range keys {
+		keyID = append(keyID, k.ID)
+	}
+
+	call := func(handler gin.HandlerFunc, body any, param string) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if param != "" {
+			c.Params = gin.Params{{Key: "id", Value: param}}
+		}
+		handler(c)
+		return w
+	}
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		body    any
+		param   string
+	}{
+		{"update channel", updateChannel, model.ChannelUpdateRequest{ID: id(channelID)}, ""},
+		{"enable channel", enableChannel, map[string]any{"id": id(channelID), "enabled": false}, ""},
+		{"delete channel", deleteChannel, nil, strconv.Itoa(id(channelID))},
+		{"update group", updateGroup, model.GroupUpdateRequest{ID: id(groupID)}, ""},
+		{"delete group", deleteGroup, nil, strconv.Itoa(id(groupID))},
+		{"update api key", updateAPIKey, model.APIKey{ID: id(keyID), Name: "renamed"}, ""},
+		{"delete api key", deleteAPIKey, nil, strconv.Itoa(id(keyID))},
+		{"set setting", setSetting, model.Setting{Key: model.SettingKeyRelayLogBodyMaxSize, Value: "0"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := call(tt.handler, tt.body, tt.param)
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body.String())
+			}
+			var body resp.ResponseStruct
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Message != resp.ErrManagedResource {
+				t.Errorf("message = %q", body.Message)
+			}
+		})
+	}
+
+	// 被拒绝的请求没有修改数据，未托管的对象仍可修改
+	if _, err := op.ChannelGet(id(channelID), ctx); err != nil {
+		t.Errorf("managed channel: %v", err)
+	}
+	if v, _ := op.SettingGetString(model.SettingKeyRelayLogBodyMaxSize); v != "1024" {
+		t.Errorf("setting = %q", v)
+	}
+	if w := call(deleteChannel, nil, strconv.Itoa(unmanaged.ID)); w.Code != http.StatusOK {
+		t.Errorf("delete unmanaged channel status = %d: %s", w.Code, w.Body.String())
+	}
+}
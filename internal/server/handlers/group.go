@@ -15,6 +15,7 @@ import (
 
 func init() {
 	router.NewGroupRouter("/api/v1/group").
+		Require(model.PermGroupRead).
 		Use(middleware.Auth()).
 		Use(middleware.RequireJSON()).
 		AddRoute(
@@ -23,14 +24,17 @@ func init() {
 		).
 		AddRoute(
 			router.NewRoute("/create", http.MethodPost).
+				Require(model.PermGroupWrite).
 				Handle(createGroup),
 		).
 		AddRoute(
 			router.NewRoute("/update", http.MethodPost).
+				Require(model.PermGroupWrite).
 				Handle(updateGroup),
 		).
 		AddRoute(
 			router.NewRoute("/delete/:id", http.MethodDelete).
+				Require(model.PermGroupWrite).
 				Handle(deleteGroup),
 		)
 	// AddRoute(
@@ -54,6 +58,7 @@ func createGroup(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	group.Managed = false
 	if group.MatchRegex != "" {
 		_, err := regexp2.Compile(group.MatchRegex, regexp2.ECMAScript)
 		if err != nil {
@@ -74,6 +79,9 @@ func updateGroup(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if groupManaged(req.ID, c) {
+		return
+	}
 	if req.MatchRegex != nil {
 		_, err := regexp2.Compile(*req.MatchRegex, regexp2.ECMAScript)
 		if err != nil {
@@ -96,6 +104,9 @@ func deleteGroup(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if groupManaged(idNum, c) {
+		return
+	}
 	if err := op.GroupDel(idNum, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -103,6 +114,16 @@ func deleteGroup(c *gin.Context) {
 	resp.Success(c, "group deleted successfully")
 }
 
+// groupManaged 分组由声明式配置管理时返回错误响应
+func groupManaged(id int, c *gin.Context) bool {
+	group, err := op.GroupGet(id, c.Request.Context())
+	if err != nil || !group.Managed {
+		return false
+	}
+	resp.Error(c, http.StatusForbidden, resp.ErrManagedResource)
+	return true
+}
+
 // func autoAddGroupItem(c *gin.Context) {
 // 	var req struct {
 // 		ID int `json:"id"`
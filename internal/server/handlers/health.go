@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/middleware"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/server/router"
+	"github.com/bestruirui/octopus/internal/task"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/gin-gonic/gin"
+)
+
+const readyCheckTimeout = 2 * time.Second
+
+var startTime = time.Now()
+
+// 就绪检查读取的缓存和排空状态在进程内只会变化一次，测试时替换
+var (
+	readyCacheLoaded = op.CacheLoaded
+	readyDraining    = middleware.Draining
+)
+
+func init() {
+	router.NewGroupRouter("").
+		AddRoute(
+			router.NewRoute("/healthz", http.MethodGet).
+				Handle(healthz),
+		).
+		AddRoute(
+			router.NewRoute("/readyz", http.MethodGet).
+				Handle(readyz),
+		)
+	router.NewGroupRouter("/api/v1/status").
+		Require(model.PermStatusRead).
+		Use(middleware.Auth()).
+		AddRoute(
+			router.NewRoute("", http.MethodGet).
+				Handle(getStatus),
+		)
+}
+
+// healthz 存活检查，进程能处理请求即返回 200
+func healthz(c *gin.Context) {
+	resp.Success(c, model.HealthStateOK)
+}
+
+// readyz 就绪检查，数据库不可用、缓存未加载或正在退出时返回 503
+func readyz(c *gin.Context) {
+	checks := readyChecks(c.Request.Context())
+	if !checksOK(checks) {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, resp.ResponseStruct{
+			Code:    http.StatusServiceUnavailable,
+			Message: string(model.HealthStateUnavailable),
+			Data:    checks,
+		})
+		return
+	}
+	resp.Success(c, checks)
+}
+
+func getStatus(c *gin.Context) {
+	status := model.HealthStatus{
+		Version:   conf.Version,
+		NodeID:    op.ClusterNodeID(),
+		Leader:    op.ClusterIsLeader(),
+		StartTime: startTime.Unix(),
+		Inflight:  middleware.InflightCount(),
+		Checks:    readyChecks(c.Request.Context()),
+		Tasks:     taskHealth(),
+		Stats:     op.StatsFlushInfo(),
+		RelayLog:  op.RelayLogBacklogInfo(),
+		Channels:  op.ChannelHealthList(),
+	}
+	status.Status = healthState(status)
+	resp.Success(c, status)
+}
+
+func readyChecks(ctx context.Context) []model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+	defer cancel()
+	checks := []model.HealthCheck{{Name: "database", OK: true}, {Name: "cache", OK: true}, {Name: "shutdown", OK: true}}
+	if err := db.Ping(ctx); err != nil {
+		// 健康检查无需认证，不返回数据库的错误详情
+		log.Warnf("ready check: database ping failed: %v", err)
+		checks[0] = model.HealthCheck{Name: "database", Error: "database unavailable"}
+	}
+	if !readyCacheLoaded() {
+		checks[1] = model.HealthCheck{Name: "cache", Error: "cache not loaded"}
+	}
+	if readyDraining() {
+		checks[2] = model.HealthCheck{Name: "shutdown", Error: "shutting down"}
+	}
+	return checks
+}
+
+func checksOK(checks []model.HealthCheck) bool {
+	for _, check := range checks {
+		if !check.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// taskHealth 从任务的执行记录中取最近一次结果和连续失败次数
+func taskHealth() []model.TaskHealth {
+	infos := task.List()
+	list := make([]model.TaskHealth, 0, len(infos))
+	for _, info := range infos {
+		h := model.TaskHealth{Name: info.Name, Paused: info.Paused, Running: info.Running}
+		if len(info.History) > 0 {
+			h.LastRun = info.History[0].StartTime
+			h.LastError = info.History[0].Error
+		}
+		for _, run := range info.History {
+			if run.Error == "" {
+				break
+			}
+			h.Failures++
+		}
+		list = append(list, h)
+	}
+	return list
+}
+
+// healthState 就绪检查失败为 unavailable，任务、数据写入失败或有渠道没有可用密钥为 degraded
+func healthState(status model.HealthStatus) model.HealthState {
+	if !checksOK(status.Checks) {
+		return model.HealthStateUnavailable
+	}
+	if status.Stats.LastError != "" || status.RelayLog.LastError != "" {
+		return model.HealthStateDegraded
+	}
+	for _, t := range status.Tasks {
+		if t.Failures > 0 {
+			return model.HealthStateDegraded
+		}
+	}
+	for _, ch := range status.Channels {
+		if ch.Circuit == model.ChannelCircuitOpen {
+			return model.HealthStateDegraded
+		}
+	}
+	return model.HealthStateOK
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadyz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oldCacheLoaded, oldDraining := readyCacheLoaded, readyDraining
+	t.Cleanup(func() { readyCacheLoaded, readyDraining = oldCacheLoaded, oldDraining })
+
+	ok := func(name string) model.HealthCheck { return model.HealthCheck{Name: name, OK: true} }
+	tests := []struct {
+		name        string
+		dbClosed    bool
+		cacheLoaded bool
+		draining    bool
+		status      int
+		checks      []model.HealthCheck
+	}{
+		{"ready", false, true, false, http.StatusOK, []model.HealthCheck{ok("database"), ok("cache"), ok("shutdown")}},
+		{"database unavailable", true, true, false, http.StatusServiceUnavailable, []model.HealthCheck{
+			{Name: "database", Error: "database unavailable"}, ok("cache"), ok("shutdown"),
+		}},
+		{"cache not loaded", false, false, false, http.StatusServiceUnavailable, []model.HealthCheck{
+			ok("database"), {Name: "cache", Error: "cache not loaded"}, ok("shutdown"),
+		}},
+		{"draining", false, true, true, http.StatusServiceUnavailable, []model.HealthCheck{
+			ok("database"), ok("cache"), {Name: "shutdown", Error: "shutting down"},
+		}},
+		{"all failed", true, false, true, http.StatusServiceUnavailable, []model.HealthCheck{
+			{Name: "database", Error: "database unavailable"}, {Name: "cache", Error: "cache not loaded"}, {Name: "shutdown", Error: "shutting down"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbtest.Init(t, "health.db")
+			if tt.dbClosed {
+				if err := db.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			readyCacheLoaded = func() bool { return tt.cacheLoaded }
+			readyDraining = func() bool { return tt.draining }
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			readyz(c)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			var body struct {
+				Code    int                 `json:"code"`
+				Message string              `json:"message"`
+				Data    []model.HealthCheck `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.status {
+				t.Errorf("code = %d, want %d", body.Code, tt.status)
+			}
+			if tt.status != http.StatusOK && body.Message != string(model.HealthStateUnavailable) {
+				t.Errorf("message = %q", body.Message)
+			}
+			if !reflect.DeepEqual(body.Data, tt.checks) {
+				t.Errorf("checks = %+v, want %+v", body.Data, tt.checks)
+			}
+		})
+	}
+}
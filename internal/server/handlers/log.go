@@ -5,23 +5,37 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/server/middleware"
 	"github.com/bestruirui/octopus/internal/server/resp"
 	"github.com/bestruirui/octopus/internal/server/router"
+	"github.com/bestruirui/octopus/internal/utils/log"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 func init() {
 	router.NewGroupRouter("/api/v1/log").
+		Require(model.PermLogRead).
 		Use(middleware.Auth()).
 		AddRoute(
 			router.NewRoute("/list", http.MethodGet).
 				Handle(listLog),
 		).
+		AddRoute(
+			router.NewRoute("/body/:id", http.MethodGet).
+				Handle(getLogBody),
+		).
+		AddRoute(
+			router.NewRoute("/export", http.MethodGet).
+				Handle(exportLog),
+		).
 		AddRoute(
 			router.NewRoute("/clear", http.MethodDelete).
+				Require(model.PermLogWrite).
 				Handle(clearLog),
 		).
 		AddRoute(
@@ -39,8 +53,6 @@ func init() {
 func listLog(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	startTimeStr := c.Query("start_time")
-	endTimeStr := c.Query("end_time")
 
 	if page < 1 {
 		page = 1
@@ -49,29 +61,133 @@ func listLog(c *gin.Context) {
 		pageSize = 20
 	}
 
-	var startTime, endTime *int
-	if startTimeStr != "" && endTimeStr != "" {
-		st, err := strconv.Atoi(startTimeStr)
+	filter, err := parseLogFilter(c)
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	// 游标分页时忽略页码
+	if filter.Cursor != nil {
+		page = 1
+	}
+
+	logs, err := op.RelayLogList(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp.Success(c, logs)
+}
+
+// parseLogFilter 从查询参数解析日志过滤条件
+func parseLogFilter(c *gin.Context) (model.RelayLogFilter, error) {
+	filter := model.RelayLogFilter{
+		Model:   c.Query("model"),
+		Keyword: c.Query("q"),
+	}
+	intParams := map[string]**int{
+		"start_time":         &filter.StartTime,
+		"end_time":           &filter.EndTime,
+		"channel_id":         &filter.ChannelID,
+		"api_key_id":         &filter.APIKeyID,
+		"attempt_channel_id": &filter.AttemptChannelID,
+	}
+	for name, target := range intParams {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
 		if err != nil {
-			resp.Error(c, http.StatusBadRequest, err.Error())
-			return
+			return filter, fmt.Errorf("invalid %s: %w", name, err)
 		}
-		et, err := strconv.Atoi(endTimeStr)
+		*target = &v
+	}
+	floatParams := map[string]**float64{
+		"min_cost": &filter.MinCost,
+		"max_cost": &filter.MaxCost,
+	}
+	for name, target := range floatParams {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
 		if err != nil {
-			resp.Error(c, http.StatusBadRequest, err.Error())
-			return
+			return filter, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		*target = &v
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid cursor: %w", err)
 		}
-		startTime = &st
-		endTime = &et
+		filter.Cursor = &v
 	}
+	switch c.Query("status") {
+	case "":
+	case "success":
+		filter.Success = lo.ToPtr(true)
+	case "error":
+		filter.Success = lo.ToPtr(false)
+	default:
+		return filter, fmt.Errorf("invalid status: must be success or error")
+	}
+	if raw := c.Query("failover"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, fmt.Errorf("invalid failover: %w", err)
+		}
+		filter.Failover = &v
+	}
+	return filter, nil
+}
 
-	logs, err := op.RelayLogList(c.Request.Context(), startTime, endTime, page, pageSize)
+func getLogBody(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		resp.Error(c, http.StatusInternalServerError, err.Error())
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	body, err := op.RelayLogBodyGet(c.Request.Context(), id)
+	if err != nil {
+		resp.Error(c, http.StatusNotFound, err.Error())
 		return
 	}
+	resp.Success(c, body)
+}
 
-	resp.Success(c, logs)
+func exportLog(c *gin.Context) {
+	format := op.RelayLogExportFormat(c.DefaultQuery("format", string(op.RelayLogExportFormatJSONL)))
+	includeBody, _ := strconv.ParseBool(c.DefaultQuery("include_body", "false"))
+
+	var contentType string
+	switch format {
+	case op.RelayLogExportFormatJSONL:
+		contentType = "application/x-ndjson"
+	case op.RelayLogExportFormatCSV:
+		contentType = "text/csv; charset=utf-8"
+	default:
+		resp.Error(c, http.StatusBadRequest, "format must be jsonl or csv")
+		return
+	}
+
+	filter, err := parseLogFilter(c)
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", "attachment; filename=\"octopus-logs-"+time.Now().Format("20060102150405")+"."+string(format)+"\"")
+	c.Status(http.StatusOK)
+
+	// 响应已开始写出，状态码无法再修改，RelayLogExport 会在内容末尾写入失败标记
+	if err := op.RelayLogExport(c.Request.Context(), c.Writer, format, filter, includeBody, c.Writer.Flush); err != nil {
+		log.Warnf("failed to export relay logs: %v", err)
+	}
 }
 
 func clearLog(c *gin.Context) {
@@ -100,6 +216,14 @@ func streamLog(c *gin.Context) {
 
 	op.RelayLogStreamTokenRevoke(token)
 
+	filter, err := parseLogFilter(c)
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	// 实时推送只关注新日志，游标没有意义
+	filter.Cursor = nil
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
@@ -114,10 +238,15 @@ func streamLog(c *gin.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case <-middleware.DrainStarted():
+			return
 		case log, ok := <-logChan:
 			if !ok {
 				return
 			}
+			if !filter.Match(&log) {
+				continue
+			}
 			data, err := json.Marshal(log)
 			if err != nil {
 				continue
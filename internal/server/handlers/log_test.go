@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func TestExportLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbtest.Init(t, "log.db")
+	logs := []model.RelayLog{
+		{ID: 1, Time: 1, RequestModelName: "a"},
+		{ID: 2, Time: 2, RequestModelName: "b", Error: "failed, \"quoted\""},
+	}
+	if err := db.GetDB().Create(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	export := func(ctx context.Context, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/log/export?"+query, nil).WithContext(ctx)
+		exportLog(c)
+		return w
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		status      int
+		contentType string
+		want        string
+		exclude     string
+	}{
+		{"jsonl", "", http.StatusOK, "application/x-ndjson", `"id":2`, ""},
+		{"jsonl filter", "status=success", http.StatusOK, "application/x-ndjson", `"id":1`, `"id":2`},
+		{"csv", "format=csv", http.StatusOK, "text/csv; charset=utf-8", `2,2,0,0,false,0,b,0,,,0,0,0,0,0,0,"failed, ""quoted"""`, "request_content"},
+		{"csv body", "format=csv&include_body=true", http.StatusOK, "text/csv; charset=utf-8", "request_content,response_content", ""},
+		{"bad format", "format=xml", http.StatusBadRequest, "", "", ""},
+		{"bad filter", "status=unknown", http.StatusBadRequest, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := export(context.Background(), tt.query)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("content type = %q, want %q", ct, tt.contentType)
+			}
+			if !strings.Contains(w.Header().Get("Content-Disposition"), "attachment") {
+				t.Errorf("content disposition = %q", w.Header().Get("Content-Disposition"))
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.want)
+			}
+			if tt.exclude != "" && strings.Contains(w.Body.String(), tt.exclude) {
+				t.Errorf("body = %s, should not contain %s", w.Body.String(), tt.exclude)
+			}
+			if strings.Contains(w.Body.String(), "export_error") {
+				t.Errorf("successful export has an error marker: %s", w.Body.String())
+			}
+		})
+	}
+
+	// 状态码已经发出后失败，响应末尾带有失败标记
+	t.Run("error after status", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := export(ctx, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d", w.Code)
+		}
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		var marker map[string]string
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &marker); err != nil || marker["export_error"] == "" {
+			t.Errorf("last line = %q, want an export_error marker", lines[len(lines)-1])
+		}
+	})
+}
+
+// 进入排空状态时实时日志推送结束，http.Server.Shutdown 不必等待长连接超时
+func TestStreamLogDrain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token, err := op.RelayLogStreamTokenCreate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/log/stream?token="+token, nil)
+	done := make(chan struct{})
+	go func() {
+		streamLog(c)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("stream ended before drain: %d %s", w.Code, w.Body.String())
+	case <-time.After(50 * time.Millisecond):
+	}
+	middleware.StartDrain()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream not closed after drain started")
+	}
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("status = %d, content type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
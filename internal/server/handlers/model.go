@@ -16,6 +16,7 @@ import (
 
 func init() {
 	router.NewGroupRouter("/api/v1/model").
+		Require(model.PermModelRead).
 		Use(middleware.Auth()).
 		Use(middleware.RequireJSON()).
 		AddRoute(
@@ -24,6 +25,7 @@ func init() {
 		).
 		AddRoute(
 			router.NewRoute("/create", http.MethodPost).
+				Require(model.PermModelWrite).
 				Handle(createLLM),
 		).
 		AddRoute(
@@ -32,14 +34,17 @@ func init() {
 		).
 		AddRoute(
 			router.NewRoute("/update", http.MethodPost).
+				Require(model.PermModelWrite).
 				Handle(updateLLM),
 		).
 		AddRoute(
 			router.NewRoute("/delete", http.MethodPost).
+				Require(model.PermModelWrite).
 				Handle(deleteLLM),
 		).
 		AddRoute(
 			router.NewRoute("/update-price", http.MethodPost).
+				Require(model.PermModelWrite).
 				Handle(updateLLMPrice),
 		).
 		AddRoute(
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/auth"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/server/router"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/bestruirui/octopus/internal/utils/oidc"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcCallbackPath = "/api/v1/user/oidc/callback"
+	oidcCookie       = "octopus_oidc"
+)
+
+// 单点登录失败时带回登录页的错误码，由前端显示对应的提示，详细原因只写入日志
+const (
+	oidcErrUnavailable  = "unavailable"
+	oidcErrInternal     = "internal"
+	oidcErrProvider     = "provider_error"
+	oidcErrInvalidState = "invalid_state"
+	oidcErrExpired      = "expired"
+	oidcErrNotAllowed   = "not_allowed"
+	oidcErrNotLinked    = "not_linked"
+	oidcErrNotFound     = "not_found"
+	oidcErrFailed       = "failed"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/user/oidc").
+		AddRoute(
+			router.NewRoute("/info", http.MethodGet).
+				Handle(oidcInfo),
+		).
+		AddRoute(
+			router.NewRoute("/login", http.MethodGet).
+				Handle(oidcLogin),
+		).
+		AddRoute(
+			router.NewRoute("/callback", http.MethodGet).
+				Handle(oidcCallback),
+		)
+}
+
+func oidcInfo(c *gin.Context) {
+	if !auth.OIDCEnabled() {
+		resp.Success(c, model.UserOIDCInfo{})
+		return
+	}
+	resp.Success(c, model.UserOIDCInfo{Enabled: true, Name: conf.AppConfig.OIDC.Name})
+}
+
+// oidcLogin 生成 state、nonce 和 PKCE 参数保存到签名 Cookie 后跳转到提供方
+func oidcLogin(c *gin.Context) {
+	if !auth.OIDCEnabled() {
+		resp.Error(c, http.StatusNotFound, "single sign-on is not enabled")
+		return
+	}
+	p, err := auth.OIDCProvider(c.Request.Context())
+	if err != nil {
+		log.Warnf("oidc discovery failed: %v", err)
+		oidcFail(c, oidcErrUnavailable)
+		return
+	}
+	state, err := oidc.RandomString(16)
+	if err != nil {
+		oidcFail(c, oidcErrInternal)
+		return
+	}
+	nonce, err := oidc.RandomString(16)
+	if err != nil {
+		oidcFail(c, oidcErrInternal)
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		oidcFail(c, oidcErrInternal)
+		return
+	}
+	expire, _ := strconv.Atoi(c.Query("expire"))
+	cookie, err := auth.OIDCStateEncode(auth.OIDCState{
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  verifier,
+		Expire:    expire,
+		ExpiresAt: time.Now().Add(auth.OIDCStateTTL).Unix(),
+	})
+	if err != nil {
+		oidcFail(c, oidcErrInternal)
+		return
+	}
+	// 回调是从提供方跳转回来的顶级 GET 请求，SameSite=Lax 时 Cookie 会被带上
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcCookie, cookie, int(auth.OIDCStateTTL.Seconds()), oidcCallbackPath, "", oidcSecure(c), true)
+	c.Redirect(http.StatusFound, p.AuthCodeURL(auth.OIDCConfig(oidcRedirectURL(c)), state, nonce, challenge))
+}
+
+// oidcCallback 校验 state 后完成登录，创建会话并把刷新令牌放在地址的 # 部分交给前端
+// 前端立即用它换取访问令牌，刷新令牌随之轮换，留在浏览历史中的值不再有效
+func oidcCallback(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	value, _ := c.Cookie(oidcCookie)
+	c.SetCookie(oidcCookie, "", -1, oidcCallbackPath, "", oidcSecure(c), true)
+	if !auth.OIDCEnabled() {
+		resp.Error(c, http.StatusNotFound, "single sign-on is not enabled")
+		return
+	}
+	// 提供方返回的错误描述不可信，只记录日志
+	if msg := c.Query("error"); msg != "" {
+		log.Warnf("oidc provider returned error %q: %q", msg, c.Query("error_description"))
+		oidcFail(c, oidcErrProvider)
+		return
+	}
+	state, err := auth.OIDCStateDecode(value)
+	if err != nil {
+		log.Warnf("oidc callback: %v", err)
+		oidcFail(c, oidcErrorCode(err))
+		return
+	}
+	if c.Query("state") != state.State || c.Query("code") == "" {
+		log.Warnf("oidc callback: state mismatch or missing code")
+		oidcFail(c, oidcErrInvalidState)
+		return
+	}
+	user, err := auth.OIDCLogin(c.Request.Context(), oidcRedirectURL(c), c.Query("code"), state)
+	if err != nil {
+		log.Warnf("oidc login failed: %v", err)
+		oidcFail(c, oidcErrorCode(err))
+		return
+	}
+	_, refresh, err := op.SessionCreate(user.ID, auth.SessionTTL(state.Expire), c.Request.UserAgent(), c.ClientIP(), c.Request.Context())
+	if err != nil {
+		log.Warnf("oidc login: failed to create session: %v", err)
+		oidcFail(c, oidcErrInternal)
+		return
+	}
+	log.Infof("user %s signed in with single sign-on", user.Username)
+	c.Redirect(http.StatusFound, "/#"+url.Values{"oidc_refresh": {refresh}}.Encode())
+}
+
+// oidcFail 跳转回登录页并显示错误，code 只能是 oidcErr 开头的固定错误码
+func oidcFail(c *gin.Context, code string) {
+	c.Redirect(http.StatusFound, "/#"+url.Values{"oidc_error": {code}}.Encode())
+}
+
+// oidcErrorCode 把登录过程的错误映射为错误码，未知错误统一为 failed
+func oidcErrorCode(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrOIDCStateInvalid):
+		return oidcErrInvalidState
+	case errors.Is(err, auth.ErrOIDCStateExpired):
+		return oidcErrExpired
+	case errors.Is(err, op.ErrOIDCUserNotAllowed):
+		return oidcErrNotAllowed
+	case errors.Is(err, op.ErrOIDCUserNotLinked):
+		return oidcErrNotLinked
+	case errors.Is(err, op.ErrOIDCUserNotFound):
+		return oidcErrNotFound
+	default:
+		return oidcErrFailed
+	}
+}
+
+// oidcRedirectURL 按请求地址生成回调地址，反向代理需要传递 Host 和 X-Forwarded-Proto
+func oidcRedirectURL(c *gin.Context) string {
+	scheme := "http"
+	if oidcSecure(c) {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + oidcCallbackPath
+}
+
+func oidcSecure(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/relay"
 	"github.com/bestruirui/octopus/internal/server/middleware"
 	"github.com/bestruirui/octopus/internal/server/resp"
 	"github.com/bestruirui/octopus/internal/server/router"
@@ -14,6 +15,7 @@ import (
 
 func init() {
 	router.NewGroupRouter("/api/v1/sensitive").
+		Require(model.PermSensitiveRead).
 		Use(middleware.Auth()).
 		AddRoute(
 			router.NewRoute("/list", http.MethodGet).
@@ -21,22 +23,52 @@ func init() {
 		).
 		AddRoute(
 			router.NewRoute("/create", http.MethodPost).
+				Require(model.PermSensitiveWrite).
 				Use(middleware.RequireJSON()).
 				Handle(createSensitiveRule),
 		).
 		AddRoute(
 			router.NewRoute("/update", http.MethodPost).
+				Require(model.PermSensitiveWrite).
 				Use(middleware.RequireJSON()).
 				Handle(updateSensitiveRule),
 		).
 		AddRoute(
 			router.NewRoute("/delete/:id", http.MethodDelete).
+				Require(model.PermSensitiveWrite).
 				Handle(deleteSensitiveRule),
 		).
 		AddRoute(
 			router.NewRoute("/toggle/:id", http.MethodPost).
+				Require(model.PermSensitiveWrite).
 				Use(middleware.RequireJSON()).
 				Handle(toggleSensitiveRule),
+		).
+		AddRoute(
+			router.NewRoute("/ruleset/list", http.MethodGet).
+				Handle(listSensitiveRuleSets),
+		).
+		AddRoute(
+			router.NewRoute("/ruleset/create", http.MethodPost).
+				Require(model.PermSensitiveWrite).
+				Use(middleware.RequireJSON()).
+				Handle(createSensitiveRuleSet),
+		).
+		AddRoute(
+			router.NewRoute("/ruleset/update", http.MethodPost).
+				Require(model.PermSensitiveWrite).
+				Use(middleware.RequireJSON()).
+				Handle(updateSensitiveRuleSet),
+		).
+		AddRoute(
+			router.NewRoute("/ruleset/delete/:id", http.MethodDelete).
+				Require(model.PermSensitiveWrite).
+				Handle(deleteSensitiveRuleSet),
+		).
+		AddRoute(
+			router.NewRoute("/test", http.MethodPost).
+				Use(middleware.RequireJSON()).
+				Handle(testSensitiveRules),
 		)
 }
 
@@ -109,3 +141,65 @@ func toggleSensitiveRule(c *gin.Context) {
 	}
 	resp.Success(c, nil)
 }
+
+func testSensitiveRules(c *gin.Context) {
+	var req model.SensitiveTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	rule := model.SensitiveFilterRule{Direction: req.Direction}
+	if err := rule.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp.Success(c, relay.SensitiveFilterTest(req.Text, req.Direction, req.RuleSetID))
+}
+
+func listSensitiveRuleSets(c *gin.Context) {
+	ruleSets, err := op.SensitiveRuleSetList(c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, ruleSets)
+}
+
+func createSensitiveRuleSet(c *gin.Context) {
+	var ruleSet model.SensitiveRuleSet
+	if err := c.ShouldBindJSON(&ruleSet); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := op.SensitiveRuleSetCreate(&ruleSet, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, ruleSet)
+}
+
+func updateSensitiveRuleSet(c *gin.Context) {
+	var ruleSet model.SensitiveRuleSet
+	if err := c.ShouldBindJSON(&ruleSet); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := op.SensitiveRuleSetUpdate(&ruleSet, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, ruleSet)
+}
+
+func deleteSensitiveRuleSet(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	if err := op.SensitiveRuleSetDelete(id, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
@@ -8,17 +8,20 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bestruirui/octopus/internal/gitops"
 	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/server/middleware"
 	"github.com/bestruirui/octopus/internal/server/resp"
 	"github.com/bestruirui/octopus/internal/server/router"
 	"github.com/bestruirui/octopus/internal/task"
+	"github.com/bestruirui/octopus/internal/utils/log"
 	"github.com/gin-gonic/gin"
 )
 
 func init() {
 	router.NewGroupRouter("/api/v1/setting").
+		Require(model.PermSettingRead).
 		Use(middleware.Auth()).
 		AddRoute(
 			router.NewRoute("/list", http.MethodGet).
@@ -26,6 +29,7 @@ func init() {
 		).
 		AddRoute(
 			router.NewRoute("/set", http.MethodPost).
+				Require(model.PermSettingWrite).
 				Use(middleware.RequireJSON()).
 				Handle(setSetting),
 		).
@@ -35,6 +39,7 @@ func init() {
 		).
 		AddRoute(
 			router.NewRoute("/import", http.MethodPost).
+				Require(model.PermSettingWrite).
 				Handle(importDB),
 		)
 }
@@ -58,25 +63,17 @@ func setSetting(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := op.SettingSetString(setting.Key, setting.Value); err != nil {
+	if op.SettingIsManaged(setting.Key) {
+		resp.Error(c, http.StatusForbidden, resp.ErrManagedResource)
+		return
+	}
+	if err := op.SettingSetString(setting.Key, setting.Value, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	switch setting.Key {
-	case model.SettingKeyModelInfoUpdateInterval:
-		hours, err := strconv.Atoi(setting.Value)
-		if err != nil {
-			resp.Error(c, http.StatusBadRequest, err.Error())
-			return
-		}
-		task.Update(string(setting.Key), time.Duration(hours)*time.Hour)
-	case model.SettingKeySyncLLMInterval:
-		hours, err := strconv.Atoi(setting.Value)
-		if err != nil {
-			resp.Error(c, http.StatusBadRequest, err.Error())
-			return
-		}
-		task.Update(string(setting.Key), time.Duration(hours)*time.Hour)
+	if err := task.SettingUpdated(setting.Key, setting.Value); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
 	}
 	resp.Success(c, setting)
 }
@@ -133,17 +130,34 @@ func importDB(c *gin.Context) {
 		}
 	}
 
-	result, err := op.DBImportIncremental(c.Request.Context(), &dump)
+	opts := model.DBImportOptions{
+		Mode:   model.DBImportMode(c.Query("mode")),
+		DryRun: c.Query("dry_run") == "true",
+	}
+	result, err := op.DBImport(c.Request.Context(), &dump, opts)
 	if err != nil {
 		resp.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	_ = op.InitCache()
+	reloadAfterImport(c, result)
 
 	resp.Success(c, result)
 }
 
+// reloadAfterImport 导入完成后刷新缓存；替换模式会清空声明式配置管理的对象，需要重新同步
+func reloadAfterImport(c *gin.Context, result *model.DBImportResult) {
+	if result.DryRun {
+		return
+	}
+	_ = op.InitCache()
+	op.SensitiveFilterInit()
+	if result.Mode == model.DBImportModeReplace && gitops.Enabled() {
+		if _, err := gitops.Sync(c.Request.Context(), false); err != nil {
+			log.Warnf("gitops sync after import failed: %v", err)
+		}
+	}
+}
+
 func decodeDBDump(body []byte, dump *model.DBDump) error {
 	if dump == nil {
 		return json.Unmarshal(body, &struct{}{})
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/server/middleware"
 	"github.com/bestruirui/octopus/internal/server/resp"
@@ -12,6 +13,7 @@ import (
 
 func init() {
 	router.NewGroupRouter("/api/v1/stats").
+		Require(model.PermStatsRead).
 		Use(middleware.Auth()).
 		AddRoute(
 			router.NewRoute("/today", http.MethodGet).
@@ -32,6 +34,10 @@ func init() {
 		AddRoute(
 			router.NewRoute("/apikey", http.MethodGet).
 				Handle(getStatsAPIKey),
+		).
+		AddRoute(
+			router.NewRoute("/sensitive", http.MethodGet).
+				Handle(getStatsSensitive),
 		)
 }
 
@@ -59,3 +65,7 @@ func getStatsTotal(c *gin.Context) {
 func getStatsAPIKey(c *gin.Context) {
 	resp.Success(c, op.StatsAPIKeyList())
 }
+
+func getStatsSensitive(c *gin.Context) {
+	resp.Success(c, op.StatsSensitiveList())
+}
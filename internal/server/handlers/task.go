@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/server/middleware"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/server/router"
+	"github.com/bestruirui/octopus/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/task").
+		Require(model.PermTaskRead).
+		Use(middleware.Auth()).
+		AddRoute(
+			router.NewRoute("/list", http.MethodGet).
+				Handle(listTask),
+		).
+		AddRoute(
+			router.NewRoute("/trigger", http.MethodPost).
+				Require(model.PermTaskWrite).
+				Use(middleware.RequireJSON()).
+				Handle(triggerTask),
+		).
+		AddRoute(
+			router.NewRoute("/pause", http.MethodPost).
+				Require(model.PermTaskWrite).
+				Use(middleware.RequireJSON()).
+				Handle(pauseTask),
+		)
+}
+
+func listTask(c *gin.Context) {
+	resp.Success(c, task.List())
+}
+
+func triggerTask(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := task.Trigger(req.Name); err != nil {
+		resp.Error(c, taskErrorStatus(err), err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
+
+func pauseTask(c *gin.Context) {
+	var req struct {
+		Name   string `json:"name"`
+		Paused bool   `json:"paused"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := task.Pause(req.Name, req.Paused); err != nil {
+		resp.Error(c, taskErrorStatus(err), err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
+
+func taskErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, task.ErrTaskNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, task.ErrTaskRunning):
+		return http.StatusConflict
+	case errors.Is(err, task.ErrTaskStopped):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
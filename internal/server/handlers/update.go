@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/server/middleware"
 	"github.com/bestruirui/octopus/internal/server/resp"
 	"github.com/bestruirui/octopus/internal/server/router"
@@ -13,6 +14,7 @@ import (
 
 func init() {
 	router.NewGroupRouter("/api/v1/update").
+		Require(model.PermAuthenticated).
 		Use(middleware.Auth()).
 		AddRoute(
 			router.NewRoute("", http.MethodGet).
@@ -24,6 +26,7 @@ func init() {
 		).
 		AddRoute(
 			router.NewRoute("", http.MethodPost).
+				Require(model.PermUpdateWrite).
 				Handle(updateFunc),
 		)
 }
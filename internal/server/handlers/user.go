@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
@@ -18,8 +21,13 @@ func init() {
 		AddRoute(
 			router.NewRoute("/login", http.MethodPost).
 				Handle(login),
+		).
+		AddRoute(
+			router.NewRoute("/refresh", http.MethodPost).
+				Handle(refreshToken),
 		)
 	router.NewGroupRouter("/api/v1/user").
+		Require(model.PermAuthenticated).
 		Use(middleware.Auth()).
 		Use(middleware.RequireJSON()).
 		AddRoute(
@@ -33,6 +41,79 @@ func init() {
 		AddRoute(
 			router.NewRoute("/status", http.MethodGet).
 				Handle(status),
+		).
+		AddRoute(
+			router.NewRoute("/me", http.MethodGet).
+				Handle(getCurrentUser),
+		).
+		AddRoute(
+			router.NewRoute("/totp/setup", http.MethodPost).
+				Handle(setupTOTP),
+		).
+		AddRoute(
+			router.NewRoute("/totp/enable", http.MethodPost).
+				Handle(enableTOTP),
+		).
+		AddRoute(
+			router.NewRoute("/totp/disable", http.MethodPost).
+				Handle(disableTOTP),
+		).
+		AddRoute(
+			router.NewRoute("/totp/recovery-codes", http.MethodPost).
+				Handle(regenerateRecoveryCodes),
+		).
+		AddRoute(
+			router.NewRoute("/logout", http.MethodPost).
+				Handle(logout),
+		).
+		AddRoute(
+			router.NewRoute("/session/list", http.MethodGet).
+				Handle(listSession),
+		).
+		AddRoute(
+			router.NewRoute("/session/revoke", http.MethodPost).
+				Handle(revokeSession),
+		).
+		AddRoute(
+			router.NewRoute("/session/revoke-all", http.MethodPost).
+				Handle(revokeAllSession),
+		).
+		AddRoute(
+			router.NewRoute("/token/list", http.MethodGet).
+				Handle(listAccessToken),
+		).
+		AddRoute(
+			router.NewRoute("/token/create", http.MethodPost).
+				Handle(createAccessToken),
+		).
+		AddRoute(
+			router.NewRoute("/token/delete/:id", http.MethodDelete).
+				Handle(deleteAccessToken),
+		).
+		AddRoute(
+			router.NewRoute("/secret/rotate", http.MethodPost).
+				Require(model.PermUserWrite).
+				Handle(rotateSecret),
+		).
+		AddRoute(
+			router.NewRoute("/list", http.MethodGet).
+				Require(model.PermUserRead).
+				Handle(listUser),
+		).
+		AddRoute(
+			router.NewRoute("/create", http.MethodPost).
+				Require(model.PermUserWrite).
+				Handle(createUser),
+		).
+		AddRoute(
+			router.NewRoute("/update", http.MethodPost).
+				Require(model.PermUserWrite).
+				Handle(updateUser),
+		).
+		AddRoute(
+			router.NewRoute("/delete/:id", http.MethodDelete).
+				Require(model.PermUserWrite).
+				Handle(deleteUser),
 		)
 }
 
@@ -42,16 +123,177 @@ func login(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
-	if err := op.UserVerify(user.Username, user.Password); err != nil {
+	ip := c.ClientIP()
+	if wait, locked := auth.LoginLocked(user.Username, ip); locked {
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		resp.Error(c, http.StatusTooManyRequests, resp.ErrTooManyAttempts)
+		return
+	}
+	u, err := op.UserVerify(user.Username, user.Password)
+	if err != nil {
+		auth.LoginFailed(user.Username, ip)
 		resp.Error(c, http.StatusUnauthorized, resp.ErrUnauthorized)
 		return
 	}
-	token, expire, err := auth.GenerateJWTToken(user.Expire)
+	if u.TOTPEnabled {
+		// 密码正确后再要求验证码，客户端带上验证码重新提交
+		if user.TOTPCode == "" && user.RecoveryCode == "" {
+			resp.Success(c, model.UserLoginResponse{TOTPRequired: true})
+			return
+		}
+		if err := op.UserTOTPVerify(u.ID, user.TOTPCode, user.RecoveryCode, c.Request.Context()); err != nil {
+			auth.LoginFailed(user.Username, ip)
+			resp.Error(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+	}
+	auth.LoginSucceeded(user.Username)
+	session, refresh, err := op.SessionCreate(u.ID, auth.SessionTTL(user.Expire), c.Request.UserAgent(), c.ClientIP(), c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, resp.ErrDatabase)
+		return
+	}
+	issueToken(c, u, session, refresh)
+}
+
+func refreshToken(c *gin.Context) {
+	var req model.UserRefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	session, refresh, err := op.SessionRefresh(req.RefreshToken, c.Request.Context())
+	if err != nil {
+		if errors.Is(err, op.ErrRefreshTokenInvalid) {
+			resp.Error(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+		resp.Error(c, http.StatusInternalServerError, resp.ErrDatabase)
+		return
+	}
+	u, ok := op.UserGet(session.UserID)
+	if !ok {
+		resp.Error(c, http.StatusUnauthorized, resp.ErrUnauthorized)
+		return
+	}
+	issueToken(c, u, session, refresh)
+}
+
+// issueToken 为会话签发访问令牌，连同刷新令牌一起返回
+func issueToken(c *gin.Context, u model.User, session model.UserSession, refresh string) {
+	token, expire, err := auth.GenerateJWTToken(u, session)
 	if err != nil {
 		resp.Error(c, http.StatusInternalServerError, resp.ErrInternalServer)
 		return
 	}
-	resp.Success(c, model.UserLoginResponse{Token: token, ExpireAt: expire})
+	info := u.Info()
+	resp.Success(c, model.UserLoginResponse{
+		Token:           token,
+		ExpireAt:        expire,
+		RefreshToken:    refresh,
+		RefreshExpireAt: time.Unix(session.ExpiresAt, 0).Format(time.RFC3339),
+		User:            &info,
+	})
+}
+
+func setupTOTP(c *gin.Context) {
+	setup, err := op.UserTOTPSetup(c.GetUint("user_id"), c.Request.Context())
+	if err != nil {
+		resp.Error(c, userErrorStatus(err), err.Error())
+		return
+	}
+	resp.Success(c, setup)
+}
+
+func enableTOTP(c *gin.Context) {
+	var req model.UserTOTPEnableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	codes, err := op.UserTOTPEnable(c.GetUint("user_id"), req.Code, c.Request.Context())
+	if err != nil {
+		resp.Error(c, userErrorStatus(err), err.Error())
+		return
+	}
+	resp.Success(c, model.UserRecoveryCodes{RecoveryCodes: codes})
+}
+
+func disableTOTP(c *gin.Context) {
+	var req model.UserPasswordConfirm
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := op.UserTOTPDisable(c.GetUint("user_id"), req.Password, c.Request.Context()); err != nil {
+		resp.Error(c, userErrorStatus(err), err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
+
+func regenerateRecoveryCodes(c *gin.Context) {
+	var req model.UserPasswordConfirm
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	codes, err := op.UserRecoveryCodesRegenerate(c.GetUint("user_id"), req.Password, c.Request.Context())
+	if err != nil {
+		resp.Error(c, userErrorStatus(err), err.Error())
+		return
+	}
+	resp.Success(c, model.UserRecoveryCodes{RecoveryCodes: codes})
+}
+
+func logout(c *gin.Context) {
+	if err := op.SessionRevoke(c.GetUint("user_id"), c.GetString("session_id"), c.Request.Context()); err != nil && !errors.Is(err, op.ErrSessionNotFound) {
+		resp.Error(c, http.StatusInternalServerError, resp.ErrDatabase)
+		return
+	}
+	resp.Success(c, nil)
+}
+
+func listSession(c *gin.Context) {
+	sessions := op.SessionList(c.GetUint("user_id"))
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == c.GetString("session_id")
+	}
+	resp.Success(c, sessions)
+}
+
+func revokeSession(c *gin.Context) {
+	var req model.UserSessionRevokeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := op.SessionRevoke(c.GetUint("user_id"), req.ID, c.Request.Context()); err != nil {
+		if errors.Is(err, op.ErrSessionNotFound) {
+			resp.Error(c, http.StatusNotFound, err.Error())
+			return
+		}
+		resp.Error(c, http.StatusInternalServerError, resp.ErrDatabase)
+		return
+	}
+	resp.Success(c, nil)
+}
+
+// revokeAllSession 退出当前用户的所有会话，包括当前会话
+func revokeAllSession(c *gin.Context) {
+	if err := op.SessionRevokeUser(c.GetUint("user_id"), "", c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, resp.ErrDatabase)
+		return
+	}
+	resp.Success(c, nil)
+}
+
+func rotateSecret(c *gin.Context) {
+	if err := auth.RotateSecret(c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, resp.ErrDatabase)
+		return
+	}
+	resp.Success(c, nil)
 }
 
 func changePassword(c *gin.Context) {
@@ -60,7 +302,12 @@ func changePassword(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
-	if err := op.UserChangePassword(user.OldPassword, user.NewPassword); err != nil {
+	if err := op.UserChangePassword(c.GetUint("user_id"), user.OldPassword, user.NewPassword); err != nil {
+		resp.Error(c, http.StatusInternalServerError, resp.ErrDatabase)
+		return
+	}
+	// 修改密码后其他设备需要重新登录
+	if err := op.SessionRevokeUser(c.GetUint("user_id"), c.GetString("session_id"), c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, resp.ErrDatabase)
 		return
 	}
@@ -73,7 +320,7 @@ func changeUsername(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
-	if err := op.UserChangeUsername(user.NewUsername); err != nil {
+	if err := op.UserChangeUsername(c.GetUint("user_id"), user.NewUsername); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -83,3 +330,119 @@ func changeUsername(c *gin.Context) {
 func status(c *gin.Context) {
 	resp.Success(c, "ok")
 }
+
+func getCurrentUser(c *gin.Context) {
+	user, ok := op.UserGet(c.GetUint("user_id"))
+	if !ok {
+		resp.Error(c, http.StatusNotFound, op.ErrUserNotFound.Error())
+		return
+	}
+	resp.Success(c, user.Info())
+}
+
+func listUser(c *gin.Context) {
+	resp.Success(c, op.UserList())
+}
+
+func createUser(c *gin.Context) {
+	var req model.UserCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	user, err := op.UserCreate(&req, c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp.Success(c, user)
+}
+
+func updateUser(c *gin.Context) {
+	var req model.UserUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	user, err := op.UserUpdate(&req, c.Request.Context())
+	if err != nil {
+		resp.Error(c, userErrorStatus(err), err.Error())
+		return
+	}
+	resp.Success(c, user)
+}
+
+func deleteUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	if uint(id) == c.GetUint("user_id") {
+		resp.Error(c, http.StatusBadRequest, "cannot delete the current user")
+		return
+	}
+	if err := op.UserDelete(uint(id), c.Request.Context()); err != nil {
+		resp.Error(c, userErrorStatus(err), err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
+
+func listAccessToken(c *gin.Context) {
+	resp.Success(c, op.AccessTokenList(c.GetUint("user_id")))
+}
+
+func createAccessToken(c *gin.Context) {
+	var req model.AccessTokenCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	user, ok := op.UserGet(c.GetUint("user_id"))
+	if !ok {
+		resp.Error(c, http.StatusNotFound, op.ErrUserNotFound.Error())
+		return
+	}
+	token, err := auth.GenerateAccessToken()
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, resp.ErrInternalServer)
+		return
+	}
+	accessToken, err := op.AccessTokenCreate(user, &req, token, c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp.Success(c, model.AccessTokenCreateResponse{AccessToken: *accessToken, Token: token})
+}
+
+func deleteAccessToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	if err := op.AccessTokenDelete(c.GetUint("user_id"), id, c.Request.Context()); err != nil {
+		if errors.Is(err, op.ErrAccessTokenNotFound) {
+			resp.Error(c, http.StatusNotFound, err.Error())
+			return
+		}
+		resp.Error(c, http.StatusInternalServerError, resp.ErrDatabase)
+		return
+	}
+	resp.Success(c, nil)
+}
+
+func userErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, op.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, op.ErrUserLastOwner), errors.Is(err, op.ErrTOTPEnabled), errors.Is(err, op.ErrTOTPNotEnabled):
+		return http.StatusConflict
+	case errors.Is(err, op.ErrWrongPassword), errors.Is(err, op.ErrTOTPInvalid):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
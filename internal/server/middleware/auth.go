@@ -5,13 +5,15 @@ import (
 	"strings"
 	"time"
 
-	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/server/auth"
 	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/server/router"
 	"github.com/gin-gonic/gin"
 )
 
+// Auth 校验登录令牌或个人访问令牌，并检查当前路由所需的权限
 func Auth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
@@ -20,15 +22,62 @@ func Auth() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		if !auth.VerifyJWTToken(strings.TrimPrefix(token, "Bearer ")) {
-			resp.Error(c, http.StatusUnauthorized, resp.ErrUnauthorized)
+		token = strings.TrimPrefix(token, "Bearer ")
+		permission, _ := c.Get(router.PermissionKey)
+		p, _ := permission.(model.Permission)
+
+		var user model.User
+		if strings.HasPrefix(token, auth.AccessTokenPrefix) {
+			u, accessToken, ok := auth.VerifyAccessToken(token, c.ClientIP(), c.Request.Context())
+			if !ok {
+				resp.Error(c, http.StatusUnauthorized, resp.ErrUnauthorized)
+				c.Abort()
+				return
+			}
+			if !accessToken.Allows(p, c.Request.Method) {
+				resp.Error(c, http.StatusForbidden, resp.ErrForbidden)
+				c.Abort()
+				return
+			}
+			user = u
+			c.Set("access_token_id", accessToken.ID)
+			c.Set("access_token", accessToken)
+		} else {
+			u, session, ok := auth.VerifyJWTToken(token, c.Request.Context())
+			if !ok {
+				resp.Error(c, http.StatusUnauthorized, resp.ErrUnauthorized)
+				c.Abort()
+				return
+			}
+			user = u
+			c.Set("session_id", session.ID)
+		}
+		if !user.Role.Can(p) {
+			resp.Error(c, http.StatusForbidden, resp.ErrForbidden)
 			c.Abort()
 			return
 		}
+		c.Set("user_id", user.ID)
+		c.Set("user_role", user.Role)
 		c.Next()
 	}
 }
 
+// Can 当前登录的用户是否拥有权限 p，使用个人访问令牌时还需要令牌允许写入该权限的接口
+// 用于同一接口按权限返回不同内容，需要在 Auth 之后调用
+func Can(c *gin.Context, p model.Permission) bool {
+	role, _ := c.Get("user_role")
+	r, _ := role.(model.UserRole)
+	if !r.Can(p) {
+		return false
+	}
+	if v, ok := c.Get("access_token"); ok {
+		accessToken := v.(model.AccessToken)
+		return accessToken.Allows(p, http.MethodPost)
+	}
+	return true
+}
+
 func APIKeyAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var apiKey string
@@ -48,7 +97,7 @@ func APIKeyAuth() gin.HandlerFunc {
 			return
 		}
 
-		if !strings.HasPrefix(apiKey, "sk-"+conf.APP_NAME+"-") {
+		if !strings.HasPrefix(apiKey, op.APIKeyPrefix) {
 			resp.Error(c, http.StatusUnauthorized, resp.ErrUnauthorized)
 			c.Abort()
 			return
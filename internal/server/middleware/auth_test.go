@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db/dbtest"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/auth"
+	"github.com/bestruirui/octopus/internal/server/router"
+	"github.com/gin-gonic/gin"
+)
+
+// 路由声明的权限由 Auth 按角色检查，个人访问令牌还受范围和只读限制
+func TestAuthRoutePermission(t *testing.T) {
+	dbtest.Init(t, "auth.db")
+	ctx := context.Background()
+	if err := op.AuthSecretInit(); err != nil {
+		t.Fatal(err)
+	}
+
+	users := map[model.UserRole]model.User{}
+	sessions := map[model.UserRole]string{}
+	for _, role := range []model.UserRole{model.UserRoleOwner, model.UserRoleOperator, model.UserRoleFinance, model.UserRoleViewer} {
+		u, err := op.UserCreate(&model.UserCreateRequest{Username: string(role), Password: "pw", Role: role}, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		session, _, err := op.SessionCreate(u.ID, time.Hour, "", "", ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _, err := auth.GenerateJWTToken(*u, session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[role], sessions[role] = *u, token
+	}
+	pat := func(role model.UserRole, scopes []model.Permission, readOnly bool) string {
+		t.Helper()
+		token, err := auth.GenerateAccessToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := &model.AccessTokenCreateRequest{Name: "t", Scopes: scopes, ReadOnly: readOnly}
+		if _, err := op.AccessTokenCreate(users[role], req, token, ctx); err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	scoped := pat(model.UserRoleOwner, []model.Permission{model.PermStatusRead, model.PermAPIKeyRead, model.PermAPIKeyWrite}, false)
+	readOnly := pat(model.UserRoleOwner, []model.Permission{model.PermChannelWrite, model.PermAPIKeyRead, model.PermAPIKeyWrite}, true)
+	financePAT := pat(model.UserRoleFinance, nil, false)
+
+	gin.SetMode(gin.TestMode)
+	ok := func(c *gin.Context) { c.String(http.StatusOK, strconv.FormatBool(Can(c, model.PermAPIKeyWrite))) }
+	router.NewGroupRouter("/test").
+		Require(model.PermStatusRead).
+		Use(Auth()).
+		AddRoute(router.NewRoute("/status", http.MethodGet).Handle(ok)).
+		AddRoute(router.NewRoute("/channel", http.MethodPost).Require(model.PermChannelWrite).Handle(ok)).
+		AddRoute(router.NewRoute("/apikey", http.MethodGet).Require(model.PermAPIKeyRead).Handle(ok)).
+		AddRoute(router.NewRoute("/me", http.MethodGet).Require(model.PermAuthenticated).Handle(ok)).
+		AddRoute(router.NewRoute("/me", http.MethodPost).Require(model.PermAuthenticated).Handle(ok))
+	router.NewGroupRouter("/owner").
+		Use(Auth()).
+		AddRoute(router.NewRoute("", http.MethodGet).Handle(ok))
+	engine := gin.New()
+	if err := router.RegisterAll(engine); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		want   int
+		// wantCan 请求成功时 Can(apikey:write) 的结果
+		wantCan string
+	}{
+		{"no token", "", http.MethodGet, "/test/status", http.StatusBadRequest, ""},
+		{"invalid token", "bad", http.MethodGet, "/test/status", http.StatusUnauthorized, ""},
+		{"unknown access token", auth.AccessTokenPrefix + "00", http.MethodGet, "/test/status", http.StatusUnauthorized, ""},
+
+		{"owner group permission", sessions[model.UserRoleOwner], http.MethodGet, "/test/status", http.StatusOK, "true"},
+		{"owner undeclared permission", sessions[model.UserRoleOwner], http.MethodGet, "/owner", http.StatusOK, "true"},
+		{"operator route permission", sessions[model.UserRoleOperator], http.MethodPost, "/test/channel", http.StatusOK, "false"},
+		{"operator undeclared permission", sessions[model.UserRoleOperator], http.MethodGet, "/owner", http.StatusForbidden, ""},
+		{"operator missing permission", sessions[model.UserRoleOperator], http.MethodGet, "/test/apikey", http.StatusForbidden, ""},
+		{"finance read api keys", sessions[model.UserRoleFinance], http.MethodGet, "/test/apikey", http.StatusOK, "false"},
+		{"viewer write", sessions[model.UserRoleViewer], http.MethodPost, "/test/channel", http.StatusForbidden, ""},
+		{"viewer authenticated", sessions[model.UserRoleViewer], http.MethodPost, "/test/me", http.StatusOK, "false"},
+
+		{"scoped token in scope", scoped, http.MethodGet, "/test/apikey", http.StatusOK, "true"},
+		{"scoped token out of scope", scoped, http.MethodPost, "/test/channel", http.StatusForbidden, ""},
+		{"scoped token authenticated read", scoped, http.MethodGet, "/test/me", http.StatusOK, "true"},
+		{"scoped token authenticated write", scoped, http.MethodPost, "/test/me", http.StatusForbidden, ""},
+		{"read-only token write", readOnly, http.MethodPost, "/test/channel", http.StatusForbidden, ""},
+		{"read-only token cannot write api keys", readOnly, http.MethodGet, "/test/apikey", http.StatusOK, "false"},
+		{"token limited by role", financePAT, http.MethodPost, "/test/channel", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want == http.StatusOK && w.Body.String() != tt.wantCan {
+				t.Errorf("Can(apikey:write) = %s, want %s", w.Body.String(), tt.wantCan)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	draining  atomic.Bool
+	drainMu   sync.RWMutex
+	drainCh   = make(chan struct{})
+	inflight  sync.WaitGroup
+	inflightN atomic.Int64
+)
+
+// Inflight 记录正在处理的转发请求，进入排空状态后拒绝新请求并返回 503
+// 只用于转发接口，管理接口和健康检查由 http.Server.Shutdown 等待，长连接通过 DrainStarted 自行退出
+func Inflight() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		drainMu.RLock()
+		if draining.Load() {
+			drainMu.RUnlock()
+			// 关闭长连接，让负载均衡把后续请求发往其他实例
+			c.Header("Connection", "close")
+			resp.Error(c, http.StatusServiceUnavailable, resp.ErrShuttingDown)
+			c.Abort()
+			return
+		}
+		inflight.Add(1)
+		inflightN.Add(1)
+		drainMu.RUnlock()
+		defer func() {
+			inflightN.Add(-1)
+			inflight.Done()
+		}()
+		c.Next()
+	}
+}
+
+// StartDrain 进入排空状态，之后的新转发请求都会被拒绝
+func StartDrain() {
+	drainMu.Lock()
+	if !draining.Swap(true) {
+		close(drainCh)
+	}
+	drainMu.Unlock()
+}
+
+// DrainStarted 返回进入排空状态时关闭的 channel，日志推送等长连接据此结束
+func DrainStarted() <-chan struct{} {
+	return drainCh
+}
+
+// Draining 是否正在排空请求
+func Draining() bool {
+	return draining.Load()
+}
+
+// InflightCount 返回正在处理的请求数
+func InflightCount() int64 {
+	return inflightN.Load()
+}
+
+// WaitInflight 等待正在处理的请求全部结束，ctx 结束时返回 ctx 的错误
+func WaitInflight(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
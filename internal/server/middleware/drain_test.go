@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/gin-gonic/gin"
+)
+
+// resetDrain 恢复排空前的状态，排空在进程内只会发生一次，测试之间需要重置
+func resetDrain(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		drainMu.Lock()
+		draining.Store(false)
+		drainCh = make(chan struct{})
+		drainMu.Unlock()
+	})
+}
+
+func TestInflightDrain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetDrain(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r := gin.New()
+	r.Use(Inflight())
+	r.GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusOK)
+	})
+	r.GET("/fast", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	slow := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		r.ServeHTTP(slow, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		close(done)
+	}()
+	<-started
+	if n := InflightCount(); n != 1 {
+		t.Fatalf("inflight = %d, want 1", n)
+	}
+
+	StartDrain()
+	if !Draining() {
+		t.Fatal("not draining after StartDrain")
+	}
+	select {
+	case <-DrainStarted():
+	default:
+		t.Fatal("DrainStarted not closed")
+	}
+	// 重复调用不会再次关闭 channel
+	StartDrain()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if w.Header().Get("Connection") != "close" {
+		t.Errorf("connection = %q, want close", w.Header().Get("Connection"))
+	}
+	var body resp.ResponseStruct
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Message != resp.ErrShuttingDown {
+		t.Errorf("body = %s, err %v", w.Body.String(), err)
+	}
+	if n := InflightCount(); n != 1 {
+		t.Errorf("rejected request counted as inflight: %d", n)
+	}
+
+	// 请求未结束时 WaitInflight 在 ctx 结束后返回
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := WaitInflight(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitInflight = %v, want deadline exceeded", err)
+	}
+
+	close(release)
+	<-done
+	if slow.Code != http.StatusOK {
+		t.Errorf("inflight request status = %d, want 200", slow.Code)
+	}
+	if err := WaitInflight(context.Background()); err != nil {
+		t.Fatalf("WaitInflight = %v", err)
+	}
+	if n := InflightCount(); n != 0 {
+		t.Errorf("inflight = %d after request finished", n)
+	}
+}
@@ -10,4 +10,8 @@ const (
 	ErrInternalServer    = "An unexpected error occurred"
 	ErrDatabase          = "Database operation failed"
 	ErrUnauthorized      = "Authentication failed"
+	ErrForbidden         = "Permission denied"
+	ErrManagedResource   = "Resource is managed by declarative config and is read-only"
+	ErrShuttingDown      = "Server is shutting down"
+	ErrTooManyAttempts   = "Too many failed attempts, try again later"
 )
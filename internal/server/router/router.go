@@ -5,14 +5,19 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/bestruirui/octopus/internal/model"
 	"github.com/gin-gonic/gin"
 )
 
+// PermissionKey is the context key holding the permission required by the matched route.
+const PermissionKey = "required_permission"
+
 // GroupRouter represents a group of routes with shared path prefix and middlewares
 type GroupRouter struct {
 	Path        string
 	Routes      []*Route
 	Middlewares []gin.HandlerFunc
+	Permission  model.Permission
 }
 
 // Global registry for route groups
@@ -34,6 +39,12 @@ func (g *GroupRouter) Use(middlewares ...gin.HandlerFunc) *GroupRouter {
 	return g
 }
 
+// Require sets the permission required by routes in the group that don't set their own.
+func (g *GroupRouter) Require(permission model.Permission) *GroupRouter {
+	g.Permission = permission
+	return g
+}
+
 // AddRoute adds a route to the group.
 func (g *GroupRouter) AddRoute(route *Route) *GroupRouter {
 	g.Routes = append(g.Routes, route)
@@ -46,6 +57,7 @@ type Route struct {
 	Method      string
 	Handlers    []gin.HandlerFunc
 	Middlewares []gin.HandlerFunc
+	Permission  model.Permission
 }
 
 // NewRoute creates a new Route instance with the given path and method.
@@ -63,6 +75,13 @@ func (r *Route) Handle(handlers ...gin.HandlerFunc) *Route {
 	return r
 }
 
+// Require sets the permission required by the route, overriding the group's.
+// It is checked by the auth middleware; routes without one are limited to owners.
+func (r *Route) Require(permission model.Permission) *Route {
+	r.Permission = permission
+	return r
+}
+
 // Use adds middlewares to the route.
 func (r *Route) Use(middlewares ...gin.HandlerFunc) *Route {
 	r.Middlewares = append(r.Middlewares, middlewares...)
@@ -97,11 +116,18 @@ func RegisterAll(engine *gin.Engine) error {
 		}
 
 		// Create the route group
-		group := engine.Group(router.Path, router.Middlewares...)
+		group := engine.Group(router.Path)
 
 		// Register all routes in the group
 		for _, route := range router.Routes {
-			handlers := make([]gin.HandlerFunc, 0, len(route.Middlewares)+len(route.Handlers))
+			// The required permission is set before the group middlewares so that auth can check it
+			permission := route.Permission
+			if permission == "" {
+				permission = router.Permission
+			}
+			handlers := make([]gin.HandlerFunc, 0, 1+len(router.Middlewares)+len(route.Middlewares)+len(route.Handlers))
+			handlers = append(handlers, requirePermission(permission))
+			handlers = append(handlers, router.Middlewares...)
 			handlers = append(handlers, route.Middlewares...)
 			handlers = append(handlers, route.Handlers...)
 
@@ -143,3 +169,10 @@ func registerRoute(group *gin.RouterGroup, method string, path string, handlers
 		group.GET(path, handlers...)
 	}
 }
+
+func requirePermission(permission model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(PermissionKey, permission)
+		c.Next()
+	}
+}
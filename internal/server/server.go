@@ -1,8 +1,11 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/bestruirui/octopus/internal/conf"
 	_ "github.com/bestruirui/octopus/internal/server/handlers"
@@ -47,6 +50,32 @@ func Start() error {
 	return nil
 }
 
+// forceCloseWait 强制断开连接后等待处理函数记录日志的时间
+const forceCloseWait = 5 * time.Second
+
+// Close 停止接收新请求，等待正在处理的请求结束，超过 server.shutdown_timeout 后强制断开
 func Close() error {
-	return httpSrv.Close()
+	timeout := time.Duration(conf.AppConfig.Server.ShutdownTimeout) * time.Second
+	middleware.StartDrain()
+	if n := middleware.InflightCount(); n > 0 {
+		log.Infof("waiting for %d in-flight requests to finish, timeout %v", n, timeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := middleware.WaitInflight(ctx); err == nil {
+		return httpSrv.Shutdown(ctx)
+	}
+
+	n := middleware.InflightCount()
+	log.Warnf("shutdown timeout, closing %d in-flight requests", n)
+	if err := httpSrv.Close(); err != nil {
+		return err
+	}
+	// 连接断开后请求上下文被取消，等待处理函数保存中断请求的日志
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), forceCloseWait)
+	defer waitCancel()
+	if err := middleware.WaitInflight(waitCtx); err != nil {
+		return errors.Join(fmt.Errorf("%d requests still running after close", middleware.InflightCount()), err)
+	}
+	return nil
 }
@@ -2,6 +2,7 @@ package task
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/bestruirui/octopus/internal/helper"
@@ -9,20 +10,23 @@ import (
 	"github.com/bestruirui/octopus/internal/utils/log"
 )
 
-func ChannelBaseUrlDelayTask() {
+func ChannelBaseUrlDelayTask(ctx context.Context) error {
 	log.Debugf("channel base url delay task started")
 	startTime := time.Now()
 	defer func() {
 		log.Debugf("channel base url delay task finished, update time: %s", time.Since(startTime))
 	}()
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Minute)
 	defer cancel()
 	channels, err := op.ChannelList(ctx)
 	if err != nil {
-		log.Errorf("failed to list channels: %v", err)
-		return
+		return fmt.Errorf("failed to list channels: %w", err)
 	}
 	for _, channel := range channels {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		helper.ChannelBaseUrlDelayUpdate(&channel, ctx)
 	}
+	return nil
 }
@@ -2,8 +2,10 @@ package task
 
 import (
 	"context"
+	"strconv"
 	"time"
 
+	"github.com/bestruirui/octopus/internal/conf"
 	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/price"
@@ -17,6 +19,7 @@ const (
 	TaskSyncLLM      = "sync_llm"
 	TaskCleanLLM     = "clean_llm"
 	TaskBaseUrlDelay = "base_url_delay"
+	TaskBackup       = "backup"
 )
 
 func Init() {
@@ -27,14 +30,10 @@ func Init() {
 	}
 	priceUpdateInterval := time.Duration(priceUpdateIntervalHours) * time.Hour
 	// 注册价格更新任务
-	Register(string(model.SettingKeyModelInfoUpdateInterval), priceUpdateInterval, true, func() {
-		if err := price.UpdateLLMPrice(context.Background()); err != nil {
-			log.Warnf("failed to update price info: %v", err)
-		}
-	})
+	Register(TaskPriceUpdate, Every(priceUpdateInterval), true, leaderOnly(price.UpdateLLMPrice))
 
 	// 注册基础URL延迟任务
-	Register(TaskBaseUrlDelay, 1*time.Hour, true, ChannelBaseUrlDelayTask)
+	Register(TaskBaseUrlDelay, Every(1*time.Hour), true, ChannelBaseUrlDelayTask)
 
 	// 注册LLM同步任务
 	syncLLMIntervalHours, err := op.SettingGetInt(model.SettingKeySyncLLMInterval)
@@ -43,7 +42,7 @@ func Init() {
 		return
 	}
 	syncLLMInterval := time.Duration(syncLLMIntervalHours) * time.Hour
-	Register(string(model.SettingKeySyncLLMInterval), syncLLMInterval, true, SyncModelsTask)
+	Register(TaskSyncLLM, Every(syncLLMInterval), true, leaderOnly(SyncModelsTask))
 
 	// 注册统计保存任务
 	statsSaveIntervalMinutes, err := op.SettingGetInt(model.SettingKeyStatsSaveInterval)
@@ -52,11 +51,42 @@ func Init() {
 		return
 	}
 	statsSaveInterval := time.Duration(statsSaveIntervalMinutes) * time.Minute
-	Register(TaskStatsSave, statsSaveInterval, false, op.StatsSaveDBTask)
+	Register(TaskStatsSave, Every(statsSaveInterval), false, op.StatsSaveDBTask)
 	// 注册中继日志保存任务
-	Register(TaskRelayLogSave, 10*time.Minute, false, func() {
-		if err := op.RelayLogSaveDBTask(context.Background()); err != nil {
-			log.Warnf("relay log save db task failed: %v", err)
+	Register(TaskRelayLogSave, Every(10*time.Minute), false, op.RelayLogSaveDBTask)
+	// 注册定时备份任务，按设置的备份间隔判断是否需要备份；配置了执行计划或手动执行时每次都备份
+	Register(TaskBackup, Every(10*time.Minute), true, leaderOnly(func(ctx context.Context) error {
+		_, scheduled := conf.AppConfig.Task.Schedules[TaskBackup]
+		return op.BackupTask(ctx, scheduled || TriggerOf(ctx) == model.TaskTriggerManual)
+	}))
+}
+
+// leaderOnly 多实例部署时定时执行只在主节点进行，手动触发不受限制
+// 统计和日志保存等每个实例各自的任务不需要包装
+func leaderOnly(fn Func) Func {
+	return func(ctx context.Context) error {
+		if TriggerOf(ctx) != model.TaskTriggerManual && !op.ClusterIsLeader() {
+			return nil
 		}
-	})
+		return fn(ctx)
+	}
+}
+
+// SettingUpdated 设置项变更后同步调整对应任务的执行间隔
+func SettingUpdated(key model.SettingKey, value string) error {
+	var name string
+	switch key {
+	case model.SettingKeyModelInfoUpdateInterval:
+		name = TaskPriceUpdate
+	case model.SettingKeySyncLLMInterval:
+		name = TaskSyncLLM
+	default:
+		return nil
+	}
+	hours, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	Update(name, time.Duration(hours)*time.Hour)
+	return nil
 }
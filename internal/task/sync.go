@@ -2,6 +2,7 @@ package task
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
@@ -16,22 +17,24 @@ import (
 var lastSyncModelsTime = time.Now()
 
 // SyncModelsTask 同步模型任务
-func SyncModelsTask() {
+func SyncModelsTask(ctx context.Context) error {
 	log.Debugf("sync models task started")
 	startTime := time.Now()
 	defer func() {
 		log.Debugf("sync models task finished, sync time: %s", time.Since(startTime))
 	}()
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Minute)
 	defer cancel()
 	channels, err := op.ChannelList(ctx)
 	if err != nil {
-		log.Errorf("failed to list channels: %v", err)
-		return
+		return fmt.Errorf("failed to list channels: %w", err)
 	}
 	totalNewModels := make([]string, 0, 128)
 	seenTotalNewModels := make(map[string]struct{}, 128)
 	for _, channel := range channels {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if !channel.AutoSync {
 			continue
 		}
@@ -84,8 +87,7 @@ func SyncModelsTask() {
 	}
 	llmPrice, err := op.LLMList(ctx)
 	if err != nil {
-		log.Errorf("failed to list models price: %v", err)
-		return
+		return fmt.Errorf("failed to list models price: %w", err)
 	}
 	llmPriceNames := make([]string, 0, len(llmPrice))
 	for _, price := range llmPrice {
@@ -104,6 +106,7 @@ func SyncModelsTask() {
 		}
 	}
 	lastSyncModelsTime = time.Now()
+	return nil
 }
 
 func GetLastSyncModelsTime() time.Time {
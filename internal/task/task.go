@@ -1,31 +1,76 @@
 package task
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/bestruirui/octopus/internal/conf"
+	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/utils/log"
 )
 
+// Func 任务函数，ctx 在程序退出时取消
+type Func func(ctx context.Context) error
+
+const historySize = 20
+
+type triggerCtxKey struct{}
+
+var (
+	ErrTaskNotFound = errors.New("task not found")
+	ErrTaskRunning  = errors.New("task is already running")
+	ErrTaskStopped  = errors.New("task scheduler is stopped")
+)
+
 type taskEntry struct {
 	name       string
-	interval   time.Duration
-	fn         func()
+	fn         Func
 	runOnStart bool
-	ticker     *time.Ticker
-	stopCh     chan struct{}
-	updateCh   chan time.Duration
+	// fixed 由配置文件指定的计划，设置项变更时不再修改
+	fixed   bool
+	running atomic.Bool
+	paused  atomic.Bool
+	wake    chan struct{}
+	stopCh  chan struct{}
+
+	mu       sync.Mutex
+	schedule Schedule
+	next     time.Time
+	history  []model.TaskRun
 }
 
 var (
 	tasks   = make(map[string]*taskEntry)
 	tasksMu sync.RWMutex
+
+	started  bool
+	stopped  bool // Stop 之后不再启动新的执行，wg.Add 与其在同一把锁内判断，避免与 wg.Wait 竞争
+	rootCtx  context.Context
+	rootStop context.CancelFunc
+	wg       sync.WaitGroup
+	stopWait = 30 * time.Second
 )
 
-// Register 注册一个定时任务
+// Register 注册一个定时任务，schedule 为 nil 时不注册
+// 配置文件 task.schedules 中指定了同名任务的计划时使用配置的计划，并且启动时不执行
 // runOnStart: 是否在启动时立即执行一次
-func Register(name string, interval time.Duration, runOnStart bool, fn func()) {
-	if interval <= 0 {
+func Register(name string, schedule Schedule, runOnStart bool, fn Func) {
+	fixed := false
+	if spec, ok := conf.AppConfig.Task.Schedules[name]; ok {
+		s, err := ParseSchedule(spec)
+		if err != nil {
+			log.Errorf("task %s: %v, using default schedule", name, err)
+		} else {
+			schedule, fixed, runOnStart = s, true, false
+		}
+	}
+	if schedule == nil {
 		log.Debugf("task %s not registered: interval is 0", name)
 		return
 	}
@@ -38,15 +83,21 @@ func Register(name string, interval time.Duration, runOnStart bool, fn func()) {
 		return
 	}
 
-	tasks[name] = &taskEntry{
+	entry := &taskEntry{
 		name:       name,
-		interval:   interval,
+		schedule:   schedule,
 		fn:         fn,
 		runOnStart: runOnStart,
+		fixed:      fixed,
+		wake:       make(chan struct{}, 1),
 		stopCh:     make(chan struct{}),
-		updateCh:   make(chan time.Duration),
 	}
-	log.Debugf("task %s registered with interval %v, runOnStart: %v", name, interval, runOnStart)
+	tasks[name] = entry
+	if started && !stopped {
+		wg.Add(1)
+		go entry.loop()
+	}
+	log.Debugf("task %s registered with schedule %s, runOnStart: %v", name, schedule, runOnStart)
 }
 
 // Update 更新任务的执行间隔
@@ -59,6 +110,11 @@ func Update(name string, interval time.Duration) {
 		log.Warnf("task %s not found", name)
 		return
 	}
+	if entry.fixed {
+		tasksMu.Unlock()
+		log.Infof("task %s schedule is set in config, ignoring interval %v", name, interval)
+		return
+	}
 
 	if interval <= 0 {
 		delete(tasks, name)
@@ -69,45 +125,205 @@ func Update(name string, interval time.Duration) {
 	}
 	tasksMu.Unlock()
 
+	entry.mu.Lock()
+	entry.schedule = Every(interval)
+	entry.mu.Unlock()
+	entry.notify()
+	log.Infof("task %s interval updated to %v", name, interval)
+}
+
+// Start 启动所有注册的任务，之后注册的任务会立即启动
+func Start() {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	if started {
+		return
+	}
+	started = true
+	rootCtx, rootStop = context.WithCancel(context.Background())
+	for _, entry := range tasks {
+		wg.Add(1)
+		go entry.loop()
+	}
+}
+
+// Stop 取消所有任务的上下文，并等待正在执行的任务退出
+func Stop() error {
+	tasksMu.Lock()
+	if !started {
+		tasksMu.Unlock()
+		return nil
+	}
+	stopped = true
+	rootStop()
+	tasksMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
 	select {
-	case entry.updateCh <- interval:
-		log.Infof("task %s interval updated to %v", name, interval)
-	default:
-		log.Warnf("task %s update channel full, skipping", name)
+	case <-done:
+		return nil
+	case <-time.After(stopWait):
+		return fmt.Errorf("tasks did not stop within %v", stopWait)
 	}
 }
 
-// RUN 启动所有注册的任务
-func RUN() {
+// List 返回所有任务的状态，按名称排序
+func List() []model.TaskInfo {
 	tasksMu.RLock()
+	entries := make([]*taskEntry, 0, len(tasks))
 	for _, entry := range tasks {
-		go runTask(entry)
+		entries = append(entries, entry)
 	}
 	tasksMu.RUnlock()
 
-	// 阻塞主协程
-	select {}
+	infos := make([]model.TaskInfo, 0, len(entries))
+	for _, entry := range entries {
+		infos = append(infos, entry.info())
+	}
+	slices.SortFunc(infos, func(a, b model.TaskInfo) int { return strings.Compare(a.Name, b.Name) })
+	return infos
 }
 
-func runTask(entry *taskEntry) {
-	// 根据配置决定是否在启动时立即执行
-	if entry.runOnStart {
-		go entry.fn()
+// Trigger 立即在后台执行一次任务，暂停的任务也可以手动执行
+func Trigger(name string) error {
+	entry, err := lookup(name)
+	if err != nil {
+		return err
+	}
+	return entry.run(model.TaskTriggerManual)
+}
+
+// Pause 暂停或恢复任务的定时执行，重启后恢复
+func Pause(name string, paused bool) error {
+	entry, err := lookup(name)
+	if err != nil {
+		return err
 	}
+	entry.paused.Store(paused)
+	entry.notify()
+	log.Infof("task %s paused: %v", name, paused)
+	return nil
+}
 
-	entry.ticker = time.NewTicker(entry.interval)
-	defer entry.ticker.Stop()
+// TriggerOf 返回本次执行的触发方式
+func TriggerOf(ctx context.Context) model.TaskTrigger {
+	trigger, _ := ctx.Value(triggerCtxKey{}).(model.TaskTrigger)
+	return trigger
+}
 
+func lookup(name string) (*taskEntry, error) {
+	tasksMu.RLock()
+	defer tasksMu.RUnlock()
+	entry, ok := tasks[name]
+	if !ok || !started {
+		return nil, ErrTaskNotFound
+	}
+	return entry, nil
+}
+
+func (e *taskEntry) notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (e *taskEntry) loop() {
+	defer wg.Done()
+	// 根据配置决定是否在启动时立即执行
+	if e.runOnStart {
+		e.run(model.TaskTriggerStart)
+	}
 	for {
+		e.mu.Lock()
+		e.next = time.Time{}
+		if !e.paused.Load() {
+			e.next = e.schedule.Next(time.Now())
+		}
+		next := e.next
+		e.mu.Unlock()
+
+		// 暂停或没有下次执行时间时只等待唤醒
+		timer := time.NewTimer(time.Until(next))
+		if next.IsZero() {
+			timer.Stop()
+		}
 		select {
-		case <-entry.ticker.C:
-			go entry.fn()
-		case newInterval := <-entry.updateCh:
-			entry.ticker.Stop()
-			entry.interval = newInterval
-			entry.ticker = time.NewTicker(newInterval)
-		case <-entry.stopCh:
+		case <-timer.C:
+			e.run(model.TaskTriggerSchedule)
+		case <-e.wake:
+			timer.Stop()
+		case <-e.stopCh:
+			timer.Stop()
+			return
+		case <-rootCtx.Done():
+			timer.Stop()
 			return
 		}
 	}
 }
+
+// run 在后台执行一次任务，上一次执行尚未结束时跳过并返回 ErrTaskRunning，Stop 之后返回 ErrTaskStopped
+func (e *taskEntry) run(trigger model.TaskTrigger) error {
+	tasksMu.RLock()
+	defer tasksMu.RUnlock()
+	if stopped {
+		return ErrTaskStopped
+	}
+	if !e.running.CompareAndSwap(false, true) {
+		log.Debugf("task %s is still running, skipping %s run", e.name, trigger)
+		return ErrTaskRunning
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer e.running.Store(false)
+		start := time.Now()
+		err := e.call(context.WithValue(rootCtx, triggerCtxKey{}, trigger))
+		record := model.TaskRun{
+			Trigger:   trigger,
+			StartTime: start.UnixMilli(),
+			Duration:  time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			record.Error = err.Error()
+			log.Warnf("task %s failed: %v", e.name, err)
+		}
+		e.mu.Lock()
+		e.history = append([]model.TaskRun{record}, e.history[:min(len(e.history), historySize-1)]...)
+		e.mu.Unlock()
+	}()
+	return nil
+}
+
+func (e *taskEntry) call(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return e.fn(ctx)
+}
+
+func (e *taskEntry) info() model.TaskInfo {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	info := model.TaskInfo{
+		Name:     e.name,
+		Schedule: e.schedule.String(),
+		Paused:   e.paused.Load(),
+		Running:  e.running.Load(),
+		History:  slices.Clone(e.history),
+	}
+	if !e.next.IsZero() {
+		info.NextRun = e.next.Unix()
+	}
+	if info.History == nil {
+		info.History = []model.TaskRun{}
+	}
+	return info
+}
@@ -0,0 +1,122 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+func TestParseSchedule(t *testing.T) {
+	loc := time.UTC
+	base := time.Date(2026, 3, 14, 10, 17, 30, 0, loc) // 周六
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 3, 14, 10, 30, 0, 0, loc)},
+		{"0 3 * * *", time.Date(2026, 3, 15, 3, 0, 0, 0, loc)},
+		{"30 9-17/4 * * 1-5", time.Date(2026, 3, 16, 9, 30, 0, 0, loc)},
+		{"0 0 1 * *", time.Date(2026, 4, 1, 0, 0, 0, 0, loc)},
+		{"0 12 * * 7", time.Date(2026, 3, 15, 12, 0, 0, 0, loc)},
+		// 日和周都有限制时满足其一即可
+		{"0 0 20 * 1", time.Date(2026, 3, 16, 0, 0, 0, 0, loc)},
+		{"@hourly", time.Date(2026, 3, 14, 11, 0, 0, 0, loc)},
+		{"@every 90m", base.Add(90 * time.Minute)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(tt.want) {
+			t.Errorf("%q.Next = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "@every 10ms", "@often"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) should fail", spec)
+		}
+	}
+}
+
+func TestTaskTriggerNoOverlap(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan model.TaskTrigger, 1)
+	Register("test_overlap", Every(time.Hour), false, func(ctx context.Context) error {
+		started <- TriggerOf(ctx)
+		select {
+		case <-release:
+			return errors.New("boom")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	Start()
+	t.Cleanup(func() { Stop() })
+
+	if err := Trigger("test_overlap"); err != nil {
+		t.Fatal(err)
+	}
+	if trigger := <-started; trigger != model.TaskTriggerManual {
+		t.Errorf("trigger = %q, want manual", trigger)
+	}
+	if err := Trigger("test_overlap"); !errors.Is(err, ErrTaskRunning) {
+		t.Errorf("second trigger err = %v, want ErrTaskRunning", err)
+	}
+	if err := Trigger("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("missing trigger err = %v, want ErrTaskNotFound", err)
+	}
+	close(release)
+
+	var info model.TaskInfo
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		for _, i := range List() {
+			if i.Name == "test_overlap" {
+				info = i
+			}
+		}
+		if len(info.History) == 1 && !info.Running {
+			break
+		}
+	}
+	if len(info.History) != 1 || info.History[0].Error != "boom" || info.History[0].Trigger != model.TaskTriggerManual {
+		t.Fatalf("history = %+v", info.History)
+	}
+
+	if err := Pause("test_overlap", true); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if info = List()[0]; info.NextRun == 0 {
+			break
+		}
+	}
+	if !info.Paused || info.NextRun != 0 {
+		t.Errorf("paused task = %+v", info)
+	}
+}
+
+// Stop 之后手动执行不能再启动新的执行，避免 wg.Add 与 wg.Wait 竞争
+func TestTaskTriggerAfterStop(t *testing.T) {
+	var runs atomic.Int32
+	Register("test_stopped", Every(time.Hour), false, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	Start()
+	if err := Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Trigger("test_stopped"); !errors.Is(err, ErrTaskStopped) {
+		t.Errorf("trigger after stop err = %v, want ErrTaskStopped", err)
+	}
+	if n := runs.Load(); n != 0 {
+		t.Errorf("task ran %d times after stop", n)
+	}
+}
@@ -0,0 +1,188 @@
+// Package ahocorasick implements an ASCII case-insensitive Aho-Corasick
+// automaton used to find which of many literal patterns occur in a text
+// with a single pass over the input.
+package ahocorasick
+
+import "slices"
+
+// Matcher is an immutable Aho-Corasick automaton. Every pattern carries a
+// caller-defined value; Mark reports, for each value, where one of its
+// patterns first occurs in the scanned text. Matching folds ASCII letters only, bytes
+// outside the ASCII range are compared as-is.
+type Matcher struct {
+	// root is the dense transition row of the start state, where most
+	// lookups on clean text end; a missing transition stays at the root.
+	root [256]int32
+	// edges holds the sorted goto transitions of every other state.
+	edges [][]edge
+	// fail is the failure link of every state.
+	fail []int32
+	// out holds the deduplicated values reported when a state is reached,
+	// including the values of all its suffix states.
+	out [][]int32
+	// values lists the distinct values, used to stop scanning early.
+	values []int32
+	// maxLen is the length of the longest pattern.
+	maxLen int
+}
+
+type edge struct {
+	c    byte
+	next int32
+}
+
+var lowerTable = func() (t [256]byte) {
+	for i := range t {
+		c := byte(i)
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		t[i] = c
+	}
+	return t
+}()
+
+// New builds a matcher. patterns[i] is reported as values[i]; values must be
+// in the range [0, n) where n is the length of the slice later passed to Mark.
+// Empty patterns are ignored.
+func New(patterns []string, values []int) *Matcher {
+	m := &Matcher{
+		edges: make([][]edge, 1, 16),
+		out:   make([][]int32, 1, 16),
+	}
+
+	for i, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		state := int32(0)
+		for j := 0; j < len(pattern); j++ {
+			c := lowerTable[pattern[j]]
+			next := m.child(state, c)
+			if next == 0 {
+				m.edges = append(m.edges, nil)
+				m.out = append(m.out, nil)
+				next = int32(len(m.edges) - 1)
+				m.addChild(state, c, next)
+			}
+			state = next
+		}
+		m.out[state] = appendUnique(m.out[state], int32(values[i]))
+		m.values = appendUnique(m.values, int32(values[i]))
+		m.maxLen = max(m.maxLen, len(pattern))
+	}
+
+	// Breadth-first construction of failure links. States at depth one fail
+	// to the root; deeper states follow their parent's failure chain.
+	m.fail = make([]int32, len(m.edges))
+	queue := make([]int32, 0, len(m.edges))
+	for c := 0; c < 256; c++ {
+		if next := m.root[c]; next != 0 {
+			queue = append(queue, next)
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for _, v := range m.out[m.fail[state]] {
+			m.out[state] = appendUnique(m.out[state], v)
+		}
+		for _, e := range m.edges[state] {
+			m.fail[e.next] = m.step(m.fail[state], e.c)
+			queue = append(queue, e.next)
+		}
+	}
+	return m
+}
+
+// MaxLen returns the length of the longest pattern.
+func (m *Matcher) MaxLen() int {
+	return m.maxLen
+}
+
+// Mark scans text from offset from and, for every value v with ends[v] < 0,
+// sets ends[v] to the offset just past the first occurrence of one of its
+// patterns. It returns the number of values newly found. Occurrences starting
+// before from are not reported. Scanning stops as soon as every value has a
+// non-negative end, including values set by the caller beforehand.
+func (m *Matcher) Mark(text string, from int, ends []int) int {
+	remaining := 0
+	for _, v := range m.values {
+		if ends[v] < 0 {
+			remaining++
+		}
+	}
+	if remaining == 0 {
+		return 0
+	}
+
+	marked := 0
+	state := int32(0)
+	for i := from; i < len(text); i++ {
+		state = m.step(state, lowerTable[text[i]])
+		for _, v := range m.out[state] {
+			if ends[v] >= 0 {
+				continue
+			}
+			ends[v] = i + 1
+			marked++
+			if marked == remaining {
+				return marked
+			}
+		}
+	}
+	return marked
+}
+
+// step returns the state reached from state on the folded byte c, following
+// failure links until a transition exists or the root is reached.
+func (m *Matcher) step(state int32, c byte) int32 {
+	for state != 0 {
+		if next := m.child(state, c); next != 0 {
+			return next
+		}
+		state = m.fail[state]
+	}
+	return m.root[c]
+}
+
+// child returns the goto transition of state on c, or 0 if there is none.
+func (m *Matcher) child(state int32, c byte) int32 {
+	if state == 0 {
+		return m.root[c]
+	}
+	edges := m.edges[state]
+	if i, ok := slices.BinarySearchFunc(edges, c, compareEdge); ok {
+		return edges[i].next
+	}
+	return 0
+}
+
+func (m *Matcher) addChild(state int32, c byte, next int32) {
+	if state == 0 {
+		m.root[c] = next
+		return
+	}
+	i, _ := slices.BinarySearchFunc(m.edges[state], c, compareEdge)
+	m.edges[state] = slices.Insert(m.edges[state], i, edge{c: c, next: next})
+}
+
+func compareEdge(e edge, c byte) int {
+	return int(e.c) - int(c)
+}
+
+func appendUnique(s []int32, v int32) []int32 {
+	if contains(s, v) {
+		return s
+	}
+	return append(s, v)
+}
+
+func contains(s []int32, v int32) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
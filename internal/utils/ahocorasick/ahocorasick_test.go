@@ -0,0 +1,122 @@
+package ahocorasick
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMark(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		values   []int
+		text     string
+		from     int
+		want     []int
+	}{
+		{"empty text", []string{"a"}, []int{0}, "", 0, []int{-1}},
+		{"no patterns", nil, nil, "abc", 0, nil},
+		{"empty pattern ignored", []string{"", "b"}, []int{0, 1}, "abc", 0, []int{-1, 2}},
+		{"overlapping", []string{"he", "she", "his", "hers"}, []int{0, 1, 2, 3}, "ushers", 0, []int{4, 4, -1, 6}},
+		{"suffix via failure link", []string{"abcd", "bc"}, []int{0, 1}, "abce", 0, []int{-1, 3}},
+		{"nested", []string{"a", "aa", "aaa"}, []int{0, 1, 2}, "aa", 0, []int{1, 2, -1}},
+		{"shared value", []string{"foo", "bar"}, []int{0, 0}, "xbar foo", 0, []int{4}},
+		{"first occurrence", []string{"ab"}, []int{0}, "xabab", 0, []int{3}},
+		{"ascii fold", []string{"Bearer"}, []int{0}, "BEARER x", 0, []int{6}},
+		{"unicode", []string{"密码", "ключ"}, []int{0, 1}, "输入密码：", 0, []int{12, -1}},
+		{"no unicode fold", []string{"ключ"}, []int{0}, "КЛЮЧ", 0, []int{-1}},
+		{"non-ascii bytes unchanged", []string{"\xc3\xa4"}, []int{0}, "\xc3\x84", 0, []int{-1}},
+		{"from offset", []string{"ab"}, []int{0}, "abxab", 1, []int{5}},
+		{"starts before from", []string{"abc"}, []int{0}, "abc", 1, []int{-1}},
+		{"from end", []string{"a"}, []int{0}, "a", 1, []int{-1}},
+	}
+	for _, tt := range tests {
+		ends := make([]int, len(tt.want))
+		for i := range ends {
+			ends[i] = -1
+		}
+		marked := New(tt.patterns, tt.values).Mark(tt.text, tt.from, ends)
+		if !slices.Equal(ends, tt.want) {
+			t.Errorf("%s: ends %v, want %v", tt.name, ends, tt.want)
+		}
+		want := 0
+		for _, end := range tt.want {
+			if end >= 0 {
+				want++
+			}
+		}
+		if marked != want {
+			t.Errorf("%s: returned %d, want %d", tt.name, marked, want)
+		}
+	}
+}
+
+func TestMarkPreset(t *testing.T) {
+	m := New([]string{"a", "b"}, []int{0, 1})
+	ends := []int{0, -1}
+	if got := m.Mark("ab", 0, ends); got != 1 || !slices.Equal(ends, []int{0, 2}) {
+		t.Fatalf("Mark = %d, ends = %v", got, ends)
+	}
+	if got := m.Mark("ab", 0, ends); got != 0 {
+		t.Fatalf("Mark with all values found = %d, want 0", got)
+	}
+}
+
+func TestMaxLen(t *testing.T) {
+	if got := New([]string{"ab", "", "abcd", "x"}, []int{0, 1, 2, 3}).MaxLen(); got != 4 {
+		t.Fatalf("MaxLen = %d, want 4", got)
+	}
+}
+
+// TestMarkNaive compares against strings.Index on random short alphabets,
+// where overlapping prefixes and suffixes are common.
+func TestMarkNaive(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = "abAB\xff"[r.Intn(5)]
+		}
+		return string(b)
+	}
+	for round := 0; round < 500; round++ {
+		patterns := make([]string, 1+r.Intn(8))
+		values := make([]int, len(patterns))
+		for i := range patterns {
+			patterns[i] = random(r.Intn(5))
+			values[i] = r.Intn(4)
+		}
+		text := random(r.Intn(40))
+		from := r.Intn(len(text) + 1)
+
+		want := []int{-1, -1, -1, -1}
+		for i, p := range patterns {
+			if p == "" {
+				continue
+			}
+			if j := strings.Index(asciiLower(text[from:]), asciiLower(p)); j >= 0 {
+				end := from + j + len(p)
+				if want[values[i]] < 0 || end < want[values[i]] {
+					want[values[i]] = end
+				}
+			}
+		}
+		got := []int{-1, -1, -1, -1}
+		New(patterns, values).Mark(text, from, got)
+		if !slices.Equal(got, want) {
+			t.Fatalf("patterns %q text %q from %d: got %v, want %v", patterns, text, from, got, want)
+		}
+	}
+}
+
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
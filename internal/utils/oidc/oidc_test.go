@@ -0,0 +1,222 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockProvider is a minimal OpenID provider that issues an ID token for a
+// single pending authorization code.
+type mockProvider struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	kid       string
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{key: key, kid: "k1"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"userinfo_endpoint":      m.URL + "/userinfo",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		pub := m.key.PublicKey
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": m.kid, "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if id != "octopus" || secret != "s3cret" || r.FormValue("code") != "code-1" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != m.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "at-1",
+			"token_type":   "Bearer",
+			"id_token":     m.sign(t, m.claims),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at-1" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"sub": "user-1", "groups": []string{"admins"}})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	s, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (m *mockProvider) idClaims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": m.URL, "aud": "octopus", "sub": "user-1", "nonce": nonce,
+		"iat": now.Unix(), "exp": now.Add(time.Minute).Unix(),
+		"preferred_username": "alice",
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	m := newMockProvider(t)
+	ctx := context.Background()
+	cfg := Config{ClientID: "octopus", ClientSecret: "s3cret", RedirectURL: "http://localhost/cb", Scopes: []string{"openid", "profile"}}
+
+	p, err := Discover(ctx, m.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(p.AuthCodeURL(cfg, "st", "no", challenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("state") != "st" || q.Get("nonce") != "no" || q.Get("code_challenge") != challenge || q.Get("scope") != "openid profile" {
+		t.Fatalf("unexpected auth url %s", u)
+	}
+
+	m.challenge, m.claims = challenge, m.idClaims("no")
+	if _, err := p.Exchange(ctx, cfg, "code-1", "wrong-verifier"); err == nil {
+		t.Fatal("exchange with wrong verifier succeeded")
+	}
+	token, err := p.Exchange(ctx, cfg, "code-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := p.VerifyIDToken(ctx, cfg.ClientID, token.IDToken, "no")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["preferred_username"] != "alice" {
+		t.Errorf("claims = %v", claims)
+	}
+	if _, err := p.VerifyIDToken(ctx, cfg.ClientID, token.IDToken, "other"); err == nil {
+		t.Error("nonce mismatch accepted")
+	}
+	if _, err := p.VerifyIDToken(ctx, "someone-else", token.IDToken, "no"); err == nil {
+		t.Error("wrong audience accepted")
+	}
+	info, err := p.UserInfo(ctx, token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups, _ := info["groups"].([]any); len(groups) != 1 || groups[0] != "admins" {
+		t.Errorf("userinfo = %v", info)
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	m := newMockProvider(t)
+	ctx := context.Background()
+	p, err := Discover(ctx, m.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired := m.idClaims("n")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	otherIssuer := m.idClaims("n")
+	otherIssuer["iss"] = "https://evil.example"
+	forged, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	badSig := jwt.NewWithClaims(jwt.SigningMethodRS256, m.idClaims("n"))
+	badSig.Header["kid"] = m.kid
+	badSigned, _ := badSig.SignedString(forged)
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, m.idClaims("n"))
+	hsSigned, _ := hs.SignedString([]byte("octopus"))
+
+	for name, raw := range map[string]string{
+		"expired":   m.sign(t, expired),
+		"issuer":    m.sign(t, otherIssuer),
+		"signature": badSigned,
+		"hmac":      hsSigned,
+	} {
+		if _, err := p.VerifyIDToken(ctx, "octopus", raw, "n"); err == nil {
+			t.Errorf("%s: token accepted", name)
+		} else if !strings.Contains(err.Error(), "invalid id_token") {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	m := newMockProvider(t)
+	ctx := context.Background()
+	p, err := Discover(ctx, m.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.VerifyIDToken(ctx, "octopus", m.sign(t, m.idClaims("n")), "n"); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.key, m.kid = key, "k2"
+	// 未知 kid 只在超过刷新间隔后才重新获取
+	if _, err := p.VerifyIDToken(ctx, "octopus", m.sign(t, m.idClaims("n")), "n"); err == nil {
+		t.Fatal("rotated key accepted before refresh interval")
+	}
+	p.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+	if _, err := p.VerifyIDToken(ctx, "octopus", m.sign(t, m.idClaims("n")), "n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	m := newMockProvider(t)
+	if _, err := Discover(context.Background(), strings.Replace(m.URL, "127.0.0.1", "localhost", 1), nil); err == nil {
+		t.Fatal("issuer mismatch accepted")
+	}
+}
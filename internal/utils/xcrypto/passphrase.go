@@ -0,0 +1,98 @@
+// Package xcrypto 提供基于口令的认证加密，用于加密落盘的文件
+package xcrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Magic Encrypt 输出的前缀，用于区分加密内容
+var Magic = []byte("OCTOENC1")
+
+const (
+	saltSize = 16
+	keySize  = 32
+
+	// scrypt 参数，采用交互式登录场景的推荐值
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var ErrDecrypt = errors.New("decryption failed: wrong passphrase or corrupted data")
+
+// IsEncrypted 判断 data 是否以 Encrypt 的头部开头
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, Magic)
+}
+
+// Encrypt 使用 scrypt 从口令派生密钥，以 AES-256-GCM 加密 plaintext
+// 输出格式：magic | salt | nonce | 密文+tag
+func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(Magic)+saltSize+len(nonce))
+	header = append(header, Magic...)
+	header = append(header, salt...)
+	header = append(header, nonce...)
+	out := make([]byte, len(header), len(header)+len(plaintext)+aead.Overhead())
+	copy(out, header)
+	// 头部作为附加数据参与认证，不能被替换
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// Decrypt 解密 Encrypt 的输出
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, errors.New("data is not encrypted")
+	}
+	if len(data) < len(Magic)+saltSize {
+		return nil, ErrDecrypt
+	}
+	salt := data[len(Magic) : len(Magic)+saltSize]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(Magic) + saltSize + aead.NonceSize()
+	if len(data) < headerSize+aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+	nonce := data[len(Magic)+saltSize : headerSize]
+	plaintext, err := aead.Open(nil, nonce, data[headerSize:], data[:headerSize])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
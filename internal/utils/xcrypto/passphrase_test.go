@@ -0,0 +1,39 @@
+package xcrypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	plaintext := []byte(`{"version":1}`)
+	data, err := Encrypt(plaintext, "secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !IsEncrypted(data) {
+		t.Fatalf("IsEncrypted() = false, want true")
+	}
+	if bytes.Contains(data, plaintext) {
+		t.Fatalf("ciphertext contains plaintext")
+	}
+
+	got, err := Decrypt(data, "secret")
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("Decrypt() = %q, want %q", got, plaintext)
+	}
+
+	if _, err := Decrypt(data, "wrong"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("Decrypt() with wrong passphrase error = %v, want ErrDecrypt", err)
+	}
+
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := Decrypt(tampered, "secret"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("Decrypt() of tampered data error = %v, want ErrDecrypt", err)
+	}
+}
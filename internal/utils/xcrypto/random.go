@@ -0,0 +1,20 @@
+package xcrypto
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// RandomString 使用 crypto/rand 生成长度为 n、由 charset 中字符组成的随机字符串
+func RandomString(n int, charset string) (string, error) {
+	b := make([]byte, n)
+	maxI := big.NewInt(int64(len(charset)))
+	for i := range b {
+		v, err := rand.Int(rand.Reader, maxI)
+		if err != nil {
+			return "", err
+		}
+		b[i] = charset[v.Int64()]
+	}
+	return string(b), nil
+}
@@ -6,12 +6,22 @@ export const API_BASE_URL = process.env.NEXT_PUBLIC_API_BASE_URL || '.';
 /**
  * 获取认证 Store（延迟导入以避免循环依赖）
  */
-let getAuthStore: (() => { token: string | null; logout: () => void }) | null = null;
+interface AuthStoreAccessor {
+    token: string | null;
+    logout: () => void;
+    // 使用刷新令牌换取新的访问令牌，成功时返回 true
+    refresh: () => Promise<boolean>;
+}
+
+let getAuthStore: (() => AuthStoreAccessor) | null = null;
 
-export function setAuthStoreGetter(getter: () => { token: string | null; logout: () => void }) {
+export function setAuthStoreGetter(getter: () => AuthStoreAccessor) {
     getAuthStore = getter;
 }
 
+// 登录和刷新接口返回 401 时不再尝试刷新
+const NO_REFRESH_PATHS = ['/api/v1/user/login', '/api/v1/user/refresh'];
+
 /**
  * 全局错误处理
  */
@@ -68,7 +78,8 @@ async function request<T>(
     method: string,
     path: string,
     body?: BodyInit,
-    params?: Record<string, string | number | boolean>
+    params?: Record<string, string | number | boolean>,
+    retried = false
 ): Promise<T> {
     // 构建 URL
     const searchParams = params ? new URLSearchParams(
@@ -99,6 +110,13 @@ async function request<T>(
         body,
     });
 
+    // 访问令牌过期时刷新一次后重试
+    if (response.status === HttpStatus.UNAUTHORIZED && !retried && getAuthStore && !NO_REFRESH_PATHS.includes(path)) {
+        if (await getAuthStore().refresh()) {
+            return request<T>(method, path, body, params, true);
+        }
+    }
+
     return handleResponse<T>(response);
 }
 
@@ -16,6 +16,11 @@ export interface APIKey {
     expire_at?: number; // Unix 时间戳（秒），不传表示永不过期
     max_cost?: number; // 不传表示无限制
     supported_models?: string; // 不传表示支持所有模型
+    log_body_disabled?: boolean; // 不保存请求/响应内容
+    log_body_max_size?: number; // 请求/响应内容最大保存字节数，不传使用全局设置
+    log_body_keep_days?: number; // 请求/响应内容保存天数，不传使用全局设置
+    sensitive_rule_set_id?: number; // 敏感信息规则集，不传使用分组或默认规则集
+    managed?: boolean; // 由声明式配置管理，只读
 }
 
 /**
@@ -0,0 +1,118 @@
+import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
+import { apiClient, API_BASE_URL } from '../client';
+import { logger } from '@/lib/logger';
+import { downloadBlob, getAuthHeader, type DBImportMode, type DBImportResult } from './setting';
+
+/**
+ * 定时备份目录中的一份备份
+ */
+export interface BackupFile {
+    name: string;
+    size: number;
+    created_at: number;
+    encrypted: boolean;
+}
+
+export interface BackupList {
+    path: string;
+    encryption_enabled: boolean;
+    files: BackupFile[];
+}
+
+/**
+ * 获取备份列表 Hook
+ */
+export function useBackupList() {
+    return useQuery({
+        queryKey: ['backups', 'list'],
+        queryFn: async () => {
+            return apiClient.get<BackupList>('/api/v1/backup/list');
+        },
+        refetchOnMount: 'always',
+    });
+}
+
+/**
+ * 立即创建备份 Hook
+ */
+export function useCreateBackup() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async () => {
+            return apiClient.post<BackupFile>('/api/v1/backup/create');
+        },
+        onSuccess: (data) => {
+            logger.log('备份创建成功:', data);
+            queryClient.invalidateQueries({ queryKey: ['backups', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('备份创建失败:', error);
+        },
+    });
+}
+
+/**
+ * 从备份增量恢复 Hook
+ */
+export function useRestoreBackup() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (data: { name: string; mode?: DBImportMode; dry_run?: boolean }) => {
+            return apiClient.post<DBImportResult>('/api/v1/backup/restore', data);
+        },
+        onSuccess: (data) => {
+            if (data.dry_run) return;
+            logger.log('备份恢复成功:', data);
+            queryClient.invalidateQueries();
+        },
+        onError: (error) => {
+            logger.error('备份恢复失败:', error);
+        },
+    });
+}
+
+/**
+ * 删除备份 Hook
+ */
+export function useDeleteBackup() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (name: string) => {
+            return apiClient.delete<null>(`/api/v1/backup/delete/${encodeURIComponent(name)}`);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['backups', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('备份删除失败:', error);
+        },
+    });
+}
+
+/**
+ * 下载备份文件
+ */
+export function useDownloadBackup() {
+    return useMutation({
+        mutationFn: async (name: string) => {
+            const res = await fetch(`${API_BASE_URL}/api/v1/backup/download/${encodeURIComponent(name)}`, {
+                method: 'GET',
+                headers: {
+                    Authorization: getAuthHeader(),
+                },
+            });
+            if (!res.ok) {
+                const text = await res.text();
+                throw new Error(text || res.statusText);
+            }
+            await downloadBlob(await res.blob(), name);
+            return { name };
+        },
+        onError: (error) => {
+            logger.error('备份下载失败:', error);
+        },
+    });
+}
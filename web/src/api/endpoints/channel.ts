@@ -62,6 +62,7 @@ export type Channel = {
     custom_header: CustomHeader[];
     param_override?: string | null;
     channel_proxy?: string | null;
+    managed?: boolean; // 由声明式配置管理，只读
     stats: StatsChannel;
 };
 
@@ -0,0 +1,86 @@
+import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
+import { apiClient } from '../client';
+import { logger } from '@/lib/logger';
+
+export type GitOpsKind = 'channel' | 'group' | 'api_key' | 'setting';
+export type GitOpsAction = 'create' | 'update' | 'delete' | 'unmanage';
+
+/**
+ * 声明式配置与数据库之间的一处差异，fields 只包含字段名
+ */
+export interface GitOpsChange {
+    kind: GitOpsKind;
+    name: string;
+    action: GitOpsAction;
+    fields?: string[];
+}
+
+export interface GitOpsPlan {
+    dry_run: boolean;
+    hash: string;
+    changes: GitOpsChange[];
+}
+
+export interface GitOpsStatus {
+    enabled: boolean;
+    path: string;
+    prune: boolean;
+    interval: number;
+    last_sync_time?: number;
+    last_hash?: string;
+    last_error?: string;
+    last_changes?: GitOpsChange[];
+}
+
+/**
+ * 获取声明式配置同步状态 Hook
+ */
+export function useGitOpsStatus() {
+    return useQuery({
+        queryKey: ['gitops', 'status'],
+        queryFn: async () => {
+            return apiClient.get<GitOpsStatus>('/api/v1/gitops/status');
+        },
+        refetchInterval: 30000,
+        refetchOnMount: 'always',
+    });
+}
+
+/**
+ * 预览声明式配置变更 Hook（不写入数据库）
+ */
+export function usePlanGitOps() {
+    return useMutation({
+        mutationFn: async () => {
+            return apiClient.post<GitOpsPlan>('/api/v1/gitops/plan');
+        },
+        onError: (error) => {
+            logger.error('GitOps 预览失败:', error);
+        },
+    });
+}
+
+/**
+ * 立即同步声明式配置 Hook
+ */
+export function useApplyGitOps() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async () => {
+            return apiClient.post<GitOpsPlan>('/api/v1/gitops/apply');
+        },
+        onSuccess: (data) => {
+            logger.log('GitOps 同步成功:', data);
+            queryClient.invalidateQueries({ queryKey: ['gitops'] });
+            queryClient.invalidateQueries({ queryKey: ['channels'] });
+            queryClient.invalidateQueries({ queryKey: ['groups'] });
+            queryClient.invalidateQueries({ queryKey: ['apikeys'] });
+            queryClient.invalidateQueries({ queryKey: ['settings'] });
+        },
+        onError: (error) => {
+            logger.error('GitOps 同步失败:', error);
+            queryClient.invalidateQueries({ queryKey: ['gitops', 'status'] });
+        },
+    });
+}
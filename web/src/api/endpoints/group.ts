@@ -33,6 +33,8 @@ export interface Group {
     mode: GroupMode;
     match_regex: string;
     first_token_time_out?: number;
+    sensitive_rule_set_id?: number; // 敏感信息规则集，0 使用默认规则集
+    managed?: boolean; // 由声明式配置管理，只读
     items?: GroupItem[];
 }
 
@@ -64,6 +66,7 @@ export interface GroupUpdateRequest {
     mode?: GroupMode;                     // 仅在模式变更时发送
     match_regex?: string;                 // 仅在匹配正则变更时发送
     first_token_time_out?: number;        // 仅在超时变更时发送
+    sensitive_rule_set_id?: number;       // 仅在规则集变更时发送
     items_to_add?: GroupItemAddRequest[];    // 新增的 items
     items_to_update?: GroupItemUpdateRequest[]; // 更新的 items (priority 变更)
     items_to_delete?: number[];              // 删除的 item IDs
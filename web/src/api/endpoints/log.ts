@@ -1,5 +1,5 @@
 import type { InfiniteData } from '@tanstack/react-query';
-import { useInfiniteQuery, useMutation, useQueryClient } from '@tanstack/react-query';
+import { useInfiniteQuery, useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
 import { apiClient, API_BASE_URL } from '../client';
 import { logger } from '@/lib/logger';
 import { useCallback, useEffect, useMemo, useRef, useState } from 'react';
@@ -9,6 +9,10 @@ import { useCallback, useEffect, useMemo, useRef, useState } from 'react';
 export interface RelayLog {
     id: number;
     time: number;                // 时间戳
+    api_key_id: number;          // 请求使用的 API Key ID
+    inbound_type: number;        // 入站协议类型
+    stream: boolean;             // 是否为流式请求
+    status_code: number;         // 返回给客户端的 HTTP 状态码
     request_model_name: string;  // 请求模型名称
     channel: number;             // 实际使用的渠道ID
     channel_name: string;        // 渠道名称
@@ -18,9 +22,27 @@ export interface RelayLog {
     ftut: number;                // 首字时间(毫秒)
     use_time: number;            // 总用时(毫秒)
     cost: number;                // 消耗费用
-    request_content: string;     // 请求内容
-    response_content: string;    // 响应内容
+    request_content?: string;    // 请求内容，仅实时日志携带，历史日志需通过 useLogBody 获取
+    response_content?: string;   // 响应内容，仅实时日志携带，历史日志需通过 useLogBody 获取
+    has_body: boolean;           // 是否存有请求/响应内容
+    body_truncated?: boolean;    // 内容是否被截断
     error: string;                // 错误信息
+    attempt_count: number;       // 上游尝试次数
+    attempts: RelayAttempt[] | null; // 按顺序记录的上游尝试
+}
+
+/**
+ * 上游请求尝试
+ */
+export interface RelayAttempt {
+    channel_id: number;
+    channel_name: string;
+    key_id: number;
+    base_url: string;
+    model_name: string;
+    status_code: number;         // 上游状态码，未收到响应时为 0
+    latency: number;             // 耗时(毫秒)
+    error?: string;
 }
 
 /**
@@ -29,8 +51,44 @@ export interface RelayLog {
 export interface LogListParams {
     page?: number;
     page_size?: number;
+    cursor?: number;             // 游标，只返回 ID 小于该值的日志
     start_time?: number;
     end_time?: number;
+    model?: string;              // 请求模型或实际模型
+    channel_id?: number;
+    api_key_id?: number;
+    status?: 'success' | 'error';
+    min_cost?: number;
+    max_cost?: number;
+    q?: string;                  // 在模型、渠道名称和错误信息中模糊搜索
+    attempt_channel_id?: number; // 尝试过指定渠道
+    failover?: boolean;          // 是否发生过故障转移
+}
+
+/**
+ * 日志请求/响应内容
+ */
+export interface RelayLogBody {
+    request_content: string;
+    response_content: string;
+    truncated: boolean;
+}
+
+/**
+ * 日志内容 Hook，按需加载单条日志的请求/响应内容
+ *
+ * @example
+ * const { data: body } = useLogBody(log.id, isOpen && log.has_body);
+ */
+export function useLogBody(id: number, enabled: boolean) {
+    return useQuery({
+        queryKey: ['logs', 'body', id],
+        queryFn: async () => {
+            return apiClient.get<RelayLogBody>(`/api/v1/log/body/${id}`);
+        },
+        enabled,
+        staleTime: Infinity,
+    });
 }
 
 /**
@@ -1,14 +1,48 @@
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
 import { apiClient } from '../client';
 
+export type SensitiveDirection = 'request' | 'response' | 'both';
+export type SensitiveAction = 'replace' | 'mask' | 'block' | 'log';
+
 export interface SensitiveFilterRule {
     id: number;
     name: string;
     pattern: string;
     replacement: string;
+    direction: SensitiveDirection;
+    action: SensitiveAction;
     enabled: boolean;
     built_in: boolean;
     priority: number;
+    rule_set_id: number; // 0 为默认规则集
+}
+
+export interface SensitiveRuleSet {
+    id: number;
+    name: string;
+    description: string;
+    include_default: boolean;
+}
+
+export interface SensitiveRuleHit {
+    rule_id: number;
+    name: string;
+    action: SensitiveAction;
+    count: number;
+}
+
+export interface SensitiveTestResult {
+    text: string;
+    hits: SensitiveRuleHit[];
+    blocked: boolean;
+    blocked_rule?: string;
+}
+
+export interface StatsSensitive {
+    rule_id: number;
+    api_key_id: number;
+    hits: number;
+    last_hit_time: number;
 }
 
 // 获取规则列表
@@ -72,3 +106,66 @@ export function useToggleSensitiveRule() {
         },
     });
 }
+
+// 使用规则集的当前规则测试示例文本
+export function useTestSensitiveRules() {
+    return useMutation({
+        mutationFn: async ({ text, direction, rule_set_id }: { text: string; direction: SensitiveDirection; rule_set_id: number }) => {
+            return apiClient.post<SensitiveTestResult>('/api/v1/sensitive/test', { text, direction, rule_set_id });
+        },
+    });
+}
+
+// 获取规则集列表
+export function useSensitiveRuleSetList() {
+    return useQuery({
+        queryKey: ['sensitive-rule-sets'],
+        queryFn: async () => {
+            return apiClient.get<SensitiveRuleSet[]>('/api/v1/sensitive/ruleset/list');
+        },
+    });
+}
+
+// 创建规则集
+export function useCreateSensitiveRuleSet() {
+    const queryClient = useQueryClient();
+    return useMutation({
+        mutationFn: async (ruleSet: Omit<SensitiveRuleSet, 'id'>) => {
+            return apiClient.post<SensitiveRuleSet>('/api/v1/sensitive/ruleset/create', ruleSet);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['sensitive-rule-sets'] });
+        },
+    });
+}
+
+// 删除规则集，同时删除其中的规则
+export function useDeleteSensitiveRuleSet() {
+    const queryClient = useQueryClient();
+    return useMutation({
+        mutationFn: async (id: number) => {
+            return apiClient.delete(`/api/v1/sensitive/ruleset/delete/${id}`);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['sensitive-rule-sets'] });
+            queryClient.invalidateQueries({ queryKey: ['sensitive-rules'] });
+        },
+    });
+}
+
+// 获取规则命中统计，按规则汇总所有 API Key
+export function useSensitiveRuleHits() {
+    return useQuery({
+        queryKey: ['stats', 'sensitive'],
+        queryFn: async () => {
+            return apiClient.get<StatsSensitive[]>('/api/v1/stats/sensitive');
+        },
+        select: (data) => {
+            const hits: Record<number, number> = {};
+            for (const item of data) {
+                hits[item.rule_id] = (hits[item.rule_id] ?? 0) + item.hits;
+            }
+            return hits;
+        },
+    });
+}
@@ -9,6 +9,7 @@ import { useAuthStore } from './user';
 export interface Setting {
     key: string;
     value: string;
+    managed?: boolean; // 由声明式配置管理，只读
 }
 
 export const SettingKey = {
@@ -18,8 +19,15 @@ export const SettingKey = {
     SyncLLMInterval: 'sync_llm_interval',
     RelayLogKeepEnabled: 'relay_log_keep_enabled',
     RelayLogKeepPeriod: 'relay_log_keep_period',
+    RelayLogBodyKeepPeriod: 'relay_log_body_keep_period',
+    RelayLogBodyMaxSize: 'relay_log_body_max_size',
     CORSAllowOrigins: 'cors_allow_origins',
     SensitiveFilterEnabled: 'sensitive_filter_enabled',
+    BackupInterval: 'backup_interval',
+    BackupKeepCount: 'backup_keep_count',
+    BackupKeepDays: 'backup_keep_days',
+    BackupIncludeLogs: 'backup_include_logs',
+    BackupIncludeStats: 'backup_include_stats',
 } as const;
 
 /**
@@ -75,8 +83,35 @@ export function useSetSetting() {
 /**
  * 数据库导入/导出
  */
+export type DBImportMode = 'merge' | 'replace';
+
+export interface DBImportTableStats {
+    create: number;
+    update: number;
+    conflict: number;
+    delete: number;
+}
+
+export interface DBImportChange {
+    kind: 'channel' | 'group' | 'api_key';
+    name: string;
+    action: 'create' | 'update' | 'conflict' | 'delete';
+    fields?: string[];
+}
+
 export interface DBImportResult {
+    mode: DBImportMode;
+    dry_run: boolean;
+    from_version: number;
     rows_affected: Record<string, number>;
+    tables?: Record<string, DBImportTableStats>; // 仅预览
+    changes?: DBImportChange[]; // 仅预览
+}
+
+export interface DBImportOptions {
+    file: File;
+    mode?: DBImportMode;
+    dry_run?: boolean;
 }
 
 export interface DBExportOptions {
@@ -105,7 +140,7 @@ function getDataField<T>(value: unknown): T | undefined {
     return (value as ApiResponse<T>).data;
 }
 
-function getAuthHeader(): string {
+export function getAuthHeader(): string {
     const token = useAuthStore.getState().token;
     if (!token) throw new Error('Not authenticated');
     return `Bearer ${token}`;
@@ -125,7 +160,7 @@ function exportFallbackFilename() {
     return `octopus-export-${ts}.json`;
 }
 
-async function downloadBlob(blob: Blob, filename: string) {
+export async function downloadBlob(blob: Blob, filename: string) {
     const url = URL.createObjectURL(blob);
     try {
         const a = document.createElement('a');
@@ -173,15 +208,18 @@ export function useExportDB() {
 }
 
 /**
- * 导入数据库（上传 JSON 文件，增量导入）
+ * 导入数据库（上传 JSON 文件，增量合并或替换，dry_run 时仅返回预览）
  */
 export function useImportDB() {
     return useMutation({
-        mutationFn: async (file: File) => {
+        mutationFn: async ({ file, mode = 'merge', dry_run = false }: DBImportOptions) => {
             const form = new FormData();
             form.append('file', file);
+            const params = new URLSearchParams();
+            params.set('mode', mode);
+            params.set('dry_run', String(dry_run));
 
-            const res = await fetch(`${API_BASE_URL}/api/v1/setting/import`, {
+            const res = await fetch(`${API_BASE_URL}/api/v1/setting/import?${params.toString()}`, {
                 method: 'POST',
                 headers: {
                     Authorization: getAuthHeader(),
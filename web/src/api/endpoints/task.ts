@@ -0,0 +1,79 @@
+import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
+import { apiClient } from '../client';
+import { logger } from '@/lib/logger';
+
+export type TaskTrigger = 'start' | 'schedule' | 'manual';
+
+/**
+ * 定时任务的一次执行记录，start_time 为毫秒时间戳
+ */
+export interface TaskRun {
+    trigger: TaskTrigger;
+    start_time: number;
+    duration: number;
+    error?: string;
+}
+
+/**
+ * 定时任务状态，next_run 为秒级时间戳，暂停时为 0
+ */
+export interface TaskInfo {
+    name: string;
+    schedule: string;
+    paused: boolean;
+    running: boolean;
+    next_run: number;
+    history: TaskRun[];
+}
+
+/**
+ * 获取定时任务列表 Hook
+ */
+export function useTaskList() {
+    return useQuery({
+        queryKey: ['tasks', 'list'],
+        queryFn: async () => {
+            return apiClient.get<TaskInfo[]>('/api/v1/task/list');
+        },
+        refetchInterval: 10000,
+        refetchOnMount: 'always',
+    });
+}
+
+/**
+ * 立即执行任务 Hook
+ */
+export function useTriggerTask() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (name: string) => {
+            return apiClient.post<null>('/api/v1/task/trigger', { name });
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['tasks', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('任务执行失败:', error);
+        },
+    });
+}
+
+/**
+ * 暂停或恢复任务 Hook
+ */
+export function usePauseTask() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (data: { name: string; paused: boolean }) => {
+            return apiClient.post<null>('/api/v1/task/pause', data);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['tasks', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('任务暂停失败:', error);
+        },
+    });
+}
@@ -1,8 +1,8 @@
 import { useEffect } from 'react';
-import { useMutation } from '@tanstack/react-query';
+import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
 import { create } from 'zustand';
 import { persist } from 'zustand/middleware';
-import { apiClient, setAuthStoreGetter } from '../client';
+import { API_BASE_URL, apiClient, setAuthStoreGetter } from '../client';
 import { logger } from '@/lib/logger';
 
 /**
@@ -11,15 +11,111 @@ import { logger } from '@/lib/logger';
 export interface UserLoginRequest {
     username: string;
     password: string;
-    expire: number; // token 过期时间（秒）
+    expire: number; // 会话有效期（分钟），期间通过刷新令牌保持登录
+    totp_code?: string; // 启用两步验证后需要的验证码
+    recovery_code?: string; // 无法使用验证器时可用恢复码代替验证码
 }
 
 /**
- * 用户登录响应
+ * 用户角色
+ */
+export type UserRole = 'owner' | 'operator' | 'finance' | 'viewer';
+
+export const USER_ROLES: UserRole[] = ['owner', 'operator', 'finance', 'viewer'];
+
+/**
+ * 接口权限，格式为 资源:read 或 资源:write，authenticated 表示登录即可
+ */
+export type Permission = string;
+
+/**
+ * 当前登录用户信息
+ */
+export interface UserInfo {
+    id: number;
+    username: string;
+    role: UserRole;
+    totp_enabled: boolean;
+    permissions: Permission[];
+}
+
+/**
+ * 管理后台用户
+ */
+export interface User {
+    id: number;
+    username: string;
+    role: UserRole;
+    totp_enabled: boolean;
+    sso: boolean; // 是否已关联单点登录
+}
+
+/**
+ * 用户登录响应，密码正确但需要两步验证时只返回 totp_required
  */
 export interface UserLoginResponse {
     token: string;
     expire_at: string; // ISO 8601 格式
+    refresh_token: string;
+    refresh_expire_at: string; // ISO 8601 格式，会话的过期时间
+    user: UserInfo;
+    totp_required?: boolean;
+}
+
+/**
+ * 单点登录信息，enabled 时登录页显示单点登录按钮
+ */
+export interface OIDCInfo {
+    enabled: boolean;
+    name?: string;
+}
+
+/**
+ * 两步验证设置信息，secret 可手动输入验证器，uri 为 otpauth 链接
+ */
+export interface TOTPSetup {
+    secret: string;
+    uri: string;
+}
+
+/**
+ * 登录会话
+ */
+export interface UserSession {
+    id: string;
+    user_id: number;
+    user_agent: string;
+    ip: string;
+    created_at: number;
+    last_used_at: number;
+    expires_at: number;
+    current: boolean;
+}
+
+/**
+ * 个人访问令牌，用于脚本调用管理接口
+ */
+export interface AccessToken {
+    id: number;
+    user_id: number;
+    name: string;
+    hint: string;
+    scopes: Permission[] | null; // 为空时不限制
+    read_only: boolean;
+    expires_at?: number; // 0 或未设置为永不过期
+    last_used_at?: number;
+    last_used_ip?: string;
+    created_at: number;
+}
+
+/**
+ * 创建个人访问令牌请求
+ */
+export interface AccessTokenCreateRequest {
+    name: string;
+    scopes: Permission[];
+    read_only: boolean;
+    expires_at: number;
 }
 
 /**
@@ -46,14 +142,23 @@ interface AuthState {
     isAPIKeyAuth: boolean;
     token: string | null;
     expireAt: string | null;
+    refreshToken: string | null;
+    refreshExpireAt: string | null;
+    user: UserInfo | null;
 
     // Actions
-    setAuth: (token: string, expireAt: string) => void;
+    setAuth: (data: UserLoginResponse) => void;
     setAPIKeyAuth: (apiKey: string) => void;
     checkAuth: () => Promise<void>;
+    refresh: () => Promise<boolean>;
     logout: () => void;
 }
 
+// 同一时间只发起一次刷新，并发的 401 请求共用结果
+let refreshing: Promise<boolean> | null = null;
+
+const isExpired = (at: string | null) => !at || Date.now() >= new Date(at).getTime();
+
 /**
  * 认证状态管理 Store（使用 zustand + persist）
  */
@@ -65,13 +170,19 @@ export const useAuthStore = create<AuthState>()(
             isAPIKeyAuth: false,
             token: null,
             expireAt: null,
+            refreshToken: null,
+            refreshExpireAt: null,
+            user: null,
 
-            setAuth: (token: string, expireAt: string) => {
+            setAuth: (data: UserLoginResponse) => {
                 set({
                     isAuthenticated: true,
                     isAPIKeyAuth: false,
-                    token,
-                    expireAt,
+                    token: data.token,
+                    expireAt: data.expire_at,
+                    refreshToken: data.refresh_token,
+                    refreshExpireAt: data.refresh_expire_at,
+                    user: data.user,
                     isLoading: false
                 });
             },
@@ -82,11 +193,27 @@ export const useAuthStore = create<AuthState>()(
                     isAPIKeyAuth: true,
                     token: apiKey,
                     expireAt: null,
+                    refreshToken: null,
+                    refreshExpireAt: null,
+                    user: null,
                     isLoading: false
                 });
             },
 
             checkAuth: async () => {
+                // 单点登录回调把刷新令牌放在地址的 # 部分，换取访问令牌后立即从地址中移除
+                const oidcRefresh = new URLSearchParams(window.location.hash.slice(1)).get('oidc_refresh');
+                if (oidcRefresh) {
+                    window.history.replaceState(null, '', window.location.pathname + window.location.search);
+                    try {
+                        const data = await apiClient.post<UserLoginResponse>('/api/v1/user/refresh', { refresh_token: oidcRefresh });
+                        get().setAuth(data);
+                        return;
+                    } catch (error) {
+                        logger.error('单点登录失败:', error);
+                    }
+                }
+
                 const { token, expireAt, isAPIKeyAuth } = get();
 
                 if (!token) {
@@ -94,31 +221,73 @@ export const useAuthStore = create<AuthState>()(
                     return;
                 }
 
-                // API Key 不检查本地过期时间
-                if (!isAPIKeyAuth) {
-                    if (!expireAt || Date.now() >= new Date(expireAt).getTime()) {
-                        get().logout();
-                        return;
-                    }
+                // API Key 不检查本地过期时间，访问令牌过期时先尝试刷新
+                if (!isAPIKeyAuth && isExpired(expireAt) && !(await get().refresh())) {
+                    get().logout();
+                    return;
                 }
 
                 try {
-                    // API Key 模式只需校验 key 是否有效即可
-                    const endpoint = isAPIKeyAuth ? '/api/v1/apikey/login' : '/api/v1/user/status';
-                    await apiClient.get<unknown>(endpoint);
-                    set({ isAuthenticated: true, isLoading: false });
+                    // API Key 模式只需校验 key 是否有效即可，用户登录时同时刷新角色和权限
+                    if (isAPIKeyAuth) {
+                        await apiClient.get<unknown>('/api/v1/apikey/login');
+                        set({ isAuthenticated: true, isLoading: false });
+                    } else {
+                        const user = await apiClient.get<UserInfo>('/api/v1/user/me');
+                        set({ isAuthenticated: true, user, isLoading: false });
+                    }
                 } catch (error) {
                     logger.error('认证验证失败:', error);
                     get().logout();
                 }
             },
 
+            refresh: () => {
+                if (!refreshing) {
+                    refreshing = (async () => {
+                        const failedToken = get().token;
+                        // 其他标签页可能已经刷新过，先从本地存储重新加载
+                        await useAuthStore.persist.rehydrate();
+                        const { token, expireAt, refreshToken, refreshExpireAt, isAPIKeyAuth } = get();
+                        if (isAPIKeyAuth || !refreshToken || isExpired(refreshExpireAt)) {
+                            return false;
+                        }
+                        if (token !== failedToken && !isExpired(expireAt)) {
+                            return true;
+                        }
+                        try {
+                            const data = await apiClient.post<UserLoginResponse>('/api/v1/user/refresh', { refresh_token: refreshToken });
+                            get().setAuth(data);
+                            return true;
+                        } catch (error) {
+                            logger.error('刷新登录状态失败:', error);
+                            return false;
+                        }
+                    })().finally(() => {
+                        refreshing = null;
+                    });
+                }
+                return refreshing;
+            },
+
             logout: () => {
+                const { token, isAPIKeyAuth } = get();
+                // 通知服务端撤销当前会话，失败时忽略
+                if (token && !isAPIKeyAuth) {
+                    fetch(`${API_BASE_URL}/api/v1/user/logout`, {
+                        method: 'POST',
+                        headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
+                        body: '{}',
+                    }).catch(() => undefined);
+                }
                 set({
                     isAuthenticated: false,
                     isAPIKeyAuth: false,
                     token: null,
                     expireAt: null,
+                    refreshToken: null,
+                    refreshExpireAt: null,
+                    user: null,
                     isLoading: false
                 });
             }
@@ -128,7 +297,10 @@ export const useAuthStore = create<AuthState>()(
             partialize: (state) => ({
                 token: state.token,
                 expireAt: state.expireAt,
+                refreshToken: state.refreshToken,
+                refreshExpireAt: state.refreshExpireAt,
                 isAPIKeyAuth: state.isAPIKeyAuth,
+                user: state.user,
             })
         }
     )
@@ -140,7 +312,8 @@ if (typeof window !== 'undefined') {
         const state = useAuthStore.getState();
         return {
             token: state.token,
-            logout: state.logout
+            logout: state.logout,
+            refresh: state.refresh
         };
     });
 }
@@ -163,8 +336,10 @@ export function useLogin() {
             return apiClient.post<UserLoginResponse>('/api/v1/user/login', data);
         },
         onSuccess: (data) => {
+            // 需要两步验证时由登录页继续提交验证码
+            if (data.totp_required) return;
             // 保存到 zustand store
-            setAuth(data.token, data.expire_at);
+            setAuth(data);
         },
         onError: (error) => {
             logger.error('登录失败:', error);
@@ -172,6 +347,26 @@ export function useLogin() {
     });
 }
 
+/**
+ * 获取单点登录配置 Hook
+ */
+export function useOIDCInfo() {
+    return useQuery({
+        queryKey: ['user', 'oidc'],
+        queryFn: async () => {
+            return apiClient.get<OIDCInfo>('/api/v1/user/oidc/info');
+        },
+        staleTime: Infinity,
+    });
+}
+
+/**
+ * 跳转到单点登录，expire 为会话有效期（分钟）
+ */
+export function startOIDCLogin(expire: number) {
+    window.location.href = `${API_BASE_URL}/api/v1/user/oidc/login?expire=${expire}`;
+}
+
 /**
  * 修改密码 Hook
  * 
@@ -221,6 +416,186 @@ export function useChangeUsername() {
     });
 }
 
+/**
+ * 获取当前用户的登录会话 Hook
+ */
+export function useSessionList() {
+    return useQuery({
+        queryKey: ['user', 'sessions'],
+        queryFn: async () => {
+            return apiClient.get<UserSession[]>('/api/v1/user/session/list');
+        },
+    });
+}
+
+/**
+ * 撤销一个登录会话 Hook
+ */
+export function useRevokeSession() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (id: string) => {
+            return apiClient.post<null>('/api/v1/user/session/revoke', { id });
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['user', 'sessions'] });
+        },
+        onError: (error) => {
+            logger.error('会话撤销失败:', error);
+        },
+    });
+}
+
+/**
+ * 退出所有会话 Hook，包括当前会话
+ */
+export function useRevokeAllSessions() {
+    const { logout } = useAuthStore();
+
+    return useMutation({
+        mutationFn: async () => {
+            return apiClient.post<null>('/api/v1/user/session/revoke-all', {});
+        },
+        onSuccess: () => {
+            logout();
+        },
+        onError: (error) => {
+            logger.error('退出所有会话失败:', error);
+        },
+    });
+}
+
+/**
+ * 轮换令牌签名密钥 Hook
+ */
+export function useRotateSecret() {
+    return useMutation({
+        mutationFn: async () => {
+            return apiClient.post<null>('/api/v1/user/secret/rotate', {});
+        },
+        onError: (error) => {
+            logger.error('签名密钥轮换失败:', error);
+        },
+    });
+}
+
+/**
+ * 开始设置两步验证 Hook，返回新的密钥，确认验证码后才会启用
+ */
+export function useSetupTOTP() {
+    return useMutation({
+        mutationFn: async () => {
+            return apiClient.post<TOTPSetup>('/api/v1/user/totp/setup', {});
+        },
+        onError: (error) => {
+            logger.error('两步验证设置失败:', error);
+        },
+    });
+}
+
+/**
+ * 同步当前用户的两步验证状态到 store
+ */
+function setTOTPEnabled(enabled: boolean) {
+    const { user } = useAuthStore.getState();
+    if (user) useAuthStore.setState({ user: { ...user, totp_enabled: enabled } });
+}
+
+/**
+ * 启用两步验证 Hook，返回只显示一次的恢复码
+ */
+export function useEnableTOTP() {
+    return useMutation({
+        mutationFn: async (code: string) => {
+            return apiClient.post<{ recovery_codes: string[] }>('/api/v1/user/totp/enable', { code });
+        },
+        onSuccess: () => setTOTPEnabled(true),
+        onError: (error) => {
+            logger.error('两步验证启用失败:', error);
+        },
+    });
+}
+
+/**
+ * 关闭两步验证 Hook，需要确认密码
+ */
+export function useDisableTOTP() {
+    return useMutation({
+        mutationFn: async (password: string) => {
+            return apiClient.post<null>('/api/v1/user/totp/disable', { password });
+        },
+        onSuccess: () => setTOTPEnabled(false),
+        onError: (error) => {
+            logger.error('两步验证关闭失败:', error);
+        },
+    });
+}
+
+/**
+ * 重新生成恢复码 Hook，需要确认密码，旧恢复码全部失效
+ */
+export function useRegenerateRecoveryCodes() {
+    return useMutation({
+        mutationFn: async (password: string) => {
+            return apiClient.post<{ recovery_codes: string[] }>('/api/v1/user/totp/recovery-codes', { password });
+        },
+        onError: (error) => {
+            logger.error('恢复码生成失败:', error);
+        },
+    });
+}
+
+/**
+ * 获取当前用户的个人访问令牌 Hook
+ */
+export function useAccessTokenList() {
+    return useQuery({
+        queryKey: ['user', 'tokens'],
+        queryFn: async () => {
+            return apiClient.get<AccessToken[]>('/api/v1/user/token/list');
+        },
+    });
+}
+
+/**
+ * 创建个人访问令牌 Hook，明文令牌只在返回结果中出现一次
+ */
+export function useCreateAccessToken() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (data: AccessTokenCreateRequest) => {
+            return apiClient.post<AccessToken & { token: string }>('/api/v1/user/token/create', data);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['user', 'tokens'] });
+        },
+        onError: (error) => {
+            logger.error('访问令牌创建失败:', error);
+        },
+    });
+}
+
+/**
+ * 删除个人访问令牌 Hook
+ */
+export function useDeleteAccessToken() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (id: number) => {
+            return apiClient.delete<null>(`/api/v1/user/token/delete/${id}`);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['user', 'tokens'] });
+        },
+        onError: (error) => {
+            logger.error('访问令牌删除失败:', error);
+        },
+    });
+}
+
 /**
  * 认证状态和方法 Hook
  * 
@@ -253,3 +628,82 @@ export function useAuth() {
     };
 }
 
+/**
+ * 当前用户是否拥有权限，API Key 登录时始终为 false
+ *
+ * @example
+ * const canEdit = usePermission('channel:write');
+ */
+export function usePermission(permission: Permission) {
+    const user = useAuthStore((state) => state.user);
+    return !!user?.permissions.includes(permission);
+}
+
+/**
+ * 获取用户列表 Hook
+ */
+export function useUserList() {
+    return useQuery({
+        queryKey: ['users', 'list'],
+        queryFn: async () => {
+            return apiClient.get<User[]>('/api/v1/user/list');
+        },
+    });
+}
+
+/**
+ * 创建用户 Hook
+ */
+export function useCreateUser() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (data: { username: string; password: string; role: UserRole }) => {
+            return apiClient.post<User>('/api/v1/user/create', data);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['users', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('用户创建失败:', error);
+        },
+    });
+}
+
+/**
+ * 修改用户角色、重置密码或关闭两步验证 Hook
+ */
+export function useUpdateUser() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (data: { id: number; role?: UserRole; password?: string; disable_totp?: boolean }) => {
+            return apiClient.post<User>('/api/v1/user/update', data);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['users', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('用户更新失败:', error);
+        },
+    });
+}
+
+/**
+ * 删除用户 Hook
+ */
+export function useDeleteUser() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (id: number) => {
+            return apiClient.delete<null>(`/api/v1/user/delete/${id}`);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['users', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('用户删除失败:', error);
+        },
+    });
+}
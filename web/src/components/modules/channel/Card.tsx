@@ -45,7 +45,7 @@ export function Card({ channel, stats }: { channel: Channel; stats: StatsMetrics
                         <Switch
                             checked={channel.enabled}
                             onCheckedChange={handleEnableChange}
-                            disabled={enableChannel.isPending}
+                            disabled={enableChannel.isPending || channel.managed}
                             onClick={(e) => e.stopPropagation()}
                         />
                     </header>
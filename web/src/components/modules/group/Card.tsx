@@ -283,7 +283,7 @@ export function GroupCard({ group }: { group: Group }) {
                         </TooltipTrigger>
                         <TooltipContent>{t('detail.actions.copyName')}</TooltipContent>
                     </Tooltip>
-                    {!confirmDelete && (
+                    {!confirmDelete && !group.managed && (
                         <Tooltip side="top" sideOffset={10} align="center">
                             <TooltipTrigger>
                                 <motion.button layoutId={`delete-btn-group-${group.id}`} type="button" onClick={() => setConfirmDelete(true)} className="p-1.5 rounded-lg hover:bg-destructive/10 text-muted-foreground hover:text-destructive transition-colors">
@@ -8,7 +8,7 @@ import JsonView from '@uiw/react-json-view';
 import { githubDarkTheme } from '@uiw/react-json-view/githubDark';
 import { githubLightTheme } from '@uiw/react-json-view/githubLight';
 import { useTheme } from 'next-themes';
-import { type RelayLog } from '@/api/endpoints/log';
+import { type RelayLog, useLogBody } from '@/api/endpoints/log';
 import { getModelIcon } from '@/lib/model-icons';
 import { Badge } from '@/components/ui/badge';
 import { cn } from '@/lib/utils';
@@ -125,6 +125,14 @@ function DeferredJsonContent({ content, fallbackText }: { content: string | unde
     );
 }
 
+function LogBodyContent({ log, field, fallbackText }: { log: RelayLog; field: 'request_content' | 'response_content'; fallbackText: string }) {
+    const { isOpen } = useMorphingDialog();
+    const inline = log[field];
+    const { data: body } = useLogBody(log.id, isOpen && log.has_body && !inline);
+
+    return <DeferredJsonContent content={inline || body?.[field]} fallbackText={fallbackText} />;
+}
+
 export function LogCard({ log }: { log: RelayLog }) {
     const t = useTranslations('log.card');
     const { Avatar: ModelAvatar, color: brandColor } = useMemo(
@@ -244,7 +252,7 @@ export function LogCard({ log }: { log: RelayLog }) {
                                             </Badge>
                                         </div>
                                         <div className="flex-1 overflow-auto min-h-0">
-                                            <DeferredJsonContent content={log.request_content} fallbackText={t('noRequestContent')} />
+                                            <LogBodyContent log={log} field="request_content" fallbackText={t('noRequestContent')} />
                                         </div>
                                     </div>
                                     <div className="flex flex-col rounded-2xl border border-border bg-muted/30 overflow-hidden min-h-0">
@@ -256,7 +264,7 @@ export function LogCard({ log }: { log: RelayLog }) {
                                             </Badge>
                                         </div>
                                         <div className="flex-1 overflow-auto min-h-0">
-                                            <DeferredJsonContent content={log.response_content} fallbackText={t('noResponseContent')} />
+                                            <LogBodyContent log={log} field="response_content" fallbackText={t('noResponseContent')} />
                                         </div>
                                     </div>
                                 </div>
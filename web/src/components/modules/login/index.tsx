@@ -1,15 +1,15 @@
 'use client';
 
-import { useState } from "react"
+import { useEffect, useState } from "react"
 import { motion } from "motion/react"
 import { useTranslations } from 'next-intl'
 import { Button } from "@/components/ui/button"
 import { Field, FieldDescription, FieldLabel } from "@/components/ui/field"
 import { Input } from "@/components/ui/input"
-import { useLogin } from "@/api/endpoints/user"
+import { startOIDCLogin, useLogin, useOIDCInfo } from "@/api/endpoints/user"
 import { useAPIKeyLogin } from "@/api/endpoints/apikey"
 import Logo from "@/components/modules/logo"
-import { KeyRound, User } from "lucide-react"
+import { KeyRound, LogIn, User } from "lucide-react"
 import {
   Tabs,
   TabsList,
@@ -22,6 +22,9 @@ import {
 
 type LoginMode = 'user' | 'apikey';
 
+// 与后端 handlers/oidc.go 中的错误码一致
+const OIDC_ERRORS = ['unavailable', 'internal', 'provider_error', 'invalid_state', 'expired', 'not_allowed', 'not_linked', 'not_found', 'failed']
+
 export function LoginForm({ onLoginSuccess }: { onLoginSuccess?: () => void }) {
   const t = useTranslations('login')
   const [mode, setMode] = useState<LoginMode>('user')
@@ -29,9 +32,24 @@ export function LoginForm({ onLoginSuccess }: { onLoginSuccess?: () => void }) {
   const [password, setPassword] = useState("")
   const [apiKey, setApiKey] = useState("")
   const [error, setError] = useState<string | null>(null)
+  // 密码正确但需要两步验证时显示验证码输入框
+  const [totpRequired, setTotpRequired] = useState(false)
+  const [useRecovery, setUseRecovery] = useState(false)
+  const [code, setCode] = useState("")
 
   const loginMutation = useLogin()
   const apiKeyLoginMutation = useAPIKeyLogin()
+  const { data: oidc } = useOIDCInfo()
+
+  // 单点登录失败时回调把错误码放在地址的 # 部分，未知的错误码按 failed 显示
+  useEffect(() => {
+    const oidcError = new URLSearchParams(window.location.hash.slice(1)).get('oidc_error')
+    if (oidcError) {
+      const code = OIDC_ERRORS.includes(oidcError) ? oidcError : 'failed'
+      setError(t('sso.error', { message: t(`sso.errors.${code}`) }))
+      window.history.replaceState(null, '', window.location.pathname + window.location.search)
+    }
+  }, [t])
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault()
@@ -39,11 +57,18 @@ export function LoginForm({ onLoginSuccess }: { onLoginSuccess?: () => void }) {
 
     try {
       if (mode === 'user') {
-        await loginMutation.mutateAsync({
+        const data = await loginMutation.mutateAsync({
           username,
           password,
           expire: 86400,
+          ...(totpRequired && code.trim()
+            ? useRecovery ? { recovery_code: code.trim() } : { totp_code: code.trim() }
+            : {}),
         })
+        if (data.totp_required) {
+          setTotpRequired(true)
+          return
+        }
       } else {
         await apiKeyLoginMutation.mutateAsync(apiKey)
       }
@@ -62,6 +87,12 @@ export function LoginForm({ onLoginSuccess }: { onLoginSuccess?: () => void }) {
     setError(null)
   }
 
+  const resetTotp = () => {
+    setTotpRequired(false)
+    setUseRecovery(false)
+    setCode("")
+  }
+
   return (
     <motion.div
       initial={{ opacity: 0 }}
@@ -110,7 +141,7 @@ export function LoginForm({ onLoginSuccess }: { onLoginSuccess?: () => void }) {
                     type="text"
                     placeholder={t('usernamePlaceholder')}
                     value={username}
-                    onChange={(e) => setUsername(e.target.value)}
+                    onChange={(e) => { setUsername(e.target.value); resetTotp() }}
                     required={mode === 'user'}
                     disabled={isPending}
                   />
@@ -122,11 +153,35 @@ export function LoginForm({ onLoginSuccess }: { onLoginSuccess?: () => void }) {
                     type="password"
                     placeholder={t('passwordPlaceholder')}
                     value={password}
-                    onChange={(e) => setPassword(e.target.value)}
+                    onChange={(e) => { setPassword(e.target.value); resetTotp() }}
                     required={mode === 'user'}
                     disabled={isPending}
                   />
                 </Field>
+                {totpRequired && (
+                  <Field>
+                    <FieldLabel htmlFor="totp">{useRecovery ? t('totp.recoveryCode') : t('totp.code')}</FieldLabel>
+                    <Input
+                      id="totp"
+                      type="text"
+                      inputMode={useRecovery ? 'text' : 'numeric'}
+                      autoComplete="one-time-code"
+                      autoFocus
+                      placeholder={useRecovery ? t('totp.recoveryPlaceholder') : t('totp.codePlaceholder')}
+                      value={code}
+                      onChange={(e) => setCode(e.target.value)}
+                      required={mode === 'user'}
+                      disabled={isPending}
+                    />
+                    <button
+                      type="button"
+                      className="text-xs text-muted-foreground hover:text-foreground text-left"
+                      onClick={() => { setUseRecovery(!useRecovery); setCode("") }}
+                    >
+                      {useRecovery ? t('totp.useCode') : t('totp.useRecovery')}
+                    </button>
+                  </Field>
+                )}
               </TabsContent>
               <TabsContent value="apikey">
                 <Field>
@@ -149,6 +204,13 @@ export function LoginForm({ onLoginSuccess }: { onLoginSuccess?: () => void }) {
             <Button type="submit" disabled={isPending} className="w-full">
               {isPending ? t('button.loading') : t('button.submit')}
             </Button>
+
+            {mode === 'user' && oidc?.enabled && (
+              <Button type="button" variant="outline" disabled={isPending} className="w-full" onClick={() => startOIDCLogin(86400)}>
+                <LogIn className="w-4 h-4" />
+                {t('sso.button', { name: oidc.name ?? 'SSO' })}
+              </Button>
+            )}
           </form>
         </Tabs>
       </div>
@@ -1,15 +1,27 @@
 "use client"
 
+import { useEffect } from "react"
 import { motion } from "motion/react"
 import { cn } from "@/lib/utils"
 import { useNavStore, type NavItem } from "@/components/modules/navbar"
 import { ROUTES } from "@/route/config"
 import { usePreload } from "@/route/use-preload"
 import { ENTRANCE_VARIANTS } from "@/lib/animations/fluid-transitions"
+import { useAuthStore } from "@/api/endpoints/user"
 
 export function NavBar() {
     const { activeItem, setActiveItem } = useNavStore()
     const { preload } = usePreload()
+    const permissions = useAuthStore((state) => state.user?.permissions)
+    // 只显示当前用户有权限查看的页面
+    const routes = permissions ? ROUTES.filter((route) => permissions.includes(route.permission)) : ROUTES
+
+    // 切换到权限更少的用户后，上次停留的页面可能已不可见
+    useEffect(() => {
+        if (routes.length > 0 && !routes.some((route) => route.id === activeItem)) {
+            setActiveItem(routes[0].id as NavItem)
+        }
+    }, [routes, activeItem, setActiveItem])
 
     return (
         <div className="relative z-50 md:min-h-screen">
@@ -25,7 +37,7 @@ export function NavBar() {
                 initial="initial"
                 animate="animate"
             >
-                {ROUTES.map((route, index) => {
+                {routes.map((route, index) => {
                     const isActive = activeItem === route.id
                     return (
                         <motion.button
@@ -23,6 +23,7 @@ import {
     useDeleteAPIKey,
     type APIKey,
 } from '@/api/endpoints/apikey';
+import { usePermission } from '@/api/endpoints/user';
 import { useGroupList } from '@/api/endpoints/group';
 import { useStatsAPIKey } from '@/api/endpoints/stats';
 import { cn } from '@/lib/utils';
@@ -481,6 +482,8 @@ function APIKeyKeyItem({
 }) {
     const t = useTranslations('setting');
     const [confirmDelete, setConfirmDelete] = useState(false);
+    // 没有写权限时接口只返回打码后的 Key，不提供复制
+    const canCopy = usePermission('apikey:write');
 
     return (
         <motion.div
@@ -512,14 +515,16 @@ function APIKeyKeyItem({
                 >
                     <Pencil className="size-4" />
                 </motion.button>
-                <CopyIconButton
-                    text={apiKey.api_key}
-                    className="flex size-8 items-center justify-center rounded-lg bg-primary/10 text-primary transition-all hover:bg-primary hover:text-primary-foreground active:scale-95"
-                    copyIconClassName="size-4"
-                    checkIconClassName="size-4"
-                />
+                {canCopy && (
+                    <CopyIconButton
+                        text={apiKey.api_key}
+                        className="flex size-8 items-center justify-center rounded-lg bg-primary/10 text-primary transition-all hover:bg-primary hover:text-primary-foreground active:scale-95"
+                        copyIconClassName="size-4"
+                        checkIconClassName="size-4"
+                    />
+                )}
 
-                {!confirmDelete && (
+                {!confirmDelete && !apiKey.managed && (
                     <motion.button
                         layoutId={deleteLayoutId}
                         onClick={() => setConfirmDelete(true)}
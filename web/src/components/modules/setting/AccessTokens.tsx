@@ -0,0 +1,186 @@
+'use client';
+
+import { useState } from 'react';
+import { useTranslations } from 'next-intl';
+import { KeySquare, Trash2, X } from 'lucide-react';
+import { Input } from '@/components/ui/input';
+import { Button } from '@/components/ui/button';
+import { Switch } from '@/components/ui/switch';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
+import { CopyIconButton } from '@/components/common/CopyButton';
+import {
+    useAccessTokenList,
+    useAuthStore,
+    useCreateAccessToken,
+    useDeleteAccessToken,
+    type AccessToken,
+    type Permission,
+} from '@/api/endpoints/user';
+import { toast } from '@/components/common/Toast';
+
+// 有效期选项（天），0 为永不过期
+const EXPIRE_DAYS = [0, 7, 30, 90, 365];
+
+function AccessTokenRow({ token }: { token: AccessToken }) {
+    const t = useTranslations('setting.accessTokens');
+    const deleteToken = useDeleteAccessToken();
+    const [pending, setPending] = useState(false);
+    const expired = !!token.expires_at && token.expires_at * 1000 <= Date.now();
+
+    const handleDelete = () => {
+        deleteToken.mutate(token.id, {
+            onSuccess: () => toast.success(t('deleted')),
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    return (
+        <div className="flex items-center justify-between gap-4">
+            <div className="flex flex-col gap-1 min-w-0">
+                <span className="text-sm font-medium truncate">
+                    {token.name}
+                    <span className="text-xs text-muted-foreground font-mono"> …{token.hint}</span>
+                </span>
+                <span className="text-xs text-muted-foreground break-all">
+                    {token.read_only ? t('readOnly') : t('readWrite')}
+                    {' · '}
+                    {token.scopes?.length ? token.scopes.join(', ') : t('allScopes')}
+                </span>
+                <span className={`text-xs ${expired ? 'text-destructive' : 'text-muted-foreground'}`}>
+                    {token.expires_at
+                        ? t(expired ? 'expired' : 'expiresAt', { time: new Date(token.expires_at * 1000).toLocaleString() })
+                        : t('neverExpires')}
+                    {' · '}
+                    {token.last_used_at
+                        ? t('lastUsed', { time: new Date(token.last_used_at * 1000).toLocaleString(), ip: token.last_used_ip ?? '' })
+                        : t('neverUsed')}
+                </span>
+            </div>
+            {pending ? (
+                <div className="flex gap-1 shrink-0">
+                    <Button variant="ghost" size="icon" onClick={() => setPending(false)}>
+                        <X className="h-4 w-4" />
+                    </Button>
+                    <Button variant="destructive" size="sm" onClick={handleDelete} disabled={deleteToken.isPending} className="rounded-xl">
+                        {t('confirmDelete')}
+                    </Button>
+                </div>
+            ) : (
+                <Button variant="ghost" size="icon" onClick={() => setPending(true)} title={t('delete')}>
+                    <Trash2 className="h-4 w-4" />
+                </Button>
+            )}
+        </div>
+    );
+}
+
+export function SettingAccessTokens() {
+    const t = useTranslations('setting.accessTokens');
+    const { data: tokens } = useAccessTokenList();
+    const permissions = useAuthStore((state) => state.user?.permissions ?? []);
+    const createToken = useCreateAccessToken();
+
+    const [name, setName] = useState('');
+    const [expireDays, setExpireDays] = useState(90);
+    const [readOnly, setReadOnly] = useState(true);
+    const [scopes, setScopes] = useState<Permission[]>([]);
+    const [created, setCreated] = useState<string | null>(null);
+
+    const toggleScope = (scope: Permission) => {
+        setScopes((prev) => prev.includes(scope) ? prev.filter((s) => s !== scope) : [...prev, scope]);
+    };
+
+    const handleCreate = () => {
+        if (!name.trim()) {
+            toast.error(t('nameEmpty'));
+            return;
+        }
+        createToken.mutate({
+            name: name.trim(),
+            scopes,
+            read_only: readOnly,
+            expires_at: expireDays ? Math.floor(Date.now() / 1000) + expireDays * 86400 : 0,
+        }, {
+            onSuccess: (data) => {
+                setCreated(data.token);
+                setName('');
+                setScopes([]);
+            },
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    return (
+        <div className="rounded-3xl border border-border bg-card p-6 custom-shadow space-y-5">
+            <h2 className="text-lg font-bold text-card-foreground flex items-center gap-2">
+                <KeySquare className="h-5 w-5" />
+                {t('title')}
+            </h2>
+            {tokens?.map((token) => (
+                <AccessTokenRow key={token.id} token={token} />
+            ))}
+            {created && (
+                <div className="flex flex-col gap-2 rounded-xl bg-primary/5 p-3">
+                    <span className="text-xs text-muted-foreground">{t('createdHint')}</span>
+                    <div className="flex items-center gap-2">
+                        <code className="flex-1 text-xs font-mono break-all">{created}</code>
+                        <CopyIconButton
+                            text={created}
+                            className="flex size-8 shrink-0 items-center justify-center rounded-lg bg-primary/10 text-primary transition-all hover:bg-primary hover:text-primary-foreground active:scale-95"
+                            copyIconClassName="size-4"
+                        />
+                        <Button variant="ghost" size="icon" onClick={() => setCreated(null)}>
+                            <X className="h-4 w-4" />
+                        </Button>
+                    </div>
+                </div>
+            )}
+            <div className="flex flex-col gap-3">
+                <div className="flex gap-2">
+                    <Input
+                        value={name}
+                        onChange={(e) => setName(e.target.value)}
+                        placeholder={t('name')}
+                        className="flex-1 rounded-xl"
+                    />
+                    <Select value={String(expireDays)} onValueChange={(v) => setExpireDays(Number(v))}>
+                        <SelectTrigger className="w-32 rounded-xl">
+                            <SelectValue />
+                        </SelectTrigger>
+                        <SelectContent className="rounded-xl">
+                            {EXPIRE_DAYS.map((days) => (
+                                <SelectItem key={days} value={String(days)} className="rounded-xl">
+                                    {days ? t('days', { days }) : t('never')}
+                                </SelectItem>
+                            ))}
+                        </SelectContent>
+                    </Select>
+                </div>
+                <div className="flex items-center justify-between gap-4">
+                    <span className="text-sm">{t('readOnly')}</span>
+                    <Switch checked={readOnly} onCheckedChange={setReadOnly} />
+                </div>
+                <div className="flex flex-col gap-2">
+                    <span className="text-sm">{t('scopes')}</span>
+                    <div className="flex flex-wrap gap-1">
+                        {permissions.filter((p) => p !== 'authenticated').map((p) => (
+                            <Button
+                                key={p}
+                                variant={scopes.includes(p) ? 'default' : 'outline'}
+                                size="sm"
+                                onClick={() => toggleScope(p)}
+                                className="rounded-xl font-mono text-xs"
+                            >
+                                {p}
+                            </Button>
+                        ))}
+                    </div>
+                </div>
+                <Button onClick={handleCreate} disabled={createToken.isPending} className="rounded-xl">
+                    {t('create')}
+                </Button>
+            </div>
+            <p className="text-xs text-muted-foreground">{t('hint')}</p>
+        </div>
+    );
+}
@@ -5,12 +5,12 @@ import { useTranslations } from 'next-intl';
 import { User, KeyRound, Lock, Eye, EyeOff } from 'lucide-react';
 import { Input } from '@/components/ui/input';
 import { Button } from '@/components/ui/button';
-import { useChangeUsername, useChangePassword, useAuth } from '@/api/endpoints/user';
+import { useChangeUsername, useChangePassword, useAuthStore } from '@/api/endpoints/user';
 import { toast } from '@/components/common/Toast';
 
 export function SettingAccount() {
     const t = useTranslations('setting');
-    const { logout } = useAuth();
+    const checkAuth = useAuthStore((state) => state.checkAuth);
     const changeUsername = useChangeUsername();
     const changePassword = useChangePassword();
 
@@ -34,7 +34,8 @@ export function SettingAccount() {
             {
                 onSuccess: () => {
                     toast.success(t('account.username.success'));
-                    setTimeout(() => logout(), 1000);
+                    setNewUsername('');
+                    checkAuth();
                 },
                 onError: () => {
                     toast.error(t('account.username.failed'));
@@ -66,7 +67,9 @@ export function SettingAccount() {
             {
                 onSuccess: () => {
                     toast.success(t('account.password.success'));
-                    setTimeout(() => logout(), 1000);
+                    setOldPassword('');
+                    setNewPassword('');
+                    setConfirmPassword('');
                 },
                 onError: () => {
                     toast.error(t('account.password.failed'));
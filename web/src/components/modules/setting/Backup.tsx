@@ -1,13 +1,14 @@
 'use client';
 
-import { useMemo, useRef, useState } from 'react';
+import { Fragment, useMemo, useRef, useState } from 'react';
 import { useTranslations } from 'next-intl';
-import { Database, Download, Upload } from 'lucide-react';
+import { Database, Download, Eye, Upload } from 'lucide-react';
 import { Button } from '@/components/ui/button';
 import { Input } from '@/components/ui/input';
 import { Switch } from '@/components/ui/switch';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
 import { toast } from '@/components/common/Toast';
-import { useExportDB, useImportDB } from '@/api/endpoints/setting';
+import { useExportDB, useImportDB, type DBImportMode } from '@/api/endpoints/setting';
 
 export function SettingBackup() {
     const t = useTranslations('setting');
@@ -19,9 +20,11 @@ export function SettingBackup() {
     const [includeStats, setIncludeStats] = useState(false);
 
     const [file, setFile] = useState<File | null>(null);
+    const [mode, setMode] = useState<DBImportMode>('merge');
     const fileInputRef = useRef<HTMLInputElement | null>(null);
 
-    const rowsAffected = importDB.data?.rows_affected ?? null;
+    const result = importDB.data;
+    const rowsAffected = result && !result.dry_run ? result.rows_affected : null;
     const rowsAffectedList = useMemo(() => {
         if (!rowsAffected) return [];
         return Object.entries(rowsAffected)
@@ -29,8 +32,35 @@ export function SettingBackup() {
             .map(([k, v]) => ({ table: k, count: v }));
     }, [rowsAffected]);
 
+    // 预览只展示有变化的表
+    const previewTables = useMemo(() => {
+        if (!result?.dry_run || !result.tables) return [];
+        return Object.entries(result.tables)
+            .filter(([, s]) => s.create + s.update + s.conflict + s.delete > 0)
+            .sort(([a], [b]) => a.localeCompare(b));
+    }, [result]);
+    const previewChanges = result?.dry_run ? result.changes ?? [] : [];
+
     const onPickFile = (f: File | null) => {
         setFile(f);
+        importDB.reset();
+    };
+
+    const onModeChange = (value: string) => {
+        setMode(value as DBImportMode);
+        importDB.reset();
+    };
+
+    const onPreview = async () => {
+        if (!file) {
+            toast.error(t('backup.import.noFile'));
+            return;
+        }
+        try {
+            await importDB.mutateAsync({ file, mode, dry_run: true });
+        } catch (e) {
+            toast.error(e instanceof Error ? e.message : t('backup.import.failed'));
+        }
     };
 
     const onImport = async () => {
@@ -39,7 +69,7 @@ export function SettingBackup() {
             return;
         }
         try {
-            await importDB.mutateAsync(file);
+            await importDB.mutateAsync({ file, mode });
             toast.success(t('backup.import.success'));
             if (fileInputRef.current) fileInputRef.current.value = '';
             setFile(null);
@@ -104,16 +134,82 @@ export function SettingBackup() {
                     className="rounded-xl"
                 />
 
-                <Button
-                    type="button"
-                    variant="destructive"
-                    className="w-full rounded-xl"
-                    onClick={onImport}
-                    disabled={importDB.isPending}
-                >
-                    <Upload className="size-4" />
-                    {importDB.isPending ? t('backup.import.importing') : t('backup.import.button')}
-                </Button>
+                <div className="flex items-center justify-between gap-4">
+                    <div className="text-sm text-muted-foreground">{t('backup.import.mode.label')}</div>
+                    <Select value={mode} onValueChange={onModeChange}>
+                        <SelectTrigger className="w-36 rounded-xl">
+                            <SelectValue />
+                        </SelectTrigger>
+                        <SelectContent className="rounded-xl">
+                            <SelectItem value="merge" className="rounded-xl">{t('backup.import.mode.merge')}</SelectItem>
+                            <SelectItem value="replace" className="rounded-xl">{t('backup.import.mode.replace')}</SelectItem>
+                        </SelectContent>
+                    </Select>
+                </div>
+                <p className="text-xs text-muted-foreground">{t(`backup.import.mode.${mode}Hint`)}</p>
+
+                <div className="grid grid-cols-2 gap-2">
+                    <Button
+                        type="button"
+                        variant="outline"
+                        className="rounded-xl"
+                        onClick={onPreview}
+                        disabled={importDB.isPending}
+                    >
+                        <Eye className="size-4" />
+                        {t('backup.import.preview')}
+                    </Button>
+                    <Button
+                        type="button"
+                        variant="destructive"
+                        className="rounded-xl"
+                        onClick={onImport}
+                        disabled={importDB.isPending}
+                    >
+                        <Upload className="size-4" />
+                        {importDB.isPending ? t('backup.import.importing') : t('backup.import.button')}
+                    </Button>
+                </div>
+
+                {result?.dry_run && (
+                    <div className="mt-2 space-y-2">
+                        <div className="text-xs font-semibold text-card-foreground">{t('backup.import.previewResult')}</div>
+                        {previewTables.length === 0 && previewChanges.length === 0 && (
+                            <p className="text-xs text-muted-foreground">{t('backup.import.noChanges')}</p>
+                        )}
+                        {previewTables.length > 0 && (
+                            <div className="grid grid-cols-5 gap-1 text-xs text-muted-foreground">
+                                <span />
+                                <span className="text-right">{t('backup.import.action.create')}</span>
+                                <span className="text-right">{t('backup.import.action.update')}</span>
+                                <span className="text-right">{t('backup.import.action.conflict')}</span>
+                                <span className="text-right">{t('backup.import.action.delete')}</span>
+                                {previewTables.map(([table, s]) => (
+                                    <Fragment key={table}>
+                                        <span className="truncate">{table}</span>
+                                        <span className="text-right tabular-nums">{s.create}</span>
+                                        <span className="text-right tabular-nums">{s.update}</span>
+                                        <span className="text-right tabular-nums">{s.conflict}</span>
+                                        <span className="text-right tabular-nums">{s.delete}</span>
+                                    </Fragment>
+                                ))}
+                            </div>
+                        )}
+                        {previewChanges.length > 0 && (
+                            <ul className="space-y-1 max-h-48 overflow-y-auto text-xs text-muted-foreground">
+                                {previewChanges.map((c) => (
+                                    <li key={`${c.kind}-${c.name}-${c.action}`} className="flex justify-between gap-2">
+                                        <span className="truncate">
+                                            {t(`backup.import.kind.${c.kind}`)} · {c.name}
+                                            {c.fields && c.fields.length > 0 && ` (${c.fields.join(', ')})`}
+                                        </span>
+                                        <span className="shrink-0">{t(`backup.import.action.${c.action}`)}</span>
+                                    </li>
+                                ))}
+                            </ul>
+                        )}
+                    </div>
+                )}
 
                 {rowsAffectedList.length > 0 && (
                     <div className="mt-2 space-y-1">
@@ -0,0 +1,121 @@
+'use client';
+
+import { useState } from 'react';
+import { useTranslations } from 'next-intl';
+import { GitBranch, FolderOpen, Eye, RefreshCw } from 'lucide-react';
+import { Button } from '@/components/ui/button';
+import { useGitOpsStatus, usePlanGitOps, useApplyGitOps, type GitOpsChange } from '@/api/endpoints/gitops';
+import { toast } from '@/components/common/Toast';
+
+const actionSymbol: Record<GitOpsChange['action'], string> = {
+    create: '+',
+    update: '~',
+    delete: '-',
+    unmanage: '-',
+};
+
+function ChangeList({ changes }: { changes: GitOpsChange[] }) {
+    const t = useTranslations('setting.gitops');
+    if (changes.length === 0) {
+        return <p className="text-xs text-muted-foreground">{t('noChanges')}</p>;
+    }
+    return (
+        <ul className="space-y-1 rounded-xl border border-border/70 bg-background/80 p-3 font-mono text-xs max-h-60 overflow-y-auto">
+            {changes.map((change, i) => (
+                <li key={`${change.kind}-${change.name}-${i}`} className="break-all">
+                    {actionSymbol[change.action]} {t(`kind.${change.kind}`)} {change.name}
+                    {change.action === 'unmanage' && ` (${t('unmanage')})`}
+                    {change.fields && change.fields.length > 0 && (
+                        <span className="text-muted-foreground"> [{change.fields.join(', ')}]</span>
+                    )}
+                </li>
+            ))}
+        </ul>
+    );
+}
+
+export function SettingGitOps() {
+    const t = useTranslations('setting.gitops');
+    const { data: status } = useGitOpsStatus();
+    const planGitOps = usePlanGitOps();
+    const applyGitOps = useApplyGitOps();
+    const [changes, setChanges] = useState<GitOpsChange[] | null>(null);
+
+    if (!status?.enabled) {
+        return null;
+    }
+
+    const handlePlan = () => {
+        planGitOps.mutate(undefined, {
+            onSuccess: (plan) => setChanges(plan.changes),
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    const handleApply = () => {
+        applyGitOps.mutate(undefined, {
+            onSuccess: (plan) => {
+                setChanges(plan.changes);
+                toast.success(t('applySuccess'));
+            },
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    return (
+        <div className="rounded-3xl border border-border bg-card p-6 custom-shadow space-y-5">
+            <h2 className="text-lg font-bold text-card-foreground flex items-center gap-2">
+                <GitBranch className="h-5 w-5" />
+                {t('title')}
+            </h2>
+
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex flex-col gap-1 min-w-0">
+                    <div className="flex items-center gap-3">
+                        <FolderOpen className="h-5 w-5 text-muted-foreground" />
+                        <span className="text-sm font-medium">{t('path')}</span>
+                    </div>
+                    <span className="text-xs text-muted-foreground ml-8 break-all">{status.path}</span>
+                </div>
+            </div>
+
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex flex-col gap-1">
+                    <div className="flex items-center gap-3">
+                        <RefreshCw className="h-5 w-5 text-muted-foreground" />
+                        <span className="text-sm font-medium">{t('lastSync')}</span>
+                    </div>
+                    <span className="text-xs text-muted-foreground ml-8">
+                        {status.last_sync_time ? new Date(status.last_sync_time * 1000).toLocaleString() : t('neverSynced')}
+                    </span>
+                    {status.last_error && (
+                        <span className="text-xs text-destructive ml-8 break-all">{status.last_error}</span>
+                    )}
+                </div>
+                <div className="flex gap-2">
+                    <Button
+                        variant="outline"
+                        size="sm"
+                        onClick={handlePlan}
+                        disabled={planGitOps.isPending}
+                        className="rounded-xl"
+                    >
+                        <Eye className="h-4 w-4" />
+                        {t('plan')}
+                    </Button>
+                    <Button
+                        size="sm"
+                        onClick={handleApply}
+                        disabled={applyGitOps.isPending}
+                        className="rounded-xl"
+                    >
+                        {applyGitOps.isPending ? t('applying') : t('apply')}
+                    </Button>
+                </div>
+            </div>
+
+            {changes && <ChangeList changes={changes} />}
+            <p className="text-xs text-muted-foreground">{t('hint')}</p>
+        </div>
+    );
+}
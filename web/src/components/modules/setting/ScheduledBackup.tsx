@@ -0,0 +1,260 @@
+'use client';
+
+import { useEffect, useRef, useState } from 'react';
+import { useTranslations } from 'next-intl';
+import { Archive, Clock, Layers, Calendar, Download, RotateCcw, Trash2, Lock, X } from 'lucide-react';
+import { Input } from '@/components/ui/input';
+import { Switch } from '@/components/ui/switch';
+import { Button } from '@/components/ui/button';
+import { useSettingList, useSetSetting, SettingKey } from '@/api/endpoints/setting';
+import {
+    useBackupList,
+    useCreateBackup,
+    useRestoreBackup,
+    useDeleteBackup,
+    useDownloadBackup,
+} from '@/api/endpoints/backup';
+import { toast } from '@/components/common/Toast';
+
+type NumberSettingKey =
+    | typeof SettingKey.BackupInterval
+    | typeof SettingKey.BackupKeepCount
+    | typeof SettingKey.BackupKeepDays;
+
+function formatSize(size: number) {
+    if (size < 1024) return `${size} B`;
+    if (size < 1024 * 1024) return `${(size / 1024).toFixed(1)} KB`;
+    return `${(size / 1024 / 1024).toFixed(1)} MB`;
+}
+
+export function SettingScheduledBackup() {
+    const t = useTranslations('setting');
+    const { data: settings } = useSettingList();
+    const setSetting = useSetSetting();
+    const { data: backups } = useBackupList();
+    const createBackup = useCreateBackup();
+    const restoreBackup = useRestoreBackup();
+    const deleteBackup = useDeleteBackup();
+    const downloadBackup = useDownloadBackup();
+
+    const [values, setValues] = useState<Record<NumberSettingKey, string>>({
+        [SettingKey.BackupInterval]: '0',
+        [SettingKey.BackupKeepCount]: '7',
+        [SettingKey.BackupKeepDays]: '0',
+    });
+    const [includeLogs, setIncludeLogs] = useState(false);
+    const [includeStats, setIncludeStats] = useState(true);
+    const [pending, setPending] = useState<{ name: string; action: 'restore' | 'delete' } | null>(null);
+
+    const initialValues = useRef<Record<string, string>>({});
+
+    useEffect(() => {
+        if (!settings) return;
+        const next: Partial<Record<NumberSettingKey, string>> = {};
+        for (const key of [SettingKey.BackupInterval, SettingKey.BackupKeepCount, SettingKey.BackupKeepDays] as const) {
+            const setting = settings.find(s => s.key === key);
+            if (setting) {
+                next[key] = setting.value;
+                initialValues.current[key] = setting.value;
+            }
+        }
+        const logsSetting = settings.find(s => s.key === SettingKey.BackupIncludeLogs);
+        const statsSetting = settings.find(s => s.key === SettingKey.BackupIncludeStats);
+        queueMicrotask(() => {
+            setValues(prev => ({ ...prev, ...next }));
+            if (logsSetting) setIncludeLogs(logsSetting.value === 'true');
+            if (statsSetting) setIncludeStats(statsSetting.value === 'true');
+        });
+    }, [settings]);
+
+    const handleNumberSave = (key: NumberSettingKey) => {
+        const value = values[key];
+        if (value === initialValues.current[key]) return;
+        setSetting.mutate(
+            { key, value },
+            {
+                onSuccess: () => {
+                    toast.success(t('saved'));
+                    initialValues.current[key] = value;
+                }
+            }
+        );
+    };
+
+    const handleSwitchChange = (key: string, checked: boolean, setter: (v: boolean) => void) => {
+        setter(checked);
+        setSetting.mutate(
+            { key, value: checked ? 'true' : 'false' },
+            { onSuccess: () => toast.success(t('saved')) }
+        );
+    };
+
+    const handleCreate = () => {
+        createBackup.mutate(undefined, {
+            onSuccess: () => toast.success(t('scheduledBackup.createSuccess')),
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    const handleConfirm = () => {
+        if (!pending) return;
+        const { name, action } = pending;
+        const onSettled = () => setPending(null);
+        if (action === 'restore') {
+            restoreBackup.mutate({ name }, {
+                onSuccess: () => toast.success(t('scheduledBackup.restoreSuccess')),
+                onError: (error) => toast.error(error.message),
+                onSettled,
+            });
+        } else {
+            deleteBackup.mutate(name, {
+                onError: (error) => toast.error(error.message),
+                onSettled,
+            });
+        }
+    };
+
+    const handleDownload = (name: string) => {
+        downloadBackup.mutate(name, {
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    const numberRows = [
+        { key: SettingKey.BackupInterval, icon: Clock, label: 'interval' },
+        { key: SettingKey.BackupKeepCount, icon: Layers, label: 'keepCount' },
+        { key: SettingKey.BackupKeepDays, icon: Calendar, label: 'keepDays' },
+    ] as const;
+
+    return (
+        <div className="rounded-3xl border border-border bg-card p-6 custom-shadow space-y-5">
+            <h2 className="text-lg font-bold text-card-foreground flex items-center gap-2">
+                <Archive className="h-5 w-5" />
+                {t('scheduledBackup.title')}
+            </h2>
+
+            {numberRows.map(({ key, icon: Icon, label }) => (
+                <div key={key} className="flex items-center justify-between gap-4">
+                    <div className="flex items-center gap-3">
+                        <Icon className="h-5 w-5 text-muted-foreground" />
+                        <span className="text-sm font-medium">{t(`scheduledBackup.${label}.label`)}</span>
+                    </div>
+                    <Input
+                        type="number"
+                        min={0}
+                        value={values[key]}
+                        onChange={(e) => setValues(prev => ({ ...prev, [key]: e.target.value }))}
+                        onBlur={() => handleNumberSave(key)}
+                        placeholder={t(`scheduledBackup.${label}.placeholder`)}
+                        className="w-48 rounded-xl"
+                    />
+                </div>
+            ))}
+
+            <div className="flex items-center justify-between gap-4">
+                <div className="text-sm text-muted-foreground">{t('scheduledBackup.includeLogs')}</div>
+                <Switch
+                    checked={includeLogs}
+                    onCheckedChange={(checked) => handleSwitchChange(SettingKey.BackupIncludeLogs, checked, setIncludeLogs)}
+                />
+            </div>
+
+            <div className="flex items-center justify-between gap-4">
+                <div className="text-sm text-muted-foreground">{t('scheduledBackup.includeStats')}</div>
+                <Switch
+                    checked={includeStats}
+                    onCheckedChange={(checked) => handleSwitchChange(SettingKey.BackupIncludeStats, checked, setIncludeStats)}
+                />
+            </div>
+
+            <div className="h-px bg-border" />
+
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex flex-col gap-1 min-w-0">
+                    <span className="text-xs text-muted-foreground break-all">{backups?.path}</span>
+                    <span className="text-xs text-muted-foreground">
+                        {backups?.encryption_enabled ? t('scheduledBackup.encrypted') : t('scheduledBackup.unencrypted')}
+                    </span>
+                </div>
+                <Button
+                    size="sm"
+                    onClick={handleCreate}
+                    disabled={createBackup.isPending}
+                    className="rounded-xl"
+                >
+                    {createBackup.isPending ? t('scheduledBackup.creating') : t('scheduledBackup.create')}
+                </Button>
+            </div>
+
+            {backups && backups.files.length === 0 && (
+                <p className="text-xs text-muted-foreground">{t('scheduledBackup.empty')}</p>
+            )}
+            {backups && backups.files.length > 0 && (
+                <ul className="space-y-2 max-h-60 overflow-y-auto">
+                    {backups.files.map((file) => (
+                        <li
+                            key={file.name}
+                            className="flex items-center justify-between gap-2 rounded-xl border border-border/70 bg-background/80 px-3 py-2"
+                        >
+                            <div className="flex flex-col min-w-0">
+                                <span className="text-xs font-medium flex items-center gap-1">
+                                    {file.encrypted && <Lock className="h-3 w-3" />}
+                                    {new Date(file.created_at * 1000).toLocaleString()}
+                                </span>
+                                <span className="text-xs text-muted-foreground">{formatSize(file.size)}</span>
+                            </div>
+                            {pending?.name === file.name ? (
+                                <div className="flex gap-1 shrink-0">
+                                    <Button
+                                        variant="ghost"
+                                        size="icon"
+                                        onClick={() => setPending(null)}
+                                    >
+                                        <X className="h-4 w-4" />
+                                    </Button>
+                                    <Button
+                                        variant="destructive"
+                                        size="sm"
+                                        onClick={handleConfirm}
+                                        disabled={restoreBackup.isPending || deleteBackup.isPending}
+                                        className="rounded-xl"
+                                    >
+                                        {pending.action === 'restore' ? t('scheduledBackup.confirmRestore') : t('scheduledBackup.confirmDelete')}
+                                    </Button>
+                                </div>
+                            ) : (
+                                <div className="flex gap-1 shrink-0">
+                                    <Button
+                                        variant="ghost"
+                                        size="icon"
+                                        onClick={() => handleDownload(file.name)}
+                                        title={t('scheduledBackup.download')}
+                                    >
+                                        <Download className="h-4 w-4" />
+                                    </Button>
+                                    <Button
+                                        variant="ghost"
+                                        size="icon"
+                                        onClick={() => setPending({ name: file.name, action: 'restore' })}
+                                        title={t('scheduledBackup.restore')}
+                                    >
+                                        <RotateCcw className="h-4 w-4" />
+                                    </Button>
+                                    <Button
+                                        variant="ghost"
+                                        size="icon"
+                                        onClick={() => setPending({ name: file.name, action: 'delete' })}
+                                        title={t('scheduledBackup.delete')}
+                                    >
+                                        <Trash2 className="h-4 w-4" />
+                                    </Button>
+                                </div>
+                            )}
+                        </li>
+                    ))}
+                </ul>
+            )}
+            <p className="text-xs text-muted-foreground">{t('scheduledBackup.hint')}</p>
+        </div>
+    );
+}
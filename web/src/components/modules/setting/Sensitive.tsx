@@ -2,20 +2,26 @@
 
 import { useEffect, useState, useRef } from 'react';
 import { useTranslations } from 'next-intl';
-import { Shield, Plus, Trash2, Pencil, Lock } from 'lucide-react';
+import { Shield, Plus, Trash2, Pencil, Lock, FlaskConical } from 'lucide-react';
 import { Switch } from '@/components/ui/switch';
 import { Button } from '@/components/ui/button';
 import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
 import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle } from '@/components/ui/dialog';
 import { useSettingList, useSetSetting, SettingKey } from '@/api/endpoints/setting';
-import { useSensitiveRuleList, useCreateSensitiveRule, useUpdateSensitiveRule, useDeleteSensitiveRule, useToggleSensitiveRule, type SensitiveFilterRule } from '@/api/endpoints/sensitive';
+import { useSensitiveRuleList, useCreateSensitiveRule, useUpdateSensitiveRule, useDeleteSensitiveRule, useToggleSensitiveRule, useTestSensitiveRules, useSensitiveRuleHits, useSensitiveRuleSetList, useCreateSensitiveRuleSet, useDeleteSensitiveRuleSet, type SensitiveFilterRule, type SensitiveDirection, type SensitiveAction, type SensitiveTestResult } from '@/api/endpoints/sensitive';
 import { toast } from '@/components/common/Toast';
 
 export function SettingSensitive() {
     const t = useTranslations('setting');
     const { data: settings } = useSettingList();
     const { data: rules } = useSensitiveRuleList();
+    const { data: ruleHits } = useSensitiveRuleHits();
+    const testRules = useTestSensitiveRules();
+    const { data: ruleSets } = useSensitiveRuleSetList();
+    const createRuleSet = useCreateSensitiveRuleSet();
+    const deleteRuleSet = useDeleteSensitiveRuleSet();
     const setSetting = useSetSetting();
     const createRule = useCreateSensitiveRule();
     const updateRule = useUpdateSensitiveRule();
@@ -25,7 +31,13 @@ export function SettingSensitive() {
     const [globalEnabled, setGlobalEnabled] = useState(true);
     const [showDialog, setShowDialog] = useState(false);
     const [editingRule, setEditingRule] = useState<SensitiveFilterRule | null>(null);
-    const [formData, setFormData] = useState({ name: '', pattern: '', replacement: '', priority: 0 });
+    const [formData, setFormData] = useState<{ name: string; pattern: string; replacement: string; direction: SensitiveDirection; action: SensitiveAction; priority: number; rule_set_id: number }>({ name: '', pattern: '', replacement: '', direction: 'request', action: 'replace', priority: 0, rule_set_id: 0 });
+
+    const [testText, setTestText] = useState('');
+    const [testDirection, setTestDirection] = useState<SensitiveDirection>('request');
+    const [testResult, setTestResult] = useState<SensitiveTestResult | null>(null);
+    const [testRuleSetId, setTestRuleSetId] = useState(0);
+    const [newRuleSet, setNewRuleSet] = useState({ name: '', include_default: true });
 
     const initialGlobalEnabled = useRef(true);
 
@@ -54,14 +66,14 @@ export function SettingSensitive() {
 
     const handleAddRule = () => {
         setEditingRule(null);
-        setFormData({ name: '', pattern: '', replacement: '[FILTERED]', priority: 0 });
+        setFormData({ name: '', pattern: '', replacement: '[FILTERED]', direction: 'request', action: 'replace', priority: 0, rule_set_id: 0 });
         setShowDialog(true);
     };
 
     const handleEditRule = (rule: SensitiveFilterRule) => {
         if (rule.built_in) return;
         setEditingRule(rule);
-        setFormData({ name: rule.name, pattern: rule.pattern, replacement: rule.replacement, priority: rule.priority });
+        setFormData({ name: rule.name, pattern: rule.pattern, replacement: rule.replacement, direction: rule.direction || 'request', action: rule.action || 'replace', priority: rule.priority, rule_set_id: rule.rule_set_id ?? 0 });
         setShowDialog(true);
     };
 
@@ -70,6 +82,20 @@ export function SettingSensitive() {
         deleteRule.mutate(rule.id, { onSuccess: () => toast.success(t('sensitive.deleted')) });
     };
 
+    const handleTest = () => {
+        if (!testText) return;
+        testRules.mutate({ text: testText, direction: testDirection, rule_set_id: testRuleSetId }, { onSuccess: setTestResult });
+    };
+
+    const handleAddRuleSet = () => {
+        if (!newRuleSet.name) return;
+        createRuleSet.mutate({ ...newRuleSet, description: '' }, {
+            onSuccess: () => { toast.success(t('sensitive.created')); setNewRuleSet({ name: '', include_default: true }); }
+        });
+    };
+
+    const ruleSetName = (id: number) => id === 0 ? t('sensitive.ruleSet.default') : ruleSets?.find(rs => rs.id === id)?.name ?? `#${id}`;
+
     const handleSaveRule = () => {
         if (!formData.name || !formData.pattern) {
             toast.error(t('sensitive.requiredFields'));
@@ -126,9 +152,11 @@ export function SettingSensitive() {
                                     <div className="flex items-center gap-2">
                                         <span className="text-sm font-medium truncate">{rule.name}</span>
                                         {rule.built_in && <Lock className="h-3 w-3 text-muted-foreground" />}
+                                        {rule.rule_set_id > 0 && <span className="text-xs text-muted-foreground truncate">{ruleSetName(rule.rule_set_id)}</span>}
                                     </div>
                                     <span className="text-xs text-muted-foreground font-mono truncate block">{rule.pattern}</span>
                                 </div>
+                                <span className="text-xs text-muted-foreground shrink-0">{t('sensitive.hits', { count: ruleHits?.[rule.id] ?? 0 })}</span>
                             </div>
                             {!rule.built_in && (
                                 <div className="flex items-center gap-1">
@@ -145,6 +173,72 @@ export function SettingSensitive() {
                 </div>
             </div>
 
+            {/* 规则集 */}
+            <div className="space-y-2">
+                <span className="text-sm font-medium text-muted-foreground">{t('sensitive.ruleSet.title')}</span>
+                {ruleSets?.map(ruleSet => (
+                    <div key={ruleSet.id} className="flex items-center justify-between p-3 rounded-xl bg-muted/50 gap-3">
+                        <div className="flex-1 min-w-0">
+                            <span className="text-sm font-medium truncate block">{ruleSet.name}</span>
+                            {ruleSet.include_default && <span className="text-xs text-muted-foreground">{t('sensitive.ruleSet.includeDefault')}</span>}
+                        </div>
+                        <Button variant="ghost" size="icon" className="h-8 w-8 text-destructive" onClick={() => deleteRuleSet.mutate(ruleSet.id, { onSuccess: () => toast.success(t('sensitive.deleted')) })}>
+                            <Trash2 className="h-4 w-4" />
+                        </Button>
+                    </div>
+                ))}
+                <div className="flex items-center gap-2">
+                    <Input value={newRuleSet.name} onChange={e => setNewRuleSet({ ...newRuleSet, name: e.target.value })} placeholder={t('sensitive.ruleSet.name')} className="rounded-xl" />
+                    <div className="flex items-center gap-2 shrink-0">
+                        <Switch checked={newRuleSet.include_default} onCheckedChange={checked => setNewRuleSet({ ...newRuleSet, include_default: checked })} />
+                        <span className="text-xs text-muted-foreground">{t('sensitive.ruleSet.includeDefault')}</span>
+                    </div>
+                    <Button variant="outline" size="sm" onClick={handleAddRuleSet} disabled={!newRuleSet.name} className="rounded-xl">
+                        <Plus className="h-4 w-4" />
+                    </Button>
+                </div>
+            </div>
+
+            {/* 规则测试 */}
+            <div className="space-y-2">
+                <span className="text-sm font-medium text-muted-foreground">{t('sensitive.test.title')}</span>
+                <div className="flex items-center gap-2">
+                    <Input value={testText} onChange={e => setTestText(e.target.value)} placeholder={t('sensitive.test.placeholder')} className="rounded-xl font-mono" />
+                    <Select value={testDirection} onValueChange={v => setTestDirection(v as SensitiveDirection)}>
+                        <SelectTrigger className="w-28 rounded-xl">
+                            <SelectValue />
+                        </SelectTrigger>
+                        <SelectContent className="rounded-xl">
+                            <SelectItem value="request" className="rounded-xl">{t('sensitive.direction.request')}</SelectItem>
+                            <SelectItem value="response" className="rounded-xl">{t('sensitive.direction.response')}</SelectItem>
+                        </SelectContent>
+                    </Select>
+                    <Select value={String(testRuleSetId)} onValueChange={v => setTestRuleSetId(Number(v))}>
+                        <SelectTrigger className="w-32 rounded-xl">
+                            <SelectValue />
+                        </SelectTrigger>
+                        <SelectContent className="rounded-xl">
+                            <SelectItem value="0" className="rounded-xl">{t('sensitive.ruleSet.default')}</SelectItem>
+                            {ruleSets?.map(ruleSet => (
+                                <SelectItem key={ruleSet.id} value={String(ruleSet.id)} className="rounded-xl">{ruleSet.name}</SelectItem>
+                            ))}
+                        </SelectContent>
+                    </Select>
+                    <Button variant="outline" size="sm" onClick={handleTest} disabled={!testText || testRules.isPending} className="rounded-xl">
+                        <FlaskConical className="h-4 w-4 mr-1" />{t('sensitive.test.run')}
+                    </Button>
+                </div>
+                {testResult && (
+                    <div className="p-3 rounded-xl bg-muted/50 space-y-1 text-xs">
+                        {testResult.blocked && <div className="text-destructive">{t('sensitive.test.blocked', { rule: testResult.blocked_rule ?? '' })}</div>}
+                        <div className="font-mono break-all">{testResult.text}</div>
+                        {testResult.hits.map(hit => (
+                            <div key={hit.rule_id} className="text-muted-foreground">{hit.name} ({t(`sensitive.action.${hit.action || 'replace'}`)}): {hit.count}</div>
+                        ))}
+                    </div>
+                )}
+            </div>
+
             {/* 添加/编辑对话框 */}
             <Dialog open={showDialog} onOpenChange={setShowDialog}>
                 <DialogContent className="sm:max-w-md">
@@ -165,6 +259,47 @@ export function SettingSensitive() {
                             <Label htmlFor="replacement">{t('sensitive.replacement')}</Label>
                             <Input id="replacement" value={formData.replacement} onChange={e => setFormData({ ...formData, replacement: e.target.value })} placeholder="[FILTERED]" />
                         </div>
+                        <div className="space-y-2">
+                            <Label>{t('sensitive.ruleSet.label')}</Label>
+                            <Select value={String(formData.rule_set_id)} onValueChange={v => setFormData({ ...formData, rule_set_id: Number(v) })}>
+                                <SelectTrigger className="w-full rounded-xl">
+                                    <SelectValue />
+                                </SelectTrigger>
+                                <SelectContent className="rounded-xl">
+                                    <SelectItem value="0" className="rounded-xl">{t('sensitive.ruleSet.default')}</SelectItem>
+                                    {ruleSets?.map(ruleSet => (
+                                        <SelectItem key={ruleSet.id} value={String(ruleSet.id)} className="rounded-xl">{ruleSet.name}</SelectItem>
+                                    ))}
+                                </SelectContent>
+                            </Select>
+                        </div>
+                        <div className="space-y-2">
+                            <Label>{t('sensitive.action.label')}</Label>
+                            <Select value={formData.action} onValueChange={v => setFormData({ ...formData, action: v as SensitiveAction, direction: v === 'mask' || v === 'block' ? 'request' : formData.direction })}>
+                                <SelectTrigger className="w-full rounded-xl">
+                                    <SelectValue />
+                                </SelectTrigger>
+                                <SelectContent className="rounded-xl">
+                                    <SelectItem value="replace" className="rounded-xl">{t('sensitive.action.replace')}</SelectItem>
+                                    <SelectItem value="mask" className="rounded-xl">{t('sensitive.action.mask')}</SelectItem>
+                                    <SelectItem value="block" className="rounded-xl">{t('sensitive.action.block')}</SelectItem>
+                                    <SelectItem value="log" className="rounded-xl">{t('sensitive.action.log')}</SelectItem>
+                                </SelectContent>
+                            </Select>
+                        </div>
+                        <div className="space-y-2">
+                            <Label>{t('sensitive.direction.label')}</Label>
+                            <Select value={formData.direction} disabled={formData.action === 'mask' || formData.action === 'block'} onValueChange={v => setFormData({ ...formData, direction: v as SensitiveDirection })}>
+                                <SelectTrigger className="w-full rounded-xl">
+                                    <SelectValue />
+                                </SelectTrigger>
+                                <SelectContent className="rounded-xl">
+                                    <SelectItem value="request" className="rounded-xl">{t('sensitive.direction.request')}</SelectItem>
+                                    <SelectItem value="response" className="rounded-xl">{t('sensitive.direction.response')}</SelectItem>
+                                    <SelectItem value="both" className="rounded-xl">{t('sensitive.direction.both')}</SelectItem>
+                                </SelectContent>
+                            </Select>
+                        </div>
                         <div className="space-y-2">
                             <Label htmlFor="priority">{t('sensitive.priority')}</Label>
                             <Input id="priority" type="number" value={formData.priority} onChange={e => setFormData({ ...formData, priority: parseInt(e.target.value) || 0 })} />
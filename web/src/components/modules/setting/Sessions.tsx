@@ -0,0 +1,118 @@
+'use client';
+
+import { useState } from 'react';
+import { useTranslations } from 'next-intl';
+import { MonitorSmartphone, LogOut, X } from 'lucide-react';
+import { Button } from '@/components/ui/button';
+import {
+    usePermission,
+    useRevokeAllSessions,
+    useRevokeSession,
+    useRotateSecret,
+    useSessionList,
+    type UserSession,
+} from '@/api/endpoints/user';
+import { toast } from '@/components/common/Toast';
+
+function SessionRow({ session }: { session: UserSession }) {
+    const t = useTranslations('setting.sessions');
+    const revokeSession = useRevokeSession();
+
+    const handleRevoke = () => {
+        revokeSession.mutate(session.id, {
+            onSuccess: () => toast.success(t('revoked')),
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    return (
+        <div className="flex items-center justify-between gap-4">
+            <div className="flex flex-col gap-1 min-w-0">
+                <span className="text-sm font-medium truncate" title={session.user_agent}>
+                    {session.user_agent || t('unknownClient')}
+                    {session.current && <span className="text-xs text-muted-foreground"> ({t('current')})</span>}
+                </span>
+                <span className="text-xs text-muted-foreground">
+                    {t('detail', {
+                        ip: session.ip,
+                        time: new Date(session.last_used_at * 1000).toLocaleString(),
+                        expire: new Date(session.expires_at * 1000).toLocaleString(),
+                    })}
+                </span>
+            </div>
+            {!session.current && (
+                <Button variant="ghost" size="icon" onClick={handleRevoke} disabled={revokeSession.isPending} title={t('revoke')}>
+                    <LogOut className="h-4 w-4" />
+                </Button>
+            )}
+        </div>
+    );
+}
+
+export function SettingSessions() {
+    const t = useTranslations('setting.sessions');
+    const { data: sessions } = useSessionList();
+    const canRotate = usePermission('user:write');
+    const revokeAll = useRevokeAllSessions();
+    const rotateSecret = useRotateSecret();
+    const [pending, setPending] = useState<'revokeAll' | 'rotate' | null>(null);
+
+    const handleConfirm = () => {
+        if (pending === 'revokeAll') {
+            revokeAll.mutate(undefined, {
+                onError: (error) => toast.error(error.message),
+            });
+            return;
+        }
+        rotateSecret.mutate(undefined, {
+            onSuccess: () => {
+                toast.success(t('rotated'));
+                setPending(null);
+            },
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    return (
+        <div className="rounded-3xl border border-border bg-card p-6 custom-shadow space-y-5">
+            <h2 className="text-lg font-bold text-card-foreground flex items-center gap-2">
+                <MonitorSmartphone className="h-5 w-5" />
+                {t('title')}
+            </h2>
+            {sessions?.map((session) => (
+                <SessionRow key={session.id} session={session} />
+            ))}
+            {pending ? (
+                <div className="flex items-center gap-2">
+                    <span className="flex-1 text-sm text-muted-foreground">
+                        {pending === 'revokeAll' ? t('revokeAllConfirm') : t('rotateConfirm')}
+                    </span>
+                    <Button variant="ghost" size="icon" onClick={() => setPending(null)}>
+                        <X className="h-4 w-4" />
+                    </Button>
+                    <Button
+                        variant="destructive"
+                        size="sm"
+                        onClick={handleConfirm}
+                        disabled={revokeAll.isPending || rotateSecret.isPending}
+                        className="rounded-xl"
+                    >
+                        {pending === 'revokeAll' ? t('revokeAll') : t('rotate')}
+                    </Button>
+                </div>
+            ) : (
+                <div className="flex gap-2">
+                    <Button variant="outline" onClick={() => setPending('revokeAll')} className="flex-1 rounded-xl">
+                        {t('revokeAll')}
+                    </Button>
+                    {canRotate && (
+                        <Button variant="outline" onClick={() => setPending('rotate')} className="flex-1 rounded-xl">
+                            {t('rotate')}
+                        </Button>
+                    )}
+                </div>
+            )}
+            <p className="text-xs text-muted-foreground">{t('hint')}</p>
+        </div>
+    );
+}
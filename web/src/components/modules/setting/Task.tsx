@@ -0,0 +1,92 @@
+'use client';
+
+import { useTranslations } from 'next-intl';
+import { Timer, Play, Pause } from 'lucide-react';
+import { Button } from '@/components/ui/button';
+import { useTaskList, useTriggerTask, usePauseTask, type TaskInfo } from '@/api/endpoints/task';
+import { toast } from '@/components/common/Toast';
+
+function TaskRow({ task }: { task: TaskInfo }) {
+    const t = useTranslations('setting.task');
+    const triggerTask = useTriggerTask();
+    const pauseTask = usePauseTask();
+    const last = task.history[0];
+    const nameKey = `name.${task.name}`;
+
+    const handleTrigger = () => {
+        triggerTask.mutate(task.name, {
+            onSuccess: () => toast.success(t('triggered')),
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    const handlePause = () => {
+        pauseTask.mutate({ name: task.name, paused: !task.paused }, {
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    return (
+        <div className="flex items-center justify-between gap-4">
+            <div className="flex flex-col gap-1 min-w-0">
+                <span className="text-sm font-medium">{t.has(nameKey) ? t(nameKey) : task.name}</span>
+                <span className="text-xs text-muted-foreground font-mono">{task.schedule}</span>
+                <span className="text-xs text-muted-foreground">
+                    {task.running
+                        ? t('running')
+                        : task.paused
+                            ? t('paused')
+                            : task.next_run
+                                ? t('nextRun', { time: new Date(task.next_run * 1000).toLocaleString() })
+                                : t('noNextRun')}
+                </span>
+                {last && (
+                    <span className={`text-xs break-all ${last.error ? 'text-destructive' : 'text-muted-foreground'}`}>
+                        {t('lastRun', {
+                            time: new Date(last.start_time).toLocaleString(),
+                            duration: last.duration,
+                            trigger: t(`trigger.${last.trigger}`),
+                        })}
+                        {last.error && `: ${last.error}`}
+                    </span>
+                )}
+            </div>
+            <div className="flex gap-2 shrink-0">
+                <Button
+                    variant="outline"
+                    size="sm"
+                    onClick={handlePause}
+                    disabled={pauseTask.isPending}
+                    className="rounded-xl"
+                >
+                    {task.paused ? <Play className="h-4 w-4" /> : <Pause className="h-4 w-4" />}
+                    {task.paused ? t('resume') : t('pause')}
+                </Button>
+                <Button
+                    size="sm"
+                    onClick={handleTrigger}
+                    disabled={task.running || triggerTask.isPending}
+                    className="rounded-xl"
+                >
+                    {t('run')}
+                </Button>
+            </div>
+        </div>
+    );
+}
+
+export function SettingTask() {
+    const t = useTranslations('setting.task');
+    const { data: tasks } = useTaskList();
+
+    return (
+        <div className="rounded-3xl border border-border bg-card p-6 custom-shadow space-y-5">
+            <h2 className="text-lg font-bold text-card-foreground flex items-center gap-2">
+                <Timer className="h-5 w-5" />
+                {t('title')}
+            </h2>
+            {tasks?.map((task) => <TaskRow key={task.name} task={task} />)}
+            <p className="text-xs text-muted-foreground">{t('hint')}</p>
+        </div>
+    );
+}
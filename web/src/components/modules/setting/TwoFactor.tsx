@@ -0,0 +1,187 @@
+'use client';
+
+import { useState } from 'react';
+import { useTranslations } from 'next-intl';
+import { ShieldCheck, X } from 'lucide-react';
+import { Input } from '@/components/ui/input';
+import { Button } from '@/components/ui/button';
+import { CopyIconButton } from '@/components/common/CopyButton';
+import {
+    useAuthStore,
+    useDisableTOTP,
+    useEnableTOTP,
+    useRegenerateRecoveryCodes,
+    useSetupTOTP,
+    type TOTPSetup,
+} from '@/api/endpoints/user';
+import { toast } from '@/components/common/Toast';
+
+const copyClassName = 'flex size-8 shrink-0 items-center justify-center rounded-lg bg-primary/10 text-primary transition-all hover:bg-primary hover:text-primary-foreground active:scale-95';
+
+// 恢复码只在生成时显示一次
+function RecoveryCodes({ codes, onClose }: { codes: string[]; onClose: () => void }) {
+    const t = useTranslations('setting.twoFactor');
+
+    return (
+        <div className="flex flex-col gap-2 rounded-xl bg-primary/5 p-3">
+            <div className="flex items-center justify-between gap-2">
+                <span className="text-xs text-muted-foreground">{t('recoveryHint')}</span>
+                <div className="flex gap-1 shrink-0">
+                    <CopyIconButton text={codes.join('\n')} className={copyClassName} copyIconClassName="size-4" />
+                    <Button variant="ghost" size="icon" onClick={onClose}>
+                        <X className="h-4 w-4" />
+                    </Button>
+                </div>
+            </div>
+            <div className="grid grid-cols-2 gap-1">
+                {codes.map((code) => (
+                    <code key={code} className="text-xs font-mono">{code}</code>
+                ))}
+            </div>
+        </div>
+    );
+}
+
+export function SettingTwoFactor() {
+    const t = useTranslations('setting.twoFactor');
+    const enabled = useAuthStore((state) => !!state.user?.totp_enabled);
+    const setupTOTP = useSetupTOTP();
+    const enableTOTP = useEnableTOTP();
+    const disableTOTP = useDisableTOTP();
+    const regenerate = useRegenerateRecoveryCodes();
+
+    const [setup, setSetup] = useState<TOTPSetup | null>(null);
+    const [code, setCode] = useState('');
+    const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
+    // 关闭和重新生成恢复码都需要先输入密码确认
+    const [pending, setPending] = useState<'disable' | 'regenerate' | null>(null);
+    const [password, setPassword] = useState('');
+
+    const handleSetup = () => {
+        setupTOTP.mutate(undefined, {
+            onSuccess: (data) => {
+                setSetup(data);
+                setCode('');
+            },
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    const handleEnable = () => {
+        enableTOTP.mutate(code.trim(), {
+            onSuccess: (data) => {
+                toast.success(t('enabled'));
+                setSetup(null);
+                setCode('');
+                setRecoveryCodes(data.recovery_codes);
+            },
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    const handleCancel = () => {
+        setPending(null);
+        setPassword('');
+    };
+
+    const handleConfirm = () => {
+        if (pending === 'disable') {
+            disableTOTP.mutate(password, {
+                onSuccess: () => {
+                    toast.success(t('disabled'));
+                    setRecoveryCodes(null);
+                    handleCancel();
+                },
+                onError: (error) => toast.error(error.message),
+            });
+            return;
+        }
+        regenerate.mutate(password, {
+            onSuccess: (data) => {
+                setRecoveryCodes(data.recovery_codes);
+                handleCancel();
+            },
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    return (
+        <div className="rounded-3xl border border-border bg-card p-6 custom-shadow space-y-5">
+            <h2 className="text-lg font-bold text-card-foreground flex items-center gap-2">
+                <ShieldCheck className="h-5 w-5" />
+                {t('title')}
+            </h2>
+            <div className="flex items-center justify-between gap-4">
+                <span className="text-sm">{t('status')}</span>
+                <span className={`text-sm ${enabled ? 'text-primary' : 'text-muted-foreground'}`}>
+                    {enabled ? t('on') : t('off')}
+                </span>
+            </div>
+            {recoveryCodes && <RecoveryCodes codes={recoveryCodes} onClose={() => setRecoveryCodes(null)} />}
+            {!enabled && !setup && (
+                <Button onClick={handleSetup} disabled={setupTOTP.isPending} className="w-full rounded-xl">
+                    {t('setup')}
+                </Button>
+            )}
+            {!enabled && setup && (
+                <div className="flex flex-col gap-3">
+                    <span className="text-xs text-muted-foreground">{t('setupHint')}</span>
+                    <div className="flex items-center gap-2">
+                        <a href={setup.uri} className="flex-1 text-xs font-mono break-all text-primary hover:underline">
+                            {setup.secret}
+                        </a>
+                        <CopyIconButton text={setup.secret} className={copyClassName} copyIconClassName="size-4" />
+                    </div>
+                    <div className="flex gap-2">
+                        <Input
+                            value={code}
+                            onChange={(e) => setCode(e.target.value)}
+                            inputMode="numeric"
+                            autoComplete="one-time-code"
+                            placeholder={t('codePlaceholder')}
+                            className="flex-1 rounded-xl"
+                        />
+                        <Button variant="ghost" size="icon" onClick={() => setSetup(null)}>
+                            <X className="h-4 w-4" />
+                        </Button>
+                        <Button onClick={handleEnable} disabled={enableTOTP.isPending || !code.trim()} className="rounded-xl">
+                            {t('enable')}
+                        </Button>
+                    </div>
+                </div>
+            )}
+            {enabled && (pending ? (
+                <div className="flex gap-2">
+                    <Input
+                        type="password"
+                        value={password}
+                        onChange={(e) => setPassword(e.target.value)}
+                        placeholder={t('passwordPlaceholder')}
+                        className="flex-1 rounded-xl"
+                    />
+                    <Button variant="ghost" size="icon" onClick={handleCancel}>
+                        <X className="h-4 w-4" />
+                    </Button>
+                    <Button
+                        variant={pending === 'disable' ? 'destructive' : 'default'}
+                        onClick={handleConfirm}
+                        disabled={!password || disableTOTP.isPending || regenerate.isPending}
+                        className="rounded-xl"
+                    >
+                        {pending === 'disable' ? t('confirmDisable') : t('confirmRegenerate')}
+                    </Button>
+                </div>
+            ) : (
+                <div className="flex gap-2">
+                    <Button variant="outline" onClick={() => setPending('regenerate')} className="flex-1 rounded-xl">
+                        {t('regenerate')}
+                    </Button>
+                    <Button variant="destructive" onClick={() => setPending('disable')} className="flex-1 rounded-xl">
+                        {t('disable')}
+                    </Button>
+                </div>
+            ))}
+            <p className="text-xs text-muted-foreground">{t('hint')}</p>
+        </div>
+    );
+}
@@ -0,0 +1,206 @@
+'use client';
+
+import { useState } from 'react';
+import { useTranslations } from 'next-intl';
+import { Users, Trash2, KeyRound, ShieldOff, X } from 'lucide-react';
+import { Input } from '@/components/ui/input';
+import { Button } from '@/components/ui/button';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
+import {
+    USER_ROLES,
+    useAuthStore,
+    useCreateUser,
+    useDeleteUser,
+    usePermission,
+    useUpdateUser,
+    useUserList,
+    type User,
+    type UserRole,
+} from '@/api/endpoints/user';
+import { toast } from '@/components/common/Toast';
+
+function RoleSelect({ value, onChange, disabled }: { value: UserRole; onChange: (role: UserRole) => void; disabled?: boolean }) {
+    const t = useTranslations('setting.users');
+    return (
+        <Select value={value} onValueChange={(v) => onChange(v as UserRole)} disabled={disabled}>
+            <SelectTrigger className="w-32 rounded-xl">
+                <SelectValue />
+            </SelectTrigger>
+            <SelectContent className="rounded-xl">
+                {USER_ROLES.map((role) => (
+                    <SelectItem key={role} value={role} className="rounded-xl">
+                        {t(`role.${role}`)}
+                    </SelectItem>
+                ))}
+            </SelectContent>
+        </Select>
+    );
+}
+
+function UserRow({ user, canWrite, isSelf }: { user: User; canWrite: boolean; isSelf: boolean }) {
+    const t = useTranslations('setting.users');
+    const updateUser = useUpdateUser();
+    const deleteUser = useDeleteUser();
+    const [pending, setPending] = useState<'password' | 'totp' | 'delete' | null>(null);
+    const [password, setPassword] = useState('');
+
+    const handleRole = (role: UserRole) => {
+        updateUser.mutate({ id: user.id, role }, {
+            onSuccess: () => toast.success(t('updated')),
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    const handleConfirm = () => {
+        if (pending === 'delete') {
+            deleteUser.mutate(user.id, {
+                onSuccess: () => toast.success(t('deleted')),
+                onError: (error) => toast.error(error.message),
+            });
+            return;
+        }
+        if (pending === 'totp') {
+            updateUser.mutate({ id: user.id, disable_totp: true }, {
+                onSuccess: () => {
+                    toast.success(t('totpDisabled'));
+                    setPending(null);
+                },
+                onError: (error) => toast.error(error.message),
+            });
+            return;
+        }
+        if (!password) {
+            toast.error(t('passwordEmpty'));
+            return;
+        }
+        updateUser.mutate({ id: user.id, password }, {
+            onSuccess: () => {
+                toast.success(t('passwordReset'));
+                setPending(null);
+                setPassword('');
+            },
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    return (
+        <div className="flex flex-col gap-2">
+            <div className="flex items-center justify-between gap-4">
+                <span className="text-sm font-medium truncate">
+                    {user.username}
+                    {isSelf && <span className="text-xs text-muted-foreground"> ({t('you')})</span>}
+                    {user.totp_enabled && <span className="text-xs text-muted-foreground"> · {t('totp')}</span>}
+                    {user.sso && <span className="text-xs text-muted-foreground"> · {t('sso')}</span>}
+                </span>
+                <div className="flex gap-1 shrink-0">
+                    <RoleSelect value={user.role} onChange={handleRole} disabled={!canWrite || isSelf || updateUser.isPending} />
+                    {canWrite && (
+                        <>
+                            <Button variant="ghost" size="icon" onClick={() => setPending('password')} title={t('resetPassword')}>
+                                <KeyRound className="h-4 w-4" />
+                            </Button>
+                            {user.totp_enabled && !isSelf && (
+                                <Button variant="ghost" size="icon" onClick={() => setPending('totp')} title={t('disableTotp')}>
+                                    <ShieldOff className="h-4 w-4" />
+                                </Button>
+                            )}
+                            <Button variant="ghost" size="icon" onClick={() => setPending('delete')} disabled={isSelf} title={t('delete')}>
+                                <Trash2 className="h-4 w-4" />
+                            </Button>
+                        </>
+                    )}
+                </div>
+            </div>
+            {pending && (
+                <div className="flex gap-1">
+                    {pending === 'password' && (
+                        <Input
+                            type="password"
+                            value={password}
+                            onChange={(e) => setPassword(e.target.value)}
+                            placeholder={t('newPassword')}
+                            className="flex-1 rounded-xl"
+                        />
+                    )}
+                    <Button variant="ghost" size="icon" onClick={() => setPending(null)} className={pending !== 'password' ? 'ml-auto' : ''}>
+                        <X className="h-4 w-4" />
+                    </Button>
+                    <Button
+                        variant={pending === 'password' ? 'default' : 'destructive'}
+                        size="sm"
+                        onClick={handleConfirm}
+                        disabled={updateUser.isPending || deleteUser.isPending}
+                        className="rounded-xl"
+                    >
+                        {pending === 'delete' ? t('confirmDelete') : pending === 'totp' ? t('disableTotp') : t('resetPassword')}
+                    </Button>
+                </div>
+            )}
+        </div>
+    );
+}
+
+export function SettingUsers() {
+    const t = useTranslations('setting.users');
+    const { data: users } = useUserList();
+    const currentID = useAuthStore((state) => state.user?.id);
+    const canWrite = usePermission('user:write');
+    const createUser = useCreateUser();
+
+    const [username, setUsername] = useState('');
+    const [password, setPassword] = useState('');
+    const [role, setRole] = useState<UserRole>('viewer');
+
+    const handleCreate = () => {
+        if (!username.trim() || !password) {
+            toast.error(t('empty'));
+            return;
+        }
+        createUser.mutate({ username: username.trim(), password, role }, {
+            onSuccess: () => {
+                toast.success(t('created'));
+                setUsername('');
+                setPassword('');
+            },
+            onError: (error) => toast.error(error.message),
+        });
+    };
+
+    return (
+        <div className="rounded-3xl border border-border bg-card p-6 custom-shadow space-y-5">
+            <h2 className="text-lg font-bold text-card-foreground flex items-center gap-2">
+                <Users className="h-5 w-5" />
+                {t('title')}
+            </h2>
+            {users?.map((user) => (
+                <UserRow key={user.id} user={user} canWrite={canWrite} isSelf={user.id === currentID} />
+            ))}
+            {canWrite && (
+                <div className="flex flex-col gap-2">
+                    <div className="flex gap-2">
+                        <Input
+                            value={username}
+                            onChange={(e) => setUsername(e.target.value)}
+                            placeholder={t('username')}
+                            className="flex-1 rounded-xl"
+                        />
+                        <RoleSelect value={role} onChange={setRole} />
+                    </div>
+                    <div className="flex gap-2">
+                        <Input
+                            type="password"
+                            value={password}
+                            onChange={(e) => setPassword(e.target.value)}
+                            placeholder={t('password')}
+                            className="flex-1 rounded-xl"
+                        />
+                        <Button onClick={handleCreate} disabled={createUser.isPending} className="rounded-xl">
+                            {t('create')}
+                        </Button>
+                    </div>
+                </div>
+            )}
+            <p className="text-xs text-muted-foreground">{t('hint')}</p>
+        </div>
+    );
+}
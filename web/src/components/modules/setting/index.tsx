@@ -1,5 +1,6 @@
 'use client';
 
+import type { ComponentType } from 'react';
 import { PageWrapper } from '@/components/common/PageWrapper';
 import { SettingAppearance } from './Appearance';
 import { SettingSystem } from './System';
@@ -10,41 +11,47 @@ import { SettingInfo } from './Info';
 import { SettingLLMSync } from './LLMSync';
 import { SettingLog } from './Log';
 import { SettingBackup } from './Backup';
+import { SettingScheduledBackup } from './ScheduledBackup';
 import { SettingSensitive } from './Sensitive';
+import { SettingGitOps } from './GitOps';
+import { SettingTask } from './Task';
+import { SettingUsers } from './Users';
+import { SettingSessions } from './Sessions';
+import { SettingTwoFactor } from './TwoFactor';
+import { SettingAccessTokens } from './AccessTokens';
+import { useAuthStore, type Permission } from '@/api/endpoints/user';
+
+// 每张设置卡片所需的权限，没有权限的卡片不显示
+const CARDS: { key: string; permission?: Permission; Card: ComponentType }[] = [
+    { key: 'setting-info', Card: SettingInfo },
+    { key: 'setting-appearance', Card: SettingAppearance },
+    { key: 'setting-account', Card: SettingAccount },
+    { key: 'setting-two-factor', Card: SettingTwoFactor },
+    { key: 'setting-sessions', Card: SettingSessions },
+    { key: 'setting-access-tokens', Card: SettingAccessTokens },
+    { key: 'setting-users', permission: 'user:read', Card: SettingUsers },
+    { key: 'setting-system', permission: 'setting:read', Card: SettingSystem },
+    { key: 'setting-log', permission: 'setting:read', Card: SettingLog },
+    { key: 'setting-apikey', permission: 'apikey:read', Card: SettingAPIKey },
+    { key: 'setting-llmprice', permission: 'setting:read', Card: SettingLLMPrice },
+    { key: 'setting-llmsync', permission: 'setting:read', Card: SettingLLMSync },
+    { key: 'setting-sensitive', permission: 'sensitive:read', Card: SettingSensitive },
+    { key: 'setting-gitops', permission: 'gitops:read', Card: SettingGitOps },
+    { key: 'setting-task', permission: 'task:read', Card: SettingTask },
+    { key: 'setting-backup', permission: 'backup:read', Card: SettingBackup },
+    { key: 'setting-scheduled-backup', permission: 'backup:read', Card: SettingScheduledBackup },
+];
 
 export function Setting() {
+    const permissions = useAuthStore((state) => state.user?.permissions);
+
     return (
         <PageWrapper className="columns-1 md:columns-2 gap-4 [&>div]:mb-4 [&>div]:break-inside-avoid">
-            <div>
-                <SettingInfo key="setting-info" />
-            </div>
-            <div>
-                <SettingAppearance key="setting-appearance" />
-            </div>
-            <div>
-                <SettingAccount key="setting-account" />
-            </div>
-            <div>
-                <SettingSystem key="setting-system" />
-            </div>
-            <div>
-                <SettingLog key="setting-log" />
-            </div>
-            <div>
-                <SettingAPIKey key="setting-apikey" />
-            </div>
-            <div>
-                <SettingLLMPrice key="setting-llmprice" />
-            </div>
-            <div>
-                <SettingLLMSync key="setting-llmsync" />
-            </div>
-            <div>
-                <SettingSensitive key="setting-sensitive" />
-            </div>
-            <div>
-                <SettingBackup key="setting-backup" />
-            </div>
+            {CARDS.filter(({ permission }) => !permission || !permissions || permissions.includes(permission)).map(({ key, Card }) => (
+                <div key={key}>
+                    <Card />
+                </div>
+            ))}
         </PageWrapper>
     );
 }
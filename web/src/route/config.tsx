@@ -1,6 +1,7 @@
 import { lazyWithPreload } from './lazy-with-preload';
 import { lazy, ComponentType } from 'react';
 import type { LucideIcon } from 'lucide-react';
+import type { Permission } from '@/api/endpoints/user';
 import { Home, Radio, Sparkles, FolderTree, Settings, Logs } from 'lucide-react';
 
 export type LazyComponent = ReturnType<typeof lazy> & {
@@ -12,6 +13,7 @@ export interface RouteConfig {
     label: string;
     icon: LucideIcon;
     component: LazyComponent;
+    permission: Permission; // 查看页面所需的权限
 }
 
 const Home_Module = lazyWithPreload(() => import('@/components/modules/home').then(m => ({ default: m.Home })));
@@ -22,12 +24,12 @@ const Log_Module = lazyWithPreload(() => import('@/components/modules/log').then
 const Setting_Module = lazyWithPreload(() => import('@/components/modules/setting').then(m => ({ default: m.Setting })));
 
 export const ROUTES: RouteConfig[] = [
-    { id: 'home', label: 'Home', icon: Home, component: Home_Module },
-    { id: 'channel', label: 'Channel', icon: Radio, component: Channel_Module },
-    { id: 'group', label: 'Group', icon: FolderTree, component: Group_Module },
-    { id: 'model', label: 'Model', icon: Sparkles, component: Model_Module },
-    { id: 'log', label: 'Log', icon: Logs, component: Log_Module },
-    { id: 'setting', label: 'Setting', icon: Settings, component: Setting_Module },
+    { id: 'home', label: 'Home', icon: Home, component: Home_Module, permission: 'stats:read' },
+    { id: 'channel', label: 'Channel', icon: Radio, component: Channel_Module, permission: 'channel:read' },
+    { id: 'group', label: 'Group', icon: FolderTree, component: Group_Module, permission: 'group:read' },
+    { id: 'model', label: 'Model', icon: Sparkles, component: Model_Module, permission: 'model:read' },
+    { id: 'log', label: 'Log', icon: Logs, component: Log_Module, permission: 'log:read' },
+    { id: 'setting', label: 'Setting', icon: Settings, component: Setting_Module, permission: 'authenticated' },
 ];
 
 export const CONTENT_MAP = ROUTES.reduce((acc, route) => {